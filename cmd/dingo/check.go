@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/check"
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+func checkCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "check [packages | files]",
+		Short: "Type-check Dingo packages without writing files",
+		Long: `Check transpiles and type-checks Dingo packages entirely in memory.
+
+Every .dingo file runs through the preprocessor and code generator, including
+match exhaustiveness checking, and the generated Go of each package is
+type-checked together with its hand-written .go files. All problems are
+reported at their .dingo positions. No .go or .go.map files are written, which
+makes check suitable for CI and pre-commit hooks.
+
+Diagnostics carry a code (e.g. D0102 for a non-exhaustive match) and, where
+known, a range, related locations and suggested fixes. --format selects how
+they are printed:
+  text     one file:line:col: message line each (default)
+  snippet  the source lines with the range underlined, notes and help
+  json     a JSON array on stdout, for editors and CI annotations
+
+Exits with status 1 when any error is found.
+
+Examples:
+  dingo check                      # Check every package (same as ./...)
+  dingo check ./pkg/...
+  dingo check main.dingo util.dingo
+  dingo check --format=json ./...`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCheck(args, format)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "text", "Diagnostic output format: text, snippet or json")
+	return cmd
+}
+
+func runCheck(args []string, format string) error {
+	if !slices.Contains(diagnosticFormats, format) {
+		return fmt.Errorf("unknown format %q (formats: %s)", format, strings.Join(diagnosticFormats, ", "))
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		// Non-fatal: fall back to defaults and warn
+		cfg = config.DefaultConfig()
+		fmt.Fprintf(os.Stderr, "Warning: config load failed, using defaults: %v\n", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root, err := build.FindWorkspaceRoot(cwd)
+	if err != nil {
+		return err
+	}
+
+	packages, err := resolveCheckPackages(root, args)
+	if err != nil {
+		return err
+	}
+	if len(packages) == 0 {
+		return fmt.Errorf("no .dingo packages found")
+	}
+
+	checker := check.New(root, transpiler.NewWithConfig(cfg))
+	diags, err := checker.CheckPackages(packages)
+	if err != nil {
+		return err
+	}
+
+	// JSON goes to stdout so it can be piped; human formats go to stderr
+	out := os.Stderr
+	if format == "json" {
+		out = os.Stdout
+	}
+	if err := printDiagnostics(out, diags, format, cwd); err != nil {
+		return err
+	}
+
+	errorCount := 0
+	for _, d := range diags {
+		if d.Severity == diagnostic.SeverityError {
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		// Diagnostics are already printed; exit without cobra's error output
+		os.Exit(1)
+	}
+	return nil
+}
+
+// resolveCheckPackages turns arguments into packages. Package patterns are
+// discovered under root; .dingo files form ad-hoc packages per directory,
+// like go vet file.go.
+func resolveCheckPackages(root string, args []string) ([]build.Package, error) {
+	var patterns []string
+	adHoc := make(map[string]*build.Package)
+	var order []string
+
+	for _, arg := range args {
+		if build.IsPackagePattern(arg) {
+			patterns = append(patterns, arg)
+			continue
+		}
+		if !strings.HasSuffix(arg, ".dingo") {
+			return nil, fmt.Errorf("%s: not a .dingo file or package", arg)
+		}
+
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return nil, err
+		}
+		relFile, err := filepath.Rel(root, abs)
+		if err != nil || strings.HasPrefix(relFile, "..") {
+			return nil, fmt.Errorf("%s is outside workspace %s", arg, root)
+		}
+		relFile = filepath.ToSlash(relFile)
+
+		dir := filepath.ToSlash(filepath.Dir(relFile))
+		pkg, ok := adHoc[dir]
+		if !ok {
+			pkg = &build.Package{Path: dir, Name: filepath.Base(filepath.Dir(abs))}
+			adHoc[dir] = pkg
+			order = append(order, dir)
+		}
+		pkg.DingoFiles = append(pkg.DingoFiles, relFile)
+	}
+
+	var packages []build.Package
+	if len(patterns) > 0 || len(adHoc) == 0 {
+		discovered, err := build.DiscoverPackages(root, patterns)
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, discovered...)
+	}
+	for _, dir := range order {
+		packages = append(packages, *adHoc[dir])
+	}
+	return packages, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+)
+
+func cleanCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "clean [flags] [packages]",
+		Short: "Remove generated Go files and build caches",
+		Long: `Clean removes what dingo build generated: .go files and their .go.map
+source maps, the per-package .dingo-cache.json files, and the workspace
+build cache (.dingo-cache/).
+
+A .go file is only removed when it has a sibling .dingo source AND starts
+with the header dingo build writes ("// Code generated by dingo from
+x.dingo. DO NOT EDIT."). Hand-written Go files, including ones in mixed
+packages, are never touched; .go files next to a .dingo source without the
+header are reported and kept.
+
+Examples:
+  dingo clean                      # Clean every package (same as ./...)
+  dingo clean -n ./pkg/...         # List what would be removed`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runClean(args, dryRun)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "List files that would be removed without deleting them")
+
+	return cmd
+}
+
+func runClean(args []string, dryRun bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root, err := build.FindWorkspaceRoot(cwd)
+	if err != nil {
+		return err
+	}
+
+	packages, err := build.DiscoverPackages(root, args)
+	if err != nil {
+		return err
+	}
+	artifacts, err := build.FindArtifacts(root, packages)
+	if err != nil {
+		return err
+	}
+
+	display := func(path string) string {
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+		return path
+	}
+
+	for _, path := range artifacts.Kept {
+		fmt.Fprintf(os.Stderr, "%s: kept, no dingo generated code header\n", display(path))
+	}
+
+	if dryRun {
+		for _, path := range append(append([]string{}, artifacts.Files...), artifacts.Caches...) {
+			fmt.Println(display(path))
+		}
+		return nil
+	}
+
+	if err := artifacts.Remove(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %d generated file(s) and %d cache(s)\n", len(artifacts.Files), len(artifacts.Caches))
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// diagnosticFormats are the values of --format
+var diagnosticFormats = []string{"text", "snippet", "json"}
+
+// printDiagnostics writes diags to w in format: one line each (text), with
+// source snippets (snippet), or as a JSON array (json). Paths under cwd are
+// shown relative to it.
+func printDiagnostics(w io.Writer, diags []diagnostic.Diagnostic, format, cwd string) error {
+	sources := make(map[string][]byte)
+	relative := make([]diagnostic.Diagnostic, len(diags))
+	for i, d := range diags {
+		if format == "snippet" && d.Pos.Filename != "" {
+			if _, ok := sources[d.Pos.Filename]; !ok {
+				src, _ := os.ReadFile(d.Pos.Filename) // Without a source only the header is shown
+				sources[d.Pos.Filename] = src
+			}
+		}
+		relative[i] = relativeDiagnostic(d, cwd)
+	}
+
+	switch format {
+	case "text":
+		for _, d := range relative {
+			fmt.Fprintln(w, d.String())
+		}
+	case "snippet":
+		for i, d := range relative {
+			fmt.Fprintln(w, d.Snippet(sources[diags[i].Pos.Filename]))
+		}
+	case "json":
+		if relative == nil {
+			relative = []diagnostic.Diagnostic{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(relative)
+	default:
+		return fmt.Errorf("unknown format %q (formats: %s)", format, strings.Join(diagnosticFormats, ", "))
+	}
+	return nil
+}
+
+// relativeDiagnostic rewrites the file names of d under cwd relative to it
+func relativeDiagnostic(d diagnostic.Diagnostic, cwd string) diagnostic.Diagnostic {
+	rel := func(name string) string {
+		if r, err := filepath.Rel(cwd, name); err == nil && filepath.IsAbs(name) && !strings.HasPrefix(r, "..") {
+			return r
+		}
+		return name
+	}
+	d.Pos.Filename = rel(d.Pos.Filename)
+	d.End.Filename = rel(d.End.Filename)
+	labels := make([]diagnostic.Label, len(d.Labels))
+	for i, l := range d.Labels {
+		l.Pos.Filename, l.End.Filename = rel(l.Pos.Filename), rel(l.End.Filename)
+		labels[i] = l
+	}
+	d.Labels = labels
+	fixes := make([]diagnostic.Fix, len(d.Fixes))
+	for i, f := range d.Fixes {
+		edits := make([]diagnostic.Edit, len(f.Edits))
+		for j, e := range f.Edits {
+			e.Pos.Filename, e.End.Filename = rel(e.Pos.Filename), rel(e.End.Filename)
+			edits[j] = e
+		}
+		fixes[i] = diagnostic.Fix{Message: f.Message, Edits: edits}
+	}
+	d.Fixes = fixes
+	return d
+}
+
+// errorLines lists the problems behind a build error, one line each: every
+// diagnostic of a failed transpilation, otherwise the error itself
+func errorLines(err error, cwd string) []string {
+	var transpileErr *transpiler.Error
+	if !errors.As(err, &transpileErr) {
+		return []string{err.Error()}
+	}
+	lines := make([]string, len(transpileErr.Diagnostics))
+	for i, d := range transpileErr.Diagnostics {
+		lines[i] = relativeDiagnostic(d, cwd).String()
+	}
+	return lines
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/textdiff"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+func expandCmd() *cobra.Command {
+	var (
+		stage string
+		all   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "expand [flags] <file.dingo>",
+		Short: "Show the source after each transpiler stage",
+		Long: `Expand runs the transpiler on a .dingo file in memory and shows what every
+stage did to the source:
+
+  source            the .dingo input
+  generic_syntax …  each preprocessor (rust_match, lambda, error_propagation, …)
+  imports           import injection, when imports were added
+  plugin:<name>     each AST plugin's Transform
+  transform         the plugin pipeline result with injected declarations
+  post_ast          after __INFER__ placeholder resolution
+  output            the final Go code, with DINGO:GENERATED markers
+  line_directives   with [sourcemaps] line_directives, the //line directives
+
+Each stage is shown as a diff against the previous one, followed by the
+transformation metadata it emitted. Without flags the stages are listed.
+
+Examples:
+  dingo expand main.dingo                            # List stages
+  dingo expand --all main.dingo                      # Diff every stage
+  dingo expand --stage=error_propagation main.dingo  # Source and diff of one stage`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExpand(args[0], stage, all)
+		},
+	}
+
+	cmd.Flags().StringVar(&stage, "stage", "", "Show the full source and diff of one stage")
+	cmd.Flags().BoolVar(&all, "all", false, "Show the diff of every stage")
+
+	return cmd
+}
+
+func runExpand(path, stageName string, all bool) error {
+	if stageName != "" && all {
+		return fmt.Errorf("--stage and --all are mutually exclusive")
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		// Non-fatal: fall back to defaults and warn
+		cfg = config.DefaultConfig()
+		fmt.Fprintf(os.Stderr, "Warning: config load failed, using defaults: %v\n", err)
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// On failure the stages up to the failing one are still worth showing
+	stages, transpileErr := transpiler.NewWithConfig(cfg).Expand(path, src)
+
+	switch {
+	case all:
+		for i := 1; i < len(stages); i++ {
+			printStage(stages[i-1], stages[i], false)
+		}
+	case stageName != "":
+		found := false
+		for i, s := range stages {
+			if s.Name != stageName {
+				continue
+			}
+			prev := transpiler.Stage{Name: s.Name}
+			if i > 0 {
+				prev = stages[i-1]
+			}
+			printStage(prev, s, true)
+			found = true
+			break
+		}
+		if !found && transpileErr == nil {
+			return fmt.Errorf("unknown stage %q (stages: %s)", stageName, strings.Join(stageNames(stages), ", "))
+		}
+	default:
+		for i, s := range stages {
+			changed := ""
+			if i > 0 && string(s.Source) == string(stages[i-1].Source) {
+				changed = " (unchanged)"
+			}
+			metadata := ""
+			if len(s.Metadata) > 0 {
+				metadata = fmt.Sprintf(" [%d transformation(s)]", len(s.Metadata))
+			}
+			fmt.Printf("%2d  %s%s%s\n", i, s.Name, metadata, changed)
+		}
+	}
+
+	if transpileErr != nil {
+		return fmt.Errorf("after stage %s: %w", stages[len(stages)-1].Name, transpileErr)
+	}
+	return nil
+}
+
+// printStage prints a stage header, the stage's metadata and its diff
+// against prev; full also prints the complete source of the stage
+func printStage(prev, s transpiler.Stage, full bool) {
+	fmt.Printf("=== %s ===\n", s.Name)
+	for _, m := range s.Metadata {
+		fmt.Printf("  %s at %d:%d %q", m.Type, m.OriginalLine, m.OriginalColumn, m.OriginalText)
+		if m.GeneratedMarker != "" {
+			fmt.Printf(" → %s", m.GeneratedMarker)
+		}
+		fmt.Println()
+	}
+	if full {
+		fmt.Print(string(s.Source))
+		if len(s.Source) > 0 && s.Source[len(s.Source)-1] != '\n' {
+			fmt.Println()
+		}
+		fmt.Printf("--- diff from %s ---\n", prev.Name)
+	}
+
+	diff := textdiff.Unified(prev.Name, s.Name, string(prev.Source), string(s.Source))
+	if diff == "" {
+		fmt.Println("(no changes)")
+	} else {
+		fmt.Print(diff)
+	}
+	fmt.Println()
+}
+
+func stageNames(stages []transpiler.Stage) []string {
+	names := make([]string, len(stages))
+	for i, s := range stages {
+		names[i] = s.Name
+	}
+	return names
+}
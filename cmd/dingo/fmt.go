@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/formatter"
+	"github.com/MadAppGang/dingo/pkg/textdiff"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// fmtOptions controls what dingo fmt does with formatted output
+type fmtOptions struct {
+	write bool // Overwrite source files
+	list  bool // List files whose formatting differs
+	diff  bool // Print unified diffs
+}
+
+func fmtCmd() *cobra.Command {
+	var opts fmtOptions
+
+	cmd := &cobra.Command{
+		Use:   "fmt [flags] [files | packages]",
+		Short: "Format Dingo source files",
+		Long: `Fmt formats Dingo source files (.dingo) in the canonical style.
+
+The formatter re-indents with tabs, spaces operators, aligns struct fields,
+match arms, enum variants and trailing comments, and collapses blank lines.
+It is idempotent and never changes semantics: every file is transpiled before
+and after formatting, and the result is rejected if the generated Go differs.
+
+Without flags, the formatted source is written to standard output.
+With no arguments, every package in the current directory tree is formatted.
+
+Examples:
+  dingo fmt main.dingo             # Print formatted source
+  dingo fmt -w ./...               # Format the whole workspace in place
+  dingo fmt -l ./...               # List files that need formatting
+  dingo fmt -d ./pkg/foo           # Show what would change`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runFmt(args, opts)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.write, "write", "w", false, "Write result to source files instead of stdout")
+	cmd.Flags().BoolVarP(&opts.list, "list", "l", false, "List files whose formatting differs")
+	cmd.Flags().BoolVarP(&opts.diff, "diff", "d", false, "Display diffs instead of rewriting files")
+
+	return cmd
+}
+
+func runFmt(args []string, opts fmtOptions) error {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		// Non-fatal: fall back to defaults and warn
+		cfg = config.DefaultConfig()
+		fmt.Fprintf(os.Stderr, "Warning: config load failed, using defaults: %v\n", err)
+	}
+
+	files, err := resolveFmtFiles(args)
+	if err != nil {
+		return err
+	}
+
+	t := transpiler.NewWithConfig(cfg)
+	failed := 0
+	for _, file := range files {
+		if err := formatFile(t, file, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to format %d file(s)", failed)
+	}
+	return nil
+}
+
+// resolveFmtFiles expands arguments into .dingo files. Arguments are either
+// .dingo files or package patterns (./..., ./pkg/foo).
+func resolveFmtFiles(args []string) ([]string, error) {
+	if len(args) == 0 {
+		args = []string{"./..."}
+	}
+
+	var files []string
+	var patterns []string
+	for _, arg := range args {
+		if build.IsPackagePattern(arg) {
+			patterns = append(patterns, arg)
+		} else {
+			files = append(files, arg)
+		}
+	}
+	if len(patterns) == 0 {
+		return files, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	root, err := build.FindWorkspaceRoot(cwd)
+	if err != nil {
+		return nil, err
+	}
+	packages, err := build.DiscoverPackages(root, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range packages {
+		for _, rel := range pkg.DingoFiles {
+			path := filepath.Join(root, filepath.FromSlash(rel))
+			if display, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(display, "..") {
+				path = display
+			}
+			files = append(files, path)
+		}
+	}
+
+	return files, nil
+}
+
+// formatFile formats a single file according to opts
+func formatFile(t *transpiler.Transpiler, path string, opts fmtOptions) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	formatted, err := formatter.Format(src)
+	if err != nil {
+		return err
+	}
+
+	changed := !bytes.Equal(src, formatted)
+	if changed {
+		if err := formatter.Verify(t, path, src, formatted); err != nil {
+			return err
+		}
+	}
+
+	if opts.list && changed {
+		fmt.Println(path)
+	}
+	if opts.diff && changed {
+		fmt.Print(textdiff.Unified(path+".orig", path, string(src), string(formatted)))
+	}
+	if opts.write && changed {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if !opts.list && !opts.diff && !opts.write {
+		_, err := os.Stdout.Write(formatted)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/config"
+)
+
+// initOptions holds the dingo.toml settings chosen with dingo init flags
+type initOptions struct {
+	lambdaStyle     string
+	errorSyntax     string
+	sourceMapFormat string
+}
+
+func initCmd() *cobra.Command {
+	defaults := config.DefaultConfig()
+	opts := initOptions{
+		lambdaStyle:     defaults.Features.LambdaStyle,
+		errorSyntax:     string(defaults.Features.ErrorPropagationSyntax),
+		sourceMapFormat: string(defaults.SourceMap.Format),
+	}
+
+	cmd := &cobra.Command{
+		Use:   "init [module-path]",
+		Short: "Create a new Dingo project in the current directory",
+		Long: `Init sets up a Dingo project in the current directory:
+
+  go.mod       created with go mod init when missing
+  dingo.toml   default configuration with every setting documented
+  main.dingo   a starter program
+  .gitignore   ignores the .go file generated from each .dingo source,
+               .go.map source maps and the build caches
+
+Existing files are never overwritten; missing .gitignore entries are
+appended, so running init again lists the .go files of new .dingo
+sources. Hand-written Go files stay tracked. The module path defaults to
+the directory name.
+
+Examples:
+  dingo init
+  dingo init github.com/me/app
+  dingo init --lambda-style rust --sourcemap-format separate`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			modulePath := ""
+			if len(args) == 1 {
+				modulePath = args[0]
+			}
+			return runInit(modulePath, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.lambdaStyle, "lambda-style", opts.lambdaStyle, "Lambda syntax: typescript or rust")
+	cmd.Flags().StringVar(&opts.errorSyntax, "error-syntax", opts.errorSyntax, "Error propagation syntax: question, bang or try")
+	cmd.Flags().StringVar(&opts.sourceMapFormat, "sourcemap-format", opts.sourceMapFormat, "Source map format: inline, separate, both or none")
+
+	return cmd
+}
+
+func runInit(modulePath string, opts initOptions) error {
+	cfg := config.DefaultConfig()
+	cfg.Features.LambdaStyle = opts.lambdaStyle
+	cfg.Features.ErrorPropagationSyntax = config.SyntaxStyle(opts.errorSyntax)
+	cfg.SourceMap.Format = config.SourceMapFormat(opts.sourceMapFormat)
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if modulePath == "" {
+		modulePath = filepath.Base(dir)
+	}
+
+	// go.mod
+	if exists(filepath.Join(dir, "go.mod")) {
+		fmt.Println("  go.mod exists, keeping it")
+	} else {
+		goCmd := exec.Command("go", "mod", "init", modulePath)
+		goCmd.Dir = dir
+		if output, err := goCmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("go mod init failed: %v\n%s", err, output)
+		}
+		fmt.Printf("  created go.mod (module %s)\n", modulePath)
+	}
+
+	// dingo.toml
+	var toml bytes.Buffer
+	if err := cfg.WriteTOML(&toml); err != nil {
+		return err
+	}
+	if err := createFile(filepath.Join(dir, "dingo.toml"), toml.Bytes()); err != nil {
+		return err
+	}
+
+	// main.dingo
+	if err := createFile(filepath.Join(dir, "main.dingo"), []byte(starterSource(cfg))); err != nil {
+		return err
+	}
+
+	// .gitignore, after main.dingo so that main.go is listed
+	generated, err := generatedGoFiles(dir)
+	if err != nil {
+		return err
+	}
+	if err := updateGitignore(filepath.Join(dir, ".gitignore"), generated); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("Run your program with: dingo run main.dingo")
+	return nil
+}
+
+// createFile writes a new file and reports it; existing files are kept
+func createFile(path string, content []byte) error {
+	name := filepath.Base(path)
+	if exists(path) {
+		fmt.Printf("  %s exists, keeping it\n", name)
+		return nil
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("  created %s\n", name)
+	return nil
+}
+
+// gitignoreEntries are the patterns dingo init always ensures in .gitignore.
+// Generated .go files are listed one by one (see generatedGoFiles): a
+// pattern cannot tell them from hand-written Go in mixed packages.
+var gitignoreEntries = []string{
+	"*.go.map",
+	".dingo-cache*",
+}
+
+const gitignoreHeader = `# Generated by dingo build: source maps, build caches and the .go file of
+# each .dingo source. Hand-written Go is tracked; add the .go file of a new
+# .dingo source here, or run dingo init again.
+`
+
+// generatedGoFiles returns the .gitignore entries, anchored at dir, of the
+// .go files dingo build generates from the .dingo sources under dir
+func generatedGoFiles(dir string) ([]string, error) {
+	packages, err := build.DiscoverPackages(dir, []string{"./..."})
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	for _, pkg := range packages {
+		for _, rel := range pkg.DingoFiles {
+			entries = append(entries, "/"+strings.TrimSuffix(rel, ".dingo")+".go")
+		}
+	}
+	return entries, nil
+}
+
+// updateGitignore creates .gitignore or appends the entries it lacks, the
+// fixed patterns followed by the generated .go files
+func updateGitignore(path string, generated []string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	present := make(map[string]bool)
+	for _, line := range strings.Split(string(existing), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+	var missing []string
+	for _, entry := range append(append([]string{}, gitignoreEntries...), generated...) {
+		if !present[entry] {
+			missing = append(missing, entry)
+		}
+	}
+	if len(missing) == 0 {
+		fmt.Println("  .gitignore is up to date")
+		return nil
+	}
+
+	var buf bytes.Buffer
+	buf.Write(existing)
+	if len(existing) > 0 {
+		if !bytes.HasSuffix(existing, []byte("\n")) {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(gitignoreHeader)
+	buf.WriteString(strings.Join(missing, "\n") + "\n")
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		fmt.Println("  updated .gitignore")
+	} else {
+		fmt.Println("  created .gitignore")
+	}
+	return nil
+}
+
+// starterSource returns main.dingo written in the configured syntax
+func starterSource(cfg *config.Config) string {
+	lambda := `(name: string): string => { return "Hello, " + name + "!" }`
+	if cfg.Features.LambdaStyle == "rust" {
+		lambda = `|name: string| -> string { return "Hello, " + name + "!" }`
+	}
+
+	var src strings.Builder
+	src.WriteString("package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n\t\"strconv\"\n)\n\n")
+
+	// Only the ? operator is implemented by the transpiler; for the other
+	// syntaxes the starter handles the error explicitly
+	if cfg.Features.ErrorPropagationSyntax == config.SyntaxQuestion {
+		src.WriteString(`// parseCount converts a command-line argument. The ? operator returns the
+// error to the caller.
+func parseCount(arg string) (int, error) {
+	let n = strconv.Atoi(arg)?
+	return n, nil
+}
+`)
+	} else {
+		src.WriteString(`// parseCount converts a command-line argument
+func parseCount(arg string) (int, error) {
+	return strconv.Atoi(arg)
+}
+`)
+	}
+
+	src.WriteString(`
+func main() {
+	greet := ` + lambda + `
+	fmt.Println(greet("Dingo"))
+
+	if len(os.Args) > 1 {
+		count, err := parseCount(os.Args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println("count:", count)
+	}
+}
+`)
+	return src.String()
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -2,22 +2,20 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"go/token"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/MadAppGang/dingo/pkg/build"
 	"github.com/MadAppGang/dingo/pkg/config"
-	"github.com/MadAppGang/dingo/pkg/generator"
-	"github.com/MadAppGang/dingo/pkg/parser"
-	"github.com/MadAppGang/dingo/pkg/plugin"
-	"github.com/MadAppGang/dingo/pkg/plugin/builtin"
-	"github.com/MadAppGang/dingo/pkg/preprocessor"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 	"github.com/MadAppGang/dingo/pkg/sourcemap"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
 	"github.com/MadAppGang/dingo/pkg/ui"
 )
 
@@ -54,8 +52,16 @@ and other quality-of-life features while maintaining 100% Go ecosystem compatibi
 		},
 	})
 
+	rootCmd.AddCommand(initCmd())
 	rootCmd.AddCommand(buildCmd())
 	rootCmd.AddCommand(runCmd())
+	rootCmd.AddCommand(fmtCmd())
+	rootCmd.AddCommand(testCmd())
+	rootCmd.AddCommand(checkCmd())
+	rootCmd.AddCommand(migrateCmd())
+	rootCmd.AddCommand(cleanCmd())
+	rootCmd.AddCommand(expandCmd())
+	rootCmd.AddCommand(traceCmd())
 	rootCmd.AddCommand(versionCmd())
 
 	if err := rootCmd.Execute(); err != nil {
@@ -69,10 +75,12 @@ func buildCmd() *cobra.Command {
 		output               string
 		watch                bool
 		multiValueReturnMode string
+		sourceMapFormat      string
+		wsOpts               = build.BuildOptions{}
 	)
 
 	cmd := &cobra.Command{
-		Use:   "build [file.dingo]",
+		Use:   "build [file.dingo | packages]",
 		Short: "Transpile Dingo source files to Go",
 		Long: `Build command transpiles Dingo source files (.dingo) to Go source files (.go).
 
@@ -81,14 +89,23 @@ The transpiler:
 2. Transforms Dingo-specific features to Go equivalents
 3. Generates idiomatic Go code with source maps
 
+Packages are built in dependency order. Independent packages at the same
+dependency level are transpiled in parallel. With no arguments, every package
+in the current directory tree is built (same as ./...).
+
 Example:
   dingo build hello.dingo          # Generates hello.go
   dingo build -o output.go main.dingo
   dingo build *.dingo              # Build all .dingo files
+  dingo build ./...                # Build every package in the workspace
+  dingo build ./pkg/foo            # Build a single package
   dingo build --multi-value-return=single file.dingo  # Restrict to (T, error) only`,
-		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runBuild(args, output, watch, multiValueReturnMode)
+			overrides := &config.Config{SourceMap: config.SourceMapConfig{Format: config.SourceMapFormat(sourceMapFormat)}}
+			if len(args) == 0 || build.IsPackagePattern(args[0]) {
+				return runWorkspaceBuild(args, wsOpts, watch, overrides)
+			}
+			return runBuild(args, output, watch, multiValueReturnMode, overrides)
 		},
 	}
 
@@ -96,12 +113,22 @@ Example:
 	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Watch for file changes and rebuild")
 	cmd.Flags().StringVar(&multiValueReturnMode, "multi-value-return", "full",
 		"Multi-value return propagation mode: 'full' (default, supports (A,B,error)) or 'single' (restricts to (T,error))")
+	cmd.Flags().StringVar(&sourceMapFormat, "sourcemap-format", "",
+		"Source map format: 'inline', 'separate', 'both' or 'none' (default: [sourcemaps] format of dingo.toml)")
+	cmd.Flags().BoolVar(&wsOpts.Parallel, "parallel", true, "Build independent packages in parallel")
+	cmd.Flags().BoolVar(&wsOpts.Incremental, "incremental", true, "Only rebuild changed files")
+	cmd.Flags().IntVar(&wsOpts.Jobs, "jobs", 4, "Number of parallel jobs")
+	cmd.Flags().BoolVarP(&wsOpts.Verbose, "verbose", "v", false, "Show detailed build output")
+	cmd.Flags().BoolVar(&wsOpts.Clean, "clean", false, "Discard the build cache and rebuild everything")
 
 	return cmd
 }
 
 func runCmd() *cobra.Command {
-	var multiValueReturnMode string
+	var (
+		multiValueReturnMode string
+		watch                bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "run [file.dingo] [-- args...]",
@@ -119,7 +146,8 @@ Examples:
   dingo run hello.dingo
   dingo run main.dingo -- arg1 arg2 arg3
   dingo run server.dingo -- --port 8080
-  dingo run --multi-value-return=single file.dingo`,
+  dingo run --multi-value-return=single file.dingo
+  dingo run --watch server.dingo   # Restart the program on every save`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			inputFile := args[0]
@@ -130,12 +158,16 @@ Examples:
 				programArgs = args[1:]
 			}
 
+			if watch {
+				return runDingoFileWatch(inputFile, programArgs)
+			}
 			return runDingoFile(inputFile, programArgs, multiValueReturnMode)
 		},
 	}
 
 	cmd.Flags().StringVar(&multiValueReturnMode, "multi-value-return", "full",
 		"Multi-value return propagation mode: 'full' (default, supports (A,B,error)) or 'single' (restricts to (T,error))")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Rebuild and restart the program when source files change")
 
 	return cmd
 }
@@ -150,14 +182,15 @@ func versionCmd() *cobra.Command {
 	}
 }
 
-func runBuild(files []string, output string, watch bool, _ string) error {
+func runBuild(files []string, output string, watch bool, _ string, overrides *config.Config) error {
 	// Load main Dingo configuration (C1: Config Integration)
 	//
 	// Priority order:
-	// 1. dingo.toml in current directory
-	// 2. ~/.dingo/config.toml
-	// 3. Built-in defaults
-	cfg, err := config.Load(nil)
+	// 1. CLI flags (overrides)
+	// 2. dingo.toml in current directory
+	// 3. ~/.dingo/config.toml
+	// 4. Built-in defaults
+	cfg, err := config.Load(overrides)
 	if err != nil {
 		// Non-fatal: fall back to defaults and warn
 		cfg = config.DefaultConfig()
@@ -173,32 +206,47 @@ func runBuild(files []string, output string, watch bool, _ string) error {
 	// Print build start
 	buildUI.PrintBuildStart(len(files))
 
-	// Build each file
-	success := true
-	var lastError error
+	// Build each file, reporting every failure rather than the first
+	err = buildFiles(files, output, buildUI, cfg, nil)
+
+	if watch {
+		return watchFiles(files, output, cfg)
+	}
+
+	return err
+}
 
+// buildFiles builds each file, carrying on past failures, then lists every
+// error and prints the summary. onBuilt, when set, is called for each file
+// built successfully.
+func buildFiles(files []string, output string, buildUI *ui.BuildOutput, cfg *config.Config, onBuilt func(file string)) error {
+	cwd, _ := os.Getwd()
+	var errs []string
+	failed := 0
 	for _, file := range files {
-		if err := buildFile(file, output, buildUI, cfg); err != nil {
-			success = false
-			lastError = err
-			buildUI.PrintError(err.Error())
-			break
+		err := buildFile(file, output, buildUI, cfg)
+		if err == nil {
+			if onBuilt != nil {
+				onBuilt(file)
+			}
+			continue
+		}
+		failed++
+		var transpileErr *transpiler.Error
+		if !errors.As(err, &transpileErr) {
+			buildUI.PrintError(err.Error()) // Transpile errors were shown as snippets
 		}
+		errs = append(errs, errorLines(err, cwd)...)
 	}
 
-	// Print summary
-	if success {
+	if failed == 0 {
 		buildUI.PrintSummary(true, "")
-		if watch {
-			fmt.Println()
-			buildUI.PrintInfo("Watch mode not yet implemented")
-		}
-	} else {
-		buildUI.PrintSummary(false, lastError.Error())
-		return lastError
+		return nil
 	}
-
-	return nil
+	buildUI.PrintErrorList(errs)
+	err := fmt.Errorf("%d of %d files failed to build", failed, len(files))
+	buildUI.PrintSummary(false, err.Error())
+	return err
 }
 
 func buildFile(inputPath, outputPath string, buildUI *ui.BuildOutput, cfg *config.Config) error {
@@ -220,161 +268,76 @@ func buildFile(inputPath, outputPath string, buildUI *ui.BuildOutput, cfg *confi
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Step 2: Preprocess (with main config + package context for unqualified imports)
-	prepStart := time.Now()
-	var goSource string
-	var metadata []preprocessor.TransformMetadata // Phase 3: Collect metadata for PostASTGenerator
-	var prepDuration time.Duration
-
-	// Get package directory for cache
-	pkgDir := filepath.Dir(inputPath)
-
-	// For single-file builds, create a simple cache for just this file
-	// (Full package scanning can fail if other files have experimental syntax)
-	cache := preprocessor.NewFunctionExclusionCache(pkgDir)
-	err = cache.ScanPackage([]string{inputPath}) // Only scan the file being built
-	if err != nil {
-		// Fall back to no cache if scanning fails (e.g., syntax errors in .dingo file)
-		prep := preprocessor.NewWithMainConfig(src, cfg)
-		var legacyMap *preprocessor.SourceMap
-		goSource, legacyMap, metadata, err = prep.ProcessWithMetadata()
-		_ = legacyMap // Discard legacy map - Phase 3 uses PostASTGenerator
-		prepDuration = time.Since(prepStart)
-		if err != nil {
-			buildUI.PrintStep(ui.Step{
-				Name:     "Preprocess",
-				Status:   ui.StepError,
-				Duration: prepDuration,
-			})
-			return fmt.Errorf("preprocessing error: %w", err)
-		}
-	} else {
-		// Cache scan successful, use preprocessor with unqualified import inference
-		prep := preprocessor.NewWithCache(src, cache)
-		var legacyMap *preprocessor.SourceMap
-		goSource, legacyMap, metadata, err = prep.ProcessWithMetadata()
-		_ = legacyMap // Discard legacy map - Phase 3 uses PostASTGenerator
-		prepDuration = time.Since(prepStart)
-		if err != nil {
-			buildUI.PrintStep(ui.Step{
-				Name:     "Preprocess",
-				Status:   ui.StepError,
-				Duration: prepDuration,
-			})
-			return fmt.Errorf("preprocessing error: %w", err)
-		}
-	}
-
-	buildUI.PrintStep(ui.Step{
-		Name:     "Preprocess",
-		Status:   ui.StepSuccess,
-		Duration: prepDuration,
+	// Steps 2-4: Preprocess, parse and generate in memory, with the other
+	// files of the package for type checking. When the package has several
+	// .dingo files, injected types go to its dingo_types.go so that they can
+	// share them; a single file declares them itself.
+	res, err := transpiler.Transpile(context.Background(), inputPath, src, transpiler.Options{
+		Config:         cfg,
+		GoPath:         outputPath,
+		PackageSources: transpiler.PackageSources(inputPath, src),
+		SharedTypes:    true,
 	})
-
-	// Step 3: Parse preprocessed Go
-	parseStart := time.Now()
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, inputPath, []byte(goSource), parser.ParseComments)
-	parseDuration := time.Since(parseStart)
-
 	if err != nil {
-		buildUI.PrintStep(ui.Step{
-			Name:     "Parse",
-			Status:   ui.StepError,
-			Duration: parseDuration,
-		})
-		return fmt.Errorf("parse error: %w", err)
+		return err
 	}
-
-	buildUI.PrintStep(ui.Step{
-		Name:     "Parse",
-		Status:   ui.StepSuccess,
-		Duration: parseDuration,
-	})
-
-	// DEBUG: Check if markers are in AST
-	// Step 2: Setup plugins
-	registry, err := builtin.NewDefaultRegistry()
-	if err != nil {
-		buildUI.PrintStep(ui.Step{
-			Name:   "Setup",
-			Status: ui.StepError,
-		})
-		return fmt.Errorf("failed to setup plugins: %w", err)
+	for _, step := range transpileSteps(res) {
+		buildUI.PrintStep(step)
 	}
-
-	// Step 3: Generate with plugins
-	genStart := time.Now()
-	logger := plugin.NewNoOpLogger() // Silent logger for CLI
-	gen, err := generator.NewWithPlugins(fset, registry, logger)
-	if err != nil {
-		buildUI.PrintStep(ui.Step{
-			Name:     "Generate",
-			Status:   ui.StepError,
-			Duration: time.Since(genStart),
-		})
-		return fmt.Errorf("failed to create generator: %w", err)
+	if err := res.Err(); err != nil {
+		for _, d := range err.(*transpiler.Error).Diagnostics {
+			buildUI.PrintDiagnostic(d.Snippet(src))
+		}
+		return err
 	}
-
-	outputCode, err := gen.Generate(file)
-	genDuration := time.Since(genStart)
-
-	if err != nil {
-		buildUI.PrintStep(ui.Step{
-			Name:     "Generate",
-			Status:   ui.StepError,
-			Duration: genDuration,
-		})
-		return fmt.Errorf("generation error: %w", err)
+	for _, d := range res.Diagnostics {
+		// Non-fatal, e.g. a source map that could not be built
+		buildUI.PrintInfo(fmt.Sprintf("Warning: %s", d.Message))
 	}
 
-	buildUI.PrintStep(ui.Step{
-		Name:     "Generate",
-		Status:   ui.StepSuccess,
-		Duration: genDuration,
-	})
-
-	// Step 4: Write .go file
+	// Step 5: Write .go file and source map
 	writeStart := time.Now()
-	if err := os.WriteFile(outputPath, outputCode, 0o644); err != nil {
-		writeDuration := time.Since(writeStart)
+	if err := res.WriteFiles(outputPath); err != nil {
 		buildUI.PrintStep(ui.Step{
 			Name:     "Write",
 			Status:   ui.StepError,
-			Duration: writeDuration,
+			Duration: time.Since(writeStart),
 		})
 		return fmt.Errorf("failed to write output: %w", err)
 	}
 
-	// Phase 3: Generate source map AFTER go/printer using PostASTGenerator
-	// CRITICAL: Parse the WRITTEN .go file to get accurate FileSet positions
-	// (The in-memory version may have different line numbers after go/printer formatting)
-	sourceMapPath := outputPath + ".map"
-	sourceMap, err := sourcemap.GenerateFromFiles(inputPath, outputPath, metadata)
-	if err != nil {
-		// Non-fatal: just log warning
-		buildUI.PrintInfo(fmt.Sprintf("Warning: source map generation failed: %v", err))
-	} else {
-		// Write source map
-		sourceMapJSON, _ := json.MarshalIndent(sourceMap, "", "  ")
-		if err := os.WriteFile(sourceMapPath, sourceMapJSON, 0o644); err != nil {
-			// Non-fatal: just log warning
-			buildUI.PrintInfo(fmt.Sprintf("Warning: failed to write source map: %v", err))
-		}
-	}
-
-	writeDuration := time.Since(writeStart)
-
 	buildUI.PrintStep(ui.Step{
 		Name:     "Write",
 		Status:   ui.StepSuccess,
-		Duration: writeDuration,
-		Message:  fmt.Sprintf("%d bytes written", len(outputCode)),
+		Duration: time.Since(writeStart),
+		Message:  fmt.Sprintf("%d bytes written", len(res.Go)),
 	})
 
 	return nil
 }
 
+// transpileSteps returns the Preprocess, Parse and Generate steps of a
+// transpilation, up to the one that failed
+func transpileSteps(res *transpiler.Result) []ui.Step {
+	steps := []ui.Step{
+		{Name: "Preprocess", Status: ui.StepSuccess, Duration: res.Timings.Preprocess},
+		{Name: "Parse", Status: ui.StepSuccess, Duration: res.Timings.Parse},
+		{Name: "Generate", Status: ui.StepSuccess, Duration: res.Timings.Generate},
+	}
+	failed := map[string]int{
+		diagnostic.SourcePreprocess: 0,
+		diagnostic.SourceParse:      1,
+		diagnostic.SourceDingo:      2,
+	}
+	for _, d := range res.Diagnostics {
+		if i, ok := failed[d.Source]; ok && d.Severity == diagnostic.SeverityError {
+			steps[i].Status = ui.StepError
+			return steps[:i+1]
+		}
+	}
+	return steps
+}
+
 func runDingoFile(inputPath string, programArgs []string, _ string) error {
 	// Create beautiful output
 	buildUI := ui.NewBuildOutput()
@@ -383,17 +346,6 @@ func runDingoFile(inputPath string, programArgs []string, _ string) error {
 	buildUI.PrintHeader(version)
 	fmt.Println()
 
-	// Determine output path
-	outputPath := ""
-	if len(inputPath) > 6 && inputPath[len(inputPath)-6:] == ".dingo" {
-		outputPath = inputPath[:len(inputPath)-6] + ".go"
-	} else {
-		outputPath = inputPath + ".go"
-	}
-
-	// Step 1: Build (transpile)
-	buildStart := time.Now()
-
 	// Load main Dingo configuration (C1: Config Integration)
 	cfg, err := config.Load(nil)
 	if err != nil {
@@ -401,92 +353,33 @@ func runDingoFile(inputPath string, programArgs []string, _ string) error {
 		cfg = config.DefaultConfig()
 	}
 
-	// Read source
-	src, err := os.ReadFile(inputPath)
-	if err != nil {
-		buildUI.PrintError(fmt.Sprintf("Failed to read %s: %v", inputPath, err))
-		return err
-	}
-
-	// Preprocess (with main config + package context for unqualified imports)
-	var goSource string
-	pkgDir := filepath.Dir(inputPath)
-	pkgCtx, err := preprocessor.NewPackageContext(pkgDir, preprocessor.DefaultBuildOptions())
-	if err != nil {
-		// Fall back to no cache if package context fails
-		prep := preprocessor.NewWithMainConfig(src, cfg)
-		goSource, _, err = prep.Process()
-		if err != nil {
-			buildUI.PrintError(fmt.Sprintf("Preprocessing error: %v", err))
-			return err
-		}
-	} else {
-		prep := preprocessor.NewWithCache(src, pkgCtx.GetCache())
-		goSource, _, err = prep.Process()
-		if err != nil {
-			buildUI.PrintError(fmt.Sprintf("Preprocessing error: %v", err))
-			return err
-		}
-	}
-
-	// Parse
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, inputPath, []byte(goSource), parser.ParseComments)
-	if err != nil {
-		buildUI.PrintError(fmt.Sprintf("Parse error: %v", err))
-		return err
-	}
-
-	// Generate with plugins
-	registry, err := builtin.NewDefaultRegistry()
-	if err != nil {
-		buildUI.PrintError(fmt.Sprintf("Failed to setup plugins: %v", err))
-		return err
-	}
-
-	logger := plugin.NewNoOpLogger()
-	gen, err := generator.NewWithPlugins(fset, registry, logger)
-	if err != nil {
-		buildUI.PrintError(fmt.Sprintf("Failed to create generator: %v", err))
-		return err
-	}
-
-	goCode, err := gen.Generate(file)
+	// Step 1: Build (transpile)
+	goFiles, err := transpileForRun(inputPath, cfg, buildUI)
 	if err != nil {
-		buildUI.PrintError(fmt.Sprintf("Generation error: %v", err))
-		return err
-	}
-
-	// Write
-	if err := os.WriteFile(outputPath, goCode, 0o644); err != nil {
-		buildUI.PrintError(fmt.Sprintf("Failed to write %s: %v", outputPath, err))
 		return err
 	}
 
-	buildDuration := time.Since(buildStart)
-
-	// Show build status
-	fmt.Printf("  📝 Compiled %s → %s (%s)\n",
-		filepath.Base(inputPath),
-		filepath.Base(outputPath),
-		formatDuration(buildDuration))
-	fmt.Println()
-
 	// Step 2: Run with go run
 	fmt.Println("  🚀 Running...")
 	fmt.Println()
 
 	// Prepare go run command
-	cmdArgs := []string{"run", outputPath}
+	cmdArgs := append([]string{"run"}, goFiles...)
 	cmdArgs = append(cmdArgs, programArgs...)
 
 	cmd := exec.Command("go", cmdArgs...)
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 
+	// Panics and stack traces point at the .dingo source
+	rewriter := sourcemap.NewRewriter()
+	rewriter.SetAutoLoad(true)
+	stderr := newTraceWriter(os.Stderr, rewriter)
+	cmd.Stderr = stderr
+
 	// Run and get exit code
 	err = cmd.Run()
+	stderr.Flush()
 
 	fmt.Println()
 
@@ -503,6 +396,66 @@ func runDingoFile(inputPath string, programArgs []string, _ string) error {
 	return nil
 }
 
+// transpileForRun transpiles inputPath for `dingo run` as `dingo build` does
+// and returns the .go files to run: the one generated for inputPath, then
+// the package's dingo_types.go when it declares the types
+func transpileForRun(inputPath string, cfg *config.Config, buildUI *ui.BuildOutput) ([]string, error) {
+	// Determine output path
+	outputPath := ""
+	if len(inputPath) > 6 && inputPath[len(inputPath)-6:] == ".dingo" {
+		outputPath = inputPath[:len(inputPath)-6] + ".go"
+	} else {
+		outputPath = inputPath + ".go"
+	}
+
+	buildStart := time.Now()
+
+	// Read source
+	src, err := os.ReadFile(inputPath)
+	if err != nil {
+		buildUI.PrintError(fmt.Sprintf("Failed to read %s: %v", inputPath, err))
+		return nil, err
+	}
+
+	// Transpile with the package context for unqualified imports
+	res, err := transpiler.Transpile(context.Background(), inputPath, src, transpiler.Options{
+		Config:         cfg,
+		GoPath:         outputPath,
+		PackageSources: transpiler.PackageSources(inputPath, src),
+		SharedTypes:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.Err(); err != nil {
+		buildUI.PrintError(err.Error())
+		return nil, err
+	}
+
+	// Write, with the source map so that dingo run (and dingo trace) can map
+	// stack traces. Without one the program still runs.
+	if err := res.WriteFiles(outputPath); err != nil {
+		buildUI.PrintError(fmt.Sprintf("Failed to write %s: %v", outputPath, err))
+		return nil, err
+	}
+
+	buildDuration := time.Since(buildStart)
+
+	// Show build status
+	fmt.Printf("  📝 Compiled %s → %s (%s)\n",
+		filepath.Base(inputPath),
+		filepath.Base(outputPath),
+		formatDuration(buildDuration))
+	fmt.Println()
+
+	goFiles := []string{outputPath}
+	typesPath := filepath.Join(filepath.Dir(outputPath), transpiler.TypesFile)
+	if _, err := os.Stat(typesPath); err == nil && res.SharesTypes() {
+		goFiles = append(goFiles, typesPath)
+	}
+	return goFiles, nil
+}
+
 func formatDuration(d time.Duration) string {
 	switch {
 	case d < time.Microsecond:
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/migrate"
+	"github.com/MadAppGang/dingo/pkg/textdiff"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+func migrateCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate [flags] [packages | files]",
+		Short: "Rewrite Go source files as Dingo",
+		Long: `Migrate converts hand-written Go files to idiomatic Dingo, one file at a time,
+so existing code bases can move to Dingo gradually.
+
+Rewrites:
+  x, err := f()                           let x = f()?
+  if err != nil { return ..., err }
+
+  if err != nil {                         let x = f()? "msg"
+    return ..., fmt.Errorf("msg: %w", err)
+  }
+
+  var x T = v                             let x: T = v
+
+Nil-check ladders stay Go: ?. and ?? apply to Option values, not pointers.
+
+Every migrated file is verified: it must transpile, and its package must
+type-check without new errors. Rewrites that fail verification are dropped
+and reported. A migrated foo.go is replaced by foo.dingo; dingo build
+regenerates foo.go from it. Files with nothing to rewrite stay Go.
+
+Examples:
+  dingo migrate --dry-run ./...    # Show what would change
+  dingo migrate ./pkg/store        # Migrate one package
+  dingo migrate util.go`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrate(args, dryRun)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "Print diffs instead of writing .dingo files")
+
+	return cmd
+}
+
+func runMigrate(args []string, dryRun bool) error {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		// Non-fatal: fall back to defaults and warn
+		cfg = config.DefaultConfig()
+		fmt.Fprintf(os.Stderr, "Warning: config load failed, using defaults: %v\n", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root, err := build.FindWorkspaceRoot(cwd)
+	if err != nil {
+		return err
+	}
+
+	files, err := resolveMigrateFiles(root, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no Go files found")
+	}
+
+	migrator := migrate.New(root, transpiler.NewWithConfig(cfg))
+	migrated, failed := 0, 0
+	for _, file := range files {
+		display := file
+		if rel, err := filepath.Rel(cwd, file); err == nil && !strings.HasPrefix(rel, "..") {
+			display = rel
+		}
+
+		res, err := migrator.MigrateFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", display, err)
+			failed++
+			continue
+		}
+		for _, skipped := range res.Skipped {
+			fmt.Fprintf(os.Stderr, "%s:%s (%s): not rewritten: %s\n", display, skipped.Edit, skipped.Edit.Kind, skipped.Reason)
+		}
+		if res.Source == nil {
+			continue
+		}
+
+		dingoDisplay := strings.TrimSuffix(display, ".go") + ".dingo"
+		if dryRun {
+			fmt.Print(textdiff.Unified(display, dingoDisplay, string(res.Original), string(res.Source)))
+			migrated++
+			continue
+		}
+
+		if err := writeMigrated(res); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", display, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s → %s (%d rewrites)\n", display, dingoDisplay, len(res.Applied))
+		migrated++
+	}
+
+	if dryRun {
+		fmt.Fprintf(os.Stderr, "%d of %d file(s) would be migrated\n", migrated, len(files))
+	} else {
+		fmt.Fprintf(os.Stderr, "Migrated %d of %d file(s)\n", migrated, len(files))
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to migrate %d file(s)", failed)
+	}
+	return nil
+}
+
+// writeMigrated writes the .dingo file and removes the Go file it replaces
+func writeMigrated(res *migrate.Result) error {
+	info, err := os.Stat(res.GoPath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(res.DingoPath, res.Source, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Remove(res.GoPath)
+}
+
+// resolveMigrateFiles expands arguments into absolute .go file paths.
+// Arguments are .go files or package patterns; none means ./...
+func resolveMigrateFiles(root string, args []string) ([]string, error) {
+	if len(args) == 0 {
+		args = []string{"./..."}
+	}
+
+	var files []string
+	var patterns []string
+	for _, arg := range args {
+		if build.IsPackagePattern(arg) {
+			patterns = append(patterns, arg)
+			continue
+		}
+		if !strings.HasSuffix(arg, ".go") {
+			return nil, fmt.Errorf("%s: not a .go file or package", arg)
+		}
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return nil, err
+		}
+		files = append(files, abs)
+	}
+	if len(patterns) == 0 {
+		return files, nil
+	}
+
+	packages, err := build.DiscoverGoPackages(root, patterns)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range packages {
+		for _, rel := range pkg.GoFiles {
+			path := filepath.Join(root, filepath.FromSlash(rel))
+			// Output of dingo build is not migrated, its .dingo source is
+			if _, err := os.Stat(strings.TrimSuffix(path, ".go") + ".dingo"); err == nil {
+				continue
+			}
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
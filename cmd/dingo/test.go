@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// goTestValueFlags are go test (and build) flags that consume the next
+// argument when written without '=', e.g. "-run TestFoo"
+var goTestValueFlags = map[string]bool{
+	"bench": true, "benchtime": true, "blockprofile": true, "blockprofilerate": true,
+	"count": true, "coverpkg": true, "covermode": true, "coverprofile": true,
+	"cpu": true, "cpuprofile": true, "exec": true, "fuzz": true, "fuzzminimizetime": true,
+	"fuzztime": true, "gcflags": true, "ldflags": true, "list": true, "memprofile": true,
+	"memprofilerate": true, "mod": true, "modfile": true, "mutexprofile": true,
+	"mutexprofilefraction": true, "o": true, "outputdir": true, "p": true, "parallel": true,
+	"run": true, "shuffle": true, "skip": true, "tags": true, "timeout": true,
+	"trace": true, "vet": true,
+}
+
+// testArgs is the parsed command line of dingo test
+type testArgs struct {
+	packages []string // Package patterns and import paths
+	flags    []string // Flags passed through to go test
+	json     bool     // -json requested: emit rewritten test2json events
+	help     bool
+}
+
+func testCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "test [packages] [go test flags]",
+		Short: "Transpile packages and run their tests",
+		Long: `Test transpiles every Dingo package matching the patterns, including
+_test.dingo files, then runs go test on them.
+
+Standard go test flags (-run, -v, -count, -race, -cover, ...) are passed
+through. References to generated .go files in the test output are rewritten
+to the .dingo source positions using the .go.map source maps, so failures
+point at the code you wrote. With -json, the Output field of every event is
+rewritten and package-relative file names are resolved exactly; in text mode
+a bare file name is only rewritten when it is unique among tested packages.
+
+With no packages, the current directory tree is tested (same as ./...).
+
+Examples:
+  dingo test ./...
+  dingo test ./pkg/parser -run TestLambda -v
+  dingo test -race -count=1 ./...
+  dingo test -json ./... | tee results.json`,
+		// go test flags are passed through verbatim
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsed := parseTestArgs(args)
+			if parsed.help {
+				return cmd.Help()
+			}
+			return runTests(parsed)
+		},
+	}
+}
+
+// parseTestArgs separates package arguments from go test flags
+func parseTestArgs(args []string) testArgs {
+	var parsed testArgs
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "-args" || arg == "--args" {
+			// Everything after -args belongs to the test binary
+			parsed.flags = append(parsed.flags, args[i:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			parsed.packages = append(parsed.packages, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		name, _, hasValue := strings.Cut(name, "=")
+		switch name {
+		case "h", "help":
+			parsed.help = true
+			continue
+		case "json":
+			parsed.json = true
+			continue // Always added when running go test
+		}
+
+		parsed.flags = append(parsed.flags, arg)
+		if !hasValue && goTestValueFlags[name] && i+1 < len(args) {
+			i++
+			parsed.flags = append(parsed.flags, args[i])
+		}
+	}
+
+	if len(parsed.packages) == 0 {
+		parsed.packages = []string{"./..."}
+	}
+	return parsed
+}
+
+func runTests(args testArgs) error {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		// Non-fatal: fall back to defaults and warn
+		cfg = config.DefaultConfig()
+		fmt.Fprintf(os.Stderr, "Warning: config load failed, using defaults: %v\n", err)
+	}
+
+	rewriter, err := transpileTestPackages(args.packages, cfg)
+	if err != nil {
+		return err
+	}
+
+	goArgs := []string{"test"}
+	if args.json {
+		goArgs = append(goArgs, "-json")
+	}
+	goArgs = append(goArgs, args.packages...)
+	goArgs = append(goArgs, args.flags...)
+
+	cmd := exec.Command("go", goArgs...)
+	cmd.Stdin = os.Stdin
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run go test: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if args.json {
+			rewriteTestEvents(stdout, os.Stdout, rewriter, packageDirs(args.packages))
+		} else {
+			rewriteLines(stdout, os.Stdout, rewriter)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		rewriteLines(stderr, os.Stderr, rewriter)
+	}()
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			// Tests ran but failed
+			os.Exit(exitErr.ExitCode())
+		}
+		return err
+	}
+	return nil
+}
+
+// transpileTestPackages transpiles every local package matching patterns and
+// returns a rewriter loaded with their source maps. Import-path arguments
+// (github.com/...) are left to go test.
+func transpileTestPackages(patterns []string, cfg *config.Config) (*sourcemap.Rewriter, error) {
+	var local []string
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, ".") || build.IsPackagePattern(pattern) {
+			local = append(local, pattern)
+		}
+	}
+
+	rewriter := sourcemap.NewRewriter()
+	if len(local) == 0 {
+		return rewriter, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	root, err := build.FindWorkspaceRoot(cwd)
+	if err != nil {
+		return nil, err
+	}
+	packages, err := build.DiscoverPackages(root, local)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := build.NewWorkspaceBuilder(root, build.BuildOptions{Parallel: true, Incremental: true})
+	builder.SetTranspiler(transpiler.NewWithConfig(cfg).TranspileFile)
+
+	results, buildErr := builder.BuildAll(packages)
+	for _, result := range results {
+		if result.Error != nil {
+			fmt.Fprintf(os.Stderr, "# %s\n%v\n", result.Package.Path, result.Error)
+		}
+	}
+	if buildErr != nil {
+		return nil, fmt.Errorf("transpilation failed: %w", buildErr)
+	}
+
+	for _, pkg := range packages {
+		if err := rewriter.AddDir(filepath.Join(root, filepath.FromSlash(pkg.Path))); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return rewriter, nil
+}
+
+// packageDirs maps import paths to directories using go list.
+// Failures are not fatal: positions then resolve by file name alone.
+func packageDirs(patterns []string) map[string]string {
+	args := append([]string{"list", "-e", "-f", "{{.ImportPath}}\t{{.Dir}}"}, patterns...)
+	out, err := exec.Command("go", args...).Output()
+	dirs := make(map[string]string)
+	if err != nil {
+		return dirs
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if importPath, dir, ok := strings.Cut(line, "\t"); ok {
+			dirs[importPath] = dir
+		}
+	}
+	return dirs
+}
+
+// rewriteLines copies r to w line by line, rewriting .go positions
+func rewriteLines(r io.Reader, w io.Writer, rewriter *sourcemap.Rewriter) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			io.WriteString(w, rewriter.Rewrite(line, ""))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// rewriteTestEvents rewrites the Output field of test2json events. Lines that
+// are not JSON (e.g. build output of older go versions) are rewritten as text.
+func rewriteTestEvents(r io.Reader, w io.Writer, rewriter *sourcemap.Rewriter, dirs map[string]string) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			w.Write(rewriteTestEvent(line, rewriter, dirs))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// rewriteTestEvent rewrites a single test2json event line
+func rewriteTestEvent(line []byte, rewriter *sourcemap.Rewriter, dirs map[string]string) []byte {
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(line, &event); err != nil {
+		return []byte(rewriter.Rewrite(string(line), ""))
+	}
+
+	var output string
+	if raw, ok := event["Output"]; !ok || json.Unmarshal(raw, &output) != nil {
+		return line
+	}
+
+	var pkg string
+	if raw, ok := event["Package"]; ok {
+		_ = json.Unmarshal(raw, &pkg)
+	} else if raw, ok := event["ImportPath"]; ok {
+		_ = json.Unmarshal(raw, &pkg)
+	}
+
+	rewritten := rewriter.Rewrite(output, dirs[pkg])
+	if rewritten == output {
+		return line
+	}
+
+	event["Output"], _ = json.Marshal(rewritten)
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(event); err != nil {
+		return line
+	}
+	return buf.Bytes()
+}
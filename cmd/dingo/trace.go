@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"debug/buildinfo"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
+)
+
+func traceCmd() *cobra.Command {
+	var mapDirs []string
+
+	cmd := &cobra.Command{
+		Use:   "trace [flags] [binary-or-log] [-- args...]",
+		Short: "Map panics and stack traces back to .dingo lines",
+		Long: `Trace rewrites every file.go:LINE frame of a panic or stack dump to the
+file.dingo:LINE it was generated from, using the .go.map source maps.
+
+The input is read from stdin, from a log file, or from a running program:
+when the argument is a Go binary, trace runs it with the remaining arguments
+and rewrites its stderr as it is written. Its exit code is preserved.
+
+Source maps are loaded from the workspace tree (or the --maps directories)
+and from next to every referenced .go file. Frames recorded on another
+machine, e.g. by a binary built in CI, match by their trailing path elements.
+dingo run rewrites the stderr of the program automatically.
+
+Examples:
+  ./server 2>&1 | dingo trace
+  dingo trace crash.log
+  dingo trace ./server -- --port 8080
+  dingo trace --maps ./checkout crash.log`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTrace(args, mapDirs)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&mapDirs, "maps", nil, "Directories searched recursively for .go.map files (default: workspace root)")
+
+	return cmd
+}
+
+func runTrace(args []string, mapDirs []string) error {
+	rewriter := sourcemap.NewRewriter()
+	rewriter.SetAutoLoad(true)
+
+	if len(mapDirs) == 0 {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		root, err := build.FindWorkspaceRoot(cwd)
+		if err != nil {
+			return err
+		}
+		mapDirs = []string{root}
+	}
+	for _, dir := range mapDirs {
+		if err := rewriter.AddTree(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if len(args) == 0 || args[0] == "-" {
+		rewriteLines(os.Stdin, os.Stdout, rewriter)
+		return nil
+	}
+
+	path := args[0]
+	if _, err := buildinfo.ReadFile(path); err == nil {
+		cmd := exec.Command(path, args[1:]...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		return runWithTrace(cmd, rewriter)
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("%s is not a Go binary; program arguments are only accepted for binaries", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	rewriteLines(f, os.Stdout, rewriter)
+	return nil
+}
+
+// runWithTrace runs cmd with its stderr rewritten to .dingo positions and
+// exits with the program's exit code when it fails
+func runWithTrace(cmd *exec.Cmd, rewriter *sourcemap.Rewriter) error {
+	stderr := newTraceWriter(os.Stderr, rewriter)
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	stderr.Flush()
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			// Program ran but exited with error
+			os.Exit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run: %w", err)
+	}
+	return nil
+}
+
+// traceWriter rewrites .go positions in everything written to it, a line at
+// a time. The runtime prints a stack trace in many small writes, so text is
+// held until its line is complete; Flush writes an unterminated last line.
+type traceWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	rewriter *sourcemap.Rewriter
+	pending  []byte
+}
+
+func newTraceWriter(w io.Writer, rewriter *sourcemap.Rewriter) *traceWriter {
+	return &traceWriter{w: w, rewriter: rewriter}
+}
+
+func (t *traceWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending = append(t.pending, p...)
+	end := bytes.LastIndexByte(t.pending, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	if _, err := io.WriteString(t.w, t.rewriter.Rewrite(string(t.pending[:end+1]), "")); err != nil {
+		return 0, err
+	}
+	t.pending = append(t.pending[:0], t.pending[end+1:]...)
+	return len(p), nil
+}
+
+// Flush writes any buffered partial line
+func (t *traceWriter) Flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.pending) > 0 {
+		io.WriteString(t.w, t.rewriter.Rewrite(string(t.pending), ""))
+		t.pending = t.pending[:0]
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
+	"github.com/MadAppGang/dingo/pkg/ui"
+	"github.com/MadAppGang/dingo/pkg/watch"
+)
+
+// watchAndRebuild blocks until interrupted, calling rebuild with every
+// debounced batch of changed .dingo files. Rebuilds never overlap.
+func watchAndRebuild(root string, buildUI *ui.BuildOutput, rebuild func(changed []string)) error {
+	batches := make(chan []string, 1)
+	w, err := watch.New(root, nil, func(paths []string) {
+		batches <- paths
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start watcher: %w", err)
+	}
+	defer w.Close()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	buildUI.PrintWatching(root)
+
+	for {
+		select {
+		case changed := <-batches:
+			rebuild(changed)
+			buildUI.PrintWatching(root)
+		case <-interrupt:
+			fmt.Println()
+			return nil
+		}
+	}
+}
+
+// watchFiles rebuilds individual .dingo files passed to `dingo build --watch`
+func watchFiles(files []string, output string, cfg *config.Config) error {
+	buildUI := ui.NewBuildOutput()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root, err := build.FindWorkspaceRoot(cwd)
+	if err != nil {
+		return err
+	}
+
+	// Map absolute paths back to the names the user typed
+	watched := make(map[string]string, len(files))
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		watched[abs] = file
+	}
+
+	cache, err := build.NewBuildCache(root)
+	if err != nil {
+		return err
+	}
+
+	return watchAndRebuild(root, buildUI, func(changed []string) {
+		var targets []string
+		for _, path := range changed {
+			file, ok := watched[path]
+			if !ok {
+				continue
+			}
+			if needsRebuild, err := cache.NeedsRebuild(path); err == nil && !needsRebuild {
+				continue
+			}
+			targets = append(targets, file)
+		}
+		if len(targets) == 0 {
+			return
+		}
+
+		buildUI.PrintChangeDetected(targets)
+		_ = buildFiles(targets, output, buildUI, cfg, func(file string) {
+			if output == "" {
+				_ = cache.MarkBuilt(file)
+			}
+		})
+	})
+}
+
+// runningProgram is a program started by `dingo run --watch`
+type runningProgram struct {
+	cmd     *exec.Cmd
+	done    chan struct{}
+	stopped atomic.Bool
+}
+
+// stop kills the program and waits for it to exit
+func (p *runningProgram) stop() {
+	if p == nil {
+		return
+	}
+	p.stopped.Store(true)
+	_ = p.cmd.Process.Kill()
+	<-p.done
+}
+
+// runDingoFileWatch runs a Dingo program and restarts it whenever its source changes.
+// The program is built to a temporary binary (rather than `go run`) so that
+// killing it does not leave an orphaned child process behind.
+func runDingoFileWatch(inputPath string, programArgs []string) error {
+	buildUI := ui.NewBuildOutput()
+	buildUI.PrintHeader(version)
+	fmt.Println()
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		// Non-fatal: fall back to defaults
+		cfg = config.DefaultConfig()
+	}
+
+	absInput, err := filepath.Abs(inputPath)
+	if err != nil {
+		return err
+	}
+
+	binDir, err := os.MkdirTemp("", "dingo-run-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(binDir)
+
+	binPath := filepath.Join(binDir, "program")
+	if runtime.GOOS == "windows" {
+		binPath += ".exe"
+	}
+
+	// Panics and stack traces point at the .dingo source
+	rewriter := sourcemap.NewRewriter()
+
+	var program *runningProgram
+	start := func() {
+		goFiles, err := transpileForRun(inputPath, cfg, buildUI)
+		if err != nil {
+			return
+		}
+		if sm, err := sourcemap.Load(goFiles[0]); err == nil {
+			_ = rewriter.Add(goFiles[0], sm) // Replaces the map of the previous build
+		}
+
+		compile := exec.Command("go", append([]string{"build", "-o", binPath}, goFiles...)...)
+		compile.Stdout = os.Stdout
+		compile.Stderr = os.Stderr
+		if err := compile.Run(); err != nil {
+			buildUI.PrintError(fmt.Sprintf("go build failed: %v", err))
+			return
+		}
+
+		fmt.Println("  🚀 Running...")
+		fmt.Println()
+
+		cmd := exec.Command(binPath, programArgs...)
+		cmd.Stdout = os.Stdout
+		stderr := newTraceWriter(os.Stderr, rewriter)
+		cmd.Stderr = stderr
+		cmd.Stdin = os.Stdin
+		if err := cmd.Start(); err != nil {
+			buildUI.PrintError(fmt.Sprintf("Failed to run: %v", err))
+			return
+		}
+
+		p := &runningProgram{cmd: cmd, done: make(chan struct{})}
+		go func() {
+			err := cmd.Wait()
+			stderr.Flush()
+			if !p.stopped.Load() {
+				fmt.Println()
+				if exitErr, ok := err.(*exec.ExitError); ok {
+					buildUI.PrintInfo(fmt.Sprintf("Program exited with code %d", exitErr.ExitCode()))
+				} else {
+					buildUI.PrintInfo("Program exited")
+				}
+			}
+			close(p.done)
+		}()
+		program = p
+	}
+
+	start()
+	defer func() { program.stop() }()
+
+	return watchAndRebuild(filepath.Dir(absInput), buildUI, func(changed []string) {
+		for _, path := range changed {
+			if path == absInput {
+				program.stop()
+				program = nil
+				buildUI.PrintChangeDetected([]string{inputPath})
+				start()
+				return
+			}
+		}
+	})
+}
+
+// displayPaths makes absolute paths relative to the current directory for output
+func displayPaths(paths []string) []string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return paths
+	}
+
+	result := make([]string, len(paths))
+	for i, path := range paths {
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			result[i] = rel
+		} else {
+			result[i] = path
+		}
+	}
+	return result
+}
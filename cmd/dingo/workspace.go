@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+	"github.com/MadAppGang/dingo/pkg/ui"
+)
+
+// runWorkspaceBuild builds every package matching patterns in dependency order
+func runWorkspaceBuild(patterns []string, opts build.BuildOptions, watch bool, overrides *config.Config) error {
+	cfg, err := config.Load(overrides)
+	if err != nil {
+		// Non-fatal: fall back to defaults and warn
+		cfg = config.DefaultConfig()
+		fmt.Fprintf(os.Stderr, "Warning: config load failed, using defaults: %v\n", err)
+	}
+
+	buildUI := ui.NewBuildOutput()
+	buildUI.PrintHeader(version)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root, err := build.FindWorkspaceRoot(cwd)
+	if err != nil {
+		buildUI.PrintSummary(false, err.Error())
+		return err
+	}
+
+	packages, err := build.DiscoverPackages(root, patterns)
+	if err == nil && len(packages) == 0 {
+		err = fmt.Errorf("no .dingo packages found")
+	}
+	if err != nil {
+		buildUI.PrintSummary(false, err.Error())
+		return err
+	}
+
+	buildErr := buildPackages(buildUI, root, packages, opts, cfg)
+	if !watch {
+		return buildErr
+	}
+
+	// Watch mode: only rebuild packages containing changed files. The build
+	// cache decides which files inside those packages actually need work.
+	opts.Incremental = true
+	opts.Clean = false
+	return watchAndRebuild(root, buildUI, func(changed []string) {
+		packages, err := build.DiscoverPackages(root, patterns)
+		if err != nil {
+			buildUI.PrintError(err.Error())
+			return
+		}
+
+		affected := affectedPackages(root, packages, changed)
+		if len(affected) == 0 {
+			return
+		}
+
+		buildUI.PrintChangeDetected(displayPaths(changed))
+		_ = buildPackages(buildUI, root, affected, opts, cfg)
+	})
+}
+
+// buildPackages runs a workspace build and prints per-package results
+func buildPackages(buildUI *ui.BuildOutput, root string, packages []build.Package, opts build.BuildOptions, cfg *config.Config) error {
+	buildUI.PrintPackageBuildStart(root, len(packages))
+
+	builder := build.NewWorkspaceBuilder(root, opts)
+	t := transpiler.NewWithConfig(cfg)
+	builder.SetTranspiler(t.TranspileFile)
+
+	results, buildErr := builder.BuildAll(packages)
+	for _, result := range results {
+		buildUI.PrintPackageResult(
+			result.Package.Path,
+			result.Stats.FilesProcessed,
+			result.Stats.FilesSkipped,
+			time.Duration(result.Stats.Duration)*time.Millisecond,
+			result.Error,
+		)
+	}
+
+	if buildErr != nil {
+		// List every problem across the workspace; skipped packages only
+		// failed because of these
+		cwd, _ := os.Getwd()
+		var errs []string
+		for _, result := range results {
+			if result.Skipped {
+				continue
+			}
+			for _, err := range result.Errors {
+				errs = append(errs, errorLines(err, cwd)...)
+			}
+		}
+		buildUI.PrintErrorList(errs)
+		buildUI.PrintSummary(false, buildErr.Error())
+		return buildErr
+	}
+
+	buildUI.PrintSummary(true, "")
+	return nil
+}
+
+// affectedPackages returns the packages whose directory contains a changed file
+func affectedPackages(root string, packages []build.Package, changed []string) []build.Package {
+	dirs := make(map[string]bool, len(changed))
+	for _, path := range changed {
+		dirs[filepath.Dir(path)] = true
+	}
+
+	var affected []build.Package
+	for _, pkg := range packages {
+		if dirs[filepath.Join(root, filepath.FromSlash(pkg.Path))] {
+			affected = append(affected, pkg)
+		}
+	}
+	return affected
+}
@@ -7,7 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"time"
 )
 
@@ -32,6 +32,7 @@ type CacheEntry struct {
 	OutputHash   string    // SHA-256 hash of output content
 	LastBuilt    time.Time // When file was last built
 	Dependencies []string  // List of files this file depends on
+	Siblings     []string  // Other .dingo files of its package
 }
 
 // NewBuildCache creates or loads a build cache
@@ -99,6 +100,16 @@ func (c *BuildCache) NeedsRebuild(sourcePath string) (bool, error) {
 		return true, nil // Content changed = needs build
 	}
 
+	// A file added to or removed from the package changes where its
+	// injected types are declared (see transpiler.Options.SharedTypes)
+	siblings, err := siblingSources(absPath)
+	if err != nil {
+		return true, err
+	}
+	if !slices.Equal(siblings, entry.Siblings) {
+		return true, nil // Package files changed = needs build
+	}
+
 	// Check if any dependencies changed
 	for _, depPath := range entry.Dependencies {
 		depInfo, err := os.Stat(depPath)
@@ -134,12 +145,17 @@ func (c *BuildCache) MarkBuilt(sourcePath string) error {
 	}
 
 	// Extract dependencies from import statements
-	dependencies, err := extractImports(absPath)
+	dependencies, err := extractImports(c.Root, absPath)
 	if err != nil {
 		// Log warning but don't fail the build
 		dependencies = []string{}
 	}
 
+	siblings, err := siblingSources(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to list package files: %w", err)
+	}
+
 	// Create/update cache entry
 	entry := &CacheEntry{
 		SourcePath:   absPath,
@@ -148,6 +164,7 @@ func (c *BuildCache) MarkBuilt(sourcePath string) error {
 		OutputHash:   outputHash,
 		LastBuilt:    time.Now(),
 		Dependencies: dependencies,
+		Siblings:     siblings,
 	}
 
 	c.Entries[absPath] = entry
@@ -264,22 +281,45 @@ func (c *BuildCache) Clean() error {
 	return nil
 }
 
-// extractImports extracts import paths from a .dingo file
-func extractImports(sourcePath string) ([]string, error) {
+// siblingSources returns the other .dingo files of the package of sourcePath
+func siblingSources(sourcePath string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(sourcePath), "*.dingo"))
+	if err != nil {
+		return nil, err
+	}
+	siblings := make([]string, 0, len(matches))
+	for _, path := range matches {
+		if path != sourcePath {
+			siblings = append(siblings, path)
+		}
+	}
+	return siblings, nil
+}
+
+// extractImports resolves the workspace-local imports of a .dingo file to the
+// .dingo files of the imported packages. External imports are ignored.
+func extractImports(root, sourcePath string) ([]string, error) {
 	data, err := os.ReadFile(sourcePath)
 	if err != nil {
 		return nil, err
 	}
 
-	// Match import statements: import "package/path"
-	importRegex := regexp.MustCompile(`import\s+"([^"]+)"`)
-	matches := importRegex.FindAllSubmatch(data, -1)
+	deps := make([]string, 0)
+	for _, importPath := range parseImports(data) {
+		pkgPath := importPathToPackagePath(importPath, root)
+		if pkgPath == "" {
+			continue
+		}
 
-	deps := make([]string, 0, len(matches))
-	for _, match := range matches {
-		if len(match) > 1 {
-			deps = append(deps, string(match[1]))
+		pkgDir := filepath.Join(root, filepath.FromSlash(pkgPath))
+		if pkgDir == filepath.Dir(sourcePath) {
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(pkgDir, "*.dingo"))
+		if err != nil {
+			continue
 		}
+		deps = append(deps, matches...)
 	}
 
 	return deps, nil
@@ -0,0 +1,95 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// Artifacts are the files dingo clean removes from a set of packages
+type Artifacts struct {
+	Files  []string // Generated .go and .go.map files and dingo_types.go
+	Caches []string // .dingo-cache.json files and the workspace build cache
+	Kept   []string // .go files next to a .dingo source that lack the generated header
+}
+
+// FindArtifacts collects the build outputs of packages. A .go file is only
+// considered generated when it has a sibling .dingo source AND starts with
+// the header transpilation writes (see transpiler.IsGenerated), so
+// hand-written Go in mixed packages is never listed. A package's dingo_types.go is listed when dingo wrote it. All
+// paths are absolute.
+func FindArtifacts(root string, packages []Package) (*Artifacts, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace root: %w", err)
+	}
+
+	a := &Artifacts{}
+	for _, pkg := range packages {
+		dir := filepath.Join(absRoot, filepath.FromSlash(pkg.Path))
+		for _, rel := range pkg.DingoFiles {
+			dingoPath := filepath.Join(absRoot, filepath.FromSlash(rel))
+			goPath := strings.TrimSuffix(dingoPath, ".dingo") + ".go"
+			mapPath := goPath + ".map"
+
+			src, err := os.ReadFile(goPath)
+			switch {
+			case err == nil && transpiler.IsGenerated(src):
+				a.Files = append(a.Files, goPath)
+				if fileExists(mapPath) {
+					a.Files = append(a.Files, mapPath)
+				}
+			case err == nil:
+				a.Kept = append(a.Kept, goPath)
+			case os.IsNotExist(err):
+				// A map whose .go is gone can only be a leftover of dingo build
+				if fileExists(mapPath) {
+					a.Files = append(a.Files, mapPath)
+				}
+			default:
+				return nil, fmt.Errorf("failed to read %s: %w", goPath, err)
+			}
+		}
+
+		typesFile := filepath.Join(dir, transpiler.TypesFile)
+		if src, err := os.ReadFile(typesFile); err == nil && transpiler.IsTypesFile(src) {
+			a.Files = append(a.Files, typesFile)
+		}
+
+		if cacheFile := filepath.Join(dir, ".dingo-cache.json"); fileExists(cacheFile) {
+			a.Caches = append(a.Caches, cacheFile)
+		}
+	}
+
+	if cacheDir := filepath.Join(absRoot, ".dingo-cache"); fileExists(cacheDir) {
+		a.Caches = append(a.Caches, cacheDir)
+	}
+
+	sort.Strings(a.Files)
+	sort.Strings(a.Kept)
+	return a, nil
+}
+
+// Remove deletes the generated files and caches
+func (a *Artifacts) Remove() error {
+	for _, path := range a.Files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	for _, path := range a.Caches {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,72 @@
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const generatedGo = "// Code generated by dingo from gen.dingo. DO NOT EDIT.\n\npackage a\n\nfunc f() {}\n"
+
+func TestFindArtifacts(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":                        "module example.com/ws\n",
+		"pkg/a/gen.dingo":               "package a\n",
+		"pkg/a/gen.go":                  generatedGo,
+		"pkg/a/gen.go.map":              "{}",
+		"pkg/a/hand.dingo":              "package a\n",
+		"pkg/a/hand.go":                 "package a\n\nfunc g() {}\n", // Same name, no header
+		"pkg/a/legacy.go":               generatedGo,                  // Header but no .dingo source
+		"pkg/a/stale.dingo":             "package a\n",
+		"pkg/a/stale.go.map":            "{}",
+		"pkg/a/.dingo-cache.json":       "{}",
+		".dingo-cache/build-cache.json": "{}",
+		"pkg/other/other.dingo":         "package other\n",
+		"pkg/other/other.go":            generatedGo,
+		"pkg/other/.dingo-cache.json":   "{}",
+		"pkg/plain/plain.go":            generatedGo,
+		"pkg/plain/.dingo-cache.json":   "{}",
+	})
+	t.Chdir(root)
+
+	packages, err := DiscoverPackages(root, []string{"./pkg/a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifacts, err := FindArtifacts(root, packages)
+	if err != nil {
+		t.Fatalf("FindArtifacts() error: %v", err)
+	}
+
+	abs := func(rels ...string) []string {
+		var paths []string
+		for _, rel := range rels {
+			paths = append(paths, filepath.Join(root, filepath.FromSlash(rel)))
+		}
+		return paths
+	}
+	if want := abs("pkg/a/gen.go", "pkg/a/gen.go.map", "pkg/a/stale.go.map"); !reflect.DeepEqual(artifacts.Files, want) {
+		t.Errorf("Files = %v, want %v", artifacts.Files, want)
+	}
+	if want := abs("pkg/a/hand.go"); !reflect.DeepEqual(artifacts.Kept, want) {
+		t.Errorf("Kept = %v, want %v", artifacts.Kept, want)
+	}
+	if want := abs("pkg/a/.dingo-cache.json", ".dingo-cache"); !reflect.DeepEqual(artifacts.Caches, want) {
+		t.Errorf("Caches = %v, want %v", artifacts.Caches, want)
+	}
+
+	if err := artifacts.Remove(); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	for _, rel := range []string{"pkg/a/gen.go", "pkg/a/gen.go.map", "pkg/a/stale.go.map", "pkg/a/.dingo-cache.json", ".dingo-cache"} {
+		if _, err := os.Stat(filepath.Join(root, rel)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", rel)
+		}
+	}
+	for _, rel := range []string{"pkg/a/gen.dingo", "pkg/a/hand.go", "pkg/a/legacy.go", "pkg/other/other.go", "pkg/plain/plain.go"} {
+		if _, err := os.Stat(filepath.Join(root, rel)); err != nil {
+			t.Errorf("%s must be kept: %v", rel, err)
+		}
+	}
+}
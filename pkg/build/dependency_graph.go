@@ -2,10 +2,12 @@ package build
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -60,38 +62,20 @@ func buildDependencyGraph(packages []Package, workspaceRoot string) (*Dependency
 func extractDependencies(pkg Package, workspaceRoot string) ([]string, error) {
 	deps := make(map[string]bool) // Use map to avoid duplicates
 
-	// Regex to match import statements
-	importRegex := regexp.MustCompile(`import\s+(?:"([^"]+)"|([^\s]+))`)
-
 	for _, dingoFile := range pkg.DingoFiles {
 		fullPath := filepath.Join(workspaceRoot, dingoFile)
-		file, err := os.Open(fullPath)
+		data, err := os.ReadFile(fullPath)
 		if err != nil {
 			return nil, err
 		}
-		defer file.Close()
-
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := scanner.Text()
-			matches := importRegex.FindAllStringSubmatch(line, -1)
-			for _, match := range matches {
-				importPath := match[1]
-				if importPath == "" {
-					importPath = match[2]
-				}
 
-				// Convert import path to workspace-relative package path
-				pkgPath := importPathToPackagePath(importPath, workspaceRoot)
-				if pkgPath != "" {
-					deps[pkgPath] = true
-				}
+		for _, importPath := range parseImports(data) {
+			// Convert import path to workspace-relative package path
+			pkgPath := importPathToPackagePath(importPath, workspaceRoot)
+			if pkgPath != "" && pkgPath != pkg.Path {
+				deps[pkgPath] = true
 			}
 		}
-
-		if err := scanner.Err(); err != nil {
-			return nil, err
-		}
 	}
 
 	// Convert map to slice
@@ -99,10 +83,49 @@ func extractDependencies(pkg Package, workspaceRoot string) ([]string, error) {
 	for dep := range deps {
 		result = append(result, dep)
 	}
+	sort.Strings(result)
 
 	return result, nil
 }
 
+var (
+	singleImportRegex = regexp.MustCompile(`^import\s+(?:[\w.]+\s+)?"([^"]+)"`)
+	importSpecRegex   = regexp.MustCompile(`^(?:[\w.]+\s+)?"([^"]+)"`)
+)
+
+// parseImports returns the import paths declared in a Dingo or Go source file.
+// Handles both single-line imports and parenthesized import blocks.
+func parseImports(data []byte) []string {
+	var imports []string
+	inBlock := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if inBlock {
+			if strings.HasPrefix(line, ")") {
+				inBlock = false
+				continue
+			}
+			if m := importSpecRegex.FindStringSubmatch(line); m != nil {
+				imports = append(imports, m[1])
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "import (") || line == "import(" {
+			inBlock = true
+			continue
+		}
+		if m := singleImportRegex.FindStringSubmatch(line); m != nil {
+			imports = append(imports, m[1])
+		}
+	}
+
+	return imports
+}
+
 // importPathToPackagePath converts an import path to a workspace-relative package path
 func importPathToPackagePath(importPath, workspaceRoot string) string {
 	// Handle relative imports (./foo, ../bar)
@@ -117,9 +140,11 @@ func importPathToPackagePath(importPath, workspaceRoot string) string {
 	}
 
 	// Check if import is within workspace module
-	if strings.HasPrefix(importPath, modPath) {
-		relPath := strings.TrimPrefix(importPath, modPath+"/")
-		return relPath
+	if importPath == modPath {
+		return "."
+	}
+	if strings.HasPrefix(importPath, modPath+"/") {
+		return strings.TrimPrefix(importPath, modPath+"/")
 	}
 
 	// External dependency, not tracked
@@ -201,26 +226,69 @@ func detectCircularDependencies(graph *DependencyGraph) [][]string {
 	return cycles
 }
 
+// checkCycles returns an error listing every circular dependency chain
+func checkCycles(graph *DependencyGraph) error {
+	cycles := detectCircularDependencies(graph)
+	if len(cycles) == 0 {
+		return nil
+	}
+
+	// Format cycle paths for clear error message
+	cycleStrs := make([]string, len(cycles))
+	for i, cycle := range cycles {
+		cycleStrs[i] = strings.Join(cycle, " → ")
+	}
+	return fmt.Errorf("circular dependencies detected:\n  %s",
+		strings.Join(cycleStrs, "\n  "))
+}
+
+// SortPackages returns packages in build order (dependencies first)
+func SortPackages(packages []Package, workspaceRoot string) ([]Package, error) {
+	graph, err := buildDependencyGraph(packages, workspaceRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build dependency graph: %w", err)
+	}
+	if err := checkCycles(graph); err != nil {
+		return nil, err
+	}
+
+	sorted := make([]Package, 0, len(packages))
+	for _, pkgPath := range topologicalSort(graph) {
+		if pkg := findPackage(packages, pkgPath); pkg != nil {
+			sorted = append(sorted, *pkg)
+		}
+	}
+	return sorted, nil
+}
+
+// ImportPath returns the Go import path of a workspace-relative package path
+func ImportPath(workspaceRoot, pkgPath string) (string, error) {
+	modPath, err := getModulePath(workspaceRoot)
+	if err != nil {
+		return "", err
+	}
+	if pkgPath == "." || pkgPath == "" {
+		return modPath, nil
+	}
+	return modPath + "/" + pkgPath, nil
+}
+
 // topologicalSort returns packages in build order (dependencies first)
 func topologicalSort(graph *DependencyGraph) []string {
-	// Kahn's algorithm
+	// Kahn's algorithm: a package's in-degree is its number of unbuilt dependencies
 	inDegree := make(map[string]int)
 	for _, node := range graph.Nodes {
-		inDegree[node.Path] = 0
-	}
-	for _, node := range graph.Nodes {
-		for _, dep := range node.Dependencies {
-			inDegree[dep]++
-		}
+		inDegree[node.Path] = len(node.Dependencies)
 	}
 
-	// Queue of nodes with no dependencies
+	// Queue of nodes with no dependencies (sorted for deterministic output)
 	queue := make([]string, 0)
 	for node, degree := range inDegree {
 		if degree == 0 {
 			queue = append(queue, node)
 		}
 	}
+	sort.Strings(queue)
 
 	result := make([]string, 0, len(graph.Nodes))
 	for len(queue) > 0 {
@@ -231,31 +299,61 @@ func topologicalSort(graph *DependencyGraph) []string {
 
 		// Reduce in-degree of dependents
 		if node, exists := graph.Nodes[current]; exists {
-			for _, dep := range node.Dependents {
-				inDegree[dep]--
-				if inDegree[dep] == 0 {
-					queue = append(queue, dep)
+			ready := make([]string, 0)
+			for _, dependent := range node.Dependents {
+				inDegree[dependent]--
+				if inDegree[dependent] == 0 {
+					ready = append(ready, dependent)
 				}
 			}
+			sort.Strings(ready)
+			queue = append(queue, ready...)
 		}
 	}
 
 	// If result doesn't contain all nodes, there's a cycle
 	if len(result) != len(graph.Nodes) {
 		// Return partial order (best effort)
+		placed := make(map[string]bool, len(result))
+		for _, r := range result {
+			placed[r] = true
+		}
+		remaining := make([]string, 0)
 		for node := range graph.Nodes {
-			found := false
-			for _, r := range result {
-				if r == node {
-					found = true
-					break
-				}
-			}
-			if !found {
-				result = append(result, node)
+			if !placed[node] {
+				remaining = append(remaining, node)
 			}
 		}
+		sort.Strings(remaining)
+		result = append(result, remaining...)
 	}
 
 	return result
 }
+
+// groupByDependencyLevel groups packages by their dependency level.
+// Level 0 = no dependencies, Level 1 = depends only on Level 0, etc.
+// buildOrder must be topologically sorted (dependencies first).
+func groupByDependencyLevel(graph *DependencyGraph, buildOrder []string) [][]string {
+	levelOf := make(map[string]int, len(buildOrder))
+	levels := make([][]string, 0)
+
+	for _, pkgPath := range buildOrder {
+		level := 0
+		if node, exists := graph.Nodes[pkgPath]; exists {
+			for _, dep := range node.Dependencies {
+				if depLevel, ok := levelOf[dep]; ok && depLevel+1 > level {
+					level = depLevel + 1
+				}
+			}
+		}
+		levelOf[pkgPath] = level
+
+		for len(levels) <= level {
+			levels = append(levels, make([]string, 0))
+		}
+		levels[level] = append(levels[level], pkgPath)
+	}
+
+	return levels
+}
@@ -0,0 +1,288 @@
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultIgnoreDirs are always excluded from workspace scans
+var defaultIgnoreDirs = []string{
+	".git",
+	".dingo-cache",
+	"node_modules",
+	"vendor",
+	".idea",
+	".vscode",
+}
+
+// workspaceMarkers identify a workspace root, in priority order
+var workspaceMarkers = []string{"dingo.toml", "go.work", "go.mod"}
+
+// FindWorkspaceRoot walks up from start looking for dingo.toml, go.work or go.mod.
+// Falls back to start itself when no marker is found.
+func FindWorkspaceRoot(start string) (string, error) {
+	abs, err := filepath.Abs(start)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", start, err)
+	}
+
+	for _, marker := range workspaceMarkers {
+		dir := abs
+		for {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir, nil
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+
+	return abs, nil
+}
+
+// IsPackagePattern reports whether arg is a package pattern (./..., ./pkg/foo)
+// rather than a single .dingo file
+func IsPackagePattern(arg string) bool {
+	if strings.HasSuffix(arg, ".dingo") {
+		return false
+	}
+	if strings.HasSuffix(arg, "...") {
+		return true
+	}
+	info, err := os.Stat(arg)
+	return err == nil && info.IsDir()
+}
+
+// DiscoverPackages finds every directory containing .dingo files that matches
+// one of the given patterns. Patterns follow the go tool conventions:
+//
+//	./...          every package below the current directory
+//	./pkg/...      every package below ./pkg
+//	./pkg/foo      exactly one package
+//
+// An empty pattern list means "./...". Package paths are relative to root.
+func DiscoverPackages(root string, patterns []string) ([]Package, error) {
+	return discover(root, patterns, func(pkg Package) bool {
+		return len(pkg.DingoFiles) > 0
+	})
+}
+
+// DiscoverGoPackages is like DiscoverPackages but also returns directories
+// that hold only .go files, which are candidates for migration to Dingo
+func DiscoverGoPackages(root string, patterns []string) ([]Package, error) {
+	return discover(root, patterns, func(pkg Package) bool {
+		return len(pkg.DingoFiles) > 0 || len(pkg.GoFiles) > 0
+	})
+}
+
+// discover scans the directories matching patterns and returns the packages
+// accepted by keep
+func discover(root string, patterns []string, keep func(Package) bool) ([]Package, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace root: %w", err)
+	}
+
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	ignore := loadIgnoreFile(absRoot)
+	seen := make(map[string]bool)
+	var packages []Package
+
+	addDir := func(dir string) error {
+		rel, err := filepath.Rel(absRoot, dir)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if seen[rel] {
+			return nil
+		}
+		pkg, err := scanPackageDir(absRoot, rel, ignore)
+		if err != nil {
+			return err
+		}
+		if keep(pkg) {
+			seen[rel] = true
+			packages = append(packages, pkg)
+		}
+		return nil
+	}
+
+	for _, pattern := range patterns {
+		recursive := strings.HasSuffix(pattern, "...")
+		base := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+		if base == "" {
+			base = "."
+		}
+
+		dir, err := filepath.Abs(base)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("pattern %q: directory %s not found", pattern, base)
+		}
+		if !isWithin(absRoot, dir) {
+			return nil, fmt.Errorf("pattern %q is outside workspace %s", pattern, absRoot)
+		}
+
+		if !recursive {
+			if err := addDir(dir); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if path != dir && ignore.matchDir(absRoot, path) {
+				return filepath.SkipDir
+			}
+			return addDir(path)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", pattern, err)
+		}
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Path < packages[j].Path
+	})
+
+	return packages, nil
+}
+
+// scanPackageDir collects the .dingo and .go files of a single directory
+func scanPackageDir(root, rel string, ignore *ignoreRules) (Package, error) {
+	dir := filepath.Join(root, filepath.FromSlash(rel))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return Package{}, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	pkg := Package{Path: rel, Name: filepath.Base(dir)}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		relFile := filepath.ToSlash(filepath.Join(rel, name))
+		if ignore.matchFile(relFile) {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, ".dingo"):
+			pkg.DingoFiles = append(pkg.DingoFiles, relFile)
+		case strings.HasSuffix(name, ".go"):
+			pkg.GoFiles = append(pkg.GoFiles, relFile)
+		}
+	}
+
+	return pkg, nil
+}
+
+// isWithin reports whether path is root or a descendant of root
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || !strings.HasPrefix(rel, "..")
+}
+
+// ignoreRules holds patterns loaded from .dingoignore
+type ignoreRules struct {
+	dirs  []string // Directory patterns (entries ending in /)
+	files []string // File glob patterns
+}
+
+// loadIgnoreFile reads .dingoignore from the workspace root.
+// A missing file yields the default rules only.
+func loadIgnoreFile(root string) *ignoreRules {
+	rules := &ignoreRules{}
+
+	f, err := os.Open(filepath.Join(root, ".dingoignore"))
+	if err != nil {
+		return rules
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasSuffix(line, "/") {
+			rules.dirs = append(rules.dirs, strings.TrimSuffix(line, "/"))
+		} else {
+			rules.files = append(rules.files, line)
+		}
+	}
+
+	return rules
+}
+
+// matchDir reports whether a directory should be skipped
+func (r *ignoreRules) matchDir(root, path string) bool {
+	base := filepath.Base(path)
+	for _, ignored := range defaultIgnoreDirs {
+		if base == ignored {
+			return true
+		}
+	}
+	// Hidden directories and testdata are never packages
+	if strings.HasPrefix(base, ".") || base == "testdata" {
+		return true
+	}
+
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range append(r.dirs, r.files...) {
+		if matchPattern(pattern, rel, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFile reports whether a file (workspace-relative, slash separated) is ignored
+func (r *ignoreRules) matchFile(rel string) bool {
+	base := filepath.Base(rel)
+	for _, pattern := range r.files {
+		if matchPattern(pattern, rel, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern matches a gitignore-style pattern against a relative path.
+// Patterns without a slash match the base name anywhere in the tree.
+func matchPattern(pattern, rel, base string) bool {
+	if strings.Contains(pattern, "/") {
+		ok, _ := filepath.Match(strings.TrimPrefix(pattern, "/"), rel)
+		return ok
+	}
+	ok, _ := filepath.Match(pattern, base)
+	return ok
+}
@@ -0,0 +1,94 @@
+package build
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverPackages(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":                    "module example.com/ws\n",
+		"main.dingo":                "package main\n",
+		"pkg/a/a.dingo":             "package a\n",
+		"pkg/a/helper.go":           "package a\n",
+		"pkg/b/b.dingo":             "package b\n",
+		"pkg/plain/plain.go":        "package plain\n",
+		"vendor/x/x.dingo":          "package x\n",
+		"pkg/a/testdata/t.dingo":    "package t\n",
+		"generated/gen/gen.dingo":   "package gen\n",
+		".dingoignore":              "generated/\n",
+		"pkg/b/b_scratch.dingo":     "package b\n",
+		".dingo-cache/junk/j.dingo": "package j\n",
+	})
+	t.Chdir(root)
+
+	packages, err := DiscoverPackages(root, nil)
+	if err != nil {
+		t.Fatalf("DiscoverPackages failed: %v", err)
+	}
+
+	var paths []string
+	for _, pkg := range packages {
+		paths = append(paths, pkg.Path)
+	}
+	want := []string{".", "pkg/a", "pkg/b"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Expected packages %v, got %v", want, paths)
+	}
+
+	if got := packages[1].GoFiles; !reflect.DeepEqual(got, []string{"pkg/a/helper.go"}) {
+		t.Errorf("Expected pkg/a Go files [pkg/a/helper.go], got %v", got)
+	}
+	if got := len(packages[2].DingoFiles); got != 2 {
+		t.Errorf("Expected 2 .dingo files in pkg/b, got %d", got)
+	}
+
+	// Single package pattern
+	packages, err = DiscoverPackages(root, []string{"./pkg/b"})
+	if err != nil {
+		t.Fatalf("DiscoverPackages failed: %v", err)
+	}
+	if len(packages) != 1 || packages[0].Path != "pkg/b" {
+		t.Errorf("Expected only pkg/b, got %+v", packages)
+	}
+
+	// Subtree pattern
+	packages, err = DiscoverPackages(root, []string{"./pkg/..."})
+	if err != nil {
+		t.Fatalf("DiscoverPackages failed: %v", err)
+	}
+	if len(packages) != 2 {
+		t.Errorf("Expected 2 packages under ./pkg/..., got %d", len(packages))
+	}
+
+	// Missing directory
+	if _, err := DiscoverPackages(root, []string{"./nope"}); err == nil {
+		t.Error("Expected error for missing package directory")
+	}
+}
+
+func TestFindWorkspaceRoot(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":          "module example.com/ws\n",
+		"sub/dingo.toml":  "",
+		"sub/deep/x.txt":  "",
+		"other/deep/y.go": "package deep\n",
+	})
+
+	got, err := FindWorkspaceRoot(filepath.Join(root, "sub", "deep"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != filepath.Join(root, "sub") {
+		t.Errorf("Expected dingo.toml dir to win, got %s", got)
+	}
+
+	got, err = FindWorkspaceRoot(filepath.Join(root, "other", "deep"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != root {
+		t.Errorf("Expected go.mod dir %s, got %s", root, got)
+	}
+}
@@ -1,20 +1,28 @@
 package build
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
+	"time"
+
+	"github.com/MadAppGang/dingo/pkg/transpiler"
 )
 
+// TranspileFunc transpiles a single .dingo file (absolute path) to .go
+type TranspileFunc func(dingoPath string) error
+
 // WorkspaceBuilder handles building multiple packages in a workspace.
 // All methods are safe for concurrent use. Package builds run in parallel
 // with each writing to isolated directories. Cache updates are protected by mutex.
 type WorkspaceBuilder struct {
-	Root    string
-	Options BuildOptions
-	mu      sync.Mutex // Protects cache access during parallel builds
+	Root      string
+	Options   BuildOptions
+	transpile TranspileFunc
+	cache     *BuildCache
+	mu        sync.Mutex // Protects cache access during parallel builds
 }
 
 // BuildOptions configures workspace build behavior
@@ -22,6 +30,7 @@ type BuildOptions struct {
 	Parallel    bool // Build packages in parallel
 	Incremental bool // Only rebuild changed files
 	Verbose     bool // Enable verbose logging
+	Clean       bool // Discard the build cache before building
 	Jobs        int  // Number of parallel jobs (0 = auto)
 }
 
@@ -37,7 +46,9 @@ type Package struct {
 type BuildResult struct {
 	Package *Package
 	Success bool
-	Error   error
+	Error   error   // All of Errors joined; nil on success
+	Errors  []error // Every failure, one per .dingo file that failed
+	Skipped bool    // Not built because a dependency failed
 	Stats   BuildStats
 }
 
@@ -55,12 +66,31 @@ func NewWorkspaceBuilder(root string, opts BuildOptions) *WorkspaceBuilder {
 		opts.Jobs = 4 // Default to 4 parallel jobs
 	}
 	return &WorkspaceBuilder{
-		Root:    root,
-		Options: opts,
+		Root:      root,
+		Options:   opts,
+		transpile: defaultTranspile,
+	}
+}
+
+// SetTranspiler replaces the function used to transpile each .dingo file.
+// The CLI uses this to pass a transpiler built from the loaded config.
+func (b *WorkspaceBuilder) SetTranspiler(fn TranspileFunc) {
+	b.transpile = fn
+}
+
+// defaultTranspile transpiles a file with the default configuration
+func defaultTranspile(dingoPath string) error {
+	t, err := transpiler.New()
+	if err != nil {
+		return err
 	}
+	return t.TranspileFile(dingoPath)
 }
 
-// BuildAll builds all packages in dependency order
+// BuildAll builds all packages in dependency order. A failing file does not
+// stop the build: the other files and packages are still built, except
+// packages that depend on a failed one, which are skipped. The error
+// summarizes the failures; the results hold each of them.
 func (b *WorkspaceBuilder) BuildAll(packages []Package) ([]BuildResult, error) {
 	if len(packages) == 0 {
 		return nil, fmt.Errorf("no packages to build")
@@ -73,74 +103,71 @@ func (b *WorkspaceBuilder) BuildAll(packages []Package) ([]BuildResult, error) {
 	}
 
 	// Detect circular dependencies
-	if cycles := detectCircularDependencies(graph); len(cycles) > 0 {
-		// Format cycle paths for clear error message
-		cycleStrs := make([]string, len(cycles))
-		for i, cycle := range cycles {
-			cycleStrs[i] = strings.Join(cycle, " → ")
+	if err := checkCycles(graph); err != nil {
+		return nil, err
+	}
+
+	// Load the shared build cache once for all packages
+	if b.Options.Incremental || b.Options.Clean {
+		cache, err := NewBuildCache(b.Root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize cache: %w", err)
+		}
+		if b.Options.Clean {
+			if err := cache.InvalidateAll(); err != nil {
+				return nil, fmt.Errorf("failed to clean cache: %w", err)
+			}
 		}
-		return nil, fmt.Errorf("circular dependencies detected:\n  %s",
-			strings.Join(cycleStrs, "\n  "))
+		b.cache = cache
 	}
 
 	// Get build order (topological sort)
 	buildOrder := topologicalSort(graph)
 
-	// Build packages in order
-	results := make([]BuildResult, 0, len(packages))
-
 	if b.Options.Parallel {
-		results, err = b.buildParallel(packages, buildOrder)
-	} else {
-		results, err = b.buildSequential(packages, buildOrder)
-	}
-
-	if err != nil {
-		return results, err
+		return b.buildParallel(packages, graph, buildOrder)
 	}
-
-	return results, nil
+	return b.buildSequential(packages, graph, buildOrder)
 }
 
 // buildSequential builds packages one at a time in dependency order
-func (b *WorkspaceBuilder) buildSequential(packages []Package, buildOrder []string) ([]BuildResult, error) {
+func (b *WorkspaceBuilder) buildSequential(packages []Package, graph *DependencyGraph, buildOrder []string) ([]BuildResult, error) {
 	results := make([]BuildResult, 0, len(packages))
+	failed := make(map[string]bool)
 
 	for _, pkgPath := range buildOrder {
-		// Find package by path
-		var pkg *Package
-		for i := range packages {
-			if packages[i].Path == pkgPath {
-				pkg = &packages[i]
-				break
-			}
-		}
+		pkg := findPackage(packages, pkgPath)
 		if pkg == nil {
 			continue
 		}
 
+		if dep := failedDependency(graph, pkgPath, failed); dep != "" {
+			results = append(results, skippedResult(pkg, dep))
+			failed[pkgPath] = true
+			continue
+		}
+
 		if b.Options.Verbose {
 			fmt.Printf("Building package: %s\n", pkg.Path)
 		}
 
 		result := b.buildPackage(pkg)
 		results = append(results, result)
-
 		if !result.Success {
-			return results, fmt.Errorf("failed to build package %s: %w", pkg.Path, result.Error)
+			failed[pkgPath] = true
 		}
 	}
 
-	return results, nil
+	return results, buildError(results)
 }
 
 // buildParallel builds independent packages in parallel
-func (b *WorkspaceBuilder) buildParallel(packages []Package, buildOrder []string) ([]BuildResult, error) {
+func (b *WorkspaceBuilder) buildParallel(packages []Package, graph *DependencyGraph, buildOrder []string) ([]BuildResult, error) {
 	results := make([]BuildResult, 0, len(packages))
-	resultsMux := sync.Mutex{}
 
 	// Group packages by dependency level
-	levels := groupByDependencyLevel(buildOrder)
+	levels := groupByDependencyLevel(graph, buildOrder)
+	failed := make(map[string]bool)
 
 	// Build each level in parallel
 	for levelIdx, level := range levels {
@@ -150,23 +177,25 @@ func (b *WorkspaceBuilder) buildParallel(packages []Package, buildOrder []string
 
 		var wg sync.WaitGroup
 		semaphore := make(chan struct{}, b.Options.Jobs)
-		errors := make(chan error, len(level))
-
-		for _, pkgPath := range level {
-			// Find package
-			var pkg *Package
-			for i := range packages {
-				if packages[i].Path == pkgPath {
-					pkg = &packages[i]
-					break
-				}
-			}
+
+		// Each goroutine owns one slot, keeping results in build order
+		levelResults := make([]*BuildResult, len(level))
+
+		for i, pkgPath := range level {
+			pkg := findPackage(packages, pkgPath)
 			if pkg == nil {
 				continue
 			}
 
+			// Dependencies are in earlier levels, so their outcome is known
+			if dep := failedDependency(graph, pkgPath, failed); dep != "" {
+				result := skippedResult(pkg, dep)
+				levelResults[i] = &result
+				continue
+			}
+
 			wg.Add(1)
-			go func(p *Package) {
+			go func(idx int, p *Package) {
 				defer wg.Done()
 				semaphore <- struct{}{}        // Acquire
 				defer func() { <-semaphore }() // Release
@@ -176,44 +205,41 @@ func (b *WorkspaceBuilder) buildParallel(packages []Package, buildOrder []string
 				}
 
 				result := b.buildPackage(p)
-				resultsMux.Lock()
-				results = append(results, result)
-				resultsMux.Unlock()
-
-				if !result.Success {
-					errors <- fmt.Errorf("package %s: %w", p.Path, result.Error)
-				}
-			}(pkg)
+				levelResults[idx] = &result
+			}(i, pkg)
 		}
 
 		wg.Wait()
-		close(errors)
 
-		// Check for errors in this level
-		if err := <-errors; err != nil {
-			return results, err
+		for _, result := range levelResults {
+			if result != nil {
+				results = append(results, *result)
+				if !result.Success {
+					failed[result.Package.Path] = true
+				}
+			}
 		}
 	}
 
-	return results, nil
+	return results, buildError(results)
 }
 
 // buildPackage builds a single package.
 // Safe for concurrent use: each package writes to isolated directory.
 // Cache updates are protected by mutex.
-func (b *WorkspaceBuilder) buildPackage(pkg *Package) BuildResult {
-	result := BuildResult{
+func (b *WorkspaceBuilder) buildPackage(pkg *Package) (result BuildResult) {
+	start := time.Now()
+	result = BuildResult{
 		Package: pkg,
 		Success: false,
 		Stats:   BuildStats{},
 	}
-
-	// Get build cache (cache itself is thread-safe for reads)
-	cache, err := NewBuildCache(b.Root)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to initialize cache: %w", err)
-		return result
-	}
+	defer func() {
+		result.Stats.Duration = time.Since(start).Milliseconds()
+		if result.Error != nil && len(result.Errors) == 0 {
+			result.Errors = []error{result.Error}
+		}
+	}()
 
 	// Process each .dingo file
 	// Each file writes to its own .go file (no conflicts between goroutines)
@@ -221,9 +247,10 @@ func (b *WorkspaceBuilder) buildPackage(pkg *Package) BuildResult {
 		fullPath := filepath.Join(b.Root, dingoFile)
 
 		// Check if file needs rebuild (incremental mode)
-		if b.Options.Incremental {
-			// Read operations are safe without lock
-			needsRebuild, err := cache.NeedsRebuild(fullPath)
+		if b.Options.Incremental && b.cache != nil {
+			b.mu.Lock()
+			needsRebuild, err := b.cache.NeedsRebuild(fullPath)
+			b.mu.Unlock()
 			if err != nil {
 				result.Error = fmt.Errorf("cache check failed for %s: %w", dingoFile, err)
 				return result
@@ -237,23 +264,20 @@ func (b *WorkspaceBuilder) buildPackage(pkg *Package) BuildResult {
 			}
 		}
 
-		// Build the file (call existing transpiler)
 		if b.Options.Verbose {
 			fmt.Printf("    Transpiling: %s\n", dingoFile)
 		}
 
-		// NOTE: This would call the actual transpiler
-		// For now, placeholder to avoid import cycles
-		// err := transpile(fullPath)
-		// if err != nil {
-		// 	result.Error = fmt.Errorf("transpile failed for %s: %w", dingoFile, err)
-		// 	return result
-		// }
+		if err := b.transpile(fullPath); err != nil {
+			// Keep going so one run reports every broken file of the package
+			result.Errors = append(result.Errors, fmt.Errorf("transpile failed for %s: %w", dingoFile, err))
+			continue
+		}
 
 		// Update cache (write operation requires lock)
-		if b.Options.Incremental {
+		if b.Options.Incremental && b.cache != nil {
 			b.mu.Lock()
-			err := cache.MarkBuilt(fullPath)
+			err := b.cache.MarkBuilt(fullPath)
 			b.mu.Unlock()
 
 			if err != nil {
@@ -265,23 +289,79 @@ func (b *WorkspaceBuilder) buildPackage(pkg *Package) BuildResult {
 		result.Stats.FilesProcessed++
 	}
 
+	if len(result.Errors) > 0 {
+		result.Error = errors.Join(result.Errors...)
+		return result
+	}
 	result.Success = true
 	return result
 }
 
-// groupByDependencyLevel groups packages by their dependency level
-// Level 0 = no dependencies, Level 1 = depends only on Level 0, etc.
-func groupByDependencyLevel(buildOrder []string) [][]string {
-	// This is a simplified version - assumes buildOrder is already sorted
-	// In practice, would analyze actual dependencies
-	levels := make([][]string, 0)
+// failedDependency returns a dependency of pkgPath that failed or was
+// skipped, or "" when there is none
+func failedDependency(graph *DependencyGraph, pkgPath string, failed map[string]bool) string {
+	node := graph.Nodes[pkgPath]
+	if node == nil {
+		return ""
+	}
+	for _, dep := range node.Dependencies {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
 
-	// For now, put all packages in one level (sequential)
-	if len(buildOrder) > 0 {
-		levels = append(levels, buildOrder)
+// skippedResult is the result of a package not built because dep failed
+func skippedResult(pkg *Package, dep string) BuildResult {
+	return BuildResult{
+		Package: pkg,
+		Error:   fmt.Errorf("not built: dependency %s failed", dep),
+		Skipped: true,
 	}
+}
 
-	return levels
+// buildError summarizes the failed packages of results, or returns nil
+func buildError(results []BuildResult) error {
+	var failed []BuildResult
+	skipped := 0
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			skipped++
+		case !r.Success:
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	var err error
+	switch {
+	case len(failed) > 1:
+		err = fmt.Errorf("%d packages failed to build", len(failed))
+	case len(failed[0].Errors) > 1:
+		err = fmt.Errorf("failed to build package %s: %d files failed", failed[0].Package.Path, len(failed[0].Errors))
+	default:
+		err = fmt.Errorf("failed to build package %s: %w", failed[0].Package.Path, failed[0].Error)
+	}
+	switch {
+	case skipped == 1:
+		err = fmt.Errorf("%w (1 dependent package skipped)", err)
+	case skipped > 1:
+		err = fmt.Errorf("%w (%d dependent packages skipped)", err, skipped)
+	}
+	return err
+}
+
+// findPackage returns the package with the given path, or nil
+func findPackage(packages []Package, pkgPath string) *Package {
+	for i := range packages {
+		if packages[i].Path == pkgPath {
+			return &packages[i]
+		}
+	}
+	return nil
 }
 
 // GetTranspiledPath returns the .go path for a .dingo file
@@ -0,0 +1,332 @@
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
+)
+
+// writeWorkspace creates files (relative path -> content) under a temp root
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestParseImports(t *testing.T) {
+	src := `package main
+
+import "fmt"
+import str "strings"
+
+import (
+	"os"
+	u "example.com/ws/util"
+	_ "embed"
+)
+`
+	got := parseImports([]byte(src))
+	want := []string{"fmt", "strings", "os", "example.com/ws/util", "embed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected imports %v, got %v", want, got)
+	}
+}
+
+func chainWorkspace(t *testing.T) (string, []Package) {
+	t.Helper()
+	root := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/ws\n\ngo 1.21\n",
+		"util/util.dingo": `package util
+
+func Double(x int) int {
+	return x * 2
+}
+`,
+		"mid/mid.dingo": `package mid
+
+import "example.com/ws/util"
+
+func Quad(x int) int {
+	return util.Double(util.Double(x))
+}
+`,
+		"other/other.dingo": `package other
+
+func One() int {
+	return 1
+}
+`,
+		"app/main.dingo": `package main
+
+import (
+	"fmt"
+
+	"example.com/ws/mid"
+	"example.com/ws/other"
+)
+
+func main() {
+	fmt.Println(mid.Quad(other.One()))
+}
+`,
+	})
+	t.Chdir(root)
+
+	packages, err := DiscoverPackages(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root, packages
+}
+
+func TestBuildOrderAndLevels(t *testing.T) {
+	root, packages := chainWorkspace(t)
+
+	graph, err := buildDependencyGraph(packages, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := topologicalSort(graph)
+	want := []string{"other", "util", "mid", "app"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("Expected build order %v, got %v", want, order)
+	}
+
+	levels := groupByDependencyLevel(graph, order)
+	wantLevels := [][]string{{"other", "util"}, {"mid"}, {"app"}}
+	if !reflect.DeepEqual(levels, wantLevels) {
+		t.Errorf("Expected levels %v, got %v", wantLevels, levels)
+	}
+}
+
+func TestBuildAllCircularDependency(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":    "module example.com/ws\n",
+		"a/a.dingo": "package a\n\nimport \"example.com/ws/b\"\n",
+		"b/b.dingo": "package b\n\nimport \"example.com/ws/a\"\n",
+	})
+	t.Chdir(root)
+
+	packages, err := DiscoverPackages(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	builder := NewWorkspaceBuilder(root, BuildOptions{Parallel: true})
+	builder.SetTranspiler(func(string) error {
+		t.Error("transpiler must not run when dependencies are circular")
+		return nil
+	})
+
+	_, err = builder.BuildAll(packages)
+	if err == nil || !strings.Contains(err.Error(), "circular dependencies") {
+		t.Fatalf("Expected circular dependency error, got %v", err)
+	}
+}
+
+func TestBuildAllRespectsDependencyOrder(t *testing.T) {
+	root, packages := chainWorkspace(t)
+
+	var mu sync.Mutex
+	built := make(map[string]int)
+	seq := 0
+
+	builder := NewWorkspaceBuilder(root, BuildOptions{Parallel: true, Jobs: 2})
+	builder.SetTranspiler(func(path string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		rel, _ := filepath.Rel(root, filepath.Dir(path))
+		built[filepath.ToSlash(rel)] = seq
+		seq++
+		return nil
+	})
+
+	results, err := builder.BuildAll(packages)
+	if err != nil {
+		t.Fatalf("BuildAll failed: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+
+	before := func(dep, pkg string) {
+		if built[dep] >= built[pkg] {
+			t.Errorf("Expected %s to be built before %s", dep, pkg)
+		}
+	}
+	before("util", "mid")
+	before("mid", "app")
+	before("other", "app")
+}
+
+func TestBuildAllSkipsDependentsOnError(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		root, packages := chainWorkspace(t)
+
+		builder := NewWorkspaceBuilder(root, BuildOptions{Parallel: parallel})
+		builder.SetTranspiler(func(path string) error {
+			if strings.Contains(path, "mid") {
+				return fmt.Errorf("boom")
+			}
+			return nil
+		})
+
+		results, err := builder.BuildAll(packages)
+		if err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("parallel=%v: expected build error, got %v", parallel, err)
+		}
+
+		byPath := make(map[string]BuildResult)
+		for _, r := range results {
+			byPath[r.Package.Path] = r
+		}
+		if r := byPath["mid"]; r.Success || r.Skipped || len(r.Errors) != 1 {
+			t.Errorf("parallel=%v: expected mid to fail, got %+v", parallel, r)
+		}
+		if r := byPath["other"]; !r.Success {
+			t.Errorf("parallel=%v: independent package other must still be built, got %+v", parallel, r)
+		}
+		if r := byPath["app"]; !r.Skipped || r.Stats.FilesProcessed != 0 {
+			t.Errorf("parallel=%v: app must be skipped after its dependency failed, got %+v", parallel, r)
+		}
+	}
+}
+
+func TestBuildAllReportsEveryFile(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":      "module example.com/ws\n\ngo 1.21\n",
+		"pkg/a.dingo": "package pkg\n",
+		"pkg/b.dingo": "package pkg\n",
+		"pkg/c.dingo": "package pkg\n",
+	})
+	packages := []Package{{Path: "pkg", Name: "pkg", DingoFiles: []string{"pkg/a.dingo", "pkg/b.dingo", "pkg/c.dingo"}}}
+
+	builder := NewWorkspaceBuilder(root, BuildOptions{})
+	builder.SetTranspiler(func(path string) error {
+		if filepath.Base(path) == "b.dingo" {
+			return nil
+		}
+		return fmt.Errorf("broken %s", filepath.Base(path))
+	})
+
+	results, err := builder.BuildAll(packages)
+	if err == nil {
+		t.Fatal("Expected build error")
+	}
+	r := results[0]
+	if len(r.Errors) != 2 || r.Stats.FilesProcessed != 1 {
+		t.Fatalf("Expected 2 errors and 1 file built, got %v (%d built)", r.Errors, r.Stats.FilesProcessed)
+	}
+	for _, want := range []string{"broken a.dingo", "broken c.dingo"} {
+		if !strings.Contains(r.Error.Error(), want) {
+			t.Errorf("Error %q lacks %q", r.Error, want)
+		}
+	}
+}
+
+func TestBuildAllTranspilesAndCaches(t *testing.T) {
+	root, packages := chainWorkspace(t)
+
+	builder := NewWorkspaceBuilder(root, BuildOptions{Parallel: true, Incremental: true})
+	results, err := builder.BuildAll(packages)
+	if err != nil {
+		t.Fatalf("BuildAll failed: %v", err)
+	}
+	for _, r := range results {
+		if r.Stats.FilesProcessed != 1 {
+			t.Errorf("%s: expected 1 file transpiled, got %d", r.Package.Path, r.Stats.FilesProcessed)
+		}
+	}
+
+	for _, rel := range []string{"util/util.go", "mid/mid.go", "app/main.go"} {
+		if _, err := os.Stat(filepath.Join(root, rel)); err != nil {
+			t.Errorf("Expected %s to be generated: %v", rel, err)
+		}
+	}
+	if _, err := sourcemap.Load(filepath.Join(root, "app", "main.go")); err != nil {
+		t.Errorf("Expected app/main.go to have a source map: %v", err)
+	}
+
+	// Second build is fully cached
+	builder = NewWorkspaceBuilder(root, BuildOptions{Parallel: true, Incremental: true})
+	results, err = builder.BuildAll(packages)
+	if err != nil {
+		t.Fatalf("Second BuildAll failed: %v", err)
+	}
+	for _, r := range results {
+		if r.Stats.FilesSkipped != 1 || r.Stats.FilesProcessed != 0 {
+			t.Errorf("%s: expected cached build, got %+v", r.Package.Path, r.Stats)
+		}
+	}
+
+	// Clean forces a full rebuild
+	builder = NewWorkspaceBuilder(root, BuildOptions{Incremental: true, Clean: true})
+	results, err = builder.BuildAll(packages)
+	if err != nil {
+		t.Fatalf("Clean BuildAll failed: %v", err)
+	}
+	for _, r := range results {
+		if r.Stats.FilesProcessed != 1 {
+			t.Errorf("%s: expected rebuild after clean, got %+v", r.Package.Path, r.Stats)
+		}
+	}
+}
+
+func TestBuildAllRebuildsPackageWhenFilesChange(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":      "module example.com/ws\n\ngo 1.21\n",
+		"app/a.dingo": "package app\n\nfunc Pair() int {\n\tt := (1, \"a\")\n\treturn t._0\n}\n",
+	})
+	build := func(files ...string) BuildResult {
+		t.Helper()
+		packages := []Package{{Path: "app", Name: "app", DingoFiles: files}}
+		results, err := NewWorkspaceBuilder(root, BuildOptions{Incremental: true}).BuildAll(packages)
+		if err != nil {
+			t.Fatalf("BuildAll failed: %v", err)
+		}
+		return results[0]
+	}
+	declares := func(rel string) bool {
+		src, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel)))
+		return err == nil && strings.Contains(string(src), "type Tuple2IntString")
+	}
+
+	// A one-file package declares its types itself
+	build("app/a.dingo")
+	if !declares("app/a.go") {
+		t.Fatal("a.go of a one-file package must declare Tuple2IntString")
+	}
+
+	// A second file moves them to dingo_types.go, so a.go must be rebuilt
+	// although it did not change
+	if err := os.WriteFile(filepath.Join(root, "app", "b.dingo"), []byte("package app\n\nfunc Other() string {\n\tt := (2, \"b\")\n\treturn t._1\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if r := build("app/a.dingo", "app/b.dingo"); r.Stats.FilesProcessed != 2 {
+		t.Errorf("expected both files rebuilt after adding one, got %+v", r.Stats)
+	}
+	if declares("app/a.go") || declares("app/b.go") || !declares("app/dingo_types.go") {
+		t.Error("Tuple2IntString must be declared once, in dingo_types.go")
+	}
+
+	// The package is cached again until its files change
+	if r := build("app/a.dingo", "app/b.dingo"); r.Stats.FilesSkipped != 2 {
+		t.Errorf("expected a cached build, got %+v", r.Stats)
+	}
+}
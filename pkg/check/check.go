@@ -0,0 +1,349 @@
+// Package check type-checks Dingo packages entirely in memory.
+//
+// Every .dingo file goes through the full transpiler pipeline (preprocessor,
+// generator with its plugin checks such as match exhaustiveness), then the
+// generated Go of each package is type-checked with go/types together with the
+// package's hand-written .go files. Problems are reported as diagnostics at
+// .dingo positions. Nothing is written to disk.
+package check
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	gobuild "go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/generator"
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// Checker checks workspace packages. Packages checked earlier are used to
+// resolve imports of later ones, so check them in dependency order.
+type Checker struct {
+	root       string
+	transpiler *transpiler.Transpiler
+	fset       *token.FileSet
+	fallback   types.Importer
+	checked    map[string]*types.Package // Import path -> checked workspace package
+	failed     map[string]bool           // Import paths of packages that did not transpile
+	overlay    map[string][]byte         // Absolute path -> contents replacing the file on disk
+}
+
+// New creates a checker for the workspace at root
+func New(root string, t *transpiler.Transpiler) *Checker {
+	return &Checker{
+		root:       root,
+		transpiler: t,
+		fset:       token.NewFileSet(),
+		fallback:   importer.Default(),
+		checked:    make(map[string]*types.Package),
+		failed:     make(map[string]bool),
+	}
+}
+
+// SetOverlay makes the checker see the given contents (keyed by absolute
+// path) instead of the files on disk. A .dingo file in the overlay supersedes
+// its .go sibling even when it does not exist on disk yet.
+func (c *Checker) SetOverlay(overlay map[string][]byte) {
+	c.overlay = overlay
+}
+
+// Import implements types.Importer. Workspace packages checked earlier take
+// precedence over compiled export data, which does not exist for them.
+func (c *Checker) Import(path string) (*types.Package, error) {
+	if pkg, ok := c.checked[path]; ok {
+		return pkg, nil
+	}
+	if c.failed[path] {
+		return nil, fmt.Errorf("package %s has errors", path)
+	}
+	return c.fallback.Import(path)
+}
+
+// CheckPackages checks packages in dependency order and returns all
+// diagnostics, sorted by position
+func (c *Checker) CheckPackages(packages []build.Package) ([]diagnostic.Diagnostic, error) {
+	sorted, err := build.SortPackages(packages, c.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var diags []diagnostic.Diagnostic
+	for _, pkg := range sorted {
+		pkgDiags, err := c.CheckPackage(pkg)
+		if err != nil {
+			return nil, err
+		}
+		diags = append(diags, pkgDiags...)
+	}
+
+	diagnostic.Sort(diags)
+	return diags, nil
+}
+
+// generatedFile is the in-memory Go output of one .dingo file, or the
+// package's TypesFile, which has no source map
+type generatedFile struct {
+	goPath    string
+	code      []byte
+	sourceMap *preprocessor.SourceMap
+}
+
+// CheckPackage transpiles and type-checks a single package
+func (c *Checker) CheckPackage(pkg build.Package) ([]diagnostic.Diagnostic, error) {
+	dir := filepath.Join(c.root, filepath.FromSlash(pkg.Path))
+	importPath, err := build.ImportPath(c.root, pkg.Path)
+	if err != nil {
+		importPath = pkg.Path
+	}
+
+	sources := make(map[string][]byte, len(pkg.DingoFiles))
+	paths := make([]string, 0, len(pkg.DingoFiles))
+	for _, rel := range pkg.DingoFiles {
+		path := filepath.Join(c.root, filepath.FromSlash(rel))
+		src, err := c.readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		sources[path] = src
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// Step 1: Transpile every file, collecting diagnostics from all of them.
+	// Injected types are declared once for the package, as dingo build does.
+	var diags []diagnostic.Diagnostic
+	var generated []generatedFile
+	var pkgName string
+	var types []generator.TypeDecl
+	for _, path := range paths {
+		res, err := transpiler.Transpile(context.Background(), path, sources[path], transpiler.Options{
+			Config:         c.transpiler.Config(),
+			PackageSources: sources,
+			SharedTypes:    true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		diags = append(diags, res.Diagnostics...)
+		if res.Go == nil {
+			continue
+		}
+		sm := res.SourceMap
+		if sm == nil {
+			sm = preprocessor.NewSourceMap()
+		}
+		generated = append(generated, generatedFile{goPath: goPathFor(path), code: res.Go, sourceMap: sm})
+		pkgName = res.Package
+		types = append(types, res.Types...)
+	}
+	if diagnostic.HasErrors(diags) {
+		c.failed[importPath] = true
+		return diags, nil
+	}
+	if len(types) > 0 {
+		code, err := transpiler.TypesFileSource(pkgName, types)
+		if err != nil {
+			return nil, err
+		}
+		generated = append(generated, generatedFile{goPath: filepath.Join(dir, transpiler.TypesFile), code: code})
+	}
+
+	// Step 2: Type-check the generated Go with the hand-written .go files
+	typeDiags, err := c.typeCheck(importPath, dir, pkg, generated)
+	if err != nil {
+		return nil, err
+	}
+	return append(diags, typeDiags...), nil
+}
+
+// typeCheck runs go/types over the package the way go build would see it:
+// the library files first, then internal and external test files
+func (c *Checker) typeCheck(importPath, dir string, pkg build.Package, generated []generatedFile) ([]diagnostic.Diagnostic, error) {
+	maps := make(map[string]*preprocessor.SourceMap, len(generated))
+	var files []*ast.File
+	for _, gen := range generated {
+		file, err := parser.ParseFile(c.fset, gen.goPath, gen.code, parser.ParseComments)
+		if err != nil {
+			// Generated code that does not parse is a transpiler bug; report it at the source
+			return c.parseFailure(gen, err), nil
+		}
+		if gen.sourceMap != nil {
+			maps[gen.goPath] = gen.sourceMap
+		}
+		files = append(files, file)
+	}
+
+	handWritten, err := c.handWrittenFiles(dir, pkg)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, handWritten...)
+
+	// Split like go test: library, internal tests, external (_test package) tests
+	var lib, tests, xtests []*ast.File
+	for _, file := range files {
+		name := c.fset.Position(file.Package).Filename
+		switch {
+		case !strings.HasSuffix(name, "_test.go"):
+			lib = append(lib, file)
+		case strings.HasSuffix(file.Name.Name, "_test"):
+			xtests = append(xtests, file)
+		default:
+			tests = append(tests, file)
+		}
+	}
+
+	var diags []diagnostic.Diagnostic
+	report := func(err error) {
+		if d, ok := c.typeDiagnostic(err, maps); ok {
+			diags = append(diags, d)
+		}
+	}
+
+	if len(lib) > 0 {
+		conf := types.Config{Importer: c, Error: report}
+		checked, _ := conf.Check(importPath, c.fset, lib, nil)
+		if checked != nil {
+			c.checked[importPath] = checked
+		}
+	}
+
+	// Library errors were reported above; only keep errors in test files
+	if len(tests) > 0 {
+		libErrors := len(diags)
+		conf := types.Config{Importer: c, Error: report}
+		_, _ = conf.Check(importPath, c.fset, append(append([]*ast.File{}, lib...), tests...), nil)
+		diags = append(diags[:libErrors], onlyTestFiles(diags[libErrors:])...)
+	}
+
+	if len(xtests) > 0 {
+		conf := types.Config{Importer: c, Error: report}
+		_, _ = conf.Check(importPath+"_test", c.fset, xtests, nil)
+	}
+
+	return diags, nil
+}
+
+// handWrittenFiles parses the package's .go files that are not generated
+// from a .dingo file and match the current build constraints
+func (c *Checker) handWrittenFiles(dir string, pkg build.Package) ([]*ast.File, error) {
+	var files []*ast.File
+	for _, rel := range pkg.GoFiles {
+		path := filepath.Join(c.root, filepath.FromSlash(rel))
+		if c.exists(dingoPathFor(path)) {
+			continue // Output of a previous dingo build, superseded by the in-memory result
+		}
+		if filepath.Base(path) == transpiler.TypesFile {
+			if src, err := c.readFile(path); err == nil && transpiler.IsTypesFile(src) {
+				continue // Likewise
+			}
+		}
+		if ok, err := gobuild.Default.MatchFile(dir, filepath.Base(path)); err != nil || !ok {
+			continue
+		}
+
+		src, err := c.readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		file, err := parser.ParseFile(c.fset, path, src, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// readFile returns the overlay contents of path, or the file on disk
+func (c *Checker) readFile(path string) ([]byte, error) {
+	if src, ok := c.overlay[path]; ok {
+		return src, nil
+	}
+	return os.ReadFile(path)
+}
+
+// exists reports whether path is in the overlay or on disk
+func (c *Checker) exists(path string) bool {
+	if _, ok := c.overlay[path]; ok {
+		return true
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// typeDiagnostic converts a go/types error into a diagnostic at the .dingo
+// position when the error lies in generated code
+func (c *Checker) typeDiagnostic(err error, maps map[string]*preprocessor.SourceMap) (diagnostic.Diagnostic, bool) {
+	typeErr, ok := err.(types.Error)
+	if !ok {
+		return diagnostic.Diagnostic{Code: diagnostic.CodeType, Severity: diagnostic.SeverityError, Message: err.Error(), Source: diagnostic.SourceTypes}, true
+	}
+
+	// Follow-on errors of a dependency that failed to transpile add nothing
+	for path := range c.failed {
+		if strings.Contains(typeErr.Msg, "could not import "+path) {
+			return diagnostic.Diagnostic{}, false
+		}
+	}
+
+	pos := typeErr.Fset.PositionFor(typeErr.Pos, false) // Source maps cover //line directives
+	d := diagnostic.Diagnostic{Code: diagnostic.CodeType, Severity: diagnostic.SeverityError, Pos: pos, Message: typeErr.Msg, Source: diagnostic.SourceTypes}
+
+	if sm, ok := maps[pos.Filename]; ok {
+		d.Pos.Filename = dingoPathFor(pos.Filename)
+		if line, col, mapped := sourcemap.MapLine(sm, pos.Line, pos.Column); mapped {
+			d.Pos.Line, d.Pos.Column = line, col
+			if src, err := c.readFile(d.Pos.Filename); err == nil {
+				d.ExtendToToken(src)
+			}
+		} else {
+			d.Pos.Line, d.Pos.Column = 0, 0
+		}
+	}
+	return d, true
+}
+
+// parseFailure reports generated Go that go/parser rejects
+func (c *Checker) parseFailure(gen generatedFile, err error) []diagnostic.Diagnostic {
+	d := diagnostic.Diagnostic{
+		Code:     diagnostic.CodeGoSyntax,
+		Severity: diagnostic.SeverityError,
+		Message:  fmt.Sprintf("generated Go does not parse: %v", err),
+		Source:   diagnostic.SourceParse,
+	}
+	d.Pos.Filename = dingoPathFor(gen.goPath)
+	return []diagnostic.Diagnostic{d}
+}
+
+// onlyTestFiles keeps diagnostics located in test files
+func onlyTestFiles(diags []diagnostic.Diagnostic) []diagnostic.Diagnostic {
+	var kept []diagnostic.Diagnostic
+	for _, d := range diags {
+		if strings.HasSuffix(d.Pos.Filename, "_test.dingo") || strings.HasSuffix(d.Pos.Filename, "_test.go") {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+func goPathFor(dingoPath string) string {
+	return strings.TrimSuffix(dingoPath, ".dingo") + ".go"
+}
+
+func dingoPathFor(goPath string) string {
+	return strings.TrimSuffix(goPath, ".go") + ".dingo"
+}
@@ -0,0 +1,225 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// writeWorkspace creates files (relative path -> content) under a temp root
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func checkWorkspace(t *testing.T, root string) []diagnostic.Diagnostic {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	packages, err := build.DiscoverPackages(root, nil)
+	if err != nil {
+		t.Fatalf("DiscoverPackages() error: %v", err)
+	}
+	diags, err := New(root, transpiler.NewWithConfig(config.DefaultConfig())).CheckPackages(packages)
+	if err != nil {
+		t.Fatalf("CheckPackages() error: %v", err)
+	}
+	return diags
+}
+
+const utilSource = `package util
+
+func Half(n int) (int, error) {
+	return n / 2, nil
+}
+`
+
+func TestCheckPackagesClean(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":          "module example.com/ws\n\ngo 1.21\n",
+		"util/util.dingo": utilSource,
+		"main.dingo": `package main
+
+import (
+	"fmt"
+
+	"example.com/ws/util"
+)
+
+func quarter(n int) (int, error) {
+	let h = util.Half(n)?
+	return h / 2, nil
+}
+
+func main() {
+	fmt.Println(quarter(8))
+}
+`,
+	})
+
+	if diags := checkWorkspace(t, root); len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	// Nothing may be written next to the sources
+	for _, generated := range []string{"main.go", "main.go.map", "util/util.go"} {
+		if _, err := os.Stat(filepath.Join(root, generated)); !os.IsNotExist(err) {
+			t.Errorf("check wrote %s", generated)
+		}
+	}
+}
+
+func TestCheckPackagesTypeErrors(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":          "module example.com/ws\n\ngo 1.21\n",
+		"util/util.dingo": utilSource,
+		"main.dingo": `package main
+
+import "example.com/ws/util"
+
+func describe() string {
+	let s = "x"
+	return s + 1
+}
+
+func main() {
+	let h = util.Missing(2)
+	println(h, describe())
+}
+`,
+	})
+
+	diags := checkWorkspace(t, root)
+	want := []struct {
+		line    int
+		message string
+	}{
+		{7, "mismatched types string and untyped int"},
+		{11, "undefined: util.Missing"},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %v", len(diags), len(want), diags)
+	}
+	for i, w := range want {
+		d := diags[i]
+		if filepath.Base(d.Pos.Filename) != "main.dingo" || d.Pos.Line != w.line {
+			t.Errorf("diagnostic %d at %s:%d, want main.dingo:%d", i, d.Pos.Filename, d.Pos.Line, w.line)
+		}
+		if !strings.Contains(d.Message, w.message) {
+			t.Errorf("diagnostic %d = %q, want it to contain %q", i, d.Message, w.message)
+		}
+		if d.Source != diagnostic.SourceTypes {
+			t.Errorf("diagnostic %d source = %q, want %q", i, d.Source, diagnostic.SourceTypes)
+		}
+	}
+}
+
+func TestCheckPackagesTypeErrorColumns(t *testing.T) {
+	// let and lambda lines are rewritten, so their columns differ in Go
+	root := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/ws\n\ngo 1.21\n",
+		"main.dingo": `package main
+
+func main() {
+	let y: int = "str"
+	println(y)
+	f := (x: int): int => { return "s" }
+	println(f(1))
+}
+`,
+	})
+
+	diags := checkWorkspace(t, root)
+	want := []struct{ line, col, endCol int }{
+		{4, 15, 20}, // "str"
+		{6, 33, 36}, // "s"
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %v", len(diags), len(want), diags)
+	}
+	for i, w := range want {
+		d := diags[i]
+		if d.Pos.Line != w.line || d.Pos.Column != w.col || d.End.Column != w.endCol {
+			t.Errorf("diagnostic %d at %d:%d-%d, want %d:%d-%d: %s", i, d.Pos.Line, d.Pos.Column, d.End.Column, w.line, w.col, w.endCol, d.Message)
+		}
+	}
+}
+
+func TestCheckPackagesUnusedIsError(t *testing.T) {
+	// go build rejects unused variables and imports, so dingo check must too
+	root := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/ws\n\ngo 1.21\n",
+		"main.dingo": `package main
+
+import "os"
+
+func main() {
+	let x = 1
+}
+`,
+	})
+
+	diags := checkWorkspace(t, root)
+	want := []struct {
+		line    int
+		message string
+	}{
+		{3, `"os" imported and not used`},
+		{6, "declared and not used: x"},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %v", len(diags), len(want), diags)
+	}
+	for i, w := range want {
+		d := diags[i]
+		if d.Pos.Line != w.line || !strings.Contains(d.Message, w.message) {
+			t.Errorf("diagnostic %d = %d: %q, want %d: %q", i, d.Pos.Line, d.Message, w.line, w.message)
+		}
+		if d.Severity != diagnostic.SeverityError {
+			t.Errorf("diagnostic %d severity = %v, want error", i, d.Severity)
+		}
+	}
+}
+
+func TestCheckPackagesReportsEveryFile(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":  "module example.com/ws\n\ngo 1.21\n",
+		"a.dingo": "package main\n\nfunc a() {\n\tlet x = (1 +\n}\n",
+		"b.dingo": "package main\n\nfunc b() {\n\tlet y = [1,\n}\n",
+	})
+
+	diags := checkWorkspace(t, root)
+	files := make(map[string]bool)
+	for _, d := range diags {
+		files[filepath.Base(d.Pos.Filename)] = true
+		if d.Severity != diagnostic.SeverityError {
+			t.Errorf("expected error severity: %v", d)
+		}
+	}
+	if !files["a.dingo"] || !files["b.dingo"] {
+		t.Errorf("expected diagnostics for both files, got %v", diags)
+	}
+}
@@ -50,6 +50,45 @@ const (
 	FormatNone SourceMapFormat = "none"
 )
 
+// SourceMapSchema represents the JSON schema source maps are written in
+type SourceMapSchema string
+
+const (
+	// SchemaDingo writes Dingo's own list of mappings (docs/sourcemap-schema.md)
+	SchemaDingo SourceMapSchema = "dingo"
+
+	// SchemaV3 writes standard Source Map v3, for generic source map tools.
+	// Dingo tools read both schemas.
+	SchemaV3 SourceMapSchema = "v3"
+)
+
+// TypeGeneration represents how Option and Result types are generated
+type TypeGeneration string
+
+const (
+	// TypesMonomorphized declares a struct with its methods for every
+	// instantiation (Option_int, Result_User_error) in the generated Go, which
+	// then has no dependencies
+	TypesMonomorphized TypeGeneration = "monomorphized"
+
+	// TypesGeneric uses dingo.Option[T] and dingo.Result[T, E] from the
+	// runtime library (RuntimePath)
+	TypesGeneric TypeGeneration = "generic"
+)
+
+// RuntimePath is the import path of the runtime library used by TypesGeneric
+const RuntimePath = "github.com/MadAppGang/dingo/runtime/dingo"
+
+// IsValid reports whether the type generation mode is valid
+func (g TypeGeneration) IsValid() bool {
+	switch g {
+	case TypesMonomorphized, TypesGeneric:
+		return true
+	default:
+		return false
+	}
+}
+
 // MatchConfig controls pattern matching feature behavior
 type MatchConfig struct {
 	// Syntax selects the pattern matching syntax style
@@ -135,6 +174,13 @@ type FeatureConfig struct {
 	// - "explicit": Require parentheses for ambiguous mixing
 	OperatorPrecedence string `toml:"operator_precedence"`
 
+	// TypeGeneration controls how Option<T> and Result<T, E> are generated
+	// Valid values: "monomorphized", "generic"
+	// - "monomorphized": Option_int, Result_User_error, ... declared in the
+	//   generated Go (default, no dependencies)
+	// - "generic": dingo.Option[T] and dingo.Result[T, E] from RuntimePath
+	TypeGeneration TypeGeneration `toml:"type_generation"`
+
 	// ResultType controls Result<T, E> type generation and Go interop
 	ResultType ResultTypeConfig `toml:"result_type"`
 
@@ -150,6 +196,24 @@ type SourceMapConfig struct {
 	// Format controls the source map output format
 	// Valid values: "inline", "separate", "both", "none"
 	Format SourceMapFormat `toml:"format"`
+
+	// LineDirectives emits //line file.dingo:N:C directives into generated
+	// Go, so that compiler errors, panics, runtime.Caller, pprof and
+	// debuggers report .dingo positions without the source maps
+	LineDirectives bool `toml:"line_directives"`
+
+	// Schema controls the JSON schema of source maps
+	// Valid values: "dingo", "v3"
+	Schema SourceMapSchema `toml:"schema"`
+}
+
+// OutputFormat returns the format source maps are written in: FormatNone
+// when source maps are disabled, Format otherwise
+func (c SourceMapConfig) OutputFormat() SourceMapFormat {
+	if !c.Enabled {
+		return FormatNone
+	}
+	return c.Format
 }
 
 // NilSafetyMode represents nil safety check modes
@@ -172,6 +236,7 @@ func DefaultConfig() *Config {
 			SafeNavigationUnwrap:   "smart",        // Default to smart unwrapping
 			NullCoalescingPointers: true,           // Default to supporting Go pointers
 			OperatorPrecedence:     "standard",     // Default to standard precedence
+			TypeGeneration:         TypesMonomorphized,
 			ResultType: ResultTypeConfig{
 				Enabled:   true,
 				GoInterop: "opt-in", // Default to safe explicit wrapping
@@ -186,7 +251,8 @@ func DefaultConfig() *Config {
 		},
 		SourceMap: SourceMapConfig{
 			Enabled: true,
-			Format:  FormatInline, // Default to inline for development
+			Format:  FormatSeparate, // Keep the generated Go free of base64 map comments
+			Schema:  SchemaDingo,
 		},
 	}
 }
@@ -311,6 +377,12 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate type generation mode
+	if c.Features.TypeGeneration != "" && !c.Features.TypeGeneration.IsValid() {
+		return fmt.Errorf("invalid type_generation: %q (must be 'monomorphized' or 'generic')",
+			c.Features.TypeGeneration)
+	}
+
 	// Validate Result type go_interop mode
 	if c.Features.ResultType.GoInterop != "" {
 		switch c.Features.ResultType.GoInterop {
@@ -342,6 +414,14 @@ func (c *Config) Validate() error {
 			c.SourceMap.Format)
 	}
 
+	// Validate source map schema
+	switch c.SourceMap.Schema {
+	case "", SchemaDingo, SchemaV3:
+		// Valid
+	default:
+		return fmt.Errorf("invalid sourcemap schema: %q (must be 'dingo' or 'v3')", c.SourceMap.Schema)
+	}
+
 	return nil
 }
 
@@ -17,8 +17,8 @@ func TestDefaultConfig(t *testing.T) {
 		t.Error("Expected source maps to be enabled by default")
 	}
 
-	if cfg.SourceMap.Format != FormatInline {
-		t.Errorf("Expected default format to be 'inline', got %q", cfg.SourceMap.Format)
+	if cfg.SourceMap.Format != FormatSeparate {
+		t.Errorf("Expected default format to be 'separate', got %q", cfg.SourceMap.Format)
 	}
 
 	// Test Match defaults
@@ -124,6 +124,21 @@ func TestConfigValidation(t *testing.T) {
 			wantError: true,
 			errorMsg:  "invalid sourcemap format",
 		},
+		{
+			name: "invalid source map schema",
+			config: &Config{
+				Features: FeatureConfig{
+					ErrorPropagationSyntax: SyntaxQuestion,
+				},
+				SourceMap: SourceMapConfig{
+					Enabled: true,
+					Format:  FormatInline,
+					Schema:  SourceMapSchema("v2"),
+				},
+			},
+			wantError: true,
+			errorMsg:  "invalid sourcemap schema",
+		},
 		{
 			name: "valid result go_interop opt-in",
 			config: &Config{
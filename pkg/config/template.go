@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/template"
+)
+
+// projectTemplate renders a dingo.toml. The comments follow dingo.toml.example
+// so that a generated file documents every setting it contains.
+var projectTemplate = template.Must(template.New("dingo.toml").Funcs(template.FuncMap{
+	"quote": func(v interface{}) string { return strconv.Quote(fmt.Sprint(v)) },
+}).Parse(`# Dingo Configuration File
+# This file controls the behavior of the Dingo transpiler
+
+[features]
+# Error propagation operator syntax
+# Valid values: "question" (?), "bang" (!), "try" (try keyword)
+error_propagation_syntax = {{quote .Features.ErrorPropagationSyntax}}
+
+# Reuse "err" variable instead of generating __err0, __err1, etc.
+# When true: Generates cleaner code with reused "err" variable (more idiomatic)
+# When false: Generates unique names like __err0, __err1 (safer, avoids shadowing)
+# Default: true
+reuse_err_variable = {{.Features.ReuseErrVariable}}
+
+# Nil safety checks for pattern destructuring in sum types
+# Valid values: "off", "on", "debug"
+# - off: No nil checks (trust constructors, maximum performance)
+# - on: Always check with runtime panic (safe, recommended)
+# - debug: Check only when DINGO_DEBUG environment variable is set
+# Default: "on"
+nil_safety_checks = {{quote .Features.NilSafetyChecks}}
+
+# Lambda function syntax (only one style is active per project)
+# Valid values: "typescript", "rust"
+# - typescript: Arrow syntax: (x: int): int => x * 2
+# - rust: Pipe syntax: |x: int| -> int { x * 2 }
+# Default: "typescript"
+lambda_style = {{quote .Features.LambdaStyle}}
+
+# How the ?. operator handles return types
+# Valid values: "always_option", "smart"
+# - always_option: Always returns Option<T>
+# - smart: Unwraps to T based on context
+# Default: "smart"
+safe_navigation_unwrap = {{quote .Features.SafeNavigationUnwrap}}
+
+# Allow the ?? operator on Go pointers (*T) as well as Option<T>
+# Default: true
+null_coalescing_pointers = {{.Features.NullCoalescingPointers}}
+
+# Ternary and null-coalescing precedence checking
+# Valid values: "standard", "explicit"
+# - standard: Follow C/TypeScript precedence rules
+# - explicit: Require parentheses for ambiguous mixing
+# Default: "standard"
+operator_precedence = {{quote .Features.OperatorPrecedence}}
+
+# How Option<T> and Result<T, E> types are generated
+# Valid values: "monomorphized", "generic"
+# - monomorphized: Declares Option_int, Result_User_error, ... with their
+#   methods in the generated Go, which has no dependencies
+# - generic: Uses dingo.Option[T] and dingo.Result[T, E] from the runtime
+#   library github.com/MadAppGang/dingo/runtime/dingo
+# Default: "monomorphized"
+type_generation = {{quote .Features.TypeGeneration}}
+
+[features.result_type]
+# Enable Result<T, E> type for error handling
+# Default: true
+enabled = {{.Features.ResultType.Enabled}}
+
+# Go interoperability mode for (T, error) returns
+# Valid values: "opt-in", "auto", "disabled"
+# - "opt-in": Requires explicit Result.FromGo() wrapper (safe, recommended)
+#   Example: let user = Result.FromGo(fetchUser(id))
+# - "auto": Automatically wraps (T, error) → Result<T, E>
+#   Example: let user = fetchUser(id)  // Auto-wrapped to Result
+# - "disabled": No Go interop, pure Dingo types only
+# Default: "opt-in"
+go_interop = {{quote .Features.ResultType.GoInterop}}
+
+[features.option_type]
+# Enable Option<T> type for null safety
+# Default: true
+enabled = {{.Features.OptionType.Enabled}}
+
+# Go interoperability mode for pointer types (*T)
+# Valid values: "opt-in", "auto", "disabled"
+# - "opt-in": Requires explicit Option.FromPtr() wrapper (safe, recommended)
+#   Example: let user = Option.FromPtr(findUser(id))
+# - "auto": Automatically wraps *T → Option<T>
+#   Example: let user = findUser(id)  // Auto-wrapped to Option
+# - "disabled": No Go interop, pure Dingo types only
+# Default: "opt-in"
+go_interop = {{quote .Features.OptionType.GoInterop}}
+
+[match]
+# Pattern matching syntax
+# Valid values: "rust" (match expr { ... })
+syntax = {{quote .Match.Syntax}}
+
+[sourcemaps]
+# Enable source map generation for better debugging and IDE support
+enabled = {{.SourceMap.Enabled}}
+
+# Source map output format
+# Valid values: "inline", "separate", "both", "none"
+# - inline: Embeds source maps as comments in .go files
+# - separate: Writes .go.map files
+# - both: Generates both inline and separate files
+# - none: Disables source maps (same as enabled = false)
+format = {{quote .SourceMap.Format}}
+
+# Emit //line directives so the Go toolchain reports .dingo positions itself
+# (go build errors, panics, runtime.Caller, pprof, delve). Source maps (in
+# the format above) and // dingo:s:N / // dingo:e:N markers are still
+# generated.
+# Default: false
+line_directives = {{.SourceMap.LineDirectives}}
+
+# Source map schema
+# Valid values: "dingo", "v3"
+# - dingo: Dingo's own JSON format, read by dingo-lsp and the error rewriter
+# - v3: Standard Source Map v3 (sources, sourcesContent, names), readable by
+#   browsers, debuggers and other tools; dingo-lsp reads it too
+# Default: "dingo"
+schema = {{quote .SourceMap.Schema}}
+`))
+
+// WriteTOML writes the configuration as a documented dingo.toml, with the
+// comments of dingo.toml.example next to every setting
+func (c *Config) WriteTOML(w io.Writer) error {
+	return projectTemplate.Execute(w, c)
+}
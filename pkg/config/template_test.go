@@ -0,0 +1,58 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestWriteTOMLRoundTrip(t *testing.T) {
+	custom := DefaultConfig()
+	custom.Features.LambdaStyle = "rust"
+	custom.Features.ErrorPropagationSyntax = SyntaxTry
+	custom.Features.ReuseErrVariable = false
+	custom.SourceMap.Format = FormatSeparate
+
+	for name, cfg := range map[string]*Config{"default": DefaultConfig(), "custom": custom} {
+		var buf bytes.Buffer
+		if err := cfg.WriteTOML(&buf); err != nil {
+			t.Fatalf("%s: WriteTOML() error: %v", name, err)
+		}
+
+		// Decode over zero values so that every field must come from the file
+		var decoded Config
+		meta, err := toml.Decode(buf.String(), &decoded)
+		if err != nil {
+			t.Fatalf("%s: generated TOML does not parse: %v\n%s", name, err, buf.String())
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			t.Errorf("%s: unknown keys %v", name, undecoded)
+		}
+		if !reflect.DeepEqual(&decoded, cfg) {
+			t.Errorf("%s: round trip mismatch:\ngot  %+v\nwant %+v", name, decoded, *cfg)
+		}
+	}
+}
+
+func TestWriteTOMLKeepsExampleComments(t *testing.T) {
+	example, err := os.ReadFile("../../dingo.toml.example")
+	if err != nil {
+		t.Skipf("dingo.toml.example not available: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := DefaultConfig().WriteTOML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	generated := buf.String()
+
+	for _, line := range strings.Split(string(example), "\n") {
+		if strings.HasPrefix(line, "#") && !strings.Contains(generated, line+"\n") {
+			t.Errorf("generated dingo.toml lacks example comment %q", line)
+		}
+	}
+}
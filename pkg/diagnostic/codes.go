@@ -0,0 +1,28 @@
+package diagnostic
+
+// Codes identify the kind of a diagnostic. They are stable across releases,
+// so tools can filter on them and documentation can refer to them.
+const (
+	// D00xx: Dingo syntax the preprocessors reject
+	CodeSyntax           = "D0001" // Malformed Dingo syntax without a more specific code
+	CodeErrorPropagation = "D0002" // Invalid use of the ? operator
+	CodeMatch            = "D0003" // Malformed match expression
+	CodeLambda           = "D0004" // Lambda whose parameter types cannot be inferred
+	CodeTuple            = "D0005" // Invalid tuple type or destructuring
+	CodeSafeNavigation   = "D0006" // Invalid ?. chain
+	CodeNullCoalescing   = "D0007" // Invalid ?? expression
+	CodeTernary          = "D0008" // Invalid ternary expression
+	CodeEnum             = "D0009" // Malformed enum declaration
+	CodeImport           = "D0010" // Unqualified call that cannot be resolved
+
+	// D01xx: problems found while generating Go
+	CodeGoSyntax      = "D0101" // The preprocessed source is not valid Go
+	CodeNonExhaustive = "D0102" // match does not cover every case
+	CodeTypeInference = "D0103" // A type cannot be inferred
+	CodeNoneContext   = "D0104" // None used where no Option type is expected
+	CodeGeneration    = "D0105" // Any other code generation failure
+
+	// D02xx: the generated Go
+	CodeType      = "D0201" // go/types rejects the generated Go
+	CodeSourceMap = "D0202" // The source map could not be built
+)
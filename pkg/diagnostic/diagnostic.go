@@ -0,0 +1,210 @@
+// Package diagnostic defines problems reported against Dingo source files
+package diagnostic
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Severity classifies a diagnostic
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Source names the stage that produced a diagnostic
+const (
+	SourcePreprocess = "preprocess" // Dingo syntax rewriting
+	SourceParse      = "parse"      // Parsing the preprocessed Go
+	SourceDingo      = "dingo"      // Plugin checks (exhaustiveness, inference)
+	SourceTypes      = "types"      // go/types on the generated Go
+	SourceMapping    = "sourcemap"  // Building the source map of the generated Go
+)
+
+// Diagnostic is a single problem at a .dingo position.
+//
+// Producers that work on intermediate code (preprocessors, plugins) report
+// positions in that code; the transpiler maps them back to the .dingo file
+// before diagnostics reach renderers.
+type Diagnostic struct {
+	Code     string // One of the Code* constants; empty when uncategorized
+	Severity Severity
+	Pos      token.Position // Filename and 1-based Line/Column; Line 0 = whole file
+	End      token.Position // Exclusive end of the range; zero when only Pos is known
+	Message  string
+	Source   string  // One of the Source* constants
+	Labels   []Label // Secondary locations that explain the problem
+	Fixes    []Fix   // Suggested ways to resolve it
+
+	// For exhaustiveness: the arms a match lacks, as patterns with their
+	// bindings (Err(err), Circle(radius))
+	MissingItems []string
+}
+
+// Label points at a secondary location of a diagnostic. A label with the
+// range of the diagnostic itself annotates the primary range.
+type Label struct {
+	Pos     token.Position
+	End     token.Position // Zero when only Pos is known
+	Message string
+}
+
+// Fix is a suggested resolution. Without edits it is advice only.
+type Fix struct {
+	Message string
+	Edits   []Edit
+}
+
+// Edit replaces the text between Pos and End (empty for an insertion)
+type Edit struct {
+	Pos     token.Position
+	End     token.Position
+	NewText string
+}
+
+// String formats the diagnostic like the go tool: file:line:col: message
+func (d Diagnostic) String() string {
+	prefix := ""
+	if d.Severity == SeverityWarning {
+		prefix = "warning: "
+	}
+	switch {
+	case d.Pos.Filename == "" && d.Pos.Line > 0:
+		return fmt.Sprintf("line %d: %s%s", d.Pos.Line, prefix, d.Message) // Not yet attributed to a file
+	case d.Pos.Line == 0:
+		return fmt.Sprintf("%s: %s%s", d.Pos.Filename, prefix, d.Message)
+	case d.Pos.Column == 0:
+		return fmt.Sprintf("%s:%d: %s%s", d.Pos.Filename, d.Pos.Line, prefix, d.Message)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s%s", d.Pos.Filename, d.Pos.Line, d.Pos.Column, prefix, d.Message)
+	}
+}
+
+// Error lets producers return a diagnostic through APIs that return error.
+// Labels and suggested fixes follow the one-line form as note and help lines.
+func (d *Diagnostic) Error() string {
+	var b strings.Builder
+	b.WriteString(d.String())
+	for _, l := range d.Labels {
+		b.WriteString("\n\tnote: ")
+		if l.Pos != d.Pos {
+			b.WriteString(location(l.Pos) + ": ")
+		}
+		b.WriteString(l.Message)
+	}
+	for _, fix := range d.Fixes {
+		b.WriteString("\n\thelp: ")
+		b.WriteString(fix.Message)
+	}
+	return b.String()
+}
+
+// ExtendToToken sets End to the end of the token starting at Pos, read from
+// src, the content of Pos.Filename. It does nothing when End is already set
+// or no token starts at Pos.
+func (d *Diagnostic) ExtendToToken(src []byte) {
+	if d.End.Line > 0 || d.Pos.Line < 1 || d.Pos.Column < 1 {
+		return
+	}
+	lines := strings.Split(string(src), "\n")
+	if d.Pos.Line > len(lines) {
+		return
+	}
+	if end := tokenEnd(lines[d.Pos.Line-1], d.Pos.Column); end > d.Pos.Column {
+		d.End = d.Pos
+		d.End.Offset = 0
+		d.End.Column = end
+	}
+}
+
+// tokenEnd returns the column just past the token starting at column col of
+// line, or col when no token starts there
+func tokenEnd(line string, col int) int {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(line))
+	var s scanner.Scanner
+	s.Init(file, []byte(line), func(token.Position, string) {}, 0)
+	for {
+		pos, tok, lit := s.Scan()
+		offset := file.Offset(pos)
+		if tok == token.EOF || offset > col-1 {
+			return col
+		}
+		if offset < col-1 || (tok == token.SEMICOLON && lit == "\n") {
+			continue
+		}
+		if lit == "" {
+			lit = tok.String()
+		}
+		return col + len(lit)
+	}
+}
+
+// List holds the diagnostics of a stage that recovers from errors and keeps
+// going, like scanner.ErrorList. errors.As on a List finds its first
+// diagnostic.
+type List []*Diagnostic
+
+// Error returns the first diagnostic and the number of others
+func (l List) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0].Error(), len(l)-1)
+}
+
+// Err returns l as an error, or nil when it is empty
+func (l List) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Unwrap returns the diagnostics as errors
+func (l List) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, d := range l {
+		errs[i] = d
+	}
+	return errs
+}
+
+// HasErrors reports whether any diagnostic has error severity
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort orders diagnostics by file, line and column
+func Sort(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i].Pos, diags[j].Pos
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
@@ -0,0 +1,223 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Snippet renders the diagnostic rustc-style: a header with severity and
+// code, the location, the source lines of the primary range and of labels in
+// the same file, underlined, and the suggested fixes as help lines.
+//
+// src is the content of d.Pos.Filename; without it only the header,
+// location and help lines are rendered.
+func (d Diagnostic) Snippet(src []byte) string {
+	var b strings.Builder
+
+	b.WriteString(d.Severity.String())
+	if d.Code != "" {
+		fmt.Fprintf(&b, "[%s]", d.Code)
+	}
+	fmt.Fprintf(&b, ": %s\n", d.Message)
+
+	type mark struct {
+		line, start, end int // 1-based columns, end exclusive
+		char             byte
+		message          string
+	}
+	var lines []string
+	if src != nil {
+		lines = strings.Split(string(src), "\n")
+	}
+	var marks []mark
+	addMark := func(pos, end token.Position, char byte, message string) {
+		if pos.Line < 1 || pos.Line > len(lines) {
+			return
+		}
+		text := lines[pos.Line-1]
+		start := pos.Column
+		if start < 1 {
+			start = len(text) - len(strings.TrimLeft(text, " \t")) + 1 // Whole line
+			end = token.Position{Line: pos.Line, Column: len(strings.TrimRight(text, " \t\r")) + 1}
+		}
+		stop := start + 1
+		switch {
+		case end.Line == pos.Line && end.Column > start:
+			stop = end.Column
+		case end.Line > pos.Line:
+			stop = len(strings.TrimRight(text, " \t\r")) + 1 // Rest of the line
+		}
+		marks = append(marks, mark{pos.Line, start, stop, char, message})
+	}
+
+	primary := ""
+	for _, l := range d.Labels {
+		if l.Pos == d.Pos && l.End == d.End {
+			primary = l.Message
+		}
+	}
+	addMark(d.Pos, d.End, '^', primary)
+	for _, l := range d.Labels {
+		if l.Pos.Filename == d.Pos.Filename && (l.Pos != d.Pos || l.End != d.End) {
+			addMark(l.Pos, l.End, '-', l.Message)
+		}
+	}
+
+	width := 1
+	for _, m := range marks {
+		if w := len(strconv.Itoa(m.line)); w > width {
+			width = w
+		}
+	}
+	gutter := strings.Repeat(" ", width)
+
+	if d.Pos.Line > 0 || d.Pos.Filename != "" {
+		fmt.Fprintf(&b, "%s--> %s\n", gutter, location(d.Pos))
+	}
+
+	if len(marks) > 0 {
+		sort.SliceStable(marks, func(i, j int) bool { return marks[i].line < marks[j].line })
+		fmt.Fprintf(&b, "%s |\n", gutter)
+		prev := 0
+		for _, m := range marks {
+			if m.line != prev {
+				if prev != 0 && m.line > prev+1 {
+					fmt.Fprintf(&b, "%s |\n", strings.Repeat(".", width))
+				}
+				fmt.Fprintf(&b, "%*d | %s\n", width, m.line, expandTabs(lines[m.line-1], len(lines[m.line-1])+1))
+				prev = m.line
+			}
+			text := lines[m.line-1]
+			indent := len(expandTabs(text, m.start))
+			length := len(expandTabs(text, m.end)) - indent
+			if length < 1 {
+				length = 1
+			}
+			fmt.Fprintf(&b, "%s | %s%s", gutter, strings.Repeat(" ", indent), strings.Repeat(string(m.char), length))
+			if m.message != "" {
+				fmt.Fprintf(&b, " %s", m.message)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	for _, l := range d.Labels {
+		if l.Pos.Filename != d.Pos.Filename || l.Pos.Line < 1 || l.Pos.Line > len(lines) {
+			fmt.Fprintf(&b, "%s = note: %s: %s\n", gutter, location(l.Pos), l.Message)
+		}
+	}
+	for _, fix := range d.Fixes {
+		fmt.Fprintf(&b, "%s = help: %s\n", gutter, fix.Message)
+	}
+	return b.String()
+}
+
+// location formats a position as file:line:col, leaving out unknown parts
+func location(pos token.Position) string {
+	switch {
+	case pos.Line == 0:
+		return pos.Filename
+	case pos.Column == 0:
+		return fmt.Sprintf("%s:%d", pos.Filename, pos.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column)
+	}
+}
+
+// tabWidth is the width of a tab in snippets
+const tabWidth = 4
+
+// expandTabs returns the text of line before column col, tabs expanded
+func expandTabs(line string, col int) string {
+	if col-1 < len(line) {
+		line = line[:max(col-1, 0)]
+	}
+	var b strings.Builder
+	for _, r := range strings.TrimRight(line, "\r") {
+		if r == '\t' {
+			b.WriteString(strings.Repeat(" ", tabWidth-b.Len()%tabWidth))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// jsonPosition is the JSON form of a position: 1-based, 0 when unknown
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonRange struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonLabel struct {
+	File    string    `json:"file"`
+	Range   jsonRange `json:"range"`
+	Message string    `json:"message"`
+}
+
+type jsonEdit struct {
+	File    string    `json:"file"`
+	Range   jsonRange `json:"range"`
+	NewText string    `json:"newText"`
+}
+
+type jsonFix struct {
+	Message string     `json:"message"`
+	Edits   []jsonEdit `json:"edits,omitempty"`
+}
+
+type jsonDiagnostic struct {
+	Code     string      `json:"code,omitempty"`
+	Severity string      `json:"severity"`
+	Source   string      `json:"source,omitempty"`
+	File     string      `json:"file"`
+	Range    jsonRange   `json:"range"`
+	Message  string      `json:"message"`
+	Labels   []jsonLabel `json:"labels,omitempty"`
+	Fixes    []jsonFix   `json:"fixes,omitempty"`
+}
+
+// toJSONRange converts a range; a missing end is the start
+func toJSONRange(pos, end token.Position) jsonRange {
+	r := jsonRange{Start: jsonPosition{pos.Line, pos.Column}, End: jsonPosition{pos.Line, pos.Column}}
+	if end.Line > 0 {
+		r.End = jsonPosition{end.Line, end.Column}
+	}
+	return r
+}
+
+// MarshalJSON encodes the diagnostic for machine consumption:
+//
+//	{"code": "D0102", "severity": "error", "source": "dingo",
+//	 "file": "main.dingo", "range": {"start": {"line": 7, "column": 2}, "end": {...}},
+//	 "message": "...", "labels": [...], "fixes": [{"message": "...", "edits": [...]}]}
+func (d Diagnostic) MarshalJSON() ([]byte, error) {
+	out := jsonDiagnostic{
+		Code:     d.Code,
+		Severity: d.Severity.String(),
+		Source:   d.Source,
+		File:     d.Pos.Filename,
+		Range:    toJSONRange(d.Pos, d.End),
+		Message:  d.Message,
+	}
+	for _, l := range d.Labels {
+		out.Labels = append(out.Labels, jsonLabel{File: l.Pos.Filename, Range: toJSONRange(l.Pos, l.End), Message: l.Message})
+	}
+	for _, f := range d.Fixes {
+		fix := jsonFix{Message: f.Message}
+		for _, e := range f.Edits {
+			fix.Edits = append(fix.Edits, jsonEdit{File: e.Pos.Filename, Range: toJSONRange(e.Pos, e.End), NewText: e.NewText})
+		}
+		out.Fixes = append(out.Fixes, fix)
+	}
+	return json.Marshal(out)
+}
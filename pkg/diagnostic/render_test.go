@@ -0,0 +1,136 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const matchSource = `package main
+
+func name(r Result) string {
+	match r {
+		Ok(x) => x,
+	}
+}
+`
+
+func nonExhaustive() Diagnostic {
+	pos := func(line, col int) token.Position {
+		return token.Position{Filename: "main.dingo", Line: line, Column: col}
+	}
+	return Diagnostic{
+		Code:     CodeNonExhaustive,
+		Severity: SeverityError,
+		Pos:      pos(4, 2),
+		End:      pos(4, 7),
+		Message:  "non-exhaustive match, missing cases: Err",
+		Source:   SourceDingo,
+		Labels: []Label{
+			{Pos: pos(4, 2), End: pos(4, 7), Message: "Err not covered"},
+			{Pos: pos(3, 11), End: pos(3, 19), Message: "r has type Result"},
+		},
+		Fixes: []Fix{{
+			Message: "add the missing arms: Err => ...",
+			Edits:   []Edit{{Pos: pos(6, 2), End: pos(6, 2), NewText: "\tErr(_) => \"\",\n"}},
+		}},
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	got := nonExhaustive().Snippet([]byte(matchSource))
+	want := `error[D0102]: non-exhaustive match, missing cases: Err
+ --> main.dingo:4:2
+  |
+3 | func name(r Result) string {
+  |           -------- r has type Result
+4 |     match r {
+  |     ^^^^^ Err not covered
+  = help: add the missing arms: Err => ...
+`
+	if got != want {
+		t.Errorf("Snippet:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSnippetWithoutSource(t *testing.T) {
+	d := nonExhaustive()
+	d.Labels[1].Pos.Filename = "other.dingo"
+	got := d.Snippet(nil)
+	for _, want := range []string{
+		"error[D0102]: non-exhaustive match",
+		"--> main.dingo:4:2",
+		"= note: other.dingo:3:11: r has type Result",
+		"= help: add the missing arms",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Snippet missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "^") {
+		t.Errorf("Snippet underlined without source:\n%s", got)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(nonExhaustive())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Code     string
+		Severity string
+		File     string
+		Range    struct{ Start, End struct{ Line, Column int } }
+		Labels   []struct{ File, Message string }
+		Fixes    []struct {
+			Message string
+			Edits   []struct {
+				Range   struct{ Start struct{ Line, Column int } }
+				NewText string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Code != "D0102" || got.Severity != "error" || got.File != "main.dingo" {
+		t.Errorf("header = %s %s %s", got.Code, got.Severity, got.File)
+	}
+	if got.Range.Start.Line != 4 || got.Range.Start.Column != 2 || got.Range.End.Column != 7 {
+		t.Errorf("range = %+v", got.Range)
+	}
+	if len(got.Labels) != 2 || got.Labels[1].Message != "r has type Result" {
+		t.Errorf("labels = %+v", got.Labels)
+	}
+	if len(got.Fixes) != 1 || len(got.Fixes[0].Edits) != 1 || got.Fixes[0].Edits[0].Range.Start.Line != 6 {
+		t.Errorf("fixes = %+v", got.Fixes)
+	}
+}
+
+func TestError(t *testing.T) {
+	d := nonExhaustive()
+	want := "main.dingo:4:2: non-exhaustive match, missing cases: Err" +
+		"\n\tnote: Err not covered" +
+		"\n\tnote: main.dingo:3:11: r has type Result" +
+		"\n\thelp: add the missing arms: Err => ..."
+	if got := d.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestExtendToToken(t *testing.T) {
+	d := Diagnostic{Pos: token.Position{Filename: "main.dingo", Line: 3, Column: 13}}
+	d.ExtendToToken([]byte(matchSource))
+	if d.End.Line != 3 || d.End.Column != 19 {
+		t.Errorf("End = %v, want 3:19", d.End)
+	}
+
+	// An existing end is kept
+	d.End.Column = 14
+	d.ExtendToToken([]byte(matchSource))
+	if d.End.Column != 14 {
+		t.Errorf("End = %v, want the existing 3:14", d.End)
+	}
+}
@@ -0,0 +1,577 @@
+// Package formatter implements `dingo fmt`, the canonical formatter for .dingo source.
+//
+// gofmt cannot parse Dingo syntax (let, : annotations, match, enum, =>, ?., ??),
+// so the formatter works on tokens and lines instead of an AST. It never joins
+// or splits lines, which keeps it safe around Go's automatic semicolons.
+//
+// Formatting rules:
+//   - Lines are re-indented with tabs by bracket depth; case/default are
+//     outdented and lines continuing an expression get one extra level
+//   - Unambiguous operators (= := == != <= >= && || ?? => / % << &^ and
+//     compound assignments) get one space on each side, and so does + after
+//     an operand; commas are followed by a space
+//   - Operators that are ambiguous in Dingo (< > >> | : - * & ^) keep their
+//     spacing, since they appear in generics, lambdas, type annotations and
+//     unary expressions
+//   - Struct fields, const/var blocks, keyed elements, match arms, enum
+//     variants and trailing comments are aligned in consecutive lines
+//   - Runs of blank lines collapse to one
+//
+// Format is idempotent: Format(Format(src)) == Format(src).
+package formatter
+
+import (
+	"fmt"
+	gotoken "go/token"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// blockKind classifies a bracketed block, which decides how its lines align
+type blockKind int
+
+const (
+	blockCode      blockKind = iota // Function bodies, composite literals
+	blockParen                      // ( ... ) and [ ... ]
+	blockStruct                     // struct { ... }
+	blockInterface                  // interface { ... }
+	blockEnum                       // enum Name { ... }
+	blockMatch                      // match expr { ... }
+	blockDecl                       // const ( ... ), var ( ... ), type ( ... )
+)
+
+// block is an open bracket on the indentation stack
+type block struct {
+	kind        blockKind
+	opener      string
+	indent      int // Indentation of lines inside the block
+	closeIndent int // Indentation of the line starting with the closer
+}
+
+// srcLine is one line of source after tokenizing
+type srcLine struct {
+	tokens []token // Code and comment tokens (no newlines)
+	indent int
+	block  *block // Innermost open block at line start (nil at top level)
+	align  bool   // Line may take part in column alignment
+	cells  []string
+	widths []int // Column widths assigned during alignment
+}
+
+// spacedOps always get a single space on both sides
+var spacedOps = map[string]bool{
+	"=": true, ":=": true, "==": true, "!=": true, "<=": true, ">=": true,
+	"&&": true, "||": true, "??": true, "=>": true,
+	"/": true, "%": true, "<<": true, "&^": true,
+	"+=": true, "-=": true, "*=": true, "/=": true, "%=": true,
+	"&=": true, "|=": true, "^=": true, "<<=": true, ">>=": true, "&^=": true,
+}
+
+// continuationOps at the end of a line mean the expression continues below
+var continuationOps = map[string]bool{
+	"&&": true, "||": true, "??": true, "=>": true,
+	"=": true, ":=": true, "+=": true, "-=": true,
+	"==": true, "!=": true, "<=": true, ">=": true,
+	"+": true, "-": true, "*": true, "/": true, "%": true,
+	"&": true, "|": true, "^": true, "<<": true, ">>": true, "&^": true,
+	".": true, "?.": true,
+}
+
+var closerFor = map[string]string{"(": ")", "[": "]", "{": "}"}
+
+// Format returns the canonical formatting of Dingo source
+func Format(src []byte) ([]byte, error) {
+	tokens, err := lex(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := layout(splitLines(tokens))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range lines {
+		if l != nil {
+			l.cells = splitCells(l)
+		}
+	}
+	align(lines)
+
+	var sb strings.Builder
+	for _, l := range lines {
+		if l != nil {
+			sb.WriteString(render(l))
+		}
+		sb.WriteByte('\n')
+	}
+	return []byte(sb.String()), nil
+}
+
+// splitLines groups tokens into lines. Blank lines are empty slices.
+func splitLines(tokens []token) [][]token {
+	var lines [][]token
+	current := []token{}
+	for _, tok := range tokens {
+		if tok.kind == tokNewline {
+			lines = append(lines, current)
+			current = []token{}
+			continue
+		}
+		current = append(current, tok)
+	}
+	if len(current) > 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// layout computes indentation for every line and collapses blank lines.
+// Blank lines are returned as nil entries.
+func layout(raw [][]token) ([]*srcLine, error) {
+	var stack []*block
+	var lines []*srcLine
+	continuation := false
+	pendingBlank := false
+
+	for _, tokens := range raw {
+		if len(tokens) == 0 {
+			pendingBlank = len(lines) > 0
+			continue
+		}
+		if pendingBlank {
+			lines = append(lines, nil)
+			pendingBlank = false
+		}
+
+		l := &srcLine{tokens: tokens}
+
+		// Leading closers take the indentation of the line that opened them
+		closers := 0
+		for closers < len(tokens) && isCloser(tokens[closers].text) {
+			closers++
+		}
+		if closers > len(stack) {
+			return nil, fmt.Errorf("line %d: unexpected %s", tokens[0].line, tokens[0].text)
+		}
+
+		base := 0
+		switch {
+		case closers > 0:
+			base = stack[len(stack)-closers].closeIndent
+		case len(stack) > 0:
+			base = stack[len(stack)-1].indent
+			l.block = stack[len(stack)-1]
+		}
+		if isCaseClause(tokens) && base > 0 {
+			base--
+		}
+
+		l.indent = base
+		if continuation && closers == 0 {
+			l.indent++
+		}
+		l.align = closers == 0 && !hasMultilineToken(tokens) && !isCommentOnly(tokens)
+
+		// Update the bracket stack. Brackets opened on the same line share one
+		// indentation level, so `foo(func() {` indents its body only once.
+		lineStart := len(stack)
+		for i, tok := range tokens {
+			if tok.kind != tokOp {
+				continue
+			}
+			switch tok.text {
+			case "(", "[", "{":
+				b := &block{opener: tok.text}
+				switch {
+				case len(stack) > lineStart && len(stack) > 0:
+					b.indent = stack[len(stack)-1].indent
+					b.closeIndent = stack[len(stack)-1].closeIndent
+				case tok.text == "{":
+					// Block bodies indent relative to the statement, not its continuation
+					b.indent, b.closeIndent = base+1, base
+				default:
+					// Wrapped arguments indent relative to the printed line
+					b.indent, b.closeIndent = l.indent+1, l.indent
+				}
+
+				switch {
+				case tok.text == "{":
+					b.kind = braceKind(tokens, i)
+				case tok.text == "(" && i > 0 && isDeclKeyword(tokens[i-1].text):
+					b.kind = blockDecl
+				default:
+					b.kind = blockParen
+				}
+				stack = append(stack, b)
+			case ")", "]", "}":
+				if len(stack) == 0 {
+					return nil, fmt.Errorf("line %d: unexpected %s", tok.line, tok.text)
+				}
+				top := stack[len(stack)-1]
+				if closerFor[top.opener] != tok.text {
+					return nil, fmt.Errorf("line %d: unexpected %s, expected %s", tok.line, tok.text, closerFor[top.opener])
+				}
+				stack = stack[:len(stack)-1]
+				lineStart = min(lineStart, len(stack))
+			}
+		}
+
+		if last, ok := lastCodeToken(tokens); ok {
+			continuation = last.kind == tokOp && continuationOps[last.text]
+		}
+
+		lines = append(lines, l)
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unexpected end of file: unclosed %s", stack[len(stack)-1].opener)
+	}
+
+	return lines, nil
+}
+
+// braceKind classifies the { at tokens[i] by the tokens before it
+func braceKind(tokens []token, i int) blockKind {
+	if i > 0 {
+		switch tokens[i-1].text {
+		case "struct":
+			return blockStruct
+		case "interface":
+			return blockInterface
+		}
+	}
+	if tokens[0].text == "enum" && tokens[0].kind == tokIdent {
+		return blockEnum
+	}
+	for p := 0; p+1 < i; p++ {
+		tok := tokens[p]
+		if tok.kind != tokIdent || tok.text != "match" {
+			continue
+		}
+		// `match` must start an expression: not a selector, not `if match {`
+		if p > 0 && tokens[p-1].text == "." {
+			continue
+		}
+		if next := tokens[p+1]; next.kind == tokOp && next.text != "(" {
+			continue
+		}
+		return blockMatch
+	}
+	return blockCode
+}
+
+// splitCells renders a line into alignment cells
+func splitCells(l *srcLine) []string {
+	tokens := l.tokens
+	code := tokens
+	var comment *token
+	if n := len(tokens); n > 1 && tokens[n-1].kind == tokComment {
+		code = tokens[:n-1]
+		comment = &tokens[n-1]
+	}
+
+	var bounds []int
+	if l.align && l.block != nil {
+		bounds = cellBounds(l.block.kind, code)
+	}
+
+	var cells []string
+	start := 0
+	for _, b := range append(bounds, len(code)) {
+		cells = append(cells, renderTokens(code[start:b]))
+		start = b
+	}
+	if comment != nil {
+		cells = append(cells, comment.text)
+	}
+	return cells
+}
+
+// cellBounds returns the token indices where alignment cells start (excluding 0)
+func cellBounds(kind blockKind, code []token) []int {
+	n := len(code)
+	if n < 2 {
+		return nil
+	}
+
+	switch kind {
+	case blockStruct, blockDecl:
+		if code[0].kind != tokIdent {
+			return nil // Embedded pointer field or similar
+		}
+		i := 1
+		for i+1 < n && code[i].text == "," && code[i+1].kind == tokIdent {
+			i += 2
+		}
+		if i == n || code[i].text == "." {
+			return nil // Embedded field (sync.Mutex) or bare name
+		}
+		if code[i].text == ":" && i+1 < n {
+			i++ // Dingo-style field: the colon stays with the name
+		}
+		bounds := []int{i}
+		if kind == blockStruct {
+			if last := code[n-1]; last.kind == tokString && n-1 > i {
+				bounds = append(bounds, n-1)
+			}
+			return bounds
+		}
+		// const/var: names | type | = value
+		eq := topLevelIndex(code, "=")
+		switch {
+		case eq < 0:
+		case eq == i:
+			bounds = append(bounds, i) // Empty type column
+		default:
+			bounds = append(bounds, eq)
+		}
+		return bounds
+
+	case blockEnum:
+		if code[0].kind == tokIdent && code[1].text == "{" {
+			return []int{1}
+		}
+
+	case blockMatch:
+		if arrow := topLevelIndex(code, "=>"); arrow > 0 {
+			return []int{arrow}
+		}
+
+	case blockCode:
+		// Keyed elements in composite literals: key: value,
+		key := code[0].kind
+		if n >= 3 && code[1].text == ":" && code[n-1].text == "," &&
+			(key == tokIdent || key == tokString || key == tokNumber) {
+			return []int{2}
+		}
+	}
+
+	return nil
+}
+
+// topLevelIndex finds op outside any brackets opened on the line, or -1
+func topLevelIndex(code []token, op string) int {
+	depth := 0
+	for i, tok := range code {
+		if tok.kind != tokOp {
+			continue
+		}
+		switch {
+		case tok.text == "(" || tok.text == "[" || tok.text == "{":
+			depth++
+		case isCloser(tok.text):
+			depth--
+		case depth == 0 && tok.text == op:
+			return i
+		}
+	}
+	return -1
+}
+
+// align assigns column widths to runs of consecutive lines sharing a block
+// and indentation, like gofmt's tabwriter: a column block spans consecutive
+// lines that have a cell after that column.
+func align(lines []*srcLine) {
+	start := 0
+	for start < len(lines) {
+		end := start + 1
+		if l := lines[start]; l != nil && l.align {
+			for end < len(lines) {
+				next := lines[end]
+				if next == nil || !next.align || next.block != l.block || next.indent != l.indent {
+					break
+				}
+				end++
+			}
+		}
+		alignRun(lines[start:end])
+		start = end
+	}
+}
+
+// alignRun computes column widths for one run of lines
+func alignRun(run []*srcLine) {
+	for _, l := range run {
+		if l != nil {
+			l.widths = make([]int, len(l.cells))
+		}
+	}
+
+	for col := 0; ; col++ {
+		found := false
+		for i := 0; i < len(run); {
+			if run[i] == nil || len(run[i].cells) <= col+1 {
+				i++
+				continue
+			}
+			found = true
+
+			j, width := i, 0
+			var lnsum float64
+			for j < len(run) && run[j] != nil && len(run[j].cells) > col+1 {
+				size := utf8.RuneCountInString(run[j].cells[col])
+				if col == 0 && j > i && breaksAlignment(run[i].block.kind, size,
+					utf8.RuneCountInString(run[j-1].cells[col]), lnsum, j-i) {
+					break
+				}
+				if size > 0 {
+					lnsum += math.Log(float64(size))
+				}
+				width = max(width, size)
+				j++
+			}
+			for k := i; k < j; k++ {
+				run[k].widths[col] = width
+			}
+			i = j
+		}
+		if !found {
+			return
+		}
+	}
+}
+
+// breaksAlignment reports whether a leading cell of the given size starts a new
+// column block. Mirrors gofmt's heuristic for keyed elements: alignment breaks
+// when a key is much longer or shorter than the geometric mean of the keys
+// aligned so far, unless both neighbours are short.
+func breaksAlignment(kind blockKind, size, prevSize int, lnsum float64, count int) bool {
+	const (
+		smallSize = 40
+		ratio     = 2.5
+	)
+	if kind != blockMatch && kind != blockCode {
+		return false
+	}
+	if size == 0 || prevSize == 0 || (size <= smallSize && prevSize <= smallSize) {
+		return false
+	}
+	r := float64(size) / math.Exp(lnsum/float64(count))
+	return ratio*r <= 1 || ratio <= r
+}
+
+// render produces the final text of a line
+func render(l *srcLine) string {
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat("\t", l.indent))
+
+	for i, cell := range l.cells {
+		sb.WriteString(cell)
+		if i == len(l.cells)-1 {
+			break
+		}
+		if l.widths[i] == 0 {
+			continue // Column is empty in every line of its block
+		}
+		pad := l.widths[i] - utf8.RuneCountInString(cell) + 1
+		sb.WriteString(strings.Repeat(" ", pad))
+	}
+
+	return sb.String()
+}
+
+// renderTokens joins tokens with normalized spacing
+func renderTokens(tokens []token) string {
+	var sb strings.Builder
+	for i, tok := range tokens {
+		if i > 0 && needsSpace(tokens, i) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(tok.text)
+	}
+	return sb.String()
+}
+
+// needsSpace decides whether a space separates tokens[i] from the token
+// before it
+func needsSpace(tokens []token, i int) bool {
+	prev, next := tokens[i-1], tokens[i]
+	switch {
+	case next.kind == tokComment || prev.kind == tokComment:
+		return true
+	case next.kind == tokOp && next.text == ",":
+		return false
+	case prev.kind == tokOp && prev.text == ",":
+		return !isCloser(next.text)
+	case prev.kind == tokOp && (prev.text == "(" || prev.text == "["):
+		return false
+	case next.kind == tokOp && (next.text == ")" || next.text == "]"):
+		return false
+	case prev.kind == tokOp && spaced(tokens, i-1):
+		return true
+	case next.kind == tokOp && spaced(tokens, i):
+		return true
+	}
+	return next.spaceBefore
+}
+
+// spaced reports whether the operator tokens[i] gets a space on each side:
+// + only when it is binary, i.e. follows an operand
+func spaced(tokens []token, i int) bool {
+	if tokens[i].text == "+" {
+		return i > 0 && isOperand(tokens[i-1])
+	}
+	return spacedOps[tokens[i].text]
+}
+
+// isOperand reports whether a token ends an operand: a name, a literal or
+// a closing bracket
+func isOperand(tok token) bool {
+	switch tok.kind {
+	case tokIdent:
+		return !gotoken.Lookup(tok.text).IsKeyword()
+	case tokNumber, tokString:
+		return true
+	case tokOp:
+		return isCloser(tok.text)
+	}
+	return false
+}
+
+func isCloser(text string) bool {
+	return text == ")" || text == "]" || text == "}"
+}
+
+func isDeclKeyword(text string) bool {
+	return text == "const" || text == "var" || text == "type"
+}
+
+// isCaseClause reports whether a line starts a switch/select clause
+func isCaseClause(tokens []token) bool {
+	if tokens[0].kind != tokIdent {
+		return false
+	}
+	switch tokens[0].text {
+	case "case":
+		return true
+	case "default":
+		return len(tokens) > 1 && tokens[1].text == ":"
+	}
+	return false
+}
+
+// lastCodeToken returns the last non-comment token of a line
+func lastCodeToken(tokens []token) (token, bool) {
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].kind != tokComment {
+			return tokens[i], true
+		}
+	}
+	return token{}, false
+}
+
+func isCommentOnly(tokens []token) bool {
+	_, ok := lastCodeToken(tokens)
+	return !ok
+}
+
+func hasMultilineToken(tokens []token) bool {
+	for _, tok := range tokens {
+		if tok.multiline() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "reindents by bracket depth",
+			input: "package main\n\nfunc main() {\n  if x {\n        y()\n  }\n}\n",
+			want:  "package main\n\nfunc main() {\n\tif x {\n\t\ty()\n\t}\n}\n",
+		},
+		{
+			name:  "outdents case clauses",
+			input: "package main\n\nfunc f() {\n\tswitch x {\n\t\tcase 1:\n\t\t\ty()\n\t\tdefault:\n\t}\n}\n",
+			want:  "package main\n\nfunc f() {\n\tswitch x {\n\tcase 1:\n\t\ty()\n\tdefault:\n\t}\n}\n",
+		},
+		{
+			name:  "spaces unambiguous operators",
+			input: "package main\n\nfunc f() {\n\tlet x=a??b\n\ty:=x?.name\n\tz(a,b)\n}\n",
+			want:  "package main\n\nfunc f() {\n\tlet x = a ?? b\n\ty := x?.name\n\tz(a, b)\n}\n",
+		},
+		{
+			name:  "spaces binary arithmetic",
+			input: "package main\n\nfunc f() {\n\tx:=1+2\n\ty:=a/b%c\n\tz:=n<<2&^m\n\tw:=f(x)+s[0]\n}\n",
+			want:  "package main\n\nfunc f() {\n\tx := 1 + 2\n\ty := a / b % c\n\tz := n << 2 &^ m\n\tw := f(x) + s[0]\n}\n",
+		},
+		{
+			name:  "keeps unary plus",
+			input: "package main\n\nfunc f() int {\n\tg(+1, x)\n\tx = +y\n\treturn +x\n}\n",
+			want:  "package main\n\nfunc f() int {\n\tg(+1, x)\n\tx = +y\n\treturn +x\n}\n",
+		},
+		{
+			name:  "keeps ambiguous operators untouched",
+			input: "package main\n\nfunc f(x: int) Option<int> {\n\treturn |a| a*2\n}\n",
+			want:  "package main\n\nfunc f(x: int) Option<int> {\n\treturn |a| a*2\n}\n",
+		},
+		{
+			name:  "aligns match arms",
+			input: "package main\n\nfunc f() {\n\tmatch r {\n\t\tOk(value)=>a(),\n\t\tErr(e)=>b(),\n\t}\n}\n",
+			want:  "package main\n\nfunc f() {\n\tmatch r {\n\t\tOk(value) => a(),\n\t\tErr(e)    => b(),\n\t}\n}\n",
+		},
+		{
+			name:  "aligns enum variants",
+			input: "package main\n\nenum Shape {\n\tPoint,\n\tCircle { radius: float64 },\n\tRectangle { width: float64 },\n}\n",
+			want:  "package main\n\nenum Shape {\n\tPoint,\n\tCircle    { radius: float64 },\n\tRectangle { width: float64 },\n}\n",
+		},
+		{
+			name:  "aligns struct fields and trailing comments",
+			input: "package main\n\ntype T struct {\n\tName string // the name\n\tAge int // years\n}\n",
+			want:  "package main\n\ntype T struct {\n\tName string // the name\n\tAge  int    // years\n}\n",
+		},
+		{
+			name:  "keeps colon with Dingo-style field names",
+			input: "package main\n\ntype T struct {\n\tID: int\n\tEmail: string\n}\n",
+			want:  "package main\n\ntype T struct {\n\tID:    int\n\tEmail: string\n}\n",
+		},
+		{
+			name:  "collapses blank lines",
+			input: "\n\npackage main\n\n\n\nfunc f() {}\n\n\n",
+			want:  "package main\n\nfunc f() {}\n",
+		},
+		{
+			name:  "preserves comments and raw strings",
+			input: "package main\n\n/* block\n   comment */\nvar s = `a\n  b`\n",
+			want:  "package main\n\n/* block\n   comment */\nvar s = `a\n  b`\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Format() error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Format() mismatch\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			again, err := Format(got)
+			if err != nil {
+				t.Fatalf("second Format() error: %v", err)
+			}
+			if string(again) != string(got) {
+				t.Errorf("Format() is not idempotent\nfirst:\n%s\nsecond:\n%s", got, again)
+			}
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"unbalanced closer", "package main\n\nfunc f() {\n}\n}\n", "unexpected"},
+		{"unclosed bracket", "package main\n\nfunc f() {\n", "unclosed"},
+		{"unterminated string", "package main\n\nvar s = \"abc\n", "unterminated string"},
+		{"unterminated comment", "package main\n\n/* abc\n", "unterminated block comment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Format([]byte(tt.input))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tr := transpiler.NewWithConfig(config.DefaultConfig())
+	original := []byte("package main\n\nfunc add(a int,b int) int {\n    return a+b\n}\n")
+
+	formatted, err := Format(original)
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+	if err := Verify(tr, "add.dingo", original, formatted); err != nil {
+		t.Errorf("Verify() rejected equivalent source: %v", err)
+	}
+
+	changed := []byte("package main\n\nfunc add(a int, b int) int {\n\treturn a-b\n}\n")
+	if err := Verify(tr, "add.dingo", original, changed); err == nil {
+		t.Error("Verify() accepted source with different semantics")
+	}
+}
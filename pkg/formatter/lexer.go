@@ -0,0 +1,182 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind classifies lexical tokens of Dingo source
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokNumber
+	tokString  // "...", '...' or `...` (raw strings may span lines)
+	tokComment // // line comment or /* block comment */
+	tokOp      // Operators and punctuation
+	tokNewline
+)
+
+// token is a single lexical token
+type token struct {
+	kind        tokenKind
+	text        string
+	spaceBefore bool // Whitespace separated this token from the previous one
+	line        int  // 1-based source line where the token starts
+}
+
+// multiline reports whether the token spans more than one source line
+func (t token) multiline() bool {
+	return strings.Contains(t.text, "\n")
+}
+
+// operators lists multi-character operators, longest first.
+// Includes Dingo-specific operators (?. ?? => ::) alongside Go's.
+var operators = []string{
+	"<<=", ">>=", "&^=", "...",
+	"?.", "??", "=>", ":=", "==", "!=", "<=", ">=", "&&", "||", "<-", "->", "::",
+	"++", "--", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<", ">>", "&^",
+}
+
+// lex splits Dingo source into tokens. It only needs to be precise enough to
+// find token boundaries; it never rejects operators it does not recognize.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	line := 1
+	space := false
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		switch {
+		case c == '\n':
+			tokens = append(tokens, token{kind: tokNewline, text: "\n", line: line})
+			line++
+			space = false
+			i++
+			continue
+
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			space = true
+			i++
+			continue
+		}
+
+		start := i
+		startLine := line
+		var kind tokenKind
+
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			kind = tokComment
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				i = len(src)
+			} else {
+				i += end
+			}
+			// Trailing \r belongs to the line ending, not the comment
+			for i > start && src[i-1] == '\r' {
+				i--
+			}
+
+		case strings.HasPrefix(src[i:], "/*"):
+			kind = tokComment
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated block comment", startLine)
+			}
+			i += 2 + end + 2
+
+		case c == '"' || c == '\'':
+			kind = tokString
+			i++
+			for {
+				if i >= len(src) || src[i] == '\n' {
+					return nil, fmt.Errorf("line %d: unterminated string literal", startLine)
+				}
+				if src[i] == '\\' {
+					i += 2
+					continue
+				}
+				if src[i] == c {
+					i++
+					break
+				}
+				i++
+			}
+
+		case c == '`':
+			kind = tokString
+			end := strings.IndexByte(src[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated raw string literal", startLine)
+			}
+			i += 1 + end + 1
+
+		case isDigit(c) || (c == '.' && i+1 < len(src) && isDigit(src[i+1])):
+			kind = tokNumber
+			i++
+			for i < len(src) {
+				d := src[i]
+				if isDigit(d) || isLetter(d) || d == '_' || d == '.' {
+					i++
+					continue
+				}
+				// Exponent sign: 1e+9, 0x1p-2
+				if (d == '+' || d == '-') && strings.ContainsRune("eEpP", rune(src[i-1])) {
+					i++
+					continue
+				}
+				break
+			}
+
+		case isIdentStart(src[i:]):
+			kind = tokIdent
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+
+		default:
+			kind = tokOp
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				_, size := utf8.DecodeRuneInString(src[i:])
+				i += size
+			}
+		}
+
+		text := src[start:i]
+		line += strings.Count(text, "\n")
+		tokens = append(tokens, token{kind: kind, text: text, spaceBefore: space, line: startLine})
+		space = false
+	}
+
+	return tokens, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || unicode.IsLetter(r)
+}
@@ -0,0 +1,58 @@
+package formatter
+
+import (
+	"fmt"
+	"go/scanner"
+	gotoken "go/token"
+
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// Verify checks that formatting did not change the meaning of a file by
+// transpiling both versions and comparing the generated Go token streams
+// (comments excluded). filename is used for package context and errors only.
+func Verify(t *transpiler.Transpiler, filename string, original, formatted []byte) error {
+	before, err := t.TranspileSource(filename, original)
+	if err != nil {
+		return fmt.Errorf("cannot verify formatting, source does not transpile: %w", err)
+	}
+	after, err := t.TranspileSource(filename, formatted)
+	if err != nil {
+		return fmt.Errorf("formatted source does not transpile: %w", err)
+	}
+	return EqualGoTokens(before, after)
+}
+
+// EqualGoTokens reports an error describing the first difference between the
+// token streams of two Go sources. Comments and whitespace are ignored.
+func EqualGoTokens(a, b []byte) error {
+	var sa, sb scanner.Scanner
+	fa := gotoken.NewFileSet().AddFile("a.go", -1, len(a))
+	fb := gotoken.NewFileSet().AddFile("b.go", -1, len(b))
+	sa.Init(fa, a, nil, 0)
+	sb.Init(fb, b, nil, 0)
+
+	for {
+		posA, tokA, litA := sa.Scan()
+		posB, tokB, litB := sb.Scan()
+
+		// Automatic semicolons have literal "\n", explicit ones ";"
+		if tokA == gotoken.SEMICOLON && tokB == gotoken.SEMICOLON {
+			litA, litB = "", ""
+		}
+		if tokA != tokB || litA != litB {
+			return fmt.Errorf("generated Go differs at line %d (%s) vs line %d (%s)",
+				fa.Line(posA), describe(tokA, litA), fb.Line(posB), describe(tokB, litB))
+		}
+		if tokA == gotoken.EOF {
+			return nil
+		}
+	}
+}
+
+func describe(tok gotoken.Token, lit string) string {
+	if lit != "" {
+		return fmt.Sprintf("%q", lit)
+	}
+	return tok.String()
+}
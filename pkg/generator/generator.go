@@ -3,6 +3,7 @@ package generator
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"go/ast"
 	"go/format"
@@ -31,8 +32,22 @@ type Generator struct {
 	registry *plugin.Registry
 	pipeline *plugin.Pipeline
 	logger   plugin.Logger
+	hook     StageHook
+	ctx      context.Context
+	pkg      *Package       // Set for package-wide type checking
+	importer types.Importer // Importer of the type-checked package, for post-AST resolution
+
+	sharedTypes    bool       // Leave injected declarations out of the output
+	genericRuntime bool       // Use the runtime library's Option and Result
+	injected       []TypeDecl // Injected declarations left out by the last Generate
 }
 
+// StageHook observes the generated source after a generation stage:
+// "plugin:<name>" after each plugin's Transform, "transform" once the plugin
+// pipeline finished and injected declarations are merged, "post_ast" after
+// placeholder resolution and "output" for the final result.
+type StageHook func(stage string, source []byte)
+
 // New creates a new generator with default configuration
 func New(fset *token.FileSet) *Generator {
 	return &Generator{
@@ -126,16 +141,64 @@ func NewWithPlugins(fset *token.FileSet, registry *plugin.Registry, logger plugi
 	}, nil
 }
 
+// CompileErrors is returned by Generate when plugins report compile errors
+// (non-exhaustive match, failed type inference, ...). Errors reported through
+// plugin.Context.Report are *diagnostic.Diagnostic values.
+type CompileErrors struct {
+	Errors []error
+}
+
+func (e *CompileErrors) Error() string {
+	// Format all errors into a single message
+	var errMsg strings.Builder
+	errMsg.WriteString("compilation errors detected:\n")
+	for _, err := range e.Errors {
+		errMsg.WriteString("  - ")
+		errMsg.WriteString(err.Error())
+		errMsg.WriteString("\n")
+	}
+	return errMsg.String()
+}
+
 // SetLogger sets the logger for the generator
 func (g *Generator) SetLogger(logger plugin.Logger) {
 	g.logger = logger
 }
 
+// SetStageHook installs a hook called after every generation stage
+func (g *Generator) SetStageHook(hook StageHook) {
+	g.hook = hook
+	if g.pipeline == nil {
+		return
+	}
+	if hook == nil {
+		g.pipeline.SetTransformHook(nil)
+		return
+	}
+	g.pipeline.SetTransformHook(func(name string, file *ast.File) {
+		hook("plugin:"+name, g.printStage(file))
+	})
+}
+
+// printStage prints an intermediate AST for a stage hook. Intermediate ASTs
+// may not be printable, which is reported in place of the source.
+func (g *Generator) printStage(file *ast.File) []byte {
+	var buf bytes.Buffer
+	cfg := printer.Config{
+		Mode:     printer.TabIndent | printer.UseSpaces,
+		Tabwidth: 8,
+	}
+	if err := cfg.Fprint(&buf, g.fset, file); err != nil {
+		return []byte(fmt.Sprintf("// failed to print AST: %v\n", err))
+	}
+	return buf.Bytes()
+}
+
 // Generate converts a Dingo AST to Go source code
 func (g *Generator) Generate(file *dingoast.File) ([]byte, error) {
 	// Step 1: Set the current file in the pipeline context
 	if g.pipeline != nil && g.pipeline.Ctx != nil {
-		g.pipeline.Ctx.CurrentFile = file
+		g.pipeline.Ctx.CurrentFile = file.File
 	}
 
 	// Step 2: Build parent map for context-aware inference (Phase 4 - Task B)
@@ -148,8 +211,19 @@ func (g *Generator) Generate(file *dingoast.File) ([]byte, error) {
 	}
 
 	// Step 3: Run type checker to populate type information (Fix A5)
-	// This enables accurate type inference for plugins
-	typesInfo, err := g.runTypeChecker(file.File)
+	// This enables accurate type inference for plugins. The whole package is
+	// checked when known, so sibling files and dependencies resolve.
+	var typesInfo *types.Info
+	var err error
+	if g.pkg != nil {
+		typesInfo, err = g.checkPackage(file.File)
+		if err != nil && g.logger != nil {
+			g.logger.Debugf("Package type check failed: %v (checking the file alone)", err)
+		}
+	}
+	if typesInfo == nil {
+		typesInfo, err = g.runTypeChecker(file.File)
+	}
 	if err != nil {
 		// Type checking failure is not fatal - we can still generate code
 		// but type inference will be limited to structural analysis
@@ -183,16 +257,7 @@ func (g *Generator) Generate(file *dingoast.File) ([]byte, error) {
 
 		// C3 FIX: Check for compile errors from plugins (exhaustiveness, type inference, etc.)
 		if g.pipeline.Ctx != nil && g.pipeline.Ctx.HasErrors() {
-			errors := g.pipeline.Ctx.GetErrors()
-			// Format all errors into a single message
-			var errMsg strings.Builder
-			errMsg.WriteString("compilation errors detected:\n")
-			for _, e := range errors {
-				errMsg.WriteString("  - ")
-				errMsg.WriteString(e.Error())
-				errMsg.WriteString("\n")
-			}
-			return nil, fmt.Errorf("%s", errMsg.String())
+			return nil, &CompileErrors{Errors: g.pipeline.Ctx.GetErrors()}
 		}
 	}
 
@@ -240,6 +305,9 @@ func (g *Generator) Generate(file *dingoast.File) ([]byte, error) {
 	// Previous bug: format.Source() removed markers → PostASTGenerator couldn't match transformations
 	// Fix: Use printer output directly (still properly formatted via printer.Config)
 	printerOutput := buf.Bytes()
+	if g.hook != nil {
+		g.hook("transform", printerOutput)
+	}
 
 	// Step 6.5: Post-AST placeholder resolution
 	// This step runs AFTER go/printer to resolve any remaining
@@ -254,6 +322,28 @@ func (g *Generator) Generate(file *dingoast.File) ([]byte, error) {
 		// Continue with unresolved placeholders rather than failing
 		resolved = printerOutput
 	}
+	if g.hook != nil {
+		g.hook("post_ast", resolved)
+	}
+
+	// Step 6.55: Replace the declared Option and Result types by the generic
+	// ones of the runtime library (SetGenericRuntime)
+	if g.genericRuntime {
+		generic, err := useGenericRuntime(resolved)
+		if err != nil {
+			return nil, err
+		}
+		resolved = generic
+	}
+
+	// Step 6.6: Leave the injected declarations to the package (SetSharedTypes)
+	if g.sharedTypes {
+		split, injected, err := splitTypes(resolved, g.injectedNames())
+		if err != nil {
+			return nil, err
+		}
+		resolved, g.injected = split, injected
+	}
 
 	// Step 7: Inject DINGO:GENERATED markers (post-processing)
 	markersEnabled := true // Default
@@ -267,6 +357,9 @@ func (g *Generator) Generate(file *dingoast.File) ([]byte, error) {
 		if g.logger != nil {
 			g.logger.Warnf("Failed to inject markers: %v", err)
 		}
+		if g.hook != nil {
+			g.hook("output", resolved)
+		}
 		return resolved, nil // Return without markers on error
 	}
 
@@ -276,6 +369,9 @@ func (g *Generator) Generate(file *dingoast.File) ([]byte, error) {
 	// Step 9: Remove extra blank lines between top-level declarations
 	// This ensures consistent formatting matching golden files
 	final := removeBlankLinesBetweenDeclarations(cleaned)
+	if g.hook != nil {
+		g.hook("output", final)
+	}
 
 	return final, nil
 }
@@ -403,8 +499,12 @@ func (g *Generator) resolvePostASTPlaceholders(goCode []byte) ([]byte, error) {
 		Scopes:     make(map[ast.Node]*types.Scope),
 	}
 
+	imp := g.importer
+	if imp == nil {
+		imp = importer.Default()
+	}
 	postConf := &types.Config{
-		Importer: importer.Default(),
+		Importer: imp,
 		Error: func(err error) {
 			// Ignore errors - __INFER__ placeholders will cause type errors
 			// We want partial type information even with errors
@@ -0,0 +1,359 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/config"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// SetGenericRuntime makes Generate use dingo.Option[T] and dingo.Result[T, E]
+// of the runtime library (config.RuntimePath) in place of the Option_T and
+// Result_T_E types the plugins declare for every instantiation
+func (g *Generator) SetGenericRuntime(generic bool) {
+	g.genericRuntime = generic
+}
+
+// runtimeType is an Option or Result type declared by the plugins, to be
+// replaced by its runtime library counterpart
+type runtimeType struct {
+	generic string     // Option or Result
+	args    []ast.Expr // Type arguments: T, or T and E
+}
+
+// tagMethods maps the tag constants of the declared Option and Result types
+// to the runtime methods testing for them
+var tagMethods = map[string]string{
+	"OptionTagSome": "IsSome",
+	"OptionTagNone": "IsNone",
+	"ResultTagOk":   "IsOk",
+	"ResultTagErr":  "IsErr",
+}
+
+// useGenericRuntime rewrites src to the runtime library: the Option_T and
+// Result_T_E declarations of the plugins are removed, references to them
+// become dingo.Option[T] and dingo.Result[T, E], their constructors and
+// literals calls of dingo.Some, dingo.None, dingo.Ok and dingo.Err, and
+// switches on their tags calls of the Is* methods. src is returned as is when
+// it declares no such types.
+func useGenericRuntime(src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "generated.go", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse generated Go: %w", err)
+	}
+
+	types := runtimeTypes(file)
+	if len(types) == 0 {
+		return src, nil
+	}
+	kept := file.Decls[:0]
+	for _, decl := range file.Decls {
+		if !declaresRuntimeType(types, decl) {
+			kept = append(kept, decl)
+		}
+	}
+	file.Decls = kept
+
+	astutil.Apply(file, func(cursor *astutil.Cursor) bool {
+		switch n := cursor.Node().(type) {
+		case *ast.SwitchStmt:
+			rewriteTagSwitch(n)
+		case *ast.CallExpr:
+			if call := runtimeConstructorCall(types, n); call != nil {
+				cursor.Replace(call)
+			}
+		case *ast.CompositeLit:
+			if call := runtimeLiteralCall(types, n); call != nil {
+				cursor.Replace(call)
+			}
+		case *ast.Ident:
+			if sel, ok := cursor.Parent().(*ast.SelectorExpr); ok && sel.Sel == n {
+				return true
+			}
+			if t := types[n.Name]; t != nil {
+				cursor.Replace(runtimeTypeExpr(t, n.Pos()))
+			}
+		}
+		return true
+	}, nil)
+	astutil.AddImport(fset, file, config.RuntimePath)
+	ast.SortImports(fset, file)
+
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.TabIndent | printer.UseSpaces, Tabwidth: 8}
+	if err := cfg.Fprint(&buf, fset, file); err != nil {
+		return nil, fmt.Errorf("failed to print generated Go: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// runtimeTypes returns the Option and Result types the plugins declared in
+// file, recognized by their shape: Option_T struct{tag OptionTag; some *T}
+// and Result_T_E struct{tag ResultTag; ok *T; err *E}
+func runtimeTypes(file *ast.File) map[string]*runtimeType {
+	types := make(map[string]*runtimeType)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE || len(gen.Specs) != 1 {
+			continue
+		}
+		spec := gen.Specs[0].(*ast.TypeSpec)
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok || spec.TypeParams != nil {
+			continue
+		}
+		fields := make(map[string]ast.Expr)
+		for _, field := range st.Fields.List {
+			for _, name := range field.Names {
+				fields[name.Name] = field.Type
+			}
+		}
+		tag, _ := fields["tag"].(*ast.Ident)
+		if tag == nil {
+			continue
+		}
+		name := spec.Name.Name
+		switch {
+		case strings.HasPrefix(name, "Option_") && tag.Name == "OptionTag" && len(fields) == 2:
+			if t := pointee(fields["some"]); t != nil {
+				types[name] = &runtimeType{generic: "Option", args: []ast.Expr{t}}
+			}
+		case strings.HasPrefix(name, "Result_") && tag.Name == "ResultTag" && len(fields) == 3:
+			if t, e := pointee(fields["ok"]), pointee(fields["err"]); t != nil && e != nil {
+				types[name] = &runtimeType{generic: "Result", args: []ast.Expr{t, e}}
+			}
+		}
+	}
+	return types
+}
+
+// declaresRuntimeType reports whether decl is part of the declaration of one
+// of types: the type itself, its tag, its constructors or its methods.
+// Functions merely returning one of types are not.
+func declaresRuntimeType(types map[string]*runtimeType, decl ast.Decl) bool {
+	name, group := declGroup(decl)
+	if group == "OptionTag" || group == "ResultTag" {
+		return true
+	}
+	if types[group] == nil {
+		return false
+	}
+	fn, ok := decl.(*ast.FuncDecl)
+	return !ok || fn.Recv != nil || strings.HasPrefix(name, group+"_")
+}
+
+// pointee returns T of the type *T, or nil
+func pointee(expr ast.Expr) ast.Expr {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		return star.X
+	}
+	return nil
+}
+
+// runtimeTypeExpr returns dingo.Option[T] or dingo.Result[T, E] at pos
+func runtimeTypeExpr(t *runtimeType, pos token.Pos) ast.Expr {
+	return runtimeInstance(t.generic, t.args, pos)
+}
+
+// runtimeInstance returns dingo.name[args...] at pos. The type arguments are
+// printed as written in the removed declaration.
+func runtimeInstance(name string, args []ast.Expr, pos token.Pos) ast.Expr {
+	fn := &ast.SelectorExpr{
+		X:   &ast.Ident{NamePos: pos, Name: "dingo"},
+		Sel: &ast.Ident{NamePos: pos, Name: name},
+	}
+	indices := make([]ast.Expr, len(args))
+	for i, arg := range args {
+		indices[i] = &ast.Ident{NamePos: pos, Name: exprString(arg)}
+	}
+	if len(indices) == 1 {
+		return &ast.IndexExpr{X: fn, Lbrack: pos, Index: indices[0], Rbrack: pos}
+	}
+	return &ast.IndexListExpr{X: fn, Lbrack: pos, Indices: indices, Rbrack: pos}
+}
+
+// exprString prints a type expression
+func exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, token.NewFileSet(), expr); err != nil {
+		return "any"
+	}
+	return buf.String()
+}
+
+// runtimeCall returns dingo.name[args...](values...) in place of old
+func runtimeCall(name string, args []ast.Expr, values []ast.Expr, old ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{
+		Fun:    runtimeInstance(name, args, old.Pos()),
+		Lparen: old.Pos(),
+		Args:   values,
+		Rparen: old.End() - 1,
+	}
+}
+
+// runtimeConstructorCall rewrites a call of a declared constructor,
+// Option_int_Some(x) → dingo.Some[int](x), or returns nil
+func runtimeConstructorCall(types map[string]*runtimeType, call *ast.CallExpr) *ast.CallExpr {
+	fn, ok := call.Fun.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	i := strings.LastIndex(fn.Name, "_")
+	if i < 0 {
+		return nil
+	}
+	t, variant := types[fn.Name[:i]], fn.Name[i+1:]
+	if t == nil {
+		return nil
+	}
+	switch {
+	case t.generic == "Option" && (variant == "Some" || variant == "None"),
+		t.generic == "Result" && (variant == "Ok" || variant == "Err"):
+		return runtimeCall(variant, t.args, call.Args, call)
+	}
+	return nil
+}
+
+// runtimeLiteralCall rewrites a literal of a declared type the plugins build
+// values with, Option_int{tag: OptionTagSome, some: &x} →
+// dingo.Some[int](x), or returns nil
+func runtimeLiteralCall(types map[string]*runtimeType, lit *ast.CompositeLit) *ast.CallExpr {
+	typ, ok := lit.Type.(*ast.Ident)
+	if !ok || types[typ.Name] == nil {
+		return nil
+	}
+	t := types[typ.Name]
+	fields := make(map[string]ast.Expr)
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return nil
+		}
+		if key, ok := kv.Key.(*ast.Ident); ok {
+			fields[key.Name] = kv.Value
+		}
+	}
+	tag, _ := fields["tag"].(*ast.Ident)
+	if tag == nil {
+		return nil
+	}
+
+	var variant, field string
+	switch tag.Name {
+	case "OptionTagSome":
+		variant, field = "Some", "some"
+	case "OptionTagNone":
+		variant = "None"
+	case "ResultTagOk":
+		variant, field = "Ok", "ok"
+	case "ResultTagErr":
+		variant, field = "Err", "err"
+	default:
+		return nil
+	}
+	if field == "" {
+		return runtimeCall(variant, t.args, nil, lit)
+	}
+	value := fields[field]
+	if value == nil {
+		return nil
+	}
+	return runtimeCall(variant, t.args, []ast.Expr{dereference(value)}, lit)
+}
+
+// dereference returns the value a pointer expression of a literal points to:
+// x for &x, v for the func() *T { tmp := v; return &tmp }() the plugins wrap
+// values that are not addressable in, and *p otherwise
+func dereference(expr ast.Expr) ast.Expr {
+	if unary, ok := expr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		return unary.X
+	}
+	if call, ok := expr.(*ast.CallExpr); ok && len(call.Args) == 0 {
+		if lit, ok := call.Fun.(*ast.FuncLit); ok && len(lit.Body.List) == 2 {
+			assign, ok1 := lit.Body.List[0].(*ast.AssignStmt)
+			ret, ok2 := lit.Body.List[1].(*ast.ReturnStmt)
+			if ok1 && ok2 && len(assign.Lhs) == 1 && len(assign.Rhs) == 1 && len(ret.Results) == 1 {
+				tmp, _ := assign.Lhs[0].(*ast.Ident)
+				addr, _ := ret.Results[0].(*ast.UnaryExpr)
+				if tmp != nil && addr != nil && addr.Op == token.AND {
+					if x, ok := addr.X.(*ast.Ident); ok && x.Name == tmp.Name {
+						return assign.Rhs[0]
+					}
+				}
+			}
+		}
+	}
+	return &ast.StarExpr{Star: expr.Pos(), X: expr}
+}
+
+// rewriteTagSwitch rewrites a switch on the tag of a declared type, as
+// generated for match expressions, to the methods of the runtime library:
+//
+//	switch s.tag {              switch {
+//	case OptionTagSome:    →    case s.IsSome():
+//		x := *s.some                x := s.Unwrap()
+func rewriteTagSwitch(switchStmt *ast.SwitchStmt) {
+	sel, ok := switchStmt.Tag.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "tag" {
+		return
+	}
+	scrutinee, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return
+	}
+	for _, stmt := range switchStmt.Body.List {
+		for _, expr := range stmt.(*ast.CaseClause).List {
+			if ident, ok := expr.(*ast.Ident); !ok || tagMethods[ident.Name] == "" {
+				return
+			}
+		}
+	}
+
+	switchStmt.Tag = nil
+	for _, stmt := range switchStmt.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		for i, expr := range clause.List {
+			clause.List[i] = methodCall(scrutinee.Name, tagMethods[expr.(*ast.Ident).Name], expr)
+		}
+		ast.Inspect(clause, func(n ast.Node) bool {
+			assign, ok := n.(*ast.AssignStmt)
+			if !ok || assign.Tok != token.DEFINE || len(assign.Rhs) != 1 {
+				return true
+			}
+			star, ok := assign.Rhs[0].(*ast.StarExpr)
+			if !ok {
+				return true
+			}
+			field, ok := star.X.(*ast.SelectorExpr)
+			if x, isIdent := field.X.(*ast.Ident); !ok || !isIdent || x.Name != scrutinee.Name {
+				return true
+			}
+			switch field.Sel.Name {
+			case "some", "ok":
+				assign.Rhs[0] = methodCall(scrutinee.Name, "Unwrap", star)
+			case "err":
+				assign.Rhs[0] = methodCall(scrutinee.Name, "UnwrapErr", star)
+			}
+			return true
+		})
+	}
+}
+
+// methodCall builds recv.method() in place of old
+func methodCall(recv, method string, old ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   &ast.Ident{NamePos: old.Pos(), Name: recv},
+			Sel: &ast.Ident{NamePos: old.Pos(), Name: method},
+		},
+		Lparen: old.End(),
+		Rparen: old.End(),
+	}
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUseGenericRuntime(t *testing.T) {
+	src := `package main
+
+type OptionTag uint8
+
+const (
+	OptionTagSome OptionTag = iota
+	OptionTagNone
+)
+
+type Option_int struct {
+	tag  OptionTag
+	some *int
+}
+
+func Option_int_Some(arg0 int) Option_int {
+	return Option_int{tag: OptionTagSome, some: &arg0}
+}
+
+func (o Option_int) IsSome() bool {
+	return o.tag == OptionTagSome
+}
+
+func length(s string) Option_int {
+	if s == "" {
+		return Option_int{tag: OptionTagNone, some: nil}
+	}
+	return Option_int{tag: OptionTagSome, some: func() *int {
+		tmp := len(s)
+		return &tmp
+	}()}
+}
+
+func describe(o Option_int) int {
+	switch o.tag {
+	case OptionTagSome:
+		n := *o.some
+		return n
+	case OptionTagNone:
+		return 0
+	}
+	return Option_int_Some(1).UnwrapOr(0)
+}
+`
+	out, err := useGenericRuntime([]byte(src))
+	if err != nil {
+		t.Fatalf("useGenericRuntime failed: %v", err)
+	}
+
+	code := string(out)
+	for _, want := range []string{
+		`import "github.com/MadAppGang/dingo/runtime/dingo"`,
+		"func length(s string) dingo.Option[int]",
+		"return dingo.None[int]()",
+		"len(s)",
+		"func describe(o dingo.Option[int]) int",
+		"case o.IsSome():",
+		"n := o.Unwrap()",
+		"case o.IsNone():",
+		"dingo.Some[int](1)",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("output lacks %q:\n%s", want, code)
+		}
+	}
+	for _, unwanted := range []string{"OptionTag", "type Option_int", "func (o Option_int)", "tmp"} {
+		if strings.Contains(code, unwanted) {
+			t.Errorf("output still contains %q:\n%s", unwanted, code)
+		}
+	}
+
+	plain := []byte("package main\n\nfunc main() {}\n")
+	if out, err := useGenericRuntime(plain); err != nil || string(out) != string(plain) {
+		t.Errorf("useGenericRuntime changed Go without Option or Result types:\n%s", out)
+	}
+}
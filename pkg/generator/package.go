@@ -0,0 +1,334 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/types"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Package is the Go package a generated file belongs to. When set with
+// SetPackage, Generate type-checks the whole package at once instead of the
+// file alone, so identifiers declared in sibling files, module-local packages
+// and third-party dependencies resolve.
+type Package struct {
+	// Dir is the package directory; its .go files are part of the package
+	Dir string
+
+	// GoPath is the absolute path the file being generated stands for
+	GoPath string
+
+	// Sources holds Go sources by absolute path that replace (or add to) the
+	// files of Dir: the preprocessed Go of the package's other .dingo files
+	Sources map[string][]byte
+}
+
+// SetPackage enables package-wide type checking for Generate. ctx bounds
+// loading the package's imports.
+func (g *Generator) SetPackage(ctx context.Context, pkg *Package) {
+	g.ctx = ctx
+	g.pkg = pkg
+}
+
+// checkPackage type-checks file together with the other files of its
+// package. Imports are loaded with go/packages from module-aware export
+// data, falling back to the default importer outside a module. The returned
+// types.Info is keyed by the nodes of file and shared by all plugins.
+func (g *Generator) checkPackage(file *ast.File) (*types.Info, error) {
+	files, err := g.packageFiles(file)
+	if err != nil {
+		return nil, err
+	}
+	g.importer = g.loadImports(files)
+
+	info := newTypesInfo()
+	conf := &types.Config{
+		Importer: g.importer,
+		Error: func(err error) {
+			if g.logger != nil {
+				g.logger.Debugf("Type checker: %v", err)
+			}
+		},
+		DisableUnusedImportCheck: true,
+	}
+	// Errors are expected (placeholders, injected types not yet declared);
+	// the partial information is still useful
+	pkg, _ := conf.Check(file.Name.Name, g.fset, files, info)
+	if g.logger != nil && pkg != nil {
+		g.logger.Debugf("Type checker: package %q checked with %d files", pkg.Name(), len(files))
+	}
+	return info, nil
+}
+
+// packageFiles parses the Go files of the package into the generator's
+// FileSet: the .go files of Dir that match the build context (tests
+// excluded) and Sources, keeping those of file's package. file comes first.
+func (g *Generator) packageFiles(file *ast.File) ([]*ast.File, error) {
+	sources := make(map[string][]byte, len(g.pkg.Sources))
+	for path, src := range g.pkg.Sources {
+		sources[filepath.Clean(path)] = src
+	}
+	goPath := filepath.Clean(g.pkg.GoPath)
+
+	entries, err := os.ReadDir(g.pkg.Dir)
+	if err != nil && len(sources) == 0 {
+		return nil, fmt.Errorf("failed to read package directory: %w", err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(g.pkg.Dir, name)
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if _, ok := sources[path]; ok || path == goPath {
+			continue // Replaced by the preprocessed Go
+		}
+		if match, err := build.Default.MatchFile(g.pkg.Dir, name); err != nil || !match {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	for path := range sources {
+		if path != goPath {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	files := []*ast.File{file}
+	for _, path := range paths {
+		var src any
+		if s, ok := sources[path]; ok {
+			src = s
+		}
+		f, err := parser.ParseFile(g.fset, path, src, parser.ParseComments|parser.AllErrors)
+		if f == nil || f.Name.Name != file.Name.Name {
+			if err != nil && g.logger != nil {
+				g.logger.Debugf("Type checker: skipping %s: %v", path, err)
+			}
+			continue
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// loadImports returns the importer of the packages imported by files, as
+// the go command resolves them in Dir. The imports of a directory are loaded
+// once and reused by the files of the package and later transpilations (see
+// importCache).
+func (g *Generator) loadImports(files []*ast.File) types.Importer {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, f := range files {
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || path == "C" || path == "unsafe" || seen[path] {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	loaded := packageImports.load(g.pkg.Dir, paths, g.resolveImports)
+	fallback := importer.Default()
+	return importerFunc(func(path string) (*types.Package, error) {
+		if pkg, ok := loaded[path]; ok {
+			return pkg, nil
+		}
+		return fallback.Import(path)
+	})
+}
+
+// resolveImports loads paths in Dir, module-local and third-party packages
+// included. Export data is used when possible; packages whose export data
+// cannot be read (e.g. written by a newer toolchain) are tried with the
+// default importer, then type-checked from source. It also returns the
+// stamps of the module-local packages loaded, and false if ctx was done
+// before loading finished.
+func (g *Generator) resolveImports(paths []string) (map[string]*types.Package, []fileStamp, bool) {
+	fallback := importer.Default()
+	loaded, stamps := g.loadPackages(packages.NeedName|packages.NeedTypes, paths)
+	var missing []string
+	for _, path := range paths {
+		if loaded[path] != nil {
+			continue
+		}
+		if pkg, err := fallback.Import(path); err == nil {
+			loaded[path] = pkg
+		} else {
+			missing = append(missing, path)
+		}
+	}
+	if len(missing) > 0 {
+		sourceMode := packages.NeedName | packages.NeedImports | packages.NeedDeps |
+			packages.NeedTypes | packages.NeedSyntax
+		fromSource, sourceStamps := g.loadPackages(sourceMode, missing)
+		maps.Copy(loaded, fromSource)
+		stamps = append(stamps, sourceStamps...)
+	}
+	return loaded, stamps, g.ctx == nil || g.ctx.Err() == nil
+}
+
+// loadPackages loads paths with go/packages in Dir and returns the
+// completely typed ones by path, with the stamps of the directories and
+// files of those in the main module or a module replaced by a directory
+func (g *Generator) loadPackages(mode packages.LoadMode, paths []string) (map[string]*types.Package, []fileStamp) {
+	loaded := make(map[string]*types.Package)
+	if len(paths) == 0 {
+		return loaded, nil
+	}
+	cfg := &packages.Config{
+		Context: g.ctx,
+		Mode:    mode | packages.NeedFiles | packages.NeedModule,
+		Dir:     g.pkg.Dir,
+	}
+	pkgs, err := packages.Load(cfg, paths...)
+	if err != nil {
+		if g.logger != nil {
+			g.logger.Debugf("Type checker: failed to load imports: %v", err)
+		}
+		return loaded, nil
+	}
+	var stamps []fileStamp
+	for _, pkg := range pkgs {
+		if pkg.Types != nil && pkg.Types.Complete() && len(pkg.Errors) == 0 {
+			loaded[pkg.PkgPath] = pkg.Types
+		}
+		if m := pkg.Module; m != nil && (m.Main || m.Replace != nil && m.Replace.Version == "") && len(pkg.GoFiles) > 0 {
+			stamps = append(stamps, stampOf(filepath.Dir(pkg.GoFiles[0])))
+			for _, file := range pkg.GoFiles {
+				stamps = append(stamps, stampOf(file))
+			}
+		}
+	}
+	return loaded, stamps
+}
+
+// packageImports caches the imports loaded for each package directory
+var packageImports = &importCache{entries: make(map[string]*importEntry)}
+
+// importCache holds the packages loaded for the imports of a directory, so
+// that the files of a package, and the edits of a file, load them with the go
+// command once. An entry is reused while the directory imports the same
+// packages and its stamps are current: the go.mod and go.sum of the module,
+// and the directories and files of the module-local packages it imports.
+type importCache struct {
+	mu      sync.Mutex
+	entries map[string]*importEntry
+}
+
+// importEntry is the cached imports of a directory. mu is held while they
+// load, so that concurrent transpilations of a package wait for one load.
+type importEntry struct {
+	mu     sync.Mutex
+	paths  []string
+	loaded map[string]*types.Package
+	stamps []fileStamp
+}
+
+// load returns the packages of paths (sorted) imported in dir, calling
+// resolve unless the cached ones are current. The result is shared: callers
+// must not modify it.
+func (c *importCache) load(dir string, paths []string, resolve func([]string) (map[string]*types.Package, []fileStamp, bool)) map[string]*types.Package {
+	c.mu.Lock()
+	entry := c.entries[dir]
+	if entry == nil {
+		entry = &importEntry{}
+		c.entries[dir] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.loaded != nil && slices.Equal(entry.paths, paths) && current(entry.stamps) {
+		return entry.loaded
+	}
+	stamps := moduleStamps(dir) // Before loading, so that changes while loading invalidate
+	loaded, pkgStamps, complete := resolve(paths)
+	if complete {
+		entry.paths, entry.loaded, entry.stamps = paths, loaded, append(stamps, pkgStamps...)
+	}
+	return loaded
+}
+
+// fileStamp identifies a version of a file or directory
+type fileStamp struct {
+	path    string
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// stampOf returns the current stamp of path
+func stampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{path: path}
+	}
+	return fileStamp{path: path, exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// current reports whether no stamped file changed
+func current(stamps []fileStamp) bool {
+	for _, stamp := range stamps {
+		if stampOf(stamp.path) != stamp {
+			return false
+		}
+	}
+	return true
+}
+
+// moduleStamps returns the stamps of the go.mod and go.sum of the module
+// dir belongs to, or of the go.mod files that could be created up to the
+// root outside a module
+func moduleStamps(dir string) []fileStamp {
+	var stamps []fileStamp
+	for {
+		gomod := stampOf(filepath.Join(dir, "go.mod"))
+		stamps = append(stamps, gomod)
+		if gomod.exists {
+			return append(stamps, stampOf(filepath.Join(dir, "go.sum")))
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return stamps
+		}
+		dir = parent
+	}
+}
+
+// newTypesInfo returns a types.Info recording everything plugins look up
+func newTypesInfo() *types.Info {
+	return &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
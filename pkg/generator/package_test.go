@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportCache(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/app\n")
+	if err := os.Mkdir(filepath.Join(dir, "util"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write("util/util.go", "package util\n")
+
+	cache := &importCache{entries: make(map[string]*importEntry)}
+	loads, complete := 0, true
+	resolve := func(paths []string) (map[string]*types.Package, []fileStamp, bool) {
+		loads++
+		util := filepath.Join(dir, "util", "util.go")
+		loaded := map[string]*types.Package{"example.com/app/util": types.NewPackage("example.com/app/util", "util")}
+		return loaded, []fileStamp{stampOf(filepath.Dir(util)), stampOf(util)}, complete
+	}
+
+	imports := []string{"example.com/app/util", "fmt"}
+	for _, step := range []struct {
+		name      string
+		change    func()
+		paths     []string
+		wantLoads int
+	}{
+		{"first load", nil, imports, 1},
+		{"same imports", nil, imports, 1},
+		{"other imports", nil, imports[:1], 2},
+		{"imports back", nil, imports, 3},
+		{"go.sum created", func() { write("go.sum", "example.com/dep v1.0.0 h1:x\n") }, imports, 4},
+		{"go.mod edited", func() { write("go.mod", "module example.com/app\n\ngo 1.21\n") }, imports, 5},
+		{"local package edited", func() { write("util/util.go", "package util\n\nfunc F() {}\n") }, imports, 6},
+		{"file added to local package", func() { write("util/more.go", "package util\n") }, imports, 7},
+		{"unchanged", nil, imports, 7},
+		{"canceled load", func() { write("go.mod", "module example.com/app\n"); complete = false }, imports, 8},
+		{"after canceled load", func() { complete = true }, imports, 9},
+		{"reused", nil, imports, 9},
+	} {
+		if step.change != nil {
+			step.change()
+		}
+		loaded := cache.load(dir, step.paths, resolve)
+		if loaded["example.com/app/util"] == nil {
+			t.Fatalf("%s: imports not returned: %v", step.name, loaded)
+		}
+		if loads != step.wantLoads {
+			t.Errorf("%s: %d loads, want %d", step.name, loads, step.wantLoads)
+		}
+	}
+}
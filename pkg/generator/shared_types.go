@@ -0,0 +1,313 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// TypeDecl is a type the plugins inject (Option_int, Result_int_error,
+// Tuple2IntString, the OptionTag enum, ...) together with its constants,
+// constructors and methods
+type TypeDecl struct {
+	Name    string   // The declared type
+	Imports []string // Import specs the declarations need: "time" or t "time"
+	Source  string   // Go source of the declarations
+}
+
+// SetSharedTypes makes Generate leave the declarations injected by plugins
+// out of its output, so that a package declares them once. They are
+// returned by InjectedTypes instead.
+func (g *Generator) SetSharedTypes(shared bool) {
+	g.sharedTypes = shared
+}
+
+// InjectedTypes returns the declarations Generate left out of its output
+// with SetSharedTypes, sorted by name
+func (g *Generator) InjectedTypes() []TypeDecl {
+	return g.injected
+}
+
+// injectedNames returns the names declared by the plugins' injected AST
+func (g *Generator) injectedNames() map[string]bool {
+	names := make(map[string]bool)
+	if g.pipeline == nil {
+		return names
+	}
+	injected := g.pipeline.GetInjectedTypesAST()
+	if injected == nil {
+		return names
+	}
+	for _, decl := range injected.Decls {
+		if name, _ := declGroup(decl); name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// splitTypes removes the top-level declarations named in names from src and
+// returns them grouped by type. Imports only the removed declarations used
+// are removed as well.
+func splitTypes(src []byte, names map[string]bool) ([]byte, []TypeDecl, error) {
+	if len(names) == 0 {
+		return src, nil, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "generated.go", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse generated Go: %w", err)
+	}
+
+	var removed []ast.Decl
+	var cuts [][2]int
+	for _, decl := range file.Decls {
+		if name, _ := declGroup(decl); names[name] {
+			removed = append(removed, decl)
+			cuts = append(cuts, lineRange(fset, src, decl))
+		}
+	}
+	decls := groupTypeDecls(fset, file, src, removed)
+
+	// Imports the remaining code no longer uses
+	needed := make(map[string]bool)
+	for _, decl := range decls {
+		for _, spec := range decl.Imports {
+			needed[spec] = true
+		}
+	}
+	kept := make(map[ast.Decl]bool)
+	for _, decl := range file.Decls {
+		kept[decl] = true
+	}
+	for _, decl := range removed {
+		delete(kept, decl)
+	}
+	for _, imp := range file.Imports {
+		if !needed[importSpec(imp)] || usesImport(file, kept, importName(imp)) {
+			continue
+		}
+		if gen := importDecl(file, imp); gen != nil && !gen.Lparen.IsValid() {
+			cuts = append(cuts, lineRange(fset, src, gen))
+		} else {
+			cuts = append(cuts, lineRange(fset, src, imp))
+		}
+	}
+
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i][0] < cuts[j][0] })
+	var out bytes.Buffer
+	last := 0
+	for _, cut := range cuts {
+		if cut[1] <= last {
+			continue
+		}
+		out.Write(src[last:max(cut[0], last)])
+		last = cut[1]
+		// Drop the blank lines that separated the removed code
+		if bytes.HasSuffix(out.Bytes(), []byte("\n\n")) {
+			for last < len(src) && src[last] == '\n' {
+				last++
+			}
+		}
+	}
+	out.Write(src[last:])
+	return out.Bytes(), decls, nil
+}
+
+// ParseTypeDecls returns the package name and the type declarations of src,
+// every top-level declaration of which belongs to an injected type
+func ParseTypeDecls(src []byte) (string, []TypeDecl, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "types.go", src, parser.ParseComments)
+	if err != nil {
+		return "", nil, err
+	}
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); !ok || gen.Tok != token.IMPORT {
+			decls = append(decls, decl)
+		}
+	}
+	return file.Name.Name, groupTypeDecls(fset, file, src, decls), nil
+}
+
+// groupTypeDecls groups decls of file by the type they belong to, keeping
+// their source text as written in src
+func groupTypeDecls(fset *token.FileSet, file *ast.File, src []byte, decls []ast.Decl) []TypeDecl {
+	byName := make(map[string]*TypeDecl)
+	imports := make(map[string]map[string]bool)
+	var names []string
+	for _, decl := range decls {
+		_, group := declGroup(decl)
+		td, ok := byName[group]
+		if !ok {
+			td = &TypeDecl{Name: group}
+			byName[group] = td
+			imports[group] = make(map[string]bool)
+			names = append(names, group)
+		}
+		r := lineRange(fset, src, decl)
+		if td.Source != "" {
+			td.Source += "\n"
+		}
+		td.Source += string(bytes.TrimRight(src[r[0]:r[1]], "\n")) + "\n"
+		for _, imp := range file.Imports {
+			if usesImport(file, map[ast.Decl]bool{decl: true}, importName(imp)) {
+				imports[group][importSpec(imp)] = true
+			}
+		}
+	}
+
+	sort.Strings(names)
+	result := make([]TypeDecl, 0, len(names))
+	for _, name := range names {
+		td := byName[name]
+		for spec := range imports[name] {
+			td.Imports = append(td.Imports, spec)
+		}
+		sort.Strings(td.Imports)
+		result = append(result, *td)
+	}
+	return result
+}
+
+// declGroup returns the name a top-level declaration is matched by and the
+// type it belongs to: a declared type is its own group, constants belong to
+// their explicit type, methods to their receiver and constructors to the
+// type they return. Methods are matched by Type.Method.
+func declGroup(decl ast.Decl) (name, group string) {
+	switch d := decl.(type) {
+	case *ast.GenDecl:
+		if len(d.Specs) == 0 {
+			return "", ""
+		}
+		switch spec := d.Specs[0].(type) {
+		case *ast.TypeSpec:
+			return spec.Name.Name, spec.Name.Name
+		case *ast.ValueSpec:
+			name = spec.Names[0].Name
+			if typ, ok := spec.Type.(*ast.Ident); ok {
+				return name, typ.Name
+			}
+			return name, name
+		}
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			recv := receiverName(d.Recv.List[0].Type)
+			return recv + "." + d.Name.Name, recv
+		}
+		if d.Type.Results != nil && len(d.Type.Results.List) == 1 {
+			if typ, ok := d.Type.Results.List[0].Type.(*ast.Ident); ok {
+				return d.Name.Name, typ.Name
+			}
+		}
+		return d.Name.Name, d.Name.Name
+	}
+	return "", ""
+}
+
+// receiverName returns the base type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// lineRange returns the byte range of the whole lines node spans in src,
+// doc comment and trailing newline included
+func lineRange(fset *token.FileSet, src []byte, node ast.Node) [2]int {
+	start := node.Pos()
+	switch n := node.(type) {
+	case *ast.GenDecl:
+		if n.Doc != nil {
+			start = n.Doc.Pos()
+		}
+	case *ast.FuncDecl:
+		if n.Doc != nil {
+			start = n.Doc.Pos()
+		}
+	case *ast.ImportSpec:
+		if n.Doc != nil {
+			start = n.Doc.Pos()
+		}
+	}
+	from := fset.Position(start).Offset
+	for from > 0 && src[from-1] != '\n' {
+		from--
+	}
+	to := fset.Position(node.End()).Offset
+	for to < len(src) && src[to] != '\n' {
+		to++
+	}
+	if to < len(src) {
+		to++
+	}
+	return [2]int{from, to}
+}
+
+// importSpec returns an import as written in an import declaration
+func importSpec(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name + " " + imp.Path.Value
+	}
+	return imp.Path.Value
+}
+
+// importName returns the name an import is referred to by. Without an
+// explicit name, the last path element stands for the package name.
+func importName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+	p, err := strconv.Unquote(imp.Path.Value)
+	if err != nil {
+		return ""
+	}
+	return path.Base(p)
+}
+
+// importDecl returns the import declaration holding imp
+func importDecl(file *ast.File, imp *ast.ImportSpec) *ast.GenDecl {
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			for _, spec := range gen.Specs {
+				if spec == imp {
+					return gen
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// usesImport reports whether one of decls refers to the import called name
+func usesImport(file *ast.File, decls map[ast.Decl]bool, name string) bool {
+	used := false
+	for _, decl := range file.Decls {
+		if !decls[decl] {
+			continue
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok && id.Name == name {
+					used = true
+				}
+			}
+			return !used
+		})
+	}
+	return used
+}
@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitTypes(t *testing.T) {
+	src := `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type Option_int struct {
+	some *int
+}
+
+func Option_int_Some(arg0 int) Option_int {
+	return Option_int{some: &arg0}
+}
+
+func (o Option_int) String() string {
+	return fmt.Sprint(*o.some)
+}
+
+func main() {
+	os.Exit(0)
+}
+`
+	names := map[string]bool{"Option_int": true, "Option_int_Some": true, "Option_int.String": true}
+	out, decls, err := splitTypes([]byte(src), names)
+	if err != nil {
+		t.Fatalf("splitTypes failed: %v", err)
+	}
+
+	want := `package main
+
+import (
+	"os"
+)
+
+func main() {
+	os.Exit(0)
+}
+`
+	if string(out) != want {
+		t.Errorf("remaining Go:\n%s\nwant:\n%s", out, want)
+	}
+	if len(decls) != 1 || decls[0].Name != "Option_int" {
+		t.Fatalf("got declarations %+v, want Option_int only", decls)
+	}
+	if got := decls[0].Imports; len(got) != 1 || got[0] != `"fmt"` {
+		t.Errorf("Option_int imports = %v, want [\"fmt\"]", got)
+	}
+	for _, part := range []string{"type Option_int struct", "func Option_int_Some", "func (o Option_int) String"} {
+		if !strings.Contains(decls[0].Source, part) {
+			t.Errorf("Option_int source lacks %q:\n%s", part, decls[0].Source)
+		}
+	}
+
+	// The split-off declarations read back the same
+	pkg, parsed, err := ParseTypeDecls([]byte("package main\n\nimport \"fmt\"\n\n" + decls[0].Source))
+	if err != nil {
+		t.Fatalf("ParseTypeDecls failed: %v", err)
+	}
+	if pkg != "main" || len(parsed) != 1 || parsed[0].Source != decls[0].Source {
+		t.Errorf("ParseTypeDecls = %q %+v, want %+v", pkg, parsed, decls)
+	}
+}
@@ -31,6 +31,9 @@ type GoplsClient struct {
 	shuttingDown        bool           // CRITICAL FIX C2: Track shutdown state
 	closeMu             sync.Mutex     // CRITICAL FIX C2: Protect shutdown flag
 	diagnosticsHandler  DiagnosticsHandler // Callback for diagnostics
+
+	overlayMu sync.Mutex
+	overlays  map[protocol.DocumentURI]int32 // Documents open in gopls → version last sent
 }
 
 // NewGoplsClient creates and starts a gopls subprocess
@@ -67,6 +70,11 @@ func (c *GoplsClient) start() error {
 	// Start gopls subprocess with -mode=stdio
 	c.cmd = exec.Command(c.goplsPath, "-mode=stdio")
 
+	// A new gopls has no open documents
+	c.overlayMu.Lock()
+	c.overlays = make(map[protocol.DocumentURI]int32)
+	c.overlayMu.Unlock()
+
 	stdin, err := c.cmd.StdinPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdin pipe: %w", err)
@@ -257,6 +265,97 @@ func (c *GoplsClient) Hover(ctx context.Context, params protocol.HoverParams) (*
 	return &result, nil
 }
 
+// References forwards references request to gopls
+func (c *GoplsClient) References(ctx context.Context, params protocol.ReferenceParams) ([]protocol.Location, error) {
+	var result []protocol.Location
+	_, err := c.conn.Call(ctx, "textDocument/references", params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("gopls references failed: %w", err)
+	}
+	return result, nil
+}
+
+// DocumentHighlight forwards documentHighlight request to gopls
+func (c *GoplsClient) DocumentHighlight(ctx context.Context, params protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
+	var result []protocol.DocumentHighlight
+	_, err := c.conn.Call(ctx, "textDocument/documentHighlight", params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("gopls documentHighlight failed: %w", err)
+	}
+	return result, nil
+}
+
+// Implementation forwards implementation request to gopls
+func (c *GoplsClient) Implementation(ctx context.Context, params protocol.ImplementationParams) ([]protocol.Location, error) {
+	var result []protocol.Location
+	_, err := c.conn.Call(ctx, "textDocument/implementation", params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("gopls implementation failed: %w", err)
+	}
+	return result, nil
+}
+
+// TypeDefinition forwards typeDefinition request to gopls
+func (c *GoplsClient) TypeDefinition(ctx context.Context, params protocol.TypeDefinitionParams) ([]protocol.Location, error) {
+	var result []protocol.Location
+	_, err := c.conn.Call(ctx, "textDocument/typeDefinition", params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("gopls typeDefinition failed: %w", err)
+	}
+	return result, nil
+}
+
+// WorkspaceSymbol forwards workspace/symbol request to gopls
+func (c *GoplsClient) WorkspaceSymbol(ctx context.Context, params protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+	var result []protocol.SymbolInformation
+	_, err := c.conn.Call(ctx, "workspace/symbol", params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("gopls workspace/symbol failed: %w", err)
+	}
+	return result, nil
+}
+
+// PrepareRename forwards prepareRename request to gopls. The result is nil
+// when the position cannot be renamed.
+func (c *GoplsClient) PrepareRename(ctx context.Context, params protocol.PrepareRenameParams) (*PrepareRenameResult, error) {
+	var raw json.RawMessage
+	_, err := c.conn.Call(ctx, "textDocument/prepareRename", params, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("gopls prepareRename failed: %w", err)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	// Either a range or a range with a placeholder
+	var result PrepareRenameResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("gopls prepareRename failed: %w", err)
+	}
+	if result.Placeholder == "" && result.Range == (protocol.Range{}) {
+		if err := json.Unmarshal(raw, &result.Range); err != nil {
+			return nil, fmt.Errorf("gopls prepareRename failed: %w", err)
+		}
+	}
+	return &result, nil
+}
+
+// PrepareRenameResult is the range to rename and the text to offer
+type PrepareRenameResult struct {
+	Range       protocol.Range `json:"range"`
+	Placeholder string         `json:"placeholder,omitempty"`
+}
+
+// Rename forwards rename request to gopls
+func (c *GoplsClient) Rename(ctx context.Context, params protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
+	var result protocol.WorkspaceEdit
+	_, err := c.conn.Call(ctx, "textDocument/rename", params, &result)
+	if err != nil {
+		return nil, fmt.Errorf("gopls rename failed: %w", err)
+	}
+	return &result, nil
+}
+
 // DidOpen notifies gopls of opened file
 func (c *GoplsClient) DidOpen(ctx context.Context, params protocol.DidOpenTextDocumentParams) error {
 	return c.conn.Notify(ctx, "textDocument/didOpen", params)
@@ -288,29 +387,85 @@ func (c *GoplsClient) SyncFileContent(ctx context.Context, goPath string) error
 		return fmt.Errorf("failed to read .go file: %w", err)
 	}
 
-	// Send didChange notification with full new content
+	if err := c.SetOverlay(ctx, goPath, content); err != nil {
+		return err
+	}
+	c.logger.Debugf("gopls synchronized with .go file: %s", goPath)
+	return nil
+}
+
+// SetOverlay makes gopls analyze content as the content of the Go file
+// goPath, whatever is on disk: the file is opened in gopls on first use and
+// changed afterwards. This is how generated Go that was never written, such
+// as the transpiled unsaved buffer of a .dingo file, reaches gopls.
+func (c *GoplsClient) SetOverlay(ctx context.Context, goPath string, content []byte) error {
 	fileURI := protocol.DocumentURI(uri.File(goPath))
-	params := protocol.DidChangeTextDocumentParams{
-		TextDocument: protocol.VersionedTextDocumentIdentifier{
-			TextDocumentIdentifier: protocol.TextDocumentIdentifier{
-				URI: fileURI,
+
+	// Versions must increase for gopls to accept the change; hold the lock
+	// while sending so changes arrive in version order
+	c.overlayMu.Lock()
+	defer c.overlayMu.Unlock()
+	version, open := c.overlays[fileURI]
+	version++
+
+	if !open {
+		params := protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{
+				URI:        fileURI,
+				LanguageID: "go",
+				Version:    version,
+				Text:       string(content),
 			},
-			Version: int32(time.Now().Unix()), // Use timestamp as version
-		},
-		ContentChanges: []protocol.TextDocumentContentChangeEvent{
-			{
-				// Full document update (no range specified)
-				Text: string(content),
+		}
+		c.logger.Debugf("Sending didOpen to gopls with %d bytes for %s", len(content), goPath)
+		if err := c.conn.Notify(ctx, "textDocument/didOpen", params); err != nil {
+			return fmt.Errorf("failed to send didOpen to gopls: %w", err)
+		}
+	} else {
+		// Full document update (no range specified)
+		params := protocol.DidChangeTextDocumentParams{
+			TextDocument: protocol.VersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: fileURI},
+				Version:                version,
 			},
-		},
+			ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: string(content)}},
+		}
+		c.logger.Debugf("Sending didChange to gopls with %d bytes for %s", len(content), goPath)
+		if err := c.conn.Notify(ctx, "textDocument/didChange", params); err != nil {
+			return fmt.Errorf("failed to send didChange to gopls: %w", err)
+		}
 	}
 
-	c.logger.Debugf("Sending didChange to gopls with %d bytes for %s", len(content), goPath)
-	if err := c.conn.Notify(ctx, "textDocument/didChange", params); err != nil {
-		return fmt.Errorf("failed to send didChange to gopls: %w", err)
+	c.overlays[fileURI] = version
+	return nil
+}
+
+// HasOverlay reports whether goPath is open in gopls
+func (c *GoplsClient) HasOverlay(goPath string) bool {
+	c.overlayMu.Lock()
+	defer c.overlayMu.Unlock()
+	_, open := c.overlays[protocol.DocumentURI(uri.File(goPath))]
+	return open
+}
+
+// CloseOverlay closes goPath in gopls, which then reads it from disk again.
+// Files without an overlay are left alone.
+func (c *GoplsClient) CloseOverlay(ctx context.Context, goPath string) error {
+	fileURI := protocol.DocumentURI(uri.File(goPath))
+
+	c.overlayMu.Lock()
+	defer c.overlayMu.Unlock()
+	if _, open := c.overlays[fileURI]; !open {
+		return nil
 	}
+	delete(c.overlays, fileURI)
 
-	c.logger.Debugf("gopls synchronized with .go file: %s", goPath)
+	params := protocol.DidCloseTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: fileURI},
+	}
+	if err := c.conn.Notify(ctx, "textDocument/didClose", params); err != nil {
+		return fmt.Errorf("failed to send didClose to gopls: %w", err)
+	}
 	return nil
 }
 
@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 	"go.lsp.dev/uri"
+
+	"github.com/MadAppGang/dingo/pkg/build"
 )
 
 // Response translation methods for LSP handlers
@@ -94,6 +98,49 @@ func (t *Translator) TranslateDefinitionLocations(
 	return translatedLocations, nil
 }
 
+// TranslateReferenceLocations translates locations from Go → Dingo like
+// TranslateDefinitionLocations, leaving out the ones in code the transpiler
+// generated (injected helpers, the TypesFile) and the duplicates generated
+// code makes
+func (t *Translator) TranslateReferenceLocations(locations []protocol.Location) []protocol.Location {
+	kept := make([]protocol.Location, 0, len(locations))
+	for _, loc := range locations {
+		if _, _, ok := t.TranslateGoRange(loc.URI, loc.Range); ok {
+			kept = append(kept, loc)
+		}
+	}
+	translated, _ := t.TranslateDefinitionLocations(kept, GoToDingo)
+
+	seen := make(map[protocol.Location]bool, len(translated))
+	result := make([]protocol.Location, 0, len(translated))
+	for _, loc := range translated {
+		if !seen[loc] {
+			seen[loc] = true
+			result = append(result, loc)
+		}
+	}
+	return result
+}
+
+// TranslateDocumentHighlights translates highlights of the .go file goURI to
+// its .dingo file, leaving out the ones in code the transpiler generated
+func (t *Translator) TranslateDocumentHighlights(
+	highlights []protocol.DocumentHighlight,
+	goURI protocol.DocumentURI,
+) []protocol.DocumentHighlight {
+	seen := make(map[protocol.Range]bool, len(highlights))
+	result := make([]protocol.DocumentHighlight, 0, len(highlights))
+	for _, h := range highlights {
+		_, newRange, ok := t.TranslateGoRange(goURI, h.Range)
+		if !ok || seen[newRange] {
+			continue
+		}
+		seen[newRange] = true
+		result = append(result, protocol.DocumentHighlight{Range: newRange, Kind: h.Kind})
+	}
+	return result
+}
+
 // TranslateDiagnostics translates diagnostic positions from Go → Dingo
 func (t *Translator) TranslateDiagnostics(
 	diagnostics []protocol.Diagnostic,
@@ -132,6 +179,48 @@ func (t *Translator) TranslateDiagnostics(
 	return translatedDiagnostics, nil
 }
 
+// TranslateWorkspaceEdit translates the text edits of a workspace edit from
+// generated .go files to their .dingo files, merging documentChanges into
+// changes. Edits in code the transpiler generated cannot be mapped; they
+// are left out and returned as dropped. Edits of plain .go files are kept.
+func (t *Translator) TranslateWorkspaceEdit(
+	edit *protocol.WorkspaceEdit,
+) (*protocol.WorkspaceEdit, []protocol.Location) {
+	if edit == nil {
+		return nil, nil
+	}
+
+	type key struct {
+		uri protocol.DocumentURI
+		rng protocol.Range
+	}
+	translated := &protocol.WorkspaceEdit{Changes: make(map[protocol.DocumentURI][]protocol.TextEdit)}
+	seen := make(map[key]bool)
+	var dropped []protocol.Location
+	add := func(uri protocol.DocumentURI, edits []protocol.TextEdit) {
+		for _, e := range edits {
+			newURI, newRange, ok := t.TranslateGoRange(uri, e.Range)
+			if !ok {
+				dropped = append(dropped, protocol.Location{URI: uri, Range: e.Range})
+				continue
+			}
+			// Generated code may repeat the text of one .dingo range
+			if k := (key{newURI, newRange}); !seen[k] {
+				seen[k] = true
+				translated.Changes[newURI] = append(translated.Changes[newURI], protocol.TextEdit{Range: newRange, NewText: e.NewText})
+			}
+		}
+	}
+
+	for uri, edits := range edit.Changes {
+		add(uri, edits)
+	}
+	for _, dc := range edit.DocumentChanges {
+		add(dc.TextDocument.URI, dc.Edits)
+	}
+	return translated, dropped
+}
+
 // Enhanced LSP method handlers with full response translation
 
 // handleCompletionWithTranslation processes completion with full bidirectional translation
@@ -151,6 +240,9 @@ func (s *Server) handleCompletionWithTranslation(
 		return reply(ctx, result, err)
 	}
 
+	// gopls must see the edit that triggered the request
+	s.live.Flush(params.TextDocument.URI.Filename())
+
 	// Translate Dingo position → Go position
 	goURI, goPos, err := s.translator.TranslatePosition(params.TextDocument.URI, params.Position, DingoToGo)
 	if err != nil {
@@ -209,6 +301,9 @@ func (s *Server) handleDefinitionWithTranslation(
 		return reply(ctx, result, err)
 	}
 
+	// gopls must see the edit that triggered the request
+	s.live.Flush(params.TextDocument.URI.Filename())
+
 	// Translate Dingo position → Go position
 	goURI, goPos, err := s.translator.TranslatePosition(params.TextDocument.URI, params.Position, DingoToGo)
 	if err != nil {
@@ -257,6 +352,9 @@ func (s *Server) handleHoverWithTranslation(
 		return reply(ctx, result, err)
 	}
 
+	// gopls must see the edit that triggered the request
+	s.live.Flush(params.TextDocument.URI.Filename())
+
 	// Translate Dingo position → Go position
 	goURI, goPos, err := s.translator.TranslatePosition(params.TextDocument.URI, params.Position, DingoToGo)
 	if err != nil {
@@ -297,6 +395,337 @@ func (s *Server) handleHoverWithTranslation(
 	return reply(ctx, translatedResult, nil)
 }
 
+// toGoPosition points a request on a .dingo file at its generated .go file,
+// once gopls has the latest edit of the buffer. Requests on other files are
+// left alone.
+func (s *Server) toGoPosition(params *protocol.TextDocumentPositionParams) {
+	if !isDingoFile(params.TextDocument.URI) {
+		return
+	}
+
+	// gopls must see the edit that triggered the request
+	s.live.Flush(params.TextDocument.URI.Filename())
+
+	// The .go URI comes back even when translation fails; gopls may still
+	// know the position
+	goURI, goPos, err := s.translator.TranslatePosition(params.TextDocument.URI, params.Position, DingoToGo)
+	if err != nil {
+		s.config.Logger.Warnf("Position translation failed: %v", err)
+	}
+	params.TextDocument.URI = goURI
+	params.Position = goPos
+}
+
+// handleReferencesWithTranslation finds references with gopls. References
+// in .go files generated from .dingo files are reported in the .dingo
+// files; the ones in code the transpiler generated are left out.
+func (s *Server) handleReferencesWithTranslation(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	req jsonrpc2.Request,
+) error {
+	var params protocol.ReferenceParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+	s.toGoPosition(&params.TextDocumentPositionParams)
+
+	result, err := s.gopls.References(ctx, params)
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+	return reply(ctx, s.translator.TranslateReferenceLocations(result), nil)
+}
+
+// handleDocumentHighlightWithTranslation highlights the occurrences of the
+// symbol under the cursor in its file
+func (s *Server) handleDocumentHighlightWithTranslation(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	req jsonrpc2.Request,
+) error {
+	var params protocol.DocumentHighlightParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	// If not a .dingo file, forward directly
+	if !isDingoFile(params.TextDocument.URI) {
+		result, err := s.gopls.DocumentHighlight(ctx, params)
+		return reply(ctx, result, err)
+	}
+	s.toGoPosition(&params.TextDocumentPositionParams)
+
+	result, err := s.gopls.DocumentHighlight(ctx, params)
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+	return reply(ctx, s.translator.TranslateDocumentHighlights(result, params.TextDocument.URI), nil)
+}
+
+// handleImplementationWithTranslation finds implementations with gopls,
+// translated like definitions
+func (s *Server) handleImplementationWithTranslation(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	req jsonrpc2.Request,
+) error {
+	var params protocol.ImplementationParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+	s.toGoPosition(&params.TextDocumentPositionParams)
+
+	result, err := s.gopls.Implementation(ctx, params)
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+	translatedResult, _ := s.translator.TranslateDefinitionLocations(result, GoToDingo)
+	return reply(ctx, translatedResult, nil)
+}
+
+// handleTypeDefinitionWithTranslation finds the type definition with gopls,
+// translated like definitions
+func (s *Server) handleTypeDefinitionWithTranslation(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	req jsonrpc2.Request,
+) error {
+	var params protocol.TypeDefinitionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+	s.toGoPosition(&params.TextDocumentPositionParams)
+
+	result, err := s.gopls.TypeDefinition(ctx, params)
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+	translatedResult, _ := s.translator.TranslateDefinitionLocations(result, GoToDingo)
+	return reply(ctx, translatedResult, nil)
+}
+
+// handleDocumentSymbol outlines .dingo files from their source (see
+// DocumentSymbols); gopls outlines the others
+func (s *Server) handleDocumentSymbol(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	req jsonrpc2.Request,
+) error {
+	var params protocol.DocumentSymbolParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	// If not a .dingo file, forward directly
+	if !isDingoFile(params.TextDocument.URI) {
+		return s.forwardToGopls(ctx, reply, req)
+	}
+
+	src, err := s.readDingoFile(params.TextDocument.URI.Filename())
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+	return reply(ctx, DocumentSymbols(src), nil)
+}
+
+// handleWorkspaceSymbol merges the symbols gopls finds with the ones of the
+// .dingo files of the workspace
+func (s *Server) handleWorkspaceSymbol(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	req jsonrpc2.Request,
+) error {
+	var params protocol.WorkspaceSymbolParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	// Without gopls, the .dingo files still have symbols
+	symbols, err := s.gopls.WorkspaceSymbol(ctx, params)
+	if err != nil {
+		s.config.Logger.Warnf("gopls workspace/symbol failed: %v", err)
+	}
+
+	var dingoFiles []string
+	if s.workspacePath != "" {
+		pkgs, err := build.DiscoverPackages(s.workspacePath, nil)
+		if err != nil {
+			s.config.Logger.Warnf("Failed to find .dingo files: %v", err)
+		}
+		for _, pkg := range pkgs {
+			for _, file := range pkg.DingoFiles {
+				dingoFiles = append(dingoFiles, filepath.Join(s.workspacePath, filepath.FromSlash(file)))
+			}
+		}
+	}
+
+	return reply(ctx, s.translator.TranslateWorkspaceSymbols(params.Query, symbols, dingoFiles, s.readDingoFile), nil)
+}
+
+// handleCodeAction offers the fixes of the Dingo diagnostics of a .dingo
+// file as quick fixes
+func (s *Server) handleCodeAction(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	req jsonrpc2.Request,
+) error {
+	var params protocol.CodeActionParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	// If not a .dingo file, forward directly
+	if !isDingoFile(params.TextDocument.URI) {
+		return s.forwardToGopls(ctx, reply, req)
+	}
+
+	if len(params.Context.Only) > 0 {
+		quickFix := false
+		for _, kind := range params.Context.Only {
+			quickFix = quickFix || kind == protocol.QuickFix
+		}
+		if !quickFix {
+			return reply(ctx, []protocol.CodeAction{}, nil)
+		}
+	}
+	return reply(ctx, codeActions(params.TextDocument.URI, params.Context.Diagnostics), nil)
+}
+
+// codeActions returns a quick fix for every fix that diagnostics carry in
+// their Data (see toProtocolDiagnostics). The first fix of a diagnostic is
+// the preferred one.
+func codeActions(uri protocol.DocumentURI, diagnostics []protocol.Diagnostic) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	for _, d := range diagnostics {
+		if d.Source != "dingo" || d.Data == nil {
+			continue
+		}
+		// Data comes back as decoded JSON
+		data, err := json.Marshal(d.Data)
+		if err != nil {
+			continue
+		}
+		var fixes []diagnosticFix
+		if err := json.Unmarshal(data, &fixes); err != nil {
+			continue
+		}
+		for i, fix := range fixes {
+			actions = append(actions, protocol.CodeAction{
+				Title:       fix.Title,
+				Kind:        protocol.QuickFix,
+				Diagnostics: []protocol.Diagnostic{d},
+				IsPreferred: i == 0,
+				Edit: &protocol.WorkspaceEdit{
+					Changes: map[protocol.DocumentURI][]protocol.TextEdit{uri: fix.Edits},
+				},
+			})
+		}
+	}
+	return actions
+}
+
+// readDingoFile returns the source of a .dingo file as open in the editor,
+// or as saved
+func (s *Server) readDingoFile(dingoPath string) ([]byte, error) {
+	if text, ok := s.live.Text(dingoPath); ok {
+		return text, nil
+	}
+	return os.ReadFile(dingoPath)
+}
+
+// handlePrepareRenameWithTranslation checks that the symbol under the cursor
+// can be renamed, and returns its .dingo range
+func (s *Server) handlePrepareRenameWithTranslation(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	req jsonrpc2.Request,
+) error {
+	var params protocol.PrepareRenameParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	// If not a .dingo file, forward directly
+	if !isDingoFile(params.TextDocument.URI) {
+		result, err := s.gopls.PrepareRename(ctx, params)
+		return reply(ctx, result, err)
+	}
+
+	// gopls must see the edit that triggered the request
+	s.live.Flush(params.TextDocument.URI.Filename())
+
+	// Translate Dingo position → Go position
+	goURI, goPos, err := s.translator.TranslatePosition(params.TextDocument.URI, params.Position, DingoToGo)
+	if err != nil {
+		s.config.Logger.Warnf("Position translation failed: %v", err)
+		return reply(ctx, nil, fmt.Errorf("cannot rename: %w (try re-transpiling file)", err))
+	}
+	params.TextDocument.URI = goURI
+	params.Position = goPos
+
+	result, err := s.gopls.PrepareRename(ctx, params)
+	if err != nil || result == nil {
+		return reply(ctx, nil, err)
+	}
+
+	// Translate response: the range must exist in the .dingo file
+	_, dingoRange, ok := s.translator.TranslateGoRange(goURI, result.Range)
+	if !ok {
+		return reply(ctx, nil, fmt.Errorf("cannot rename code generated by dingo"))
+	}
+	result.Range = dingoRange
+	return reply(ctx, result, nil)
+}
+
+// handleRenameWithTranslation renames with gopls and translates the edits
+// back to .dingo files. Edits in generated code without a source mapping
+// are dropped with a warning.
+func (s *Server) handleRenameWithTranslation(
+	ctx context.Context,
+	reply jsonrpc2.Replier,
+	req jsonrpc2.Request,
+) error {
+	var params protocol.RenameParams
+	if err := json.Unmarshal(req.Params(), &params); err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	if isDingoFile(params.TextDocument.URI) {
+		// gopls must see the edit that triggered the request
+		s.live.Flush(params.TextDocument.URI.Filename())
+
+		// Translate Dingo position → Go position
+		goURI, goPos, err := s.translator.TranslatePosition(params.TextDocument.URI, params.Position, DingoToGo)
+		if err != nil {
+			s.config.Logger.Warnf("Position translation failed: %v", err)
+			return reply(ctx, nil, fmt.Errorf("cannot rename: %w (try re-transpiling file)", err))
+		}
+		params.TextDocument.URI = goURI
+		params.Position = goPos
+	}
+
+	// Forward to gopls. A rename from a .go file may reach .dingo files too.
+	result, err := s.gopls.Rename(ctx, params)
+	if err != nil {
+		return reply(ctx, nil, err)
+	}
+
+	// Translate response: Go edits → Dingo edits
+	translatedResult, dropped := s.translator.TranslateWorkspaceEdit(result)
+	if len(dropped) > 0 {
+		for _, loc := range dropped {
+			s.config.Logger.Warnf("Rename: dropped edit in generated code without source mapping: %s:%d:%d",
+				loc.URI.Filename(), loc.Range.Start.Line+1, loc.Range.Start.Character+1)
+		}
+		s.showMessage(protocol.MessageTypeWarning, fmt.Sprintf(
+			"Rename to %q skipped %d occurrence(s) in code generated by dingo; check the result with dingo build",
+			params.NewName, len(dropped)))
+	}
+
+	return reply(ctx, translatedResult, nil)
+}
+
 // handlePublishDiagnostics processes diagnostics from gopls and translates to Dingo positions
 // This is called when gopls sends diagnostics for .go files
 func (s *Server) handlePublishDiagnostics(
@@ -1,6 +1,7 @@
 package lsp
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -339,3 +340,114 @@ func TestTranslateCompletionList_WithAdditionalTextEdits(t *testing.T) {
 	assert.Len(t, result.Items, 1)
 	assert.Len(t, result.Items[0].AdditionalTextEdits, 1)
 }
+
+// fileCache holds the source maps of some .go files; the others are plain Go
+type fileCache map[string]*preprocessor.SourceMap
+
+func (c fileCache) Get(goFilePath string) (*preprocessor.SourceMap, error) {
+	if sm, ok := c[goFilePath]; ok {
+		return sm, nil
+	}
+	return nil, fmt.Errorf("source map not found: %s", goFilePath)
+}
+
+func (c fileCache) Invalidate(goFilePath string) {}
+func (c fileCache) InvalidateAll()               {}
+func (c fileCache) Size() int                    { return len(c) }
+
+func TestTranslateWorkspaceEdit(t *testing.T) {
+	// main.go line 12 is .dingo line 8; line 14 is generated
+	sm := &preprocessor.SourceMap{
+		Version: 1,
+		Mappings: []preprocessor.Mapping{
+			{GeneratedLine: 12, GeneratedColumn: 1, OriginalLine: 8, OriginalColumn: 1, Length: 40, Name: "identity"},
+			{GeneratedLine: 13, GeneratedColumn: 1, OriginalLine: 8, OriginalColumn: 1, Length: 40, Name: "identity"},
+		},
+	}
+	translator := NewTranslator(fileCache{"/ws/main.go": sm})
+
+	rng := func(line, start, end uint32) protocol.Range {
+		return protocol.Range{Start: protocol.Position{Line: line, Character: start}, End: protocol.Position{Line: line, Character: end}}
+	}
+	edit := &protocol.WorkspaceEdit{
+		DocumentChanges: []protocol.TextDocumentEdit{{
+			TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri.File("/ws/main.go")},
+			},
+			Edits: []protocol.TextEdit{
+				{Range: rng(11, 5, 9), NewText: "Conf"}, // Mapped
+				{Range: rng(12, 5, 9), NewText: "Conf"}, // Same .dingo range
+				{Range: rng(13, 1, 5), NewText: "Conf"}, // Generated
+			},
+		}},
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			uri.File("/ws/util.go"):        {{Range: rng(3, 6, 10), NewText: "Conf"}}, // Plain Go
+			uri.File("/ws/dingo_types.go"): {{Range: rng(7, 5, 9), NewText: "Conf"}},  // Injected types
+		},
+	}
+
+	result, dropped := translator.TranslateWorkspaceEdit(edit)
+	require.NotNil(t, result)
+	assert.Len(t, dropped, 2)
+	assert.Empty(t, result.DocumentChanges)
+	assert.Equal(t, []protocol.TextEdit{{Range: rng(7, 5, 9), NewText: "Conf"}}, result.Changes[uri.File("/ws/main.dingo")])
+	assert.Equal(t, []protocol.TextEdit{{Range: rng(3, 6, 10), NewText: "Conf"}}, result.Changes[uri.File("/ws/util.go")])
+	assert.NotContains(t, result.Changes, uri.File("/ws/main.go"))
+	assert.NotContains(t, result.Changes, uri.File("/ws/dingo_types.go"))
+}
+
+func TestTranslateReferenceLocations(t *testing.T) {
+	// main.go line 12 is .dingo line 8; line 14 is generated
+	sm := &preprocessor.SourceMap{
+		Version: 1,
+		Mappings: []preprocessor.Mapping{
+			{GeneratedLine: 12, GeneratedColumn: 1, OriginalLine: 8, OriginalColumn: 1, Length: 40},
+			{GeneratedLine: 13, GeneratedColumn: 1, OriginalLine: 8, OriginalColumn: 1, Length: 40},
+		},
+	}
+	// A generated .go file of another Dingo package
+	other := &preprocessor.SourceMap{
+		Version:  1,
+		Mappings: []preprocessor.Mapping{{GeneratedLine: 20, GeneratedColumn: 1, OriginalLine: 9, OriginalColumn: 1, Length: 30}},
+	}
+	translator := NewTranslator(fileCache{"/ws/main.go": sm, "/ws/lib/lib.go": other})
+
+	loc := func(path string, line, start, end uint32) protocol.Location {
+		return protocol.Location{
+			URI:   uri.File(path),
+			Range: protocol.Range{Start: protocol.Position{Line: line, Character: start}, End: protocol.Position{Line: line, Character: end}},
+		}
+	}
+	result := translator.TranslateReferenceLocations([]protocol.Location{
+		loc("/ws/main.go", 11, 5, 9),                  // Mapped
+		loc("/ws/main.go", 12, 5, 9),                  // Same .dingo range
+		loc("/ws/main.go", 13, 1, 5),                  // Injected helper
+		loc("/ws/dingo_types.go", 7, 5, 9),            // Injected types
+		loc("/ws/lib/lib.go", 19, 2, 6),               // Other Dingo package
+		loc("/usr/lib/go/src/fmt/print.go", 3, 5, 11), // Plain Go
+	})
+
+	assert.Equal(t, []protocol.Location{
+		loc("/ws/main.dingo", 7, 5, 9),
+		loc("/ws/lib/lib.dingo", 8, 2, 6),
+		loc("/usr/lib/go/src/fmt/print.go", 3, 5, 11),
+	}, result)
+}
+
+func TestTranslateDocumentHighlights(t *testing.T) {
+	sm := &preprocessor.SourceMap{
+		Version:  1,
+		Mappings: []preprocessor.Mapping{{GeneratedLine: 12, GeneratedColumn: 1, OriginalLine: 8, OriginalColumn: 1, Length: 40}},
+	}
+	translator := NewTranslator(fileCache{"/ws/main.go": sm})
+
+	rng := func(line, start, end uint32) protocol.Range {
+		return protocol.Range{Start: protocol.Position{Line: line, Character: start}, End: protocol.Position{Line: line, Character: end}}
+	}
+	result := translator.TranslateDocumentHighlights([]protocol.DocumentHighlight{
+		{Range: rng(11, 5, 9), Kind: protocol.DocumentHighlightKindWrite},
+		{Range: rng(13, 1, 5), Kind: protocol.DocumentHighlightKindRead}, // Generated
+	}, uri.File("/ws/main.go"))
+
+	assert.Equal(t, []protocol.DocumentHighlight{{Range: rng(7, 5, 9), Kind: protocol.DocumentHighlightKindWrite}}, result)
+}
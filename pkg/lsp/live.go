@@ -0,0 +1,170 @@
+package lsp
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultLiveDelay is how long typing must pause before the unsaved buffer
+// of a .dingo file is transpiled
+const DefaultLiveDelay = 250 * time.Millisecond
+
+// TranspileFunc transpiles version of the unsaved buffer src of dingoPath
+type TranspileFunc func(ctx context.Context, dingoPath string, version int32, src []byte)
+
+// LiveTranspiler keeps the buffers of the .dingo files open in the editor
+// and transpiles each one when typing pauses, so that gopls sees what is
+// being typed rather than what was last saved. A document is transpiled by
+// one call at a time; an edit cancels the transpilation of the version it
+// replaces.
+type LiveTranspiler struct {
+	logger    Logger
+	delay     time.Duration
+	transpile TranspileFunc
+
+	mu   sync.Mutex
+	cond *sync.Cond // Signaled when a transpilation ends or a document closes
+	docs map[string]*liveDocument
+}
+
+// liveDocument is the buffer of an open .dingo file
+type liveDocument struct {
+	version int32
+	text    []byte
+	stale   bool               // text was not transpiled yet
+	timer   *time.Timer        // Pending transpilation
+	armed   int                // Timers armed so far; identifies timer
+	running bool               // A transpilation is in progress
+	cancel  context.CancelFunc // Cancels the transpilation in progress
+}
+
+// NewLiveTranspiler creates a live transpiler calling transpile delay after
+// the last edit of a document
+func NewLiveTranspiler(logger Logger, delay time.Duration, transpile TranspileFunc) *LiveTranspiler {
+	lt := &LiveTranspiler{
+		logger:    logger,
+		delay:     delay,
+		transpile: transpile,
+		docs:      make(map[string]*liveDocument),
+	}
+	lt.cond = sync.NewCond(&lt.mu)
+	return lt
+}
+
+// Update records version of the buffer of dingoPath (on didOpen and
+// didChange) and schedules its transpilation
+func (lt *LiveTranspiler) Update(dingoPath string, version int32, text []byte) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	doc := lt.docs[dingoPath]
+	if doc == nil {
+		doc = &liveDocument{}
+		lt.docs[dingoPath] = doc
+	}
+	doc.version, doc.text, doc.stale = version, text, true
+	if doc.cancel != nil {
+		doc.cancel() // Its result is out of date
+	}
+	if doc.timer != nil {
+		doc.timer.Stop()
+	}
+	doc.armed++
+	armed := doc.armed
+	doc.timer = time.AfterFunc(lt.delay, func() { lt.run(dingoPath, doc, armed) })
+}
+
+// Flush transpiles the buffer of dingoPath now if an edit is pending, and
+// waits until the transpilation in progress, if any, is done. Requests that
+// depend on the latest edit, such as completion, call it first.
+func (lt *LiveTranspiler) Flush(dingoPath string) {
+	lt.mu.Lock()
+	doc := lt.docs[dingoPath]
+	if doc != nil && doc.timer != nil && doc.timer.Stop() {
+		armed := doc.armed
+		lt.mu.Unlock()
+		lt.run(dingoPath, doc, armed)
+		lt.mu.Lock()
+	}
+	// The timer may have fired already: wait for its run as well
+	for doc != nil && lt.docs[dingoPath] == doc && (doc.timer != nil || doc.running) {
+		lt.cond.Wait()
+	}
+	lt.mu.Unlock()
+}
+
+// Close forgets the buffer of dingoPath (on didClose) and cancels its
+// pending transpilation
+func (lt *LiveTranspiler) Close(dingoPath string) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	doc := lt.docs[dingoPath]
+	if doc == nil {
+		return
+	}
+	if doc.timer != nil {
+		doc.timer.Stop()
+	}
+	if doc.cancel != nil {
+		doc.cancel()
+	}
+	delete(lt.docs, dingoPath)
+	lt.cond.Broadcast()
+}
+
+// Text returns the latest version of the buffer of dingoPath, if it is open
+func (lt *LiveTranspiler) Text(dingoPath string) ([]byte, bool) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	doc := lt.docs[dingoPath]
+	if doc == nil {
+		return nil, false
+	}
+	return doc.text, true
+}
+
+// HasOpen reports whether a .dingo file of dir is open
+func (lt *LiveTranspiler) HasOpen(dir string) bool {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	for path := range lt.docs {
+		if filepath.Dir(path) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// run transpiles the latest version of doc unless it was transpiled already.
+// armed identifies the timer that called it: an edit may have armed another
+// one since, which stays pending.
+func (lt *LiveTranspiler) run(dingoPath string, doc *liveDocument, armed int) {
+	lt.mu.Lock()
+	if doc.armed == armed {
+		doc.timer = nil
+	}
+	for doc.running {
+		lt.cond.Wait()
+	}
+	if lt.docs[dingoPath] != doc || !doc.stale {
+		lt.cond.Broadcast()
+		lt.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	version, text := doc.version, doc.text
+	doc.stale, doc.running, doc.cancel = false, true, cancel
+	lt.mu.Unlock()
+
+	lt.logger.Debugf("Live transpile: %s (version %d)", dingoPath, version)
+	lt.transpile(ctx, dingoPath, version, text)
+	cancel()
+
+	lt.mu.Lock()
+	doc.running, doc.cancel = false, nil
+	lt.cond.Broadcast()
+	lt.mu.Unlock()
+}
@@ -0,0 +1,139 @@
+package lsp
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingTranspile records the versions it is called with
+type recordingTranspile struct {
+	mu       sync.Mutex
+	versions []int32
+	texts    []string
+}
+
+func (r *recordingTranspile) transpile(ctx context.Context, dingoPath string, version int32, src []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.versions = append(r.versions, version)
+	r.texts = append(r.texts, string(src))
+}
+
+func (r *recordingTranspile) calls() ([]int32, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int32(nil), r.versions...), append([]string(nil), r.texts...)
+}
+
+func TestLiveTranspiler_Debounce(t *testing.T) {
+	rec := &recordingTranspile{}
+	lt := NewLiveTranspiler(&testLogger{}, 50*time.Millisecond, rec.transpile)
+
+	// A burst of edits is transpiled once, at its last version
+	for v := int32(1); v <= 5; v++ {
+		lt.Update("/ws/main.dingo", v, []byte{byte('a' + v)})
+	}
+	time.Sleep(200 * time.Millisecond)
+	lt.Flush("/ws/main.dingo")
+
+	versions, texts := rec.calls()
+	if len(versions) != 1 || versions[0] != 5 || texts[0] != "f" {
+		t.Errorf("transpiled versions %v (%q), want [5]", versions, texts)
+	}
+}
+
+func TestLiveTranspiler_Flush(t *testing.T) {
+	rec := &recordingTranspile{}
+	lt := NewLiveTranspiler(&testLogger{}, time.Hour, rec.transpile)
+
+	lt.Update("/ws/main.dingo", 1, []byte("x."))
+	lt.Flush("/ws/main.dingo")
+	if versions, _ := rec.calls(); len(versions) != 1 || versions[0] != 1 {
+		t.Fatalf("Flush transpiled versions %v, want [1]", versions)
+	}
+
+	// Nothing pending: no transpilation
+	lt.Flush("/ws/main.dingo")
+	lt.Flush("/ws/other.dingo")
+	if versions, _ := rec.calls(); len(versions) != 1 {
+		t.Errorf("Flush without edits transpiled versions %v", versions)
+	}
+}
+
+func TestLiveTranspiler_Close(t *testing.T) {
+	rec := &recordingTranspile{}
+	lt := NewLiveTranspiler(&testLogger{}, 20*time.Millisecond, rec.transpile)
+
+	lt.Update("/ws/main.dingo", 1, []byte("package main"))
+	if !lt.HasOpen("/ws") || lt.HasOpen("/other") {
+		t.Errorf("HasOpen(/ws), HasOpen(/other) = %v, %v", lt.HasOpen("/ws"), lt.HasOpen("/other"))
+	}
+	lt.Close("/ws/main.dingo")
+	time.Sleep(100 * time.Millisecond)
+
+	if versions, _ := rec.calls(); len(versions) != 0 {
+		t.Errorf("closed document transpiled: versions %v", versions)
+	}
+	if lt.HasOpen("/ws") {
+		t.Error("HasOpen(/ws) after Close")
+	}
+}
+
+func TestLiveTranspiler_CancelsOutdated(t *testing.T) {
+	started := make(chan struct{})
+	var mu sync.Mutex
+	var canceled []int32
+	transpile := func(ctx context.Context, dingoPath string, version int32, src []byte) {
+		if version == 1 {
+			close(started)
+			<-ctx.Done()
+			mu.Lock()
+			canceled = append(canceled, version)
+			mu.Unlock()
+		}
+	}
+	lt := NewLiveTranspiler(&testLogger{}, time.Hour, transpile)
+
+	lt.Update("/ws/main.dingo", 1, []byte("a"))
+	go lt.Flush("/ws/main.dingo")
+	<-started
+	lt.Update("/ws/main.dingo", 2, []byte("ab"))
+	lt.Flush("/ws/main.dingo")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(canceled) != 1 {
+		t.Errorf("transpilation of version 1 not canceled by version 2")
+	}
+}
+
+func TestLiveTranspiler_LateTimerKeepsPending(t *testing.T) {
+	rec := &recordingTranspile{}
+	lt := NewLiveTranspiler(&testLogger{}, time.Hour, rec.transpile)
+
+	// The timer of version 1 fires, but version 2 arms another one before
+	// its run gets the lock
+	lt.Update("/ws/main.dingo", 1, []byte("a"))
+	lt.mu.Lock()
+	doc := lt.docs["/ws/main.dingo"]
+	fired := doc.armed
+	lt.mu.Unlock()
+	lt.Update("/ws/main.dingo", 2, []byte("ab"))
+	lt.run("/ws/main.dingo", doc, fired)
+
+	lt.mu.Lock()
+	pending := doc.timer
+	lt.mu.Unlock()
+	if pending == nil {
+		t.Fatal("late run dropped the timer armed by version 2")
+	}
+	lt.Close("/ws/main.dingo")
+	if pending.Stop() {
+		t.Error("Close left the timer of version 2 running")
+	}
+	if versions, _ := rec.calls(); len(versions) != 1 || versions[0] != 2 {
+		t.Errorf("transpiled versions %v, want [2]", versions)
+	}
+}
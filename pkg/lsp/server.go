@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
-	lspuri "go.lsp.dev/uri"
+
+	"github.com/MadAppGang/dingo/pkg/transpiler"
 )
 
 // ServerConfig holds configuration for the LSP server
@@ -17,6 +20,10 @@ type ServerConfig struct {
 	Logger        Logger
 	GoplsPath     string
 	AutoTranspile bool
+
+	// LiveDelay is how long typing must pause before the unsaved buffer of
+	// a .dingo file is transpiled for gopls; 0 means DefaultLiveDelay
+	LiveDelay time.Duration
 }
 
 // Server implements the LSP proxy server
@@ -26,6 +33,7 @@ type Server struct {
 	mapCache      *SourceMapCache
 	translator    *Translator
 	transpiler    *AutoTranspiler
+	live          *LiveTranspiler
 	watcher       *FileWatcher
 	workspacePath string
 	initialized   bool
@@ -65,6 +73,13 @@ func NewServer(cfg ServerConfig) (*Server, error) {
 	transpiler := NewAutoTranspiler(cfg.Logger, mapCache, gopls, server)
 	server.transpiler = transpiler
 
+	// Transpile unsaved buffers in memory as they are edited
+	delay := cfg.LiveDelay
+	if delay == 0 {
+		delay = DefaultLiveDelay
+	}
+	server.live = NewLiveTranspiler(cfg.Logger, delay, transpiler.TranspileBuffer)
+
 	// Set diagnostics handler for gopls -> IDE diagnostics forwarding
 	gopls.SetDiagnosticsHandler(server.handlePublishDiagnostics)
 
@@ -118,6 +133,24 @@ func (s *Server) handleRequest(ctx context.Context, reply jsonrpc2.Replier, req
 		return s.handleDefinition(ctx, reply, req)
 	case "textDocument/hover":
 		return s.handleHover(ctx, reply, req)
+	case "textDocument/references":
+		return s.handleReferencesWithTranslation(ctx, reply, req)
+	case "textDocument/documentHighlight":
+		return s.handleDocumentHighlightWithTranslation(ctx, reply, req)
+	case "textDocument/implementation":
+		return s.handleImplementationWithTranslation(ctx, reply, req)
+	case "textDocument/typeDefinition":
+		return s.handleTypeDefinitionWithTranslation(ctx, reply, req)
+	case "textDocument/documentSymbol":
+		return s.handleDocumentSymbol(ctx, reply, req)
+	case "workspace/symbol":
+		return s.handleWorkspaceSymbol(ctx, reply, req)
+	case "textDocument/codeAction":
+		return s.handleCodeAction(ctx, reply, req)
+	case "textDocument/prepareRename":
+		return s.handlePrepareRenameWithTranslation(ctx, reply, req)
+	case "textDocument/rename":
+		return s.handleRenameWithTranslation(ctx, reply, req)
 	default:
 		// Unknown method - try forwarding to gopls
 		s.config.Logger.Debugf("Forwarding unknown method to gopls: %s", req.Method())
@@ -176,6 +209,15 @@ func (s *Server) handleInitialize(ctx context.Context, reply jsonrpc2.Replier, r
 			},
 			HoverProvider:      goplsResult.Capabilities.HoverProvider,
 			DefinitionProvider: goplsResult.Capabilities.DefinitionProvider,
+			RenameProvider:     &protocol.RenameOptions{PrepareProvider: true},
+
+			ReferencesProvider:        goplsResult.Capabilities.ReferencesProvider,
+			DocumentHighlightProvider: goplsResult.Capabilities.DocumentHighlightProvider,
+			ImplementationProvider:    goplsResult.Capabilities.ImplementationProvider,
+			TypeDefinitionProvider:    goplsResult.Capabilities.TypeDefinitionProvider,
+			DocumentSymbolProvider:    true,
+			WorkspaceSymbolProvider:   true,
+			CodeActionProvider:        true,
 		},
 		ServerInfo: &protocol.ServerInfo{
 			Name:    "dingo-lsp",
@@ -267,6 +309,9 @@ func (s *Server) handleDidOpen(ctx context.Context, reply jsonrpc2.Replier, req
 			s.config.Logger.Infof("[didOpen] Successfully opened .go file with gopls")
 		}
 
+		// The buffer may differ from what was last transpiled to disk
+		s.live.Update(dingoPath, params.TextDocument.Version, []byte(params.TextDocument.Text))
+
 		return reply(ctx, nil, nil)
 	}
 
@@ -285,11 +330,14 @@ func (s *Server) handleDidChange(ctx context.Context, reply jsonrpc2.Replier, re
 		return reply(ctx, nil, err)
 	}
 
-	// IMPORTANT: Don't forward .dingo file changes to gopls
-	// gopls reads .go files from disk (updated by auto-transpiler on save)
-	// We translate positions during queries instead
+	// .dingo changes are not forwarded to gopls: the buffer is transpiled
+	// in memory once typing pauses, and gopls gets the Go as an overlay
 	if isDingoFile(params.TextDocument.URI) {
-		s.config.Logger.Debugf("Changed .dingo file (not forwarding to gopls): %s", params.TextDocument.URI)
+		// Sync is full (see handleInitialize): the last change is the buffer
+		if n := len(params.ContentChanges); n > 0 {
+			s.config.Logger.Debugf("Changed .dingo file: %s (version %d)", params.TextDocument.URI, params.TextDocument.Version)
+			s.live.Update(params.TextDocument.URI.Filename(), params.TextDocument.Version, []byte(params.ContentChanges[n-1].Text))
+		}
 		return reply(ctx, nil, nil)
 	}
 
@@ -338,11 +386,21 @@ func (s *Server) handleDidClose(ctx context.Context, reply jsonrpc2.Replier, req
 	// CRITICAL FIX D1: When .dingo file closes, close corresponding .go file with gopls
 	if isDingoFile(params.TextDocument.URI) {
 		s.config.Logger.Debugf("Closed .dingo file: %s", params.TextDocument.URI)
+		dingoPath := params.TextDocument.URI.Filename()
+
+		// Drop the unsaved buffer: from now on the .go file on disk counts
+		s.live.Close(dingoPath)
+		s.mapCache.ClearOverlay(dingoToGoPath(dingoPath))
 
 		// Close corresponding .go file with gopls
-		if err := s.closeGoFileWithGopls(ctx, params.TextDocument.URI.Filename()); err != nil {
+		if err := s.closeGoFileWithGopls(ctx, dingoPath); err != nil {
 			s.config.Logger.Warnf("Failed to close .go file with gopls: %v", err)
 		}
+		if dir := filepath.Dir(dingoPath); !s.live.HasOpen(dir) {
+			if err := s.gopls.CloseOverlay(ctx, filepath.Join(dir, transpiler.TypesFile)); err != nil {
+				s.config.Logger.Warnf("Failed to close %s with gopls: %v", transpiler.TypesFile, err)
+			}
+		}
 
 		return reply(ctx, nil, nil)
 	}
@@ -393,18 +451,8 @@ func (s *Server) openGoFileWithGopls(ctx context.Context, dingoPath string) erro
 		return fmt.Errorf("failed to read .go file: %w", err)
 	}
 
-	// Create didOpen params for gopls
-	params := protocol.DidOpenTextDocumentParams{
-		TextDocument: protocol.TextDocumentItem{
-			URI:        protocol.DocumentURI(lspuri.File(goPath)),
-			LanguageID: "go",
-			Version:    1,
-			Text:       string(contents),
-		},
-	}
-
 	// Open with gopls
-	if err := s.gopls.DidOpen(ctx, params); err != nil {
+	if err := s.gopls.SetOverlay(ctx, goPath, contents); err != nil {
 		return fmt.Errorf("gopls didOpen failed: %w", err)
 	}
 
@@ -420,15 +468,8 @@ func (s *Server) closeGoFileWithGopls(ctx context.Context, dingoPath string) err
 
 	s.config.Logger.Debugf("[Diagnostic Fix] Closing .go file with gopls: %s", goPath)
 
-	// Create didClose params for gopls
-	params := protocol.DidCloseTextDocumentParams{
-		TextDocument: protocol.TextDocumentIdentifier{
-			URI: protocol.DocumentURI(lspuri.File(goPath)),
-		},
-	}
-
 	// Close with gopls
-	if err := s.gopls.DidClose(ctx, params); err != nil {
+	if err := s.gopls.CloseOverlay(ctx, goPath); err != nil {
 		return fmt.Errorf("gopls didClose failed: %w", err)
 	}
 
@@ -472,6 +513,21 @@ func (s *Server) publishDingoDiagnostics(uri protocol.DocumentURI, diagnostics [
 	}
 }
 
+// showMessage shows a message in the IDE
+func (s *Server) showMessage(typ protocol.MessageType, message string) {
+	ideConn, serverCtx := s.GetConn()
+	if ideConn == nil {
+		return
+	}
+	if serverCtx == nil {
+		serverCtx = context.Background()
+	}
+	params := protocol.ShowMessageParams{Type: typ, Message: message}
+	if err := ideConn.Notify(serverCtx, "window/showMessage", params); err != nil {
+		s.config.Logger.Warnf("Failed to show message: %v", err)
+	}
+}
+
 // forwardToGopls forwards unknown requests directly to gopls
 func (s *Server) forwardToGopls(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
 	// This is a simplified forwarding - full implementation would use gopls connection directly
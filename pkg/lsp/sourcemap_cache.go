@@ -1,12 +1,12 @@
 package lsp
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"sync"
 
 	"github.com/MadAppGang/dingo/pkg/preprocessor"
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
 )
 
 // MaxSupportedSourceMapVersion is the highest source map version this LSP can handle
@@ -22,28 +22,42 @@ type SourceMapGetter interface {
 
 // SourceMapCache provides in-memory caching of source maps with version validation
 type SourceMapCache struct {
-	mu      sync.RWMutex
-	maps    map[string]*preprocessor.SourceMap // mapPath -> SourceMap
-	logger  Logger
-	maxSize int
+	mu       sync.RWMutex
+	maps     map[string]*preprocessor.SourceMap // mapPath -> SourceMap
+	overlays map[string]overlayMap              // mapPath -> source map of the unsaved buffer
+	logger   Logger
+	maxSize  int
+}
+
+// overlayMap is the source map of the Go transpiled from one version of an
+// unsaved .dingo buffer
+type overlayMap struct {
+	version int32
+	sm      *preprocessor.SourceMap
 }
 
 // NewSourceMapCache creates a new source map cache
 func NewSourceMapCache(logger Logger) (*SourceMapCache, error) {
 	return &SourceMapCache{
-		maps:    make(map[string]*preprocessor.SourceMap),
-		logger:  logger,
-		maxSize: 100, // LRU limit (future: implement eviction)
+		maps:     make(map[string]*preprocessor.SourceMap),
+		overlays: make(map[string]overlayMap),
+		logger:   logger,
+		maxSize:  100, // LRU limit (future: implement eviction)
 	}, nil
 }
 
-// Get retrieves a source map from cache or loads it from disk
+// Get retrieves a source map from cache or loads it from disk. The overlay
+// set for goFilePath, if any, takes precedence.
 func (c *SourceMapCache) Get(goFilePath string) (*preprocessor.SourceMap, error) {
 	mapPath := goFilePath + ".map"
 
 	// CRITICAL FIX C5: Safe double-check locking pattern
 	// Try read lock first (optimistic)
 	c.mu.RLock()
+	if o, ok := c.overlays[mapPath]; ok {
+		c.mu.RUnlock()
+		return o.sm, nil
+	}
 	if sm, ok := c.maps[mapPath]; ok {
 		c.mu.RUnlock()
 		c.logger.Debugf("Source map cache hit: %s", mapPath)
@@ -61,8 +75,12 @@ func (c *SourceMapCache) Get(goFilePath string) (*preprocessor.SourceMap, error)
 		return sm, nil
 	}
 
-	// Load source map
+	// Load source map: the .go.map file, or else the map embedded in the
+	// .go file ([sourcemaps] format "inline")
 	data, err := os.ReadFile(mapPath)
+	if os.IsNotExist(err) {
+		data, err = c.readInline(goFilePath)
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("source map not found: %s (transpile .dingo file first: dingo build)", mapPath)
@@ -88,12 +106,31 @@ func (c *SourceMapCache) Get(goFilePath string) (*preprocessor.SourceMap, error)
 	return sm, nil
 }
 
+// readInline reads the source map embedded in a generated .go file. The
+// error satisfies os.IsNotExist when there is none.
+func (c *SourceMapCache) readInline(goFilePath string) ([]byte, error) {
+	src, err := os.ReadFile(goFilePath)
+	if err != nil {
+		return nil, err
+	}
+	data, ok, err := sourcemap.ExtractInline(src)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	c.logger.Debugf("Using inline source map of %s", goFilePath)
+	return data, nil
+}
+
+// parseSourceMap decodes a source map in the Dingo or the v3 schema
 func (c *SourceMapCache) parseSourceMap(data []byte) (*preprocessor.SourceMap, error) {
-	var sm preprocessor.SourceMap
-	if err := json.Unmarshal(data, &sm); err != nil {
+	sm, err := sourcemap.Parse(data)
+	if err != nil {
 		return nil, fmt.Errorf("JSON parse error: %w", err)
 	}
-	return &sm, nil
+	return sm, nil
 }
 
 func (c *SourceMapCache) validateVersion(sm *preprocessor.SourceMap, mapPath string) error {
@@ -117,6 +154,38 @@ func (c *SourceMapCache) validateVersion(sm *preprocessor.SourceMap, mapPath str
 	return nil
 }
 
+// SetOverlay makes sm, the source map of the Go transpiled from version of
+// the unsaved buffer of goFilePath's .dingo file, the one Get returns. It
+// reports false, and changes nothing, when a later version is already set.
+func (c *SourceMapCache) SetOverlay(goFilePath string, version int32, sm *preprocessor.SourceMap) bool {
+	mapPath := goFilePath + ".map"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if o, ok := c.overlays[mapPath]; ok && o.version > version {
+		return false
+	}
+	c.overlays[mapPath] = overlayMap{version: version, sm: sm}
+	c.logger.Debugf("Source map overlay set: %s (buffer version %d, %d mappings)", mapPath, version, len(sm.Mappings))
+	return true
+}
+
+// OverlayVersion returns the buffer version of the overlay of goFilePath
+func (c *SourceMapCache) OverlayVersion(goFilePath string) (int32, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	o, ok := c.overlays[goFilePath+".map"]
+	return o.version, ok
+}
+
+// ClearOverlay drops the overlay of goFilePath (called when the .dingo file
+// is closed), so that Get reads the source map from disk again
+func (c *SourceMapCache) ClearOverlay(goFilePath string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.overlays, goFilePath+".map")
+}
+
 // Invalidate removes a source map from cache (called after file changes)
 func (c *SourceMapCache) Invalidate(goFilePath string) {
 	mapPath := goFilePath + ".map"
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/MadAppGang/dingo/pkg/preprocessor"
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
 )
 
 func TestSourceMapCache_HitAndMiss(t *testing.T) {
@@ -202,6 +204,84 @@ func TestSourceMapCache_InvalidateAll(t *testing.T) {
 	}
 }
 
+func TestSourceMapCache_Inline(t *testing.T) {
+	logger := NewLogger("debug", &bytes.Buffer{})
+	cache, err := NewSourceMapCache(logger)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	goFile := filepath.Join(tmpDir, "test.go")
+	sm := &preprocessor.SourceMap{
+		Version:  1,
+		Mappings: []preprocessor.Mapping{{OriginalLine: 3, OriginalColumn: 2, GeneratedLine: 5, GeneratedColumn: 2, Length: 4}},
+	}
+	data, err := sourcemap.MarshalV3(sm, nil) // Either format
+	if err != nil {
+		t.Fatal(err)
+	}
+	goSrc, err := sourcemap.AppendInline([]byte("package main\n"), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(goFile, goSrc, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := cache.Get(goFile)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(got.Mappings) != 1 || got.Mappings[0].OriginalLine != 3 || got.Mappings[0].GeneratedLine != 5 {
+		t.Errorf("inline source map = %+v", got.Mappings)
+	}
+
+	// A .go file without one is still reported as missing a source map
+	plain := filepath.Join(tmpDir, "plain.go")
+	if err := os.WriteFile(plain, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get(plain); err == nil || !strings.Contains(err.Error(), "source map not found") {
+		t.Errorf("Get(plain.go) error = %v, want source map not found", err)
+	}
+}
+
+func TestSourceMapCache_Overlay(t *testing.T) {
+	logger := NewLogger("debug", &bytes.Buffer{})
+	cache, _ := NewSourceMapCache(logger)
+
+	tmpDir := t.TempDir()
+	goFile := filepath.Join(tmpDir, "test.go")
+	onDisk := &preprocessor.SourceMap{Version: 1, Mappings: []preprocessor.Mapping{{OriginalLine: 1, OriginalColumn: 1, GeneratedLine: 1, GeneratedColumn: 1}}}
+	writeSourceMap(t, goFile+".map", onDisk)
+
+	v2 := &preprocessor.SourceMap{Version: 1, Mappings: []preprocessor.Mapping{{OriginalLine: 2, OriginalColumn: 1, GeneratedLine: 4, GeneratedColumn: 1}}}
+	v1 := &preprocessor.SourceMap{Version: 1}
+	if !cache.SetOverlay(goFile, 2, v2) {
+		t.Fatal("SetOverlay rejected the first overlay")
+	}
+	if cache.SetOverlay(goFile, 1, v1) {
+		t.Error("SetOverlay accepted an older buffer version")
+	}
+	if version, ok := cache.OverlayVersion(goFile); !ok || version != 2 {
+		t.Errorf("OverlayVersion = %d, %v; want 2", version, ok)
+	}
+
+	// The overlay wins over the map on disk, until it is cleared
+	if sm, err := cache.Get(goFile); err != nil || sm != v2 {
+		t.Errorf("Get = %+v, %v; want the overlay", sm, err)
+	}
+	cache.Invalidate(goFile)
+	if sm, _ := cache.Get(goFile); sm != v2 {
+		t.Error("Invalidate dropped the overlay")
+	}
+	cache.ClearOverlay(goFile)
+	if sm, err := cache.Get(goFile); err != nil || sm.Mappings[0] != onDisk.Mappings[0] {
+		t.Errorf("Get after ClearOverlay = %+v, %v; want the map on disk", sm, err)
+	}
+}
+
 func TestSourceMapCache_MissingFile(t *testing.T) {
 	logger := NewLogger("debug", &bytes.Buffer{})
 	cache, _ := NewSourceMapCache(logger)
@@ -0,0 +1,567 @@
+package lsp
+
+import (
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"go.lsp.dev/protocol"
+	lspuri "go.lsp.dev/uri"
+
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// DocumentSymbols returns the outline of the Dingo source src: type
+// declarations, enums with their variants, functions and methods with the
+// let bindings of their bodies, and package-level vars, consts and lets.
+// It reads src itself rather than the generated Go, so positions are the
+// .dingo ones and the names the transpiler generates (Option_int_Some, enum
+// tag constants, ...) never show up. Incomplete code is outlined as far as
+// it goes.
+func DocumentSymbols(src []byte) []protocol.DocumentSymbol {
+	p := newSymbolParser(src)
+	symbols := []protocol.DocumentSymbol{}
+	for p.i < len(p.toks)-1 {
+		t := p.toks[p.i]
+		switch {
+		case t.tok == token.FUNC:
+			if sym, ok := p.funcDecl(); ok {
+				symbols = append(symbols, sym)
+			}
+		case t.tok == token.TYPE:
+			symbols = append(symbols, p.typeDecl()...)
+		case t.tok == token.VAR:
+			symbols = append(symbols, p.valueDecl(protocol.SymbolKindVariable)...)
+		case t.tok == token.CONST:
+			symbols = append(symbols, p.valueDecl(protocol.SymbolKindConstant)...)
+		case t.tok == token.IDENT && t.lit == "enum":
+			if sym, ok := p.enumDecl(); ok {
+				symbols = append(symbols, sym)
+			}
+		case t.tok == token.IDENT && t.lit == "let":
+			syms, end := p.letDecl(p.i, len(p.toks)-1)
+			symbols = append(symbols, syms...)
+			p.i = end
+		default:
+			p.i = p.skip(p.i)
+		}
+	}
+	return symbols
+}
+
+// TranslateWorkspaceSymbols merges the workspace symbols gopls found with
+// the symbols of dingoFiles that match query. The symbols gopls found in Go
+// generated from a .dingo file are replaced by the ones DocumentSymbols
+// finds in the .dingo file, which also has the declarations only Dingo
+// knows (enums and their variants, lets); the ones of the TypesFile are left
+// out. read returns the source of a .dingo file.
+func (t *Translator) TranslateWorkspaceSymbols(
+	query string,
+	symbols []protocol.SymbolInformation,
+	dingoFiles []string,
+	read func(dingoPath string) ([]byte, error),
+) []protocol.SymbolInformation {
+	result := make([]protocol.SymbolInformation, 0, len(symbols))
+	dingoFiles = append([]string(nil), dingoFiles...)
+	seen := make(map[string]bool, len(dingoFiles))
+	for _, path := range dingoFiles {
+		seen[filepath.Clean(path)] = true
+	}
+
+	for _, sym := range symbols {
+		goPath := sym.Location.URI.Filename()
+		if filepath.Base(goPath) == transpiler.TypesFile {
+			continue
+		}
+		if _, err := t.cache.Get(goPath); err != nil {
+			result = append(result, sym) // Plain Go
+			continue
+		}
+		// Generated: its .dingo file has the symbols
+		if dingoPath := goToDingoPath(goPath); !seen[dingoPath] {
+			seen[dingoPath] = true
+			dingoFiles = append(dingoFiles, dingoPath)
+		}
+	}
+
+	for _, path := range dingoFiles {
+		src, err := read(path)
+		if err != nil {
+			continue
+		}
+		uri := lspuri.File(path)
+		for _, sym := range DocumentSymbols(src) {
+			if matchesQuery(query, sym.Name) {
+				result = append(result, symbolInformation(uri, sym, ""))
+			}
+			if sym.Kind != protocol.SymbolKindEnum {
+				continue // Locals are no workspace symbols
+			}
+			for _, variant := range sym.Children {
+				if matchesQuery(query, variant.Name) {
+					result = append(result, symbolInformation(uri, variant, sym.Name))
+				}
+			}
+		}
+	}
+	return result
+}
+
+// symbolInformation flattens a document symbol of uri
+func symbolInformation(uri protocol.DocumentURI, sym protocol.DocumentSymbol, container string) protocol.SymbolInformation {
+	return protocol.SymbolInformation{
+		Name:          sym.Name,
+		Kind:          sym.Kind,
+		Location:      protocol.Location{URI: uri, Range: sym.SelectionRange},
+		ContainerName: container,
+	}
+}
+
+// matchesQuery reports whether the characters of query appear in name in
+// order, ignoring case, like the fuzzy matching of gopls. An empty query
+// matches everything.
+func matchesQuery(query, name string) bool {
+	rest := []rune(strings.ToLower(name))
+	for _, q := range strings.ToLower(query) {
+		if unicode.IsSpace(q) {
+			continue
+		}
+		i := 0
+		for i < len(rest) && rest[i] != q {
+			i++
+		}
+		if i == len(rest) {
+			return false
+		}
+		rest = rest[i+1:]
+	}
+	return true
+}
+
+// symToken is a token of Dingo source as go/scanner sees it: Dingo-only
+// operators come out as several tokens or token.ILLEGAL, which is precise
+// enough to find declarations
+type symToken struct {
+	pos, end token.Pos
+	tok      token.Token
+	lit      string
+}
+
+// symbolParser finds the declarations of Dingo source
+type symbolParser struct {
+	src   []byte
+	file  *token.File
+	toks  []symToken // Ends with token.EOF
+	match []int      // Index of the matching bracket of each bracket token
+	i     int
+}
+
+func newSymbolParser(src []byte) *symbolParser {
+	fset := token.NewFileSet()
+	p := &symbolParser{src: src, file: fset.AddFile("", fset.Base(), len(src))}
+
+	var s scanner.Scanner
+	s.Init(p.file, src, nil, 0) // Errors are Dingo syntax: keep going
+	for {
+		pos, tok, lit := s.Scan()
+		end := pos + token.Pos(len(lit))
+		if lit == "" || tok == token.SEMICOLON {
+			end = pos + token.Pos(len(tok.String()))
+		}
+		if tok == token.EOF {
+			end = pos
+		}
+		p.toks = append(p.toks, symToken{pos: pos, end: end, tok: tok, lit: lit})
+		if tok == token.EOF {
+			break
+		}
+	}
+
+	// Unclosed brackets run to the end of the source
+	p.match = make([]int, len(p.toks))
+	var open []int
+	for i, t := range p.toks {
+		p.match[i] = -1
+		switch t.tok {
+		case token.LPAREN, token.LBRACK, token.LBRACE:
+			open = append(open, i)
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			if n := len(open); n > 0 && closing(p.toks[open[n-1]].tok) == t.tok {
+				p.match[open[n-1]], p.match[i] = i, open[n-1]
+				open = open[:n-1]
+			}
+		}
+	}
+	for _, i := range open {
+		p.match[i] = len(p.toks) - 1
+	}
+	return p
+}
+
+// closing returns the bracket closing the opening bracket tok
+func closing(tok token.Token) token.Token {
+	switch tok {
+	case token.LPAREN:
+		return token.RPAREN
+	case token.LBRACK:
+		return token.RBRACK
+	}
+	return token.RBRACE
+}
+
+// skip returns the index after the token at i, or after its matching
+// bracket when it opens one
+func (p *symbolParser) skip(i int) int {
+	if p.match[i] > i {
+		return p.match[i] + 1
+	}
+	return i + 1
+}
+
+// is reports whether the token at i is tok
+func (p *symbolParser) is(i int, tok token.Token) bool {
+	return i < len(p.toks) && p.toks[i].tok == tok
+}
+
+// stmtEnd returns the index of the token ending the statement starting at
+// i: a semicolon or newline, the closing bracket of the enclosing block or
+// limit
+func (p *symbolParser) stmtEnd(i, limit int) int {
+	for i < limit {
+		switch p.toks[i].tok {
+		case token.SEMICOLON, token.RPAREN, token.RBRACK, token.RBRACE:
+			return i
+		}
+		i = p.skip(i)
+	}
+	return limit
+}
+
+// typeParams returns the index after the type parameters at i, in brackets
+// or angle brackets, or i when there are none
+func (p *symbolParser) typeParams(i int) int {
+	switch {
+	case p.is(i, token.LBRACK) && p.is(i+1, token.IDENT) && !p.is(i+2, token.RBRACK):
+		return p.skip(i) // [T any], not the length of an array type
+	case p.is(i, token.LSS):
+		depth := 0
+		for j := i; j < len(p.toks)-1; j++ {
+			switch p.toks[j].tok {
+			case token.LSS:
+				depth++
+			case token.GTR:
+				depth--
+			case token.SHR:
+				depth -= 2
+			case token.LBRACE, token.SEMICOLON:
+				return i // Not type parameters after all
+			}
+			if depth <= 0 {
+				return j + 1
+			}
+		}
+	}
+	return i
+}
+
+// position converts pos to an LSP position
+func (p *symbolParser) position(pos token.Pos) protocol.Position {
+	position := p.file.Position(pos)
+	return protocol.Position{Line: uint32(position.Line - 1), Character: uint32(position.Column - 1)}
+}
+
+// rangeOf returns the range from the token at from to the token at to
+func (p *symbolParser) rangeOf(from, to int) protocol.Range {
+	return protocol.Range{Start: p.position(p.toks[from].pos), End: p.position(p.toks[to].end)}
+}
+
+// text returns the source from the token at from to the token at to, on
+// one line
+func (p *symbolParser) text(from, to int) string {
+	if to < from {
+		return ""
+	}
+	src := p.src[p.file.Offset(p.toks[from].pos):p.file.Offset(p.toks[to].end)]
+	return strings.Join(strings.Fields(string(src)), " ")
+}
+
+// symbol returns the symbol named by the token at name, declared from the
+// token at from to the token at to
+func (p *symbolParser) symbol(name, from, to int, kind protocol.SymbolKind, detail string) protocol.DocumentSymbol {
+	return protocol.DocumentSymbol{
+		Name:           p.toks[name].lit,
+		Detail:         detail,
+		Kind:           kind,
+		Range:          p.rangeOf(from, to),
+		SelectionRange: p.rangeOf(name, name),
+	}
+}
+
+// funcDecl parses the function or method declared at p.i, with the let
+// bindings of its body as children. Its detail is the signature as written.
+func (p *symbolParser) funcDecl() (protocol.DocumentSymbol, bool) {
+	start := p.i
+	i := start + 1
+
+	receiver := ""
+	if p.is(i, token.LPAREN) {
+		receiver = p.receiverType(i+1, p.match[i])
+		i = p.skip(i)
+	}
+	if !p.is(i, token.IDENT) {
+		p.i = p.stmtEnd(start, len(p.toks)-1) // A function literal
+		return protocol.DocumentSymbol{}, false
+	}
+	name := i
+	i = p.typeParams(i + 1)
+
+	// The signature runs up to the body; struct and interface types of the
+	// results have braces of their own
+	sigStart, sigEnd := i, i-1
+	for i < len(p.toks)-1 && !p.is(i, token.LBRACE) && !p.is(i, token.SEMICOLON) {
+		if p.is(i, token.STRUCT) || p.is(i, token.INTERFACE) {
+			i++
+		}
+		sigEnd = p.skip(i) - 1
+		i = p.skip(i)
+	}
+
+	end := sigEnd
+	var children []protocol.DocumentSymbol
+	if p.is(i, token.LBRACE) {
+		end = p.match[i]
+		children = p.lets(i+1, end)
+		i = end + 1
+	}
+	if end < name {
+		end = name
+	}
+	p.i = i
+
+	sym := p.symbol(name, start, end, protocol.SymbolKindFunction, "func"+p.text(sigStart, sigEnd))
+	if receiver != "" {
+		sym.Name = "(" + receiver + ")." + sym.Name
+		sym.Kind = protocol.SymbolKindMethod
+	}
+	sym.Children = children
+	return sym, true
+}
+
+// receiverType returns the type of the receiver between the tokens at from
+// and to, such as *T
+func (p *symbolParser) receiverType(from, to int) string {
+	i := from
+	if p.is(i, token.IDENT) && (p.is(i+1, token.IDENT) || p.is(i+1, token.MUL) || p.is(i+1, token.COLON)) {
+		i++ // Receiver name
+	}
+	if p.is(i, token.COLON) {
+		i++
+	}
+	star := ""
+	if p.is(i, token.MUL) {
+		star = "*"
+		i++
+	}
+	if i >= to || !p.is(i, token.IDENT) {
+		return ""
+	}
+	return star + p.toks[i].lit
+}
+
+// lets returns the let bindings declared between the tokens at from and to,
+// in nested blocks as well
+func (p *symbolParser) lets(from, to int) []protocol.DocumentSymbol {
+	var symbols []protocol.DocumentSymbol
+	for i := from; i < to; i++ {
+		t := p.toks[i]
+		if t.tok != token.IDENT || t.lit != "let" {
+			continue
+		}
+		switch p.toks[i-1].tok {
+		case token.LBRACE, token.SEMICOLON, token.COLON:
+			syms, _ := p.letDecl(i, to)
+			symbols = append(symbols, syms...)
+		}
+	}
+	return symbols
+}
+
+// letDecl parses the let binding at i, such as let x: int = 1 or
+// let (a, b) = f(), and returns a symbol per name and the index after it
+func (p *symbolParser) letDecl(i, limit int) ([]protocol.DocumentSymbol, int) {
+	end := p.stmtEnd(i, limit)
+
+	var names []int
+	j := i + 1
+	if p.is(j, token.LPAREN) {
+		for k := j + 1; k < p.match[j] && k < end; k++ {
+			if p.is(k, token.IDENT) && p.toks[k].lit != "_" {
+				names = append(names, k)
+			}
+		}
+		j = p.skip(j)
+	} else {
+		for p.is(j, token.IDENT) {
+			if p.toks[j].lit != "_" {
+				names = append(names, j)
+			}
+			j++
+			if !p.is(j, token.COMMA) {
+				break
+			}
+			j++
+		}
+	}
+
+	detail := ""
+	if p.is(j, token.COLON) {
+		k := j + 1
+		for k < end && !p.is(k, token.ASSIGN) {
+			k = p.skip(k)
+		}
+		detail = p.text(j+1, k-1)
+	}
+
+	symbols := make([]protocol.DocumentSymbol, 0, len(names))
+	for _, name := range names {
+		symbols = append(symbols, p.symbol(name, i, end-1, protocol.SymbolKindVariable, detail))
+	}
+	return symbols, end
+}
+
+// typeDecl parses the type declaration at p.i, grouped or not
+func (p *symbolParser) typeDecl() []protocol.DocumentSymbol {
+	start := p.i
+	if p.is(start+1, token.LPAREN) {
+		close := p.match[start+1]
+		var symbols []protocol.DocumentSymbol
+		for i := start + 2; i < close; {
+			end := p.stmtEnd(i, close)
+			if sym, ok := p.typeSpec(i, i, end); ok {
+				symbols = append(symbols, sym)
+			}
+			i = end + 1
+		}
+		p.i = close + 1
+		return symbols
+	}
+
+	end := p.stmtEnd(start+1, len(p.toks)-1)
+	p.i = end
+	if sym, ok := p.typeSpec(start, start+1, end); ok {
+		return []protocol.DocumentSymbol{sym}
+	}
+	return nil
+}
+
+// typeSpec parses the type spec at i, declared from the token at from up
+// to the token at end
+func (p *symbolParser) typeSpec(from, i, end int) (protocol.DocumentSymbol, bool) {
+	if !p.is(i, token.IDENT) || end <= i {
+		return protocol.DocumentSymbol{}, false
+	}
+	name := i
+	i = p.typeParams(i + 1)
+	if p.is(i, token.ASSIGN) {
+		i++
+	}
+
+	kind, detail := protocol.SymbolKindClass, p.text(i, end-1)
+	switch {
+	case p.is(i, token.STRUCT):
+		kind, detail = protocol.SymbolKindStruct, "struct"
+	case p.is(i, token.INTERFACE):
+		kind, detail = protocol.SymbolKindInterface, "interface"
+	}
+	return p.symbol(name, from, end-1, kind, detail), true
+}
+
+// enumDecl parses the enum declared at p.i, with its variants as children
+func (p *symbolParser) enumDecl() (protocol.DocumentSymbol, bool) {
+	start := p.i
+	if !p.is(start+1, token.IDENT) {
+		p.i = p.stmtEnd(start, len(p.toks)-1)
+		return protocol.DocumentSymbol{}, false
+	}
+	name := start + 1
+	i := p.typeParams(name + 1)
+	if !p.is(i, token.LBRACE) {
+		p.i = p.stmtEnd(start, len(p.toks)-1)
+		return protocol.DocumentSymbol{}, false
+	}
+	close := p.match[i]
+	p.i = close + 1
+
+	sym := p.symbol(name, start, close, protocol.SymbolKindEnum, "enum")
+	for j := i + 1; j < close; {
+		if !p.is(j, token.IDENT) {
+			j = p.skip(j)
+			continue
+		}
+		// Variant: Name, Name(T, U) or Name { field: T }
+		variant, end, detail := j, j, ""
+		if p.is(j+1, token.LPAREN) || p.is(j+1, token.LBRACE) {
+			end = p.match[j+1]
+			detail = p.text(j+1, end)
+		}
+		sym.Children = append(sym.Children, p.symbol(variant, variant, end, protocol.SymbolKindEnumMember, detail))
+
+		for j = end + 1; j < close && !p.is(j, token.COMMA) && !p.is(j, token.SEMICOLON); {
+			j = p.skip(j)
+		}
+	}
+	return sym, true
+}
+
+// valueDecl parses the var or const declaration at p.i, grouped or not,
+// with a symbol of kind per name
+func (p *symbolParser) valueDecl(kind protocol.SymbolKind) []protocol.DocumentSymbol {
+	start := p.i
+	if p.is(start+1, token.LPAREN) {
+		close := p.match[start+1]
+		var symbols []protocol.DocumentSymbol
+		for i := start + 2; i < close; {
+			end := p.stmtEnd(i, close)
+			symbols = append(symbols, p.valueSpec(i, i, end, kind)...)
+			i = end + 1
+		}
+		p.i = close + 1
+		return symbols
+	}
+
+	end := p.stmtEnd(start+1, len(p.toks)-1)
+	p.i = end
+	return p.valueSpec(start, start+1, end, kind)
+}
+
+// valueSpec parses the names of the value spec at i, declared from the
+// token at from up to the token at end; the detail is their type
+func (p *symbolParser) valueSpec(from, i, end int, kind protocol.SymbolKind) []protocol.DocumentSymbol {
+	var names []int
+	for p.is(i, token.IDENT) && i < end {
+		if p.toks[i].lit != "_" {
+			names = append(names, i)
+		}
+		i++
+		if !p.is(i, token.COMMA) {
+			break
+		}
+		i++
+	}
+	if p.is(i, token.COLON) {
+		i++
+	}
+	k := i
+	for k < end && !p.is(k, token.ASSIGN) {
+		k = p.skip(k)
+	}
+	detail := p.text(i, k-1)
+
+	symbols := make([]protocol.DocumentSymbol, 0, len(names))
+	for _, name := range names {
+		symbols = append(symbols, p.symbol(name, from, end-1, kind, detail))
+	}
+	return symbols
+}
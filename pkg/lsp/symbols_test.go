@@ -0,0 +1,159 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+const symbolsSrc = `package main
+
+import "fmt"
+
+enum Shape {
+	Point,
+	Circle { radius: float64 },
+	Rectangle(float64, float64),
+}
+
+enum Option<T> { Some(T), None }
+
+type (
+	User struct {
+		Name string
+	}
+	Reader interface{ Read() }
+)
+
+type ID int
+
+const limit = 10
+
+let greeting: string = "hi"
+
+func area(s: Shape, scale: float64) -> float64 {
+	let factor = scale * 2
+	if factor > 1 {
+		let (w, _, h) = dims(s)?
+		return w * h
+	}
+	return match s {
+		Point => 0,
+		_ => factor,
+	}
+}
+
+func (u *User) Greet() string {
+	return fmt.Sprintf("%s, %s", greeting, u.Name)
+}
+`
+
+// outline renders symbols as name kind [detail] @line:col, nested by indent
+func outline(symbols []protocol.DocumentSymbol, indent string) string {
+	var b strings.Builder
+	for _, s := range symbols {
+		fmt.Fprintf(&b, "%s%s %v [%s] @%d:%d\n", indent, s.Name, s.Kind, s.Detail, s.SelectionRange.Start.Line, s.SelectionRange.Start.Character)
+		b.WriteString(outline(s.Children, indent+"  "))
+	}
+	return b.String()
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	got := outline(DocumentSymbols([]byte(symbolsSrc)), "")
+	want := `Shape Enum [enum] @4:5
+  Point EnumMember [] @5:1
+  Circle EnumMember [{ radius: float64 }] @6:1
+  Rectangle EnumMember [(float64, float64)] @7:1
+Option Enum [enum] @10:5
+  Some EnumMember [(T)] @10:17
+  None EnumMember [] @10:26
+User Struct [struct] @13:1
+Reader Interface [interface] @16:1
+ID Class [int] @19:5
+limit Constant [] @21:6
+greeting Variable [string] @23:4
+area Function [func(s: Shape, scale: float64) -> float64] @25:5
+  factor Variable [] @26:5
+  w Variable [] @28:7
+  h Variable [] @28:13
+(*User).Greet Method [func() string] @37:15
+`
+	if got != want {
+		t.Errorf("DocumentSymbols:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The declaration ranges hold the names
+	symbols := DocumentSymbols([]byte(symbolsSrc))
+	area := symbols[len(symbols)-2]
+	if area.Range.Start.Line != 25 || area.Range.End.Line != 35 {
+		t.Errorf("area range = %v, want lines 25-35", area.Range)
+	}
+}
+
+func TestDocumentSymbols_Incomplete(t *testing.T) {
+	src := "package main\n\nenum Color {\n\tRed,\n\tGre\n\nfunc main() {\n\tlet x = \n"
+	got := outline(DocumentSymbols([]byte(src)), "")
+	if !strings.HasPrefix(got, "Color Enum [enum] @2:5\n  Red EnumMember [] @3:1\n  Gre EnumMember [] @4:1\n") {
+		t.Errorf("DocumentSymbols of incomplete code:\n%s", got)
+	}
+}
+
+func TestTranslateWorkspaceSymbols(t *testing.T) {
+	translator := NewTranslator(fileCache{
+		"/ws/main.go":        {Version: 1},
+		"/other/lib.go":      {Version: 1},
+		"/ws/dingo_types.go": {Version: 1},
+	})
+	sources := map[string]string{
+		"/ws/main.dingo":   "package main\n\nenum Shape { Point, Circle(float64) }\n\nfunc main() {\n\tlet shape = Point()\n}\n",
+		"/other/lib.dingo": "package lib\n\nfunc ShapeOf() int { return 0 }\n",
+	}
+	read := func(path string) ([]byte, error) {
+		if src, ok := sources[path]; ok {
+			return []byte(src), nil
+		}
+		return nil, os.ErrNotExist
+	}
+	sym := func(name, path string, kind protocol.SymbolKind, line, col uint32, container string) protocol.SymbolInformation {
+		pos := protocol.Position{Line: line, Character: col}
+		return protocol.SymbolInformation{
+			Name:          name,
+			Kind:          kind,
+			Location:      protocol.Location{URI: uri.File(path), Range: protocol.Range{Start: pos, End: protocol.Position{Line: line, Character: col + uint32(len(name))}}},
+			ContainerName: container,
+		}
+	}
+
+	gopls := []protocol.SymbolInformation{
+		sym("Shape", "/ws/main.go", protocol.SymbolKindStruct, 5, 5, "main"),
+		sym("ShapeTag_Point", "/ws/main.go", protocol.SymbolKindConstant, 9, 1, "main"),
+		sym("Option_Shape_Some", "/ws/dingo_types.go", protocol.SymbolKindFunction, 3, 5, "main"),
+		sym("ShapeOf", "/other/lib.go", protocol.SymbolKindFunction, 4, 5, "lib"),
+		sym("ShapeReader", "/ws/util.go", protocol.SymbolKindInterface, 2, 5, "main"),
+	}
+	got := translator.TranslateWorkspaceSymbols("shp", gopls, []string{"/ws/main.dingo"}, read)
+
+	want := []protocol.SymbolInformation{
+		sym("ShapeReader", "/ws/util.go", protocol.SymbolKindInterface, 2, 5, "main"),
+		sym("Shape", "/ws/main.dingo", protocol.SymbolKindEnum, 2, 5, ""),
+		sym("ShapeOf", "/other/lib.dingo", protocol.SymbolKindFunction, 2, 5, ""),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TranslateWorkspaceSymbols:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	// Variants are workspace symbols; locals are not
+	got = translator.TranslateWorkspaceSymbols("circle", nil, []string{"/ws/main.dingo"}, read)
+	want = []protocol.SymbolInformation{sym("Circle", "/ws/main.dingo", protocol.SymbolKindEnumMember, 2, 20, "Shape")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TranslateWorkspaceSymbols(circle) = %+v, want %+v", got, want)
+	}
+	if got := translator.TranslateWorkspaceSymbols("shape", nil, []string{"/ws/main.dingo"}, read); len(got) != 1 {
+		t.Errorf("TranslateWorkspaceSymbols(shape) = %+v, want Shape only", got)
+	}
+}
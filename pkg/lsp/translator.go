@@ -3,12 +3,14 @@ package lsp
 import (
 	"fmt"
 	"log"
+	"path/filepath"
 	"strings"
 
 	"go.lsp.dev/protocol"
 	lspuri "go.lsp.dev/uri"
 
 	"github.com/MadAppGang/dingo/pkg/preprocessor"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
 )
 
 // Direction specifies translation direction
@@ -142,6 +144,43 @@ func (t *Translator) TranslateLocation(
 	}, nil
 }
 
+// TranslateGoRange translates a range of a .go file to its .dingo file
+// through mappings that hold both of its ends, keeping its width (see
+// MapToOriginalExact). ok is false when the range is in code the transpiler
+// generated, which has no .dingo counterpart. Ranges of .go files without a
+// source map are plain Go and come back unchanged.
+func (t *Translator) TranslateGoRange(
+	uri protocol.DocumentURI,
+	rng protocol.Range,
+) (protocol.DocumentURI, protocol.Range, bool) {
+	goPath := uri.Filename()
+	if filepath.Base(goPath) == transpiler.TypesFile {
+		return uri, rng, false // Declares the injected types only
+	}
+	sm, err := t.cache.Get(goPath)
+	if err != nil {
+		return uri, rng, true
+	}
+
+	startLine, startCol, ok := sm.MapToOriginalExact(int(rng.Start.Line)+1, int(rng.Start.Character)+1)
+	if !ok {
+		return uri, rng, false
+	}
+	endLine, endCol, ok := sm.MapToOriginalExact(int(rng.End.Line)+1, int(rng.End.Character)+1)
+	if !ok {
+		return uri, rng, false
+	}
+	// A one-line range must keep its text: anything else is a mismatch
+	if rng.Start.Line == rng.End.Line && (endLine != startLine || endCol-startCol != int(rng.End.Character-rng.Start.Character)) {
+		return uri, rng, false
+	}
+
+	return lspuri.File(goToDingoPath(goPath)), protocol.Range{
+		Start: protocol.Position{Line: uint32(startLine - 1), Character: uint32(startCol - 1)},
+		End:   protocol.Position{Line: uint32(endLine - 1), Character: uint32(endCol - 1)},
+	}, true
+}
+
 // Helper functions for file path conversion
 
 func isDingoFile(uri protocol.DocumentURI) bool {
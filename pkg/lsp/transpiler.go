@@ -3,56 +3,134 @@ package lsp
 import (
 	"context"
 	"fmt"
-	"strings"
+	"go/token"
+	"os"
+	"path/filepath"
 
 	"go.lsp.dev/protocol"
 	lspuri "go.lsp.dev/uri"
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 	"github.com/MadAppGang/dingo/pkg/transpiler"
 )
 
 // AutoTranspiler handles automatic transpilation of .dingo files
 type AutoTranspiler struct {
-	logger     Logger
-	mapCache   *SourceMapCache
-	gopls      *GoplsClient
-	transpiler *transpiler.Transpiler
-	server     *Server // For publishing Dingo-specific diagnostics
+	logger   Logger
+	mapCache *SourceMapCache
+	gopls    *GoplsClient
+	config   *config.Config
+	server   *Server // For publishing Dingo-specific diagnostics
 }
 
 // NewAutoTranspiler creates an auto-transpiler instance
 func NewAutoTranspiler(logger Logger, mapCache *SourceMapCache, gopls *GoplsClient, server *Server) *AutoTranspiler {
-	// Create integrated transpiler
-	t, err := transpiler.New()
+	cfg, err := config.Load(nil)
 	if err != nil {
-		// Fall back to nil transpiler - will fail at transpile time
-		logger.Warnf("Failed to create transpiler: %v", err)
+		// Fall back to defaults on error
+		logger.Warnf("Failed to load config, using defaults: %v", err)
+		cfg = config.DefaultConfig()
 	}
 
 	return &AutoTranspiler{
-		logger:     logger,
-		mapCache:   mapCache,
-		gopls:      gopls,
-		transpiler: t,
-		server:     server,
+		logger:   logger,
+		mapCache: mapCache,
+		gopls:    gopls,
+		config:   cfg,
+		server:   server,
 	}
 }
 
-// TranspileFile transpiles a single .dingo file
+// TranspileFile transpiles a single .dingo file and writes the .go file and
+// its source map
 func (at *AutoTranspiler) TranspileFile(ctx context.Context, dingoPath string) error {
+	res, err := at.transpile(ctx, dingoPath)
+	if err != nil {
+		return err
+	}
+	return res.Err()
+}
+
+// transpile transpiles dingoPath in memory and, on success, writes the
+// results. Problems in the source are reported by the result's diagnostics.
+func (at *AutoTranspiler) transpile(ctx context.Context, dingoPath string) (*transpiler.Result, error) {
 	at.logger.Infof("Auto-rebuild: %s", dingoPath)
 
-	if at.transpiler == nil {
-		return fmt.Errorf("transpiler not initialized")
+	src, err := os.ReadFile(dingoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Use integrated transpiler library (no shell out!)
-	err := at.transpiler.TranspileFile(dingoPath)
+	res, err := transpiler.Transpile(ctx, dingoPath, src, transpiler.Options{
+		Config:         at.config,
+		PackageSources: transpiler.PackageSources(dingoPath, src),
+		SharedTypes:    true,
+	})
 	if err != nil {
-		return fmt.Errorf("transpilation failed: %w", err)
+		return nil, fmt.Errorf("transpilation failed: %w", err)
+	}
+	if diagnostic.HasErrors(res.Diagnostics) {
+		return res, nil
+	}
+
+	if err := res.WriteFiles(dingoToGoPath(dingoPath)); err != nil {
+		return nil, fmt.Errorf("failed to write output: %w", err)
 	}
 
 	at.logger.Infof("Auto-rebuild complete: %s", dingoPath)
-	return nil
+	return res, nil
+}
+
+// TranspileBuffer transpiles version of the unsaved buffer src of the .dingo
+// file dingoPath in memory, writing nothing. The source map becomes the
+// overlay of the .go file in the source map cache and the Go, with the
+// TypesFile of its package, is handed to gopls as overlays. Diagnostics are
+// published as on save; with errors, the previous overlays are kept so that
+// gopls still knows the code around the edit.
+func (at *AutoTranspiler) TranspileBuffer(ctx context.Context, dingoPath string, version int32, src []byte) {
+	// Unsaved buffers need a source map whatever [sourcemaps] says
+	cfg := *at.config
+	cfg.SourceMap.Enabled, cfg.SourceMap.Format = true, config.FormatSeparate
+
+	res, err := transpiler.Transpile(ctx, dingoPath, src, transpiler.Options{
+		Config:         &cfg,
+		PackageSources: transpiler.PackageSources(dingoPath, src),
+		SharedTypes:    true,
+	})
+	if err != nil {
+		return // Canceled by a later edit
+	}
+
+	if at.server != nil {
+		uri := protocol.DocumentURI(lspuri.File(dingoPath))
+		at.server.publishDingoDiagnostics(uri, toProtocolDiagnostics(res.Diagnostics))
+	}
+	if res.Err() != nil || res.SourceMap == nil {
+		return
+	}
+
+	goPath := dingoToGoPath(dingoPath)
+	if !at.mapCache.SetOverlay(goPath, version, res.SourceMap) {
+		return // A later version is in place
+	}
+	if at.gopls == nil {
+		return
+	}
+
+	// The Go and the source map must not disagree, so send what the cache
+	// now holds even if a later edit cancels ctx
+	ctx = context.WithoutCancel(ctx)
+	if types, err := res.TypesFileFor(goPath); err != nil {
+		at.logger.Warnf("Live transpile: %v", err)
+	} else if types != nil {
+		typesPath := filepath.Join(filepath.Dir(goPath), transpiler.TypesFile)
+		if err := at.gopls.SetOverlay(ctx, typesPath, types); err != nil {
+			at.logger.Warnf("Failed to send %s to gopls: %v", typesPath, err)
+		}
+	}
+	if err := at.gopls.SetOverlay(ctx, goPath, res.Go); err != nil {
+		at.logger.Warnf("Failed to send %s to gopls: %v", goPath, err)
+	}
 }
 
 // OnFileChange handles a .dingo file change (called by watcher)
@@ -60,20 +138,29 @@ func (at *AutoTranspiler) OnFileChange(ctx context.Context, dingoPath string) {
 	uri := protocol.DocumentURI(lspuri.File(dingoPath))
 
 	// Transpile the file
-	if err := at.TranspileFile(ctx, dingoPath); err != nil {
+	res, err := at.transpile(ctx, dingoPath)
+	if err != nil {
 		at.logger.Errorf("Auto-transpile failed for %s: %v", dingoPath, err)
 
-		// Publish Dingo-specific diagnostic for transpilation error
-		diagnostic := ParseTranspileError(dingoPath, err.Error())
-		if diagnostic != nil && at.server != nil {
-			at.server.publishDingoDiagnostics(uri, []protocol.Diagnostic{*diagnostic})
+		// Publish Dingo-specific diagnostic for the failure
+		if at.server != nil {
+			d := diagnostic.Diagnostic{
+				Severity: diagnostic.SeverityError,
+				Pos:      token.Position{Filename: dingoPath},
+				Message:  err.Error(),
+			}
+			at.server.publishDingoDiagnostics(uri, toProtocolDiagnostics([]diagnostic.Diagnostic{d}))
 		}
-		return // Don't proceed to gopls sync
+		return
 	}
 
-	// Clear diagnostics on successful transpilation
+	// Publish transpilation errors and warnings (an empty list clears them)
 	if at.server != nil {
-		at.server.publishDingoDiagnostics(uri, []protocol.Diagnostic{})
+		at.server.publishDingoDiagnostics(uri, toProtocolDiagnostics(res.Diagnostics))
+	}
+	if err := res.Err(); err != nil {
+		at.logger.Errorf("Auto-transpile failed for %s: %v", dingoPath, err)
+		return // Don't proceed to gopls sync
 	}
 
 	// Invalidate source map cache
@@ -86,6 +173,96 @@ func (at *AutoTranspiler) OnFileChange(ctx context.Context, dingoPath string) {
 	if err := at.syncGoplsWithGoFile(ctx, goPath); err != nil {
 		at.logger.Warnf("Failed to sync gopls with .go file: %v", err)
 	}
+	if err := at.syncGoplsWithTypesFile(ctx, filepath.Dir(goPath)); err != nil {
+		at.logger.Warnf("Failed to sync gopls with %s: %v", transpiler.TypesFile, err)
+	}
+}
+
+// toProtocolDiagnostics converts transpiler diagnostics to LSP diagnostics.
+// Labels become related information and fixes help lines of the message;
+// fixes with edits also go in Data for code actions.
+func toProtocolDiagnostics(diags []diagnostic.Diagnostic) []protocol.Diagnostic {
+	result := make([]protocol.Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		severity := protocol.DiagnosticSeverityError
+		if d.Severity == diagnostic.SeverityWarning {
+			severity = protocol.DiagnosticSeverityWarning
+		}
+
+		message := d.Message
+		var related []protocol.DiagnosticRelatedInformation
+		for _, l := range d.Labels {
+			if l.Pos == d.Pos && l.End == d.End {
+				message += " (" + l.Message + ")" // Annotates the range itself
+				continue
+			}
+			related = append(related, protocol.DiagnosticRelatedInformation{
+				Location: protocol.Location{
+					URI:   protocol.DocumentURI(lspuri.File(l.Pos.Filename)),
+					Range: toProtocolRange(l.Pos, l.End),
+				},
+				Message: l.Message,
+			})
+		}
+		var fixes []diagnosticFix
+		for _, fix := range d.Fixes {
+			message += "\nhelp: " + fix.Message
+			if len(fix.Edits) == 0 {
+				continue // Advice only
+			}
+			df := diagnosticFix{Title: fix.Message}
+			for _, e := range fix.Edits {
+				df.Edits = append(df.Edits, protocol.TextEdit{Range: toProtocolRange(e.Pos, e.End), NewText: e.NewText})
+			}
+			fixes = append(fixes, df)
+		}
+
+		pd := protocol.Diagnostic{
+			Range:              toProtocolRange(d.Pos, d.End),
+			Severity:           severity,
+			Source:             "dingo",
+			Message:            message,
+			RelatedInformation: related,
+		}
+		if d.Code != "" {
+			pd.Code = d.Code
+		}
+		if len(fixes) > 0 {
+			pd.Data = fixes
+		}
+		result = append(result, pd)
+	}
+	return result
+}
+
+// diagnosticFix is a fix with edits, kept in the Data of a protocol
+// diagnostic: the client sends it back with codeAction requests
+type diagnosticFix struct {
+	Title string              `json:"title"`
+	Edits []protocol.TextEdit `json:"edits"`
+}
+
+// toProtocolRange converts a range of 1-based positions (0 = unknown) to a
+// 0-based LSP range; a missing end makes an empty range
+func toProtocolRange(pos, end token.Position) protocol.Range {
+	r := protocol.Range{Start: toProtocolPosition(pos)}
+	r.End = r.Start
+	if end.Line > 0 {
+		r.End = toProtocolPosition(end)
+	}
+	return r
+}
+
+// toProtocolPosition converts a 1-based position (0 = unknown) to 0-based
+func toProtocolPosition(pos token.Position) protocol.Position {
+	var p protocol.Position
+	if pos.Line > 0 {
+		p.Line = uint32(pos.Line - 1)
+	}
+	if pos.Column > 0 {
+		p.Character = uint32(pos.Column - 1)
+	}
+	return p
 }
 
 // syncGoplsWithGoFile sends the new .go file content to gopls via didChange
@@ -95,54 +272,15 @@ func (at *AutoTranspiler) syncGoplsWithGoFile(ctx context.Context, goPath string
 	return at.gopls.SyncFileContent(ctx, goPath)
 }
 
-// ParseTranspileError parses transpiler output into LSP diagnostic
-// Returns nil if output is not an error
-func ParseTranspileError(dingoPath, output string) *protocol.Diagnostic {
-	// Simple heuristic: check for common error patterns
-	// Format: "file.dingo:10:5: error message"
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		if strings.Contains(line, dingoPath) && strings.Contains(line, ":") {
-			parts := strings.SplitN(line, ":", 4)
-			if len(parts) >= 4 {
-				// Try to extract line:col:message
-				var lineNum, colNum int
-				_, err1 := fmt.Sscanf(parts[1], "%d", &lineNum)
-				_, err2 := fmt.Sscanf(parts[2], "%d", &colNum)
-				if err1 == nil && err2 == nil {
-					message := strings.TrimSpace(parts[3])
-					return &protocol.Diagnostic{
-						Range: protocol.Range{
-							Start: protocol.Position{
-								Line:      uint32(lineNum - 1), // 0-based
-								Character: uint32(colNum - 1),  // 0-based
-							},
-							End: protocol.Position{
-								Line:      uint32(lineNum - 1),
-								Character: uint32(colNum - 1),
-							},
-						},
-						Severity: protocol.DiagnosticSeverityError,
-						Source:   "dingo",
-						Message:  message,
-					}
-				}
-			}
-		}
+// syncGoplsWithTypesFile replaces the TypesFile overlay of dir, if
+// TranspileBuffer left one, with the TypesFile on disk
+func (at *AutoTranspiler) syncGoplsWithTypesFile(ctx context.Context, dir string) error {
+	typesPath := filepath.Join(dir, transpiler.TypesFile)
+	if at.gopls == nil || !at.gopls.HasOverlay(typesPath) {
+		return nil
 	}
-
-	// Fallback: generic error at top of file
-	if strings.Contains(output, "error") || strings.Contains(output, "failed") {
-		return &protocol.Diagnostic{
-			Range: protocol.Range{
-				Start: protocol.Position{Line: 0, Character: 0},
-				End:   protocol.Position{Line: 0, Character: 0},
-			},
-			Severity: protocol.DiagnosticSeverityError,
-			Source:   "dingo",
-			Message:  strings.TrimSpace(output),
-		}
+	if _, err := os.Stat(typesPath); os.IsNotExist(err) {
+		return at.gopls.CloseOverlay(ctx, typesPath)
 	}
-
-	return nil
+	return at.gopls.SyncFileContent(ctx, typesPath)
 }
@@ -2,92 +2,141 @@ package lsp
 
 import (
 	"context"
+	"encoding/json"
+	"go/token"
 	"os"
+	"reflect"
 	"testing"
 
 	"go.lsp.dev/protocol"
-)
-
-func TestParseTranspileError_ValidError(t *testing.T) {
-	dingoPath := "/path/to/example.dingo"
-	output := "/path/to/example.dingo:10:15: undefined: foo"
-
-	diagnostic := ParseTranspileError(dingoPath, output)
-	if diagnostic == nil {
-		t.Fatal("Expected diagnostic, got nil")
-	}
-
-	// Check position (0-based)
-	if diagnostic.Range.Start.Line != 9 {
-		t.Errorf("Expected line 9, got %d", diagnostic.Range.Start.Line)
-	}
-	if diagnostic.Range.Start.Character != 14 {
-		t.Errorf("Expected character 14, got %d", diagnostic.Range.Start.Character)
-	}
 
-	// Check severity and source
-	if diagnostic.Severity != protocol.DiagnosticSeverityError {
-		t.Errorf("Expected error severity, got %v", diagnostic.Severity)
-	}
-	if diagnostic.Source != "dingo" {
-		t.Errorf("Expected source 'dingo', got '%s'", diagnostic.Source)
-	}
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+)
 
-	// Check message
-	if diagnostic.Message != "undefined: foo" {
-		t.Errorf("Expected message 'undefined: foo', got '%s'", diagnostic.Message)
+func TestToProtocolDiagnostics(t *testing.T) {
+	pos := token.Position{Filename: "/path/to/example.dingo", Line: 7, Column: 2}
+	end := token.Position{Filename: "/path/to/example.dingo", Line: 7, Column: 7}
+	other := token.Position{Filename: "/path/to/example.dingo", Line: 3, Column: 5}
+
+	diags := toProtocolDiagnostics([]diagnostic.Diagnostic{{
+		Code:     diagnostic.CodeNonExhaustive,
+		Severity: diagnostic.SeverityError,
+		Pos:      pos,
+		End:      end,
+		Message:  "non-exhaustive match, missing cases: Err",
+		Labels: []diagnostic.Label{
+			{Pos: pos, End: end, Message: "match on Result"},
+			{Pos: other, Message: "declared here"},
+		},
+		Fixes: []diagnostic.Fix{{Message: "add a wildcard arm: _ => ..."}},
+	}})
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1", len(diags))
+	}
+	d := diags[0]
+
+	want := protocol.Range{
+		Start: protocol.Position{Line: 6, Character: 1},
+		End:   protocol.Position{Line: 6, Character: 6},
+	}
+	if d.Range != want {
+		t.Errorf("Range = %+v, want %+v", d.Range, want)
+	}
+	if d.Code != diagnostic.CodeNonExhaustive || d.Severity != protocol.DiagnosticSeverityError {
+		t.Errorf("Code, Severity = %v, %v", d.Code, d.Severity)
+	}
+	wantMessage := "non-exhaustive match, missing cases: Err (match on Result)\nhelp: add a wildcard arm: _ => ..."
+	if d.Message != wantMessage {
+		t.Errorf("Message = %q, want %q", d.Message, wantMessage)
+	}
+	if len(d.RelatedInformation) != 1 || d.RelatedInformation[0].Location.Range.Start.Line != 2 {
+		t.Errorf("RelatedInformation = %+v, want the label at line 3", d.RelatedInformation)
 	}
 }
 
-func TestParseTranspileError_GenericError(t *testing.T) {
-	dingoPath := "/path/to/example.dingo"
-	output := "error: failed to parse file"
-
-	diagnostic := ParseTranspileError(dingoPath, output)
-	if diagnostic == nil {
-		t.Fatal("Expected diagnostic, got nil")
+func TestToProtocolDiagnostics_WholeFile(t *testing.T) {
+	diags := toProtocolDiagnostics([]diagnostic.Diagnostic{{
+		Severity: diagnostic.SeverityWarning,
+		Pos:      token.Position{Filename: "example.dingo"},
+		Message:  "source map generation failed",
+	}})
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1", len(diags))
 	}
-
-	// Should create diagnostic at line 0
-	if diagnostic.Range.Start.Line != 0 {
-		t.Errorf("Expected line 0, got %d", diagnostic.Range.Start.Line)
-	}
-
-	// Check message contains error
-	if diagnostic.Message != "error: failed to parse file" {
-		t.Errorf("Expected full error message, got '%s'", diagnostic.Message)
-	}
-}
-
-func TestParseTranspileError_NoError(t *testing.T) {
-	dingoPath := "/path/to/example.dingo"
-	output := "Transpilation successful"
-
-	diagnostic := ParseTranspileError(dingoPath, output)
-	if diagnostic != nil {
-		t.Errorf("Expected nil for non-error output, got diagnostic: %+v", diagnostic)
+	if diags[0].Range != (protocol.Range{}) || diags[0].Severity != protocol.DiagnosticSeverityWarning {
+		t.Errorf("unexpected diagnostic: %+v", diags[0])
 	}
 }
 
-func TestParseTranspileError_MultilineError(t *testing.T) {
-	dingoPath := "/path/to/example.dingo"
-	output := `Build started...
-/path/to/example.dingo:25:8: type mismatch
-/path/to/example.dingo:30:12: syntax error
-Build failed`
-
-	diagnostic := ParseTranspileError(dingoPath, output)
-	if diagnostic == nil {
-		t.Fatal("Expected diagnostic, got nil")
-	}
-
-	// Should parse first error
-	if diagnostic.Range.Start.Line != 24 { // 25-1 = 24 (0-based)
-		t.Errorf("Expected line 24, got %d", diagnostic.Range.Start.Line)
-	}
-
-	if diagnostic.Message != "type mismatch" {
-		t.Errorf("Expected 'type mismatch', got '%s'", diagnostic.Message)
+func TestCodeActions(t *testing.T) {
+	at := func(line, col int) token.Position {
+		return token.Position{Filename: "/path/to/example.dingo", Line: line, Column: col}
+	}
+	insert := func(text string) diagnostic.Edit {
+		return diagnostic.Edit{Pos: at(9, 1), End: at(9, 1), NewText: text}
+	}
+	diags := toProtocolDiagnostics([]diagnostic.Diagnostic{
+		{
+			Code:     diagnostic.CodeNonExhaustive,
+			Severity: diagnostic.SeverityError,
+			Pos:      at(7, 9),
+			End:      at(7, 14),
+			Message:  "non-exhaustive match, missing cases: Err",
+			Fixes: []diagnostic.Fix{
+				{Message: "add the missing arms: Err(err) => ...", Edits: []diagnostic.Edit{insert("\t\tErr(err) => todo,\n")}},
+				{Message: "add a wildcard arm: _ => ...", Edits: []diagnostic.Edit{insert("\t\t_ => todo,\n")}},
+			},
+		},
+		{
+			Severity: diagnostic.SeverityError,
+			Pos:      at(3, 1),
+			Message:  "unused variable",
+			Fixes:    []diagnostic.Fix{{Message: "remove it"}},
+		},
+	})
+
+	// Data survives the round trip through the client
+	data, err := json.Marshal(diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent []protocol.Diagnostic
+	if err := json.Unmarshal(data, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent[1].Data != nil {
+		t.Errorf("advice-only diagnostic has Data %v", sent[1].Data)
+	}
+
+	uri := protocol.DocumentURI("file:///path/to/example.dingo")
+	actions := codeActions(uri, sent)
+	if len(actions) != 2 {
+		t.Fatalf("got %d code actions, want 2: %+v", len(actions), actions)
+	}
+	edit := func(text string) *protocol.WorkspaceEdit {
+		pos := protocol.Position{Line: 8, Character: 0}
+		return &protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			uri: {{Range: protocol.Range{Start: pos, End: pos}, NewText: text}},
+		}}
+	}
+	for i, want := range []struct {
+		title     string
+		edit      *protocol.WorkspaceEdit
+		preferred bool
+	}{
+		{"add the missing arms: Err(err) => ...", edit("\t\tErr(err) => todo,\n"), true},
+		{"add a wildcard arm: _ => ...", edit("\t\t_ => todo,\n"), false},
+	} {
+		a := actions[i]
+		if a.Title != want.title || a.Kind != protocol.QuickFix || a.IsPreferred != want.preferred {
+			t.Errorf("action %d = %q %s preferred=%v, want %q", i, a.Title, a.Kind, a.IsPreferred, want.title)
+		}
+		if !reflect.DeepEqual(a.Edit, want.edit) {
+			t.Errorf("action %d edit = %+v, want %+v", i, a.Edit, want.edit)
+		}
+		if len(a.Diagnostics) != 1 || a.Diagnostics[0].Code != diagnostic.CodeNonExhaustive {
+			t.Errorf("action %d fixes %+v, want the non-exhaustive match", i, a.Diagnostics)
+		}
 	}
 }
 
@@ -128,10 +177,9 @@ func readConfig(path string) ([]byte, error) {
 		t.Errorf(".go file not created: %s", goPath)
 	}
 
-	// Verify .go.map file exists
-	mapPath := goPath + ".map"
-	if _, err := os.Stat(mapPath); os.IsNotExist(err) {
-		t.Errorf(".go.map file not created: %s", mapPath)
+	// Verify the source map can be read
+	if _, err := cache.Get(goPath); err != nil {
+		t.Errorf("source map not readable: %v", err)
 	}
 }
 
@@ -144,3 +192,47 @@ func (l *TestLogger) Infof(format string, args ...interface{})  {}
 func (l *TestLogger) Warnf(format string, args ...interface{})  {}
 func (l *TestLogger) Errorf(format string, args ...interface{}) {}
 func (l *TestLogger) Fatalf(format string, args ...interface{}) {}
+
+func TestAutoTranspiler_TranspileBuffer(t *testing.T) {
+	tmpDir := t.TempDir()
+	dingoPath := tmpDir + "/test.dingo"
+	goPath := tmpDir + "/test.go"
+
+	logger := NewTestLogger()
+	cache, _ := NewSourceMapCache(logger)
+	at := NewAutoTranspiler(logger, cache, nil, nil) // No gopls: only the source map overlay
+
+	// The buffer is never saved: nothing is read from or written to disk
+	src := []byte(`package main
+
+func readConfig(path string) ([]byte, error) {
+	let data = os.ReadFile(path)?
+	return data, nil
+}
+`)
+	at.TranspileBuffer(context.Background(), dingoPath, 3, src)
+	if _, err := os.Stat(goPath); !os.IsNotExist(err) {
+		t.Errorf("TranspileBuffer wrote %s", goPath)
+	}
+	if version, ok := cache.OverlayVersion(goPath); !ok || version != 3 {
+		t.Fatalf("OverlayVersion = %d, %v; want 3", version, ok)
+	}
+	sm, err := cache.Get(goPath)
+	if err != nil || len(sm.Mappings) == 0 {
+		t.Fatalf("Get = %+v, %v; want the overlay", sm, err)
+	}
+
+	// A buffer that does not transpile keeps the last good overlay
+	at.TranspileBuffer(context.Background(), dingoPath, 4, []byte("package main\n\nfunc broken( {\n"))
+	if version, _ := cache.OverlayVersion(goPath); version != 3 {
+		t.Errorf("OverlayVersion = %d after a failed transpile, want 3", version)
+	}
+
+	// A canceled transpilation changes nothing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	at.TranspileBuffer(ctx, dingoPath, 5, src)
+	if version, _ := cache.OverlayVersion(goPath); version != 3 {
+		t.Errorf("OverlayVersion = %d after a canceled transpile, want 3", version)
+	}
+}
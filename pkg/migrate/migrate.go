@@ -0,0 +1,227 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	gobuild "go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/build"
+	"github.com/MadAppGang/dingo/pkg/check"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// Migrator converts the Go files of a workspace to Dingo. Every file is
+// verified: the Dingo result must transpile and its package must type-check
+// without errors the original Go did not have. Rewrites that break this are
+// dropped, so a migrated file is always at least as good as the original.
+type Migrator struct {
+	root       string
+	transpiler *transpiler.Transpiler
+}
+
+// New creates a migrator for the workspace at root
+func New(root string, t *transpiler.Transpiler) *Migrator {
+	return &Migrator{root: root, transpiler: t}
+}
+
+// Result describes the migration of one Go file
+type Result struct {
+	GoPath    string
+	DingoPath string
+	Original  []byte
+	Source    []byte // Verified Dingo source; nil when the file stays Go
+	Reason    string // Why the file stays Go, when Source is nil
+	Applied   []Edit
+	Skipped   []Skipped
+}
+
+// Skipped is a rewrite that was found but dropped during verification
+type Skipped struct {
+	Edit   Edit
+	Reason string // First diagnostic the rewrite introduced
+}
+
+// MigrateFile rewrites the Go file at goPath (absolute) and verifies the
+// result against the rest of its package as it is on disk. Nothing is written.
+func (m *Migrator) MigrateFile(goPath string) (*Result, error) {
+	res := &Result{GoPath: goPath, DingoPath: strings.TrimSuffix(goPath, ".go") + ".dingo"}
+
+	src, err := os.ReadFile(goPath)
+	if err != nil {
+		return nil, err
+	}
+	res.Original = src
+
+	if reason := m.excluded(goPath, src); reason != "" {
+		res.Reason = reason
+		return res, nil
+	}
+
+	edits, err := Rewrite(goPath, src)
+	if err != nil {
+		return nil, err
+	}
+	if len(edits) == 0 {
+		res.Reason = "nothing to rewrite"
+		return res, nil
+	}
+
+	pkg, err := m.packageOf(goPath)
+	if err != nil {
+		return nil, err
+	}
+	v := &verifier{migrator: m, pkg: pkg, dingoPath: res.DingoPath, src: src}
+	if err := v.init(); err != nil {
+		return nil, err
+	}
+
+	// The unchanged file must already be valid Dingo
+	if diags, err := v.introduced(nil); err != nil {
+		return nil, err
+	} else if len(diags) > 0 {
+		res.Reason = "does not transpile as Dingo: " + diags[0].Message
+		return res, nil
+	}
+
+	// Accept rewrites kind by kind; when a whole kind fails, try its edits
+	// one at a time so a single bad rewrite does not block the others
+	var accepted []Edit
+	for _, kind := range Kinds {
+		var stage []Edit
+		for _, e := range edits {
+			if e.Kind == kind {
+				stage = append(stage, e)
+			}
+		}
+		if len(stage) == 0 {
+			continue
+		}
+
+		diags, err := v.introduced(append(append([]Edit{}, accepted...), stage...))
+		if err != nil {
+			return nil, err
+		}
+		if len(diags) == 0 {
+			accepted = append(accepted, stage...)
+			continue
+		}
+
+		for _, e := range stage {
+			diags, err := v.introduced(append(append([]Edit{}, accepted...), e))
+			if err != nil {
+				return nil, err
+			}
+			if len(diags) > 0 {
+				res.Skipped = append(res.Skipped, Skipped{Edit: e, Reason: diags[0].Message})
+				continue
+			}
+			accepted = append(accepted, e)
+		}
+	}
+
+	if len(accepted) == 0 {
+		res.Reason = "no rewrite survived verification"
+		return res, nil
+	}
+	res.Applied = nonOverlapping(accepted)
+	res.Source = Apply(src, res.Applied)
+	return res, nil
+}
+
+// excluded explains why a file must not be migrated, or returns ""
+func (m *Migrator) excluded(goPath string, src []byte) string {
+	if _, err := os.Stat(strings.TrimSuffix(goPath, ".go") + ".dingo"); err == nil {
+		return "generated from a .dingo file"
+	}
+	if isGenerated(src) {
+		return "generated file"
+	}
+	if ok, err := gobuild.Default.MatchFile(filepath.Dir(goPath), filepath.Base(goPath)); err != nil || !ok {
+		return "excluded by build constraints"
+	}
+	return ""
+}
+
+// packageOf discovers the package holding goPath as it is on disk now
+func (m *Migrator) packageOf(goPath string) (build.Package, error) {
+	packages, err := build.DiscoverGoPackages(m.root, []string{filepath.Dir(goPath)})
+	if err != nil {
+		return build.Package{}, err
+	}
+	if len(packages) != 1 {
+		return build.Package{}, fmt.Errorf("%s: package not found", goPath)
+	}
+	return packages[0], nil
+}
+
+// verifier type-checks one file's package with candidate rewrites applied
+type verifier struct {
+	migrator  *Migrator
+	pkg       build.Package
+	dingoPath string
+	src       []byte
+	baseline  map[string]int // Diagnostics of the original package, by message
+}
+
+// init records the diagnostics the package already has in Go form
+func (v *verifier) init() error {
+	diags, err := check.New(v.migrator.root, v.migrator.transpiler).CheckPackage(v.pkg)
+	if err != nil {
+		return err
+	}
+	v.baseline = make(map[string]int, len(diags))
+	for _, d := range diags {
+		v.baseline[d.Message]++
+	}
+	return nil
+}
+
+// introduced returns the diagnostics of the package with the file replaced
+// by its Dingo form that the original package did not have
+func (v *verifier) introduced(edits []Edit) ([]diagnostic.Diagnostic, error) {
+	rel, err := filepath.Rel(v.migrator.root, v.dingoPath)
+	if err != nil {
+		return nil, err
+	}
+	pkg := v.pkg
+	pkg.DingoFiles = append(append([]string{}, pkg.DingoFiles...), filepath.ToSlash(rel))
+
+	checker := check.New(v.migrator.root, v.migrator.transpiler)
+	checker.SetOverlay(map[string][]byte{v.dingoPath: Apply(v.src, nonOverlapping(append([]Edit{}, edits...)))})
+	diags, err := checker.CheckPackage(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[string]int, len(v.baseline))
+	for message, n := range v.baseline {
+		remaining[message] = n
+	}
+	var introduced []diagnostic.Diagnostic
+	for _, d := range diags {
+		if remaining[d.Message] > 0 {
+			remaining[d.Message]--
+			continue
+		}
+		introduced = append(introduced, d)
+	}
+	return introduced, nil
+}
+
+// isGenerated reports whether src carries the standard generated-code comment
+func isGenerated(src []byte) bool {
+	for _, line := range bytes.Split(src, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("package ")) {
+			return false
+		}
+		if bytes.HasPrefix(line, []byte("// Code generated ")) && bytes.HasSuffix(line, []byte(" DO NOT EDIT.")) {
+			return true
+		}
+	}
+	return false
+}
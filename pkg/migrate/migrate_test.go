@@ -0,0 +1,191 @@
+package migrate
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+// writeWorkspace creates files (relative path -> content) under a temp root
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func newMigrator(root string) *Migrator {
+	return New(root, transpiler.NewWithConfig(config.DefaultConfig()))
+}
+
+func TestMigrateFile(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/ws\n\ngo 1.21\n",
+		"parse.go": `package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+func parse(a, b string) (int, error) {
+	var base int = 10
+	x, err := strconv.Atoi(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := strconv.Atoi(b)
+	if err != nil {
+		return 0, fmt.Errorf("second operand: %w", err)
+	}
+	return base*x + y, nil
+}
+`,
+		"main.go": `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println(parse("4", "2"))
+}
+`,
+	})
+
+	goPath := filepath.Join(root, "parse.go")
+	res, err := newMigrator(root).MigrateFile(goPath)
+	if err != nil {
+		t.Fatalf("MigrateFile() error: %v", err)
+	}
+	if res.Source == nil {
+		t.Fatalf("file was not migrated: %s", res.Reason)
+	}
+	if len(res.Applied) != 3 || len(res.Skipped) != 0 {
+		t.Errorf("applied %d, skipped %v; want 3 applied", len(res.Applied), res.Skipped)
+	}
+
+	got := string(res.Source)
+	for _, want := range []string{
+		"let base: int = 10",
+		"let x = strconv.Atoi(a)?",
+		`let y = strconv.Atoi(b)? "second operand"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("migrated source lacks %q:\n%s", want, got)
+		}
+	}
+	if res.DingoPath != filepath.Join(root, "parse.dingo") {
+		t.Errorf("DingoPath = %s", res.DingoPath)
+	}
+
+	// Nothing is written by MigrateFile
+	if _, err := os.Stat(res.DingoPath); !os.IsNotExist(err) {
+		t.Error("MigrateFile wrote the .dingo file")
+	}
+}
+
+func TestMigrateFileBuilds(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod": "module example.com/ws\n\ngo 1.21\n",
+		"user.go": `package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type Addr struct{ City string }
+type User struct{ Addr *Addr }
+
+func city(u *User) string {
+	name := "unknown"
+	if u != nil && u.Addr != nil {
+		name = u.Addr.City
+	}
+	return name
+}
+
+func age(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("age: %w", err)
+	}
+	return n, nil
+}
+
+func main() {
+	var u = &User{Addr: &Addr{City: "Oslo"}}
+	fmt.Println(city(u), city(nil))
+	fmt.Println(age("42"))
+}
+`,
+	})
+
+	goPath := filepath.Join(root, "user.go")
+	res, err := newMigrator(root).MigrateFile(goPath)
+	if err != nil {
+		t.Fatalf("MigrateFile() error: %v", err)
+	}
+	if res.Source == nil {
+		t.Fatalf("file was not migrated: %s", res.Reason)
+	}
+	if len(res.Applied) != 2 || len(res.Skipped) != 0 {
+		t.Errorf("applied %v, skipped %v; want 2 applied", res.Applied, res.Skipped)
+	}
+	if !strings.Contains(string(res.Source), "if u != nil && u.Addr != nil {") {
+		t.Errorf("nil-check ladder must stay Go:\n%s", res.Source)
+	}
+
+	// What dingo migrate writes must build
+	if err := os.WriteFile(res.DingoPath, res.Source, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(goPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := transpiler.NewWithConfig(config.DefaultConfig()).TranspileFile(res.DingoPath); err != nil {
+		t.Fatalf("TranspileFile() error: %v", err)
+	}
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = root
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, out)
+	}
+}
+
+func TestMigrateFileKeepsGo(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"go.mod":   "module example.com/ws\n\ngo 1.21\n",
+		"gen.go":   "// Code generated by stringer. DO NOT EDIT.\n\npackage main\n\nfunc f() {\n\tvar x = 1\n\tprintln(x)\n}\n",
+		"plain.go": "package main\n\nfunc main() {\n\tprintln(f)\n}\n",
+	})
+
+	tests := []struct {
+		file   string
+		reason string
+	}{
+		{"gen.go", "generated file"},
+		{"plain.go", "nothing to rewrite"},
+	}
+	for _, tt := range tests {
+		res, err := newMigrator(root).MigrateFile(filepath.Join(root, tt.file))
+		if err != nil {
+			t.Fatalf("%s: MigrateFile() error: %v", tt.file, err)
+		}
+		if res.Source != nil || res.Reason != tt.reason {
+			t.Errorf("%s: reason = %q, want %q", tt.file, res.Reason, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+// Package migrate rewrites Go source into idiomatic Dingo.
+//
+// The rewriter works on the go/ast of a single file and produces text edits
+// against the original source, so everything it does not touch (comments,
+// formatting, declarations) is carried over byte for byte. Rewrites are
+// conservative: a pattern is only converted when the Dingo form means exactly
+// the same thing. Migrator additionally verifies every file by transpiling
+// and type-checking the result.
+//
+// Nil-check ladders stay Go: ?. and ?? apply to Option values, and the
+// transpiler has no form of them for Go pointers.
+package migrate
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Kind classifies a rewrite
+type Kind string
+
+const (
+	KindLet       Kind = "let"               // var x T = v  ->  let x: T = v
+	KindErrorProp Kind = "error-propagation" // x, err := f(); if err != nil { return ..., err }  ->  let x = f()?
+)
+
+// Kinds lists every rewrite kind, from the most to the least reliable
+var Kinds = []Kind{KindLet, KindErrorProp}
+
+// Edit replaces Go source bytes [Start, End) with Dingo code
+type Edit struct {
+	Kind  Kind
+	Line  int // Line of the replaced code in the Go source
+	Start int
+	End   int
+	Text  string
+}
+
+// String describes the edit for reports, e.g. "12: let n = strconv.Atoi(s)?"
+func (e Edit) String() string {
+	return fmt.Sprintf("%d: %s", e.Line, e.Text)
+}
+
+// Rewrite finds every Dingo rewrite in a Go file. The returned edits do not
+// overlap and are sorted by position.
+func Rewrite(filename string, src []byte) ([]Edit, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// Type information resolves local variables; errors from missing
+	// imports or other files of the package do not matter here
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.Default(), Error: func(error) {}}
+	_, _ = conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+
+	r := &rewriter{
+		fset:        fset,
+		src:         src,
+		file:        file,
+		info:        info,
+		occurrences: make(map[types.Object][]*ast.Ident),
+		assignedBy:  make(map[*ast.Ident]*ast.AssignStmt),
+	}
+	r.index()
+
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			r.function(fn.Type, fn.Body)
+		}
+	}
+
+	return nonOverlapping(r.edits), nil
+}
+
+// Apply returns src with the edits applied. Edits must not overlap.
+func Apply(src []byte, edits []Edit) []byte {
+	sorted := append([]Edit(nil), edits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var out strings.Builder
+	last := 0
+	for _, e := range sorted {
+		out.Write(src[last:e.Start])
+		out.WriteString(e.Text)
+		last = e.End
+	}
+	out.Write(src[last:])
+	return []byte(out.String())
+}
+
+// nonOverlapping sorts edits and drops any edit nested in an earlier one
+func nonOverlapping(edits []Edit) []Edit {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+	var kept []Edit
+	for _, e := range edits {
+		if len(kept) > 0 && e.Start < kept[len(kept)-1].End {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept
+}
+
+type rewriter struct {
+	fset  *token.FileSet
+	src   []byte
+	file  *ast.File
+	info  *types.Info
+	edits []Edit
+
+	occurrences map[types.Object][]*ast.Ident  // Every identifier of a local variable, in source order
+	assignedBy  map[*ast.Ident]*ast.AssignStmt // Identifiers on the left of an assignment
+	funcLits    []*ast.FuncLit
+}
+
+// index records identifier occurrences, assignment targets and closures
+func (r *rewriter) index() {
+	ast.Inspect(r.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			obj := r.info.Defs[n]
+			if obj == nil {
+				obj = r.info.Uses[n]
+			}
+			if v, ok := obj.(*types.Var); ok && !v.IsField() {
+				r.occurrences[v] = append(r.occurrences[v], n)
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok {
+					r.assignedBy[ident] = n
+				}
+			}
+		case *ast.FuncLit:
+			r.funcLits = append(r.funcLits, n)
+		}
+		return true
+	})
+	for _, idents := range r.occurrences {
+		sort.Slice(idents, func(i, j int) bool { return idents[i].Pos() < idents[j].Pos() })
+	}
+}
+
+// function rewrites the statement lists of one function body. Closures are
+// rewritten against their own signature.
+func (r *rewriter) function(ftype *ast.FuncType, body *ast.BlockStmt) {
+	hasGoto := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if branch, ok := n.(*ast.BranchStmt); ok && branch.Tok == token.GOTO {
+			hasGoto = true
+		}
+		return true
+	})
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			r.function(n.Type, n.Body)
+			return false
+		case *ast.BlockStmt:
+			r.block(ftype, n.List, hasGoto)
+		case *ast.CaseClause:
+			r.block(ftype, n.Body, hasGoto)
+		case *ast.CommClause:
+			r.block(ftype, n.Body, hasGoto)
+		}
+		return true
+	})
+}
+
+// block tries each rewrite at every statement of a statement list
+func (r *rewriter) block(ftype *ast.FuncType, stmts []ast.Stmt, hasGoto bool) {
+	for i := 0; i < len(stmts); i++ {
+		if i+1 < len(stmts) && !hasGoto {
+			if edit, ok := r.errorProp(ftype, stmts[i], stmts[i+1]); ok {
+				r.edits = append(r.edits, edit)
+				i++
+				continue
+			}
+		}
+		if edit, ok := r.letDecl(stmts[i]); ok {
+			r.edits = append(r.edits, edit)
+		}
+	}
+}
+
+// letDecl rewrites a single-variable declaration with a value:
+//
+//	var x T = v  ->  let x: T = v
+//	var x = v    ->  let x = v
+func (r *rewriter) letDecl(stmt ast.Stmt) (Edit, bool) {
+	spec, ok := singleVarSpec(stmt)
+	if !ok || spec.Names[0].Name == "_" || r.hasComments(stmt.Pos(), spec.Values[0].Pos()) {
+		return Edit{}, false
+	}
+
+	text := "let " + spec.Names[0].Name
+	if spec.Type != nil {
+		text += ": " + r.text(spec.Type)
+	}
+	text += " = "
+	return r.edit(KindLet, stmt.Pos(), spec.Values[0].Pos(), text), true
+}
+
+// errorProp rewrites an error check that returns the error unchanged or
+// wrapped with fmt.Errorf("msg: %w", err):
+//
+//	x, err := f()
+//	if err != nil {
+//		return nil, err            ->  let x = f()?
+//		return nil, fmt.Errorf("msg: %w", err)  ->  let x = f()? "msg"
+//	}
+func (r *rewriter) errorProp(ftype *ast.FuncType, first, second ast.Stmt) (Edit, bool) {
+	assign, ok := first.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != 2 || len(assign.Rhs) != 1 {
+		return Edit{}, false
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok || !r.singleLine(assign) {
+		return Edit{}, false
+	}
+
+	// The value must be a new variable: let cannot assign to an existing one
+	value, ok := assign.Lhs[0].(*ast.Ident)
+	if !ok || (value.Name != "_" && r.info.Defs[value] == nil) {
+		return Edit{}, false
+	}
+	errIdent, ok := assign.Lhs[1].(*ast.Ident)
+	if !ok {
+		return Edit{}, false
+	}
+	errVar := r.object(errIdent)
+	if errVar == nil {
+		return Edit{}, false
+	}
+
+	ifStmt, ok := second.(*ast.IfStmt)
+	if !ok || ifStmt.Init != nil || ifStmt.Else != nil || !r.isNilCheck(ifStmt.Cond, errVar) || len(ifStmt.Body.List) != 1 {
+		return Edit{}, false
+	}
+	ret, ok := ifStmt.Body.List[0].(*ast.ReturnStmt)
+	if !ok || !returnsError(ftype) || len(ret.Results) != resultCount(ftype) {
+		return Edit{}, false
+	}
+	for _, result := range ret.Results[:len(ret.Results)-1] {
+		if !r.isZeroValue(result) {
+			return Edit{}, false
+		}
+	}
+
+	text := "let " + value.Name + " = " + r.text(call) + "?"
+	last := ret.Results[len(ret.Results)-1]
+	if ident, ok := last.(*ast.Ident); !ok || r.object(ident) != errVar {
+		message, ok := r.wrapMessage(last, errVar)
+		if !ok {
+			return Edit{}, false
+		}
+		text += " " + message
+	}
+
+	if r.hasComments(assign.Pos(), ifStmt.End()) || !r.unusedAfter(errVar, assign, ifStmt) {
+		return Edit{}, false
+	}
+	return r.edit(KindErrorProp, assign.Pos(), ifStmt.End(), text), true
+}
+
+// unusedAfter reports whether the error variable is dead after the if
+// statement: its next occurrence overwrites it without reading it. Closures
+// that capture the variable make this impossible to tell.
+func (r *rewriter) unusedAfter(errVar types.Object, assign *ast.AssignStmt, ifStmt *ast.IfStmt) bool {
+	for _, ident := range r.occurrences[errVar] {
+		if ident.Pos() >= assign.Pos() && ident.Pos() < ifStmt.End() {
+			continue
+		}
+		for _, lit := range r.funcLits {
+			if ident.Pos() >= lit.Pos() && ident.Pos() < lit.End() && (assign.Pos() < lit.Pos() || assign.Pos() >= lit.End()) {
+				return false
+			}
+		}
+	}
+
+	for _, ident := range r.occurrences[errVar] {
+		if ident.Pos() < ifStmt.End() {
+			continue
+		}
+		next, ok := r.assignedBy[ident]
+		if !ok {
+			return false // Read before being overwritten
+		}
+		for _, rhs := range next.Rhs {
+			if r.mentions(rhs, errVar) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+// wrapMessage returns the Dingo message literal for fmt.Errorf("msg: %w", err)
+func (r *rewriter) wrapMessage(expr ast.Expr, errVar types.Object) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 2 || call.Ellipsis.IsValid() {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Errorf" {
+		return "", false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+	pkgName, ok := r.info.Uses[pkg].(*types.PkgName)
+	if !ok || pkgName.Imported().Path() != "fmt" {
+		return "", false
+	}
+	if ident, ok := call.Args[1].(*ast.Ident); !ok || r.object(ident) != errVar {
+		return "", false
+	}
+
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING || !strings.HasPrefix(lit.Value, `"`) {
+		return "", false
+	}
+	const suffix = `: %w"`
+	message := strings.TrimSuffix(lit.Value, suffix)
+	if message == lit.Value || message == `"` || strings.Contains(message, "%") {
+		return "", false
+	}
+	return message + `"`, true
+}
+
+// isNilCheck reports whether cond is v != nil for the given variable
+func (r *rewriter) isNilCheck(cond ast.Expr, v types.Object) bool {
+	bin, ok := cond.(*ast.BinaryExpr)
+	if !ok || bin.Op != token.NEQ || !isNil(bin.Y) {
+		return false
+	}
+	ident, ok := bin.X.(*ast.Ident)
+	return ok && r.object(ident) == v
+}
+
+// isZeroValue reports whether expr is a literal zero value: nil, false, 0,
+// "" or an empty composite literal
+func (r *rewriter) isZeroValue(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return isNil(e) || (e.Name == "false" && r.info.Uses[e] == types.Universe.Lookup("false"))
+	case *ast.BasicLit:
+		value := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		switch value.Kind() {
+		case constant.String:
+			return constant.StringVal(value) == ""
+		case constant.Int, constant.Float:
+			return constant.Sign(value) == 0
+		}
+	case *ast.CompositeLit:
+		return len(e.Elts) == 0
+	}
+	return false
+}
+
+// mentions reports whether expr refers to the variable
+func (r *rewriter) mentions(expr ast.Expr, v types.Object) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && r.info.Uses[ident] == v {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// object returns the variable an identifier defines or refers to
+func (r *rewriter) object(ident *ast.Ident) types.Object {
+	if obj := r.info.Defs[ident]; obj != nil {
+		return obj
+	}
+	return r.info.Uses[ident]
+}
+
+// hasComments reports whether a comment lies within [start, end)
+func (r *rewriter) hasComments(start, end token.Pos) bool {
+	for _, group := range r.file.Comments {
+		if group.Pos() < end && group.End() > start {
+			return true
+		}
+	}
+	return false
+}
+
+// singleLine reports whether a node fits on one line; the Dingo
+// preprocessor rewrites error propagation line by line
+func (r *rewriter) singleLine(node ast.Node) bool {
+	return r.fset.Position(node.Pos()).Line == r.fset.Position(node.End()).Line
+}
+
+func (r *rewriter) text(node ast.Node) string {
+	return string(r.src[r.offset(node.Pos()):r.offset(node.End())])
+}
+
+func (r *rewriter) offset(pos token.Pos) int {
+	return r.fset.Position(pos).Offset
+}
+
+func (r *rewriter) edit(kind Kind, start, end token.Pos, text string) Edit {
+	return Edit{
+		Kind:  kind,
+		Line:  r.fset.Position(start).Line,
+		Start: r.offset(start),
+		End:   r.offset(end),
+		Text:  text,
+	}
+}
+
+// singleVarSpec returns the spec of a statement var name [T] = value
+func singleVarSpec(stmt ast.Stmt) (*ast.ValueSpec, bool) {
+	declStmt, ok := stmt.(*ast.DeclStmt)
+	if !ok {
+		return nil, false
+	}
+	gen, ok := declStmt.Decl.(*ast.GenDecl)
+	if !ok || gen.Tok != token.VAR || gen.Lparen.IsValid() || len(gen.Specs) != 1 {
+		return nil, false
+	}
+	spec := gen.Specs[0].(*ast.ValueSpec)
+	if len(spec.Names) != 1 || len(spec.Values) != 1 {
+		return nil, false
+	}
+	return spec, true
+}
+
+// returnsError reports whether the last result of a function is error
+func returnsError(ftype *ast.FuncType) bool {
+	if ftype.Results == nil || len(ftype.Results.List) == 0 {
+		return false
+	}
+	ident, ok := ftype.Results.List[len(ftype.Results.List)-1].Type.(*ast.Ident)
+	return ok && ident.Name == "error"
+}
+
+func resultCount(ftype *ast.FuncType) int {
+	if ftype.Results == nil {
+		return 0
+	}
+	return ftype.Results.NumFields()
+}
+
+func isNil(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
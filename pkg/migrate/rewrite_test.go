@@ -0,0 +1,212 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+)
+
+const header = `package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type City struct{ name string }
+type User struct{ city *City }
+
+`
+
+func rewriteBody(t *testing.T, body string) string {
+	t.Helper()
+	src := []byte(header + body)
+	edits, err := Rewrite("main.go", src)
+	if err != nil {
+		t.Fatalf("Rewrite() error: %v", err)
+	}
+	return strings.TrimPrefix(string(Apply(src, edits)), header)
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "error propagation",
+			in: `func f(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+`,
+			want: `func f(s string) (int, error) {
+	let n = strconv.Atoi(s)?
+	return n, nil
+}
+`,
+		},
+		{
+			name: "error wrapping",
+			in: `func f(s string) (*City, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("parse city: %w", err)
+	}
+	return &City{name: fmt.Sprint(n)}, nil
+}
+`,
+			want: `func f(s string) (*City, error) {
+	let n = strconv.Atoi(s)? "parse city"
+	return &City{name: fmt.Sprint(n)}, nil
+}
+`,
+		},
+		{
+			name: "redeclared error",
+			in: `func f(a, b string) (int, error) {
+	x, err := strconv.Atoi(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := strconv.Atoi(b)
+	if err != nil {
+		return 0, err
+	}
+	return x + y, nil
+}
+`,
+			want: `func f(a, b string) (int, error) {
+	let x = strconv.Atoi(a)?
+	let y = strconv.Atoi(b)?
+	return x + y, nil
+}
+`,
+		},
+		{
+			name: "let",
+			in: `func f() {
+	var a int = 1
+	var b = "x"
+	var c, d = 1, 2
+	var e int
+	println(a, b, c, d, e)
+}
+`,
+			want: `func f() {
+	let a: int = 1
+	let b = "x"
+	var c, d = 1, 2
+	var e int
+	println(a, b, c, d, e)
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteBody(t, tt.in); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteKeepsUnsafePatterns(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{
+			name: "non-zero result",
+			in: `func f(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1, err
+	}
+	return n, nil
+}
+`,
+		},
+		{
+			name: "error used later",
+			in: `func f(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	return n, err
+}
+`,
+		},
+		{
+			name: "extra format arguments",
+			in: `func f(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("parse %q: %w", s, err)
+	}
+	return n, nil
+}
+`,
+		},
+		{
+			name: "comment in body",
+			in: `func f(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		// Callers check for strconv.ErrSyntax
+		return 0, err
+	}
+	return n, nil
+}
+`,
+		},
+		{
+			name: "existing variable",
+			in: `func f(s string) (n int, err error) {
+	n, err = strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+`,
+		},
+		{
+			// ?. and ?? have no form for Go pointers
+			name: "nil-check ladder",
+			in: `func f(u *User) string {
+	name := "unknown"
+	if u != nil && u.city != nil {
+		name = u.city.name
+	}
+	return name
+}
+`,
+		},
+		{
+			name: "pointer default",
+			in: `func f(p *int) int {
+	n := 0
+	if p != nil {
+		n = *p
+	}
+	return n
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteBody(t, tt.in); got != tt.in {
+				t.Errorf("unexpected rewrite:\n%s", got)
+			}
+		})
+	}
+}
@@ -30,6 +30,9 @@ type LambdaTypeInferencePlugin struct {
 
 	// Track function literals that need type inference
 	untypedLiterals []*funcLiteralContext
+
+	// File being processed; its package's types are not qualified
+	file *ast.File
 }
 
 // funcLiteralContext tracks a function literal needing type inference
@@ -82,7 +85,10 @@ func (p *LambdaTypeInferencePlugin) Process(node ast.Node) error {
 		return fmt.Errorf("plugin context not initialized")
 	}
 
+	p.file, _ = node.(*ast.File)
+
 	// Phase 1: Discover function literals with untyped parameters
+	p.untypedLiterals = p.untypedLiterals[:0]
 	p.discoverUntypedLiterals(node)
 
 	// Phase 2: Attempt type inference for each literal
@@ -199,6 +205,13 @@ func (p *LambdaTypeInferencePlugin) inferFromMethodCall(sel *ast.SelectorExpr, c
 		return nil
 	}
 
+	// Package-qualified function: pkg.Func(lambda)
+	if fn, ok := p.typeInference.typesInfo.ObjectOf(sel.Sel).(*types.Func); ok {
+		if sig, ok := fn.Type().(*types.Signature); ok && sig.Recv() == nil {
+			return sig
+		}
+	}
+
 	// Get the type of the receiver (X in X.method)
 	recvType := p.typeInference.typesInfo.TypeOf(sel.X)
 	if recvType == nil {
@@ -300,7 +313,11 @@ func (p *LambdaTypeInferencePlugin) applyInferredTypes(funcLit *ast.FuncLit, sig
 		}
 
 		paramType := sigParams.At(sigIndex).Type()
-		field.Type = p.typeToAST(paramType)
+		pos := field.Names[len(field.Names)-1].End() + 1
+		if field.Type != nil {
+			pos = field.Type.Pos()
+		}
+		field.Type = p.typeToAST(paramType, pos)
 		sigIndex += len(field.Names)
 	}
 
@@ -314,7 +331,7 @@ func (p *LambdaTypeInferencePlugin) applyInferredTypes(funcLit *ast.FuncLit, sig
 			for i := 0; i < sig.Results().Len(); i++ {
 				resultType := sig.Results().At(i).Type()
 				funcLit.Type.Results.List[i] = &ast.Field{
-					Type: p.typeToAST(resultType),
+					Type: p.typeToAST(resultType, funcLit.Type.Params.End()+1),
 				}
 			}
 		}
@@ -323,32 +340,48 @@ func (p *LambdaTypeInferencePlugin) applyInferredTypes(funcLit *ast.FuncLit, sig
 	return true
 }
 
-// typeToAST converts a go/types.Type to an ast.Expr
-func (p *LambdaTypeInferencePlugin) typeToAST(typ types.Type) ast.Expr {
-	typeName := typ.String()
-
+// typeToAST converts a go/types.Type to an ast.Expr placed at pos, so the
+// printer keeps it on the line of the parameter it types
+func (p *LambdaTypeInferencePlugin) typeToAST(typ types.Type, pos token.Pos) ast.Expr {
 	// Handle basic types
 	if basic, ok := typ.(*types.Basic); ok {
-		return &ast.Ident{Name: basic.Name()}
+		return &ast.Ident{NamePos: pos, Name: basic.Name()}
 	}
 
 	// Handle named types
 	if named, ok := typ.(*types.Named); ok {
 		obj := named.Obj()
-		if obj.Pkg() == nil {
-			// Predeclared type
-			return &ast.Ident{Name: obj.Name()}
+		if obj.Pkg() == nil || p.isLocalPackage(obj.Pkg()) {
+			// Predeclared type or declared in this package
+			return &ast.Ident{NamePos: pos, Name: obj.Name()}
 		}
 		// Qualified type
 		return &ast.SelectorExpr{
-			X:   &ast.Ident{Name: obj.Pkg().Name()},
+			X:   &ast.Ident{NamePos: pos, Name: obj.Pkg().Name()},
 			Sel: &ast.Ident{Name: obj.Name()},
 		}
 	}
 
 	// Fallback: create identifier from string representation
 	// This is not perfect but works for simple cases
-	return &ast.Ident{Name: typeName}
+	return &ast.Ident{NamePos: pos, Name: types.TypeString(typ, p.qualifier)}
+}
+
+// isLocalPackage reports whether pkg is the package of the file being processed
+func (p *LambdaTypeInferencePlugin) isLocalPackage(pkg *types.Package) bool {
+	if p.file == nil || p.typeInference == nil || p.typeInference.typesInfo == nil {
+		return false
+	}
+	fileScope := p.typeInference.typesInfo.Scopes[p.file]
+	return fileScope != nil && fileScope.Parent() == pkg.Scope()
+}
+
+// qualifier names packages as the file being processed refers to them
+func (p *LambdaTypeInferencePlugin) qualifier(pkg *types.Package) string {
+	if p.isLocalPackage(pkg) {
+		return ""
+	}
+	return pkg.Name()
 }
 
 // reportTypeInferenceRequired reports that type inference failed and explicit types are required
@@ -8,6 +8,7 @@ import (
 	"go/types"
 	"strings"
 
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 	"github.com/MadAppGang/dingo/pkg/plugin"
 	"golang.org/x/tools/go/ast/astutil"
 )
@@ -122,14 +123,27 @@ func (p *NoneContextPlugin) Transform(node ast.Node) (ast.Node, error) {
 				return true
 			}
 
+			// An Option declared by an imported package is built by its
+			// constructor, its fields being unexported
+			if ctor := importedConstructorCall(p.ctx, ident, "None", nil); ctor != nil {
+				cursor.Replace(ctor)
+				return true
+			}
+
 			// Infer type from context
 			optionType, err := p.inferNoneType(ident)
 			if err != nil {
 				// Emit compile error for ambiguous None
-				p.ctx.ReportError(
-					fmt.Sprintf("cannot infer type for None constant: %v. Add explicit type annotation: let x: Option<T> = None", err),
-					ident.Pos(),
-				)
+				p.ctx.Report(diagnostic.Diagnostic{
+					Code:     diagnostic.CodeNoneContext,
+					Severity: diagnostic.SeverityError,
+					Pos:      p.ctx.Position(ident.Pos()),
+					End:      p.ctx.Position(ident.End()),
+					Message:  fmt.Sprintf("cannot infer type for None constant: %v", err),
+					Fixes: []diagnostic.Fix{
+						{Message: "add an explicit type annotation: let x: Option<T> = None"},
+					},
+				})
 				return true
 			}
 
@@ -7,7 +7,9 @@ import (
 	"go/types"
 	"strings"
 
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 	"github.com/MadAppGang/dingo/pkg/plugin"
+	"golang.org/x/tools/go/ast/astutil"
 )
 // OptionTypePlugin generates Option<T> type declarations and transformations
 //
@@ -115,6 +117,30 @@ func (p *OptionTypePlugin) Process(node ast.Node) error {
 	return nil
 }
 
+// Transform replaces Some(value) with the constructor of the Option type
+// the context requires when an imported package declares it:
+// Some(u) → a.Option_User_Some(u)
+func (p *OptionTypePlugin) Transform(node ast.Node) (ast.Node, error) {
+	if p.ctx == nil {
+		return nil, fmt.Errorf("plugin context not initialized")
+	}
+
+	transformed := astutil.Apply(node, func(cursor *astutil.Cursor) bool {
+		call, ok := cursor.Node().(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 {
+			return true
+		}
+		if ident, ok := call.Fun.(*ast.Ident); ok && ident.Name == "Some" {
+			if ctor := importedConstructorCall(p.ctx, call, "Some", call.Args); ctor != nil {
+				cursor.Replace(ctor)
+			}
+		}
+		return true
+	}, nil)
+
+	return transformed, nil
+}
+
 // handleGenericOption processes Option<T> syntax
 func (p *OptionTypePlugin) handleGenericOption(expr *ast.IndexExpr) {
 	// Check if the base type is "Option"
@@ -162,20 +188,30 @@ func (p *OptionTypePlugin) handleNoneExpression(ident *ast.Ident) {
 		return
 	}
 
+	// An Option declared by an imported package needs no declaration; the
+	// None context plugin builds it with its constructor
+	if importedConstructorCall(p.ctx, ident, "None", nil) != nil {
+		return
+	}
+
 	// Try to infer target Option type from context
 	targetType, inferred := p.inferNoneTypeFromContext(ident)
 
 	if !inferred {
 		// Cannot infer type from context
 		pos := p.ctx.FileSet.Position(ident.Pos())
-		errorMsg := fmt.Sprintf(
-			"Cannot infer Option type for None constant at %s\n"+
-				"Hint: Use explicit type annotation or Option_T_None() constructor\n"+
-				"Example: var x Option_int = Option_int_None() or var x Option_int = None with type declaration",
-			pos,
-		)
+		errorMsg := fmt.Sprintf("Cannot infer Option type for None constant at %s", pos)
 		p.ctx.Logger.Error(errorMsg)
-		p.ctx.ReportError(errorMsg, ident.Pos())
+		p.ctx.Report(diagnostic.Diagnostic{
+			Code:     diagnostic.CodeNoneContext,
+			Severity: diagnostic.SeverityError,
+			Pos:      p.ctx.Position(ident.Pos()),
+			End:      p.ctx.Position(ident.End()),
+			Message:  "cannot infer Option type for None constant",
+			Fixes: []diagnostic.Fix{
+				{Message: "use an explicit type annotation or the Option_T_None() constructor, e.g. var x Option_int = Option_int_None()"},
+			},
+		})
 		return
 	}
 
@@ -215,6 +251,11 @@ func (p *OptionTypePlugin) handleSomeConstructor(call *ast.CallExpr) {
 		return
 	}
 
+	// An Option declared by an imported package needs no declaration
+	if importedConstructorCall(p.ctx, call, "Some", call.Args) != nil {
+		return
+	}
+
 	// Type inference: Infer from argument type
 	valueArg := call.Args[0]
 
@@ -9,7 +9,7 @@ import (
 	"go/types"
 	"strings"
 
-	"github.com/MadAppGang/dingo/pkg/errors"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 	"github.com/MadAppGang/dingo/pkg/plugin"
 )
 
@@ -133,7 +133,11 @@ func (p *PatternMatchPlugin) Process(node ast.Node) error {
 	for _, match := range p.matchExpressions {
 		if err := p.checkExhaustiveness(match); err != nil {
 			// Report compile error
-			p.ctx.ReportError(err.Error(), match.startPos)
+			if d, ok := err.(*diagnostic.Diagnostic); ok {
+				p.ctx.Report(*d)
+			} else {
+				p.ctx.ReportError(err.Error(), match.startPos)
+			}
 		}
 	}
 
@@ -427,8 +431,8 @@ func (p *PatternMatchPlugin) extractConstructorName(pattern string) string {
 		return "_"
 	}
 
-	// Extract name before '('
-	idx := strings.Index(pattern, "(")
+	// Extract name before '(' or, for struct variants, '{'
+	idx := strings.IndexAny(pattern, "({")
 	if idx > 0 {
 		return strings.TrimSpace(pattern[:idx])
 	}
@@ -469,11 +473,26 @@ func (p *PatternMatchPlugin) checkExhaustiveness(match *matchExpression) error {
 		return nil
 	}
 
+	// Track covered variants
+	coveredVariants := make(map[string]bool)
+	for _, pattern := range match.patterns {
+		coveredVariants[pattern] = true
+	}
+
 	// Determine scrutinee type to get all possible variants
-	// Try scrutinee name first, then fall back to pattern inference
-	allVariants := p.getAllVariants(match.scrutinee)
-	if len(allVariants) == 0 {
-		allVariants = p.getAllVariantsFromPatterns(match)
+	// The checked type wins, then the scrutinee name, then pattern inference
+	var allVariants []string
+	if st := p.scrutineeSumType(match.switchStmt); st != nil {
+		allVariants = st.variants
+		coveredVariants = make(map[string]bool)
+		for _, pattern := range match.patterns {
+			coveredVariants[st.variantOf(pattern)] = true
+		}
+	} else {
+		allVariants = p.getAllVariants(match.scrutinee)
+		if len(allVariants) == 0 {
+			allVariants = p.getAllVariantsFromPatterns(match)
+		}
 	}
 
 	if len(allVariants) == 0 {
@@ -481,12 +500,6 @@ func (p *PatternMatchPlugin) checkExhaustiveness(match *matchExpression) error {
 		return nil
 	}
 
-	// Track covered variants
-	coveredVariants := make(map[string]bool)
-	for _, pattern := range match.patterns {
-		coveredVariants[pattern] = true
-	}
-
 	// Compute uncovered variants
 	uncovered := make([]string, 0)
 	for _, variant := range allVariants {
@@ -497,7 +510,13 @@ func (p *PatternMatchPlugin) checkExhaustiveness(match *matchExpression) error {
 
 	// Error if non-exhaustive
 	if len(uncovered) > 0 {
-		return p.createNonExhaustiveError(match.scrutinee, uncovered, match.startPos)
+		st := p.scrutineeSumType(match.switchStmt)
+		prefix := patternPrefix(match.patterns, allVariants)
+		arms := make([]string, len(uncovered))
+		for i, variant := range uncovered {
+			arms[i] = prefix + variant + variantBindings(st, variant)
+		}
+		return p.createNonExhaustiveError(match.scrutinee, uncovered, arms, match.startPos)
 	}
 
 	return nil
@@ -560,15 +579,84 @@ func (p *PatternMatchPlugin) getAllVariantsFromPatterns(match *matchExpression)
 	return []string{}
 }
 
-// createNonExhaustiveError creates a compile error for non-exhaustive match
-func (p *PatternMatchPlugin) createNonExhaustiveError(scrutinee string, missingCases []string, pos token.Pos) error {
-	message := fmt.Sprintf("non-exhaustive match, missing cases: %s", strings.Join(missingCases, ", "))
-	hint := "add a wildcard arm: _ => ..."
+// patternPrefix returns how the patterns of a match name variants: "" for
+// Circle, "Shape_" for Shape_Circle, "Shape" for ShapeCircle
+func patternPrefix(patterns, variants []string) string {
+	for _, pattern := range patterns {
+		longest := ""
+		for _, v := range variants {
+			if strings.HasSuffix(pattern, v) && len(v) > len(longest) {
+				longest = v
+			}
+		}
+		if longest != "" {
+			return strings.TrimSuffix(pattern, longest)
+		}
+	}
+	return ""
+}
 
-	// Create CompileError using existing infrastructure
-	compileErr := errors.NewCodeGenerationError(message, pos, hint)
+// variantBindings returns the destructuring of a variant in a match arm,
+// such as (value) for Ok or (radius) for Circle { radius: float64 }, or ""
+// when it has no payload. Bindings of enum variants are named after their
+// fields; tuple variants get value, or value1, value2, ...
+func variantBindings(st *sumType, variant string) string {
+	switch variant {
+	case "Ok", "Some":
+		return "(value)"
+	case "Err":
+		return "(err)"
+	case "None":
+		return ""
+	}
+	if st == nil {
+		return ""
+	}
+	fn := st.constructor(variant)
+	if fn == nil {
+		return ""
+	}
+	params := fn.Type().(*types.Signature).Params()
+	names := make([]string, params.Len())
+	for i := range names {
+		names[i] = params.At(i).Name()
+		if names[i] == "" || names[i] == "_" || isArgName(names[i]) {
+			names[i] = "value"
+			if len(names) > 1 {
+				names[i] = fmt.Sprintf("value%d", i+1)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(names, ", ") + ")"
+}
+
+// isArgName reports whether name is a generated parameter name (arg0, arg1)
+func isArgName(name string) bool {
+	return strings.HasPrefix(name, "arg") && strings.Trim(name[3:], "0123456789") == ""
+}
 
-	return fmt.Errorf("%s", compileErr.Error())
+// createNonExhaustiveError creates a compile error for non-exhaustive match.
+// arms are the missing patterns with their bindings, for editors to insert.
+func (p *PatternMatchPlugin) createNonExhaustiveError(scrutinee string, missingCases, arms []string, pos token.Pos) error {
+	help := make([]string, len(arms))
+	for i, arm := range arms {
+		help[i] = arm + " => ..."
+	}
+	return &diagnostic.Diagnostic{
+		Code:         diagnostic.CodeNonExhaustive,
+		Severity:     diagnostic.SeverityError,
+		Pos:          p.ctx.Position(pos),
+		Message:      fmt.Sprintf("non-exhaustive match, missing cases: %s", strings.Join(missingCases, ", ")),
+		Source:       diagnostic.SourceDingo,
+		MissingItems: arms,
+		Fixes: []diagnostic.Fix{
+			{Message: fmt.Sprintf("add the missing arms: %s", strings.Join(help, ", "))},
+			{Message: "add a wildcard arm: _ => ..."},
+		},
+	}
 }
 
 // parseGuards extracts guard conditions from case clauses
@@ -901,21 +989,185 @@ func (p *PatternMatchPlugin) Transform(node ast.Node) (ast.Node, error) {
 
 // transformMatchExpression transforms a single match expression
 // The preprocessor already generates correct switch statements with scrutinee variables
-// and panic statements. This plugin only validates exhaustiveness during Process phase.
-// No transformation is needed here - the AST is already correct from the preprocessor.
+// and panic statements, reading the tag and payload fields of the scrutinee. Those
+// fields are unexported, so switches over sum types declared by another package are
+// rewritten to use the exported Is* and accessor methods instead.
 func (p *PatternMatchPlugin) transformMatchExpression(file *ast.File, match *matchExpression) error {
 	// The preprocessor (rust_match.go) generates:
 	// 1. scrutinee := <expr> (before switch)
 	// 2. switch scrutinee.tag { ... } (NO Init statement)
 	// 3. panic("unreachable: match is exhaustive") (after switch)
 	//
-	// This is already the correct Go code structure. The plugin's job is only to
-	// validate exhaustiveness during the Process phase, not to transform the AST.
-
-	// No transformation needed - return without modifying the switch statement
+	// Tuple matches nest one such switch per element
+	ast.Inspect(match.switchStmt, func(n ast.Node) bool {
+		if switchStmt, ok := n.(*ast.SwitchStmt); ok {
+			p.rewriteImportedSwitch(switchStmt)
+		}
+		return true
+	})
 	return nil
 }
 
+// scrutineeSumType returns the sum type a generated switch statement
+// (switch scrutinee.tag) matches on, or nil when it is unknown
+func (p *PatternMatchPlugin) scrutineeSumType(switchStmt *ast.SwitchStmt) *sumType {
+	if p.ctx == nil || switchStmt == nil {
+		return nil
+	}
+	info, _ := p.ctx.TypeInfo.(*types.Info)
+	sel, ok := switchStmt.Tag.(*ast.SelectorExpr)
+	if info == nil || !ok || sel.Sel.Name != "tag" {
+		return nil
+	}
+	return sumTypeOf(info.TypeOf(sel.X))
+}
+
+// rewriteImportedSwitch rewrites a switch over the tag of a sum type declared
+// by another package into a tagless switch on its exported methods:
+//
+//	switch s.tag {                  switch {
+//	case ShapeTagRect:         →    case s.IsRect():
+//		w := *s.rect                    w, _ := s.Rect()
+//
+// Option and Result payloads are read with Unwrap and UnwrapErr. The switch
+// is left alone when a method is missing; the type checker reports it then.
+func (p *PatternMatchPlugin) rewriteImportedSwitch(switchStmt *ast.SwitchStmt) {
+	st := p.scrutineeSumType(switchStmt)
+	info, _ := p.ctx.TypeInfo.(*types.Info)
+	if st == nil || st.pkg() == checkedPackage(info) {
+		return
+	}
+	scrutinee, ok := switchStmt.Tag.(*ast.SelectorExpr).X.(*ast.Ident)
+	if !ok {
+		return
+	}
+
+	// Resolve everything first, so that a switch is rewritten entirely or not at all
+	type clauseRewrite struct {
+		cases    []ast.Expr
+		bindings map[*ast.AssignStmt][]ast.Expr
+		methods  map[*ast.AssignStmt]string
+	}
+	rewrites := make(map[*ast.CaseClause]*clauseRewrite)
+	for _, stmt := range switchStmt.Body.List {
+		clause, ok := stmt.(*ast.CaseClause)
+		if !ok {
+			return
+		}
+		rw := &clauseRewrite{bindings: make(map[*ast.AssignStmt][]ast.Expr), methods: make(map[*ast.AssignStmt]string)}
+		variant := ""
+		for _, expr := range clause.List {
+			ident, ok := expr.(*ast.Ident)
+			if !ok {
+				return
+			}
+			variant = st.variantOfTag(ident.Name)
+			if variant == "" || st.method("Is"+variant) == nil {
+				return
+			}
+			rw.cases = append(rw.cases, methodCall(scrutinee.Name, "Is"+variant, ident))
+		}
+
+		failed := false
+		ast.Inspect(clause, func(n ast.Node) bool {
+			assign, ok := n.(*ast.AssignStmt)
+			if !ok || failed {
+				return !failed
+			}
+			field := payloadField(assign, scrutinee.Name)
+			if field == "" {
+				return true
+			}
+			method, lhs := payloadAccessor(st, variant, field, assign.Lhs[0])
+			if method == "" {
+				failed = true
+				return false
+			}
+			rw.methods[assign], rw.bindings[assign] = method, lhs
+			return true
+		})
+		if failed {
+			return
+		}
+		rewrites[clause] = rw
+	}
+
+	switchStmt.Tag = nil
+	for clause, rw := range rewrites {
+		if clause.List != nil {
+			clause.List = rw.cases
+		}
+		for assign, lhs := range rw.bindings {
+			assign.Lhs = lhs
+			assign.Rhs = []ast.Expr{methodCall(scrutinee.Name, rw.methods[assign], assign.Rhs[0])}
+		}
+	}
+}
+
+// methodCall builds recv.method() in place of old, keeping its position so
+// that comments stay where they were
+func methodCall(recv, method string, old ast.Expr) *ast.CallExpr {
+	x := &ast.Ident{NamePos: old.Pos(), Name: recv}
+	sel := &ast.Ident{NamePos: old.Pos(), Name: method}
+	return &ast.CallExpr{
+		Fun:    &ast.SelectorExpr{X: x, Sel: sel},
+		Lparen: old.End(),
+		Rparen: old.End(),
+	}
+}
+
+// payloadField returns the payload field a binding generated for a match arm
+// reads (x := *scrutinee.field), or ""
+func payloadField(assign *ast.AssignStmt, scrutinee string) string {
+	if assign.Tok != token.DEFINE || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return ""
+	}
+	star, ok := assign.Rhs[0].(*ast.StarExpr)
+	if !ok {
+		return ""
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if x, ok := sel.X.(*ast.Ident); !ok || x.Name != scrutinee {
+		return ""
+	}
+	return sel.Sel.Name
+}
+
+// payloadAccessor returns the exported method reading field of a variant and
+// the left-hand side binding lhs to it: enums have one accessor per variant
+// returning every field, Option and Result have Unwrap and UnwrapErr
+func payloadAccessor(st *sumType, variant, field string, lhs ast.Expr) (string, []ast.Expr) {
+	if fn := st.method(variant); fn != nil {
+		results := fn.Type().(*types.Signature).Results()
+		exprs := make([]ast.Expr, results.Len())
+		found := false
+		for i := range exprs {
+			exprs[i] = ast.NewIdent("_")
+			if results.At(i).Name() == field {
+				exprs[i], found = lhs, true
+			}
+		}
+		if found {
+			return variant, exprs
+		}
+	}
+
+	var method string
+	switch field {
+	case "some", "ok":
+		method = "Unwrap"
+	case "err":
+		method = "UnwrapErr"
+	}
+	if method == "" || st.method(method) == nil {
+		return "", nil
+	}
+	return method, []ast.Expr{lhs}
+}
+
 // findParent walks the AST to find the parent of a node
 func findParent(root ast.Node, target ast.Node) ast.Node {
 	var parent ast.Node
@@ -1221,7 +1473,7 @@ func (p *PatternMatchPlugin) checkTupleExhaustiveness(match *matchExpression) er
 
 	if !exhaustive {
 		// Create error for missing patterns
-		return p.createTupleNonExhaustiveError(match.scrutinee, missing, match.startPos)
+		return p.createTupleNonExhaustiveError(match.scrutinee, missing, match.tupleArity, match.startPos)
 	}
 
 	return nil
@@ -1252,11 +1504,32 @@ func (p *PatternMatchPlugin) inferVariantsFromTupleArms(arms []tupleArmInfo) []s
 	return []string{}
 }
 
-// createTupleNonExhaustiveError creates error for non-exhaustive tuple match
-func (p *PatternMatchPlugin) createTupleNonExhaustiveError(scrutinee string, missingPatterns []string, pos token.Pos) error {
-	message := fmt.Sprintf("non-exhaustive tuple match, missing patterns: %s", strings.Join(missingPatterns, ", "))
-	hint := "add a wildcard arm: (_, _, ...) => ..."
-
-	compileErr := errors.NewCodeGenerationError(message, pos, hint)
-	return fmt.Errorf("%s", compileErr.Error())
+// createTupleNonExhaustiveError creates error for non-exhaustive tuple match.
+// The missing patterns get an arm each, binding payloads to _.
+func (p *PatternMatchPlugin) createTupleNonExhaustiveError(scrutinee string, missingPatterns []string, arity int, pos token.Pos) error {
+	arms := make([]string, len(missingPatterns))
+	help := make([]string, len(missingPatterns))
+	for i, pattern := range missingPatterns {
+		elems := strings.Split(strings.Trim(pattern, "()"), ", ")
+		for j, elem := range elems {
+			if elem != "_" && elem != "None" {
+				elems[j] = elem + "(_)"
+			}
+		}
+		arms[i] = "(" + strings.Join(elems, ", ") + ")"
+		help[i] = arms[i] + " => ..."
+	}
+	wildcard := "(" + strings.TrimSuffix(strings.Repeat("_, ", arity), ", ") + ")"
+	return &diagnostic.Diagnostic{
+		Code:         diagnostic.CodeNonExhaustive,
+		Severity:     diagnostic.SeverityError,
+		Pos:          p.ctx.Position(pos),
+		Message:      fmt.Sprintf("non-exhaustive tuple match, missing patterns: %s", strings.Join(missingPatterns, ", ")),
+		Source:       diagnostic.SourceDingo,
+		MissingItems: arms,
+		Fixes: []diagnostic.Fix{
+			{Message: fmt.Sprintf("add the missing arms: %s", strings.Join(help, ", "))},
+			{Message: fmt.Sprintf("add a wildcard arm: %s => ...", wildcard)},
+		},
+	}
 }
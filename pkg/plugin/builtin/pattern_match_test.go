@@ -4,9 +4,11 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 	"github.com/MadAppGang/dingo/pkg/plugin"
 )
 
@@ -128,6 +130,30 @@ func handleResult(result Result_int_string) int {
 	if !strings.Contains(errMsg, "Err") {
 		t.Errorf("expected 'Err' in missing cases, got: %s", errMsg)
 	}
+	d, ok := errors[0].(*diagnostic.Diagnostic)
+	if !ok {
+		t.Fatalf("expected *diagnostic.Diagnostic, got %T", errors[0])
+	}
+	if want := []string{"Err(err)"}; !reflect.DeepEqual(d.MissingItems, want) {
+		t.Errorf("MissingItems = %v, want %v", d.MissingItems, want)
+	}
+}
+
+func TestPatternMatchPlugin_TupleMissingItems(t *testing.T) {
+	p := NewPatternMatchPlugin()
+	p.SetContext(&plugin.Context{Logger: plugin.NewNoOpLogger()})
+
+	err := p.createTupleNonExhaustiveError("(a, b)", []string{"(Ok, Err)", "(None, _)"}, 2, token.NoPos)
+	d, ok := err.(*diagnostic.Diagnostic)
+	if !ok {
+		t.Fatalf("expected *diagnostic.Diagnostic, got %T", err)
+	}
+	if want := []string{"(Ok(_), Err(_))", "(None, _)"}; !reflect.DeepEqual(d.MissingItems, want) {
+		t.Errorf("MissingItems = %v, want %v", d.MissingItems, want)
+	}
+	if len(d.Fixes) != 2 || d.Fixes[1].Message != "add a wildcard arm: (_, _) => ..." {
+		t.Errorf("Fixes = %+v, want missing arms and a (_, _) wildcard", d.Fixes)
+	}
 }
 
 func TestPatternMatchPlugin_ExhaustiveOption(t *testing.T) {
@@ -230,6 +256,13 @@ func handleOption(opt Option_int) int {
 	if !strings.Contains(errMsg, "None") {
 		t.Errorf("expected 'None' in missing cases, got: %s", errMsg)
 	}
+	d, ok := errors[0].(*diagnostic.Diagnostic)
+	if !ok {
+		t.Fatalf("expected *diagnostic.Diagnostic, got %T", errors[0])
+	}
+	if want := []string{"None"}; !reflect.DeepEqual(d.MissingItems, want) {
+		t.Errorf("MissingItems = %v, want %v", d.MissingItems, want)
+	}
 }
 
 func TestPatternMatchPlugin_WildcardCoversAll(t *testing.T) {
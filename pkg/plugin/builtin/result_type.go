@@ -238,6 +238,11 @@ func (p *ResultTypePlugin) transformOkConstructor(call *ast.CallExpr) ast.Expr {
 		return call // Return unchanged
 	}
 
+	// A Result declared by an imported package is built by its constructor
+	if ctor := importedConstructorCall(p.ctx, call, "Ok", call.Args); ctor != nil {
+		return ctor
+	}
+
 	valueArg := call.Args[0]
 
 	// CRITICAL FIX #3: Check error from inferTypeFromExpr
@@ -317,6 +322,11 @@ func (p *ResultTypePlugin) transformErrConstructor(call *ast.CallExpr) ast.Expr
 		return call // Return unchanged
 	}
 
+	// A Result declared by an imported package is built by its constructor
+	if ctor := importedConstructorCall(p.ctx, call, "Err", call.Args); ctor != nil {
+		return ctor
+	}
+
 	errorArg := call.Args[0]
 
 	// CRITICAL FIX #3: Check error from inferTypeFromExpr
@@ -8,6 +8,7 @@ import (
 	"go/types"
 	"strings"
 
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 	"github.com/MadAppGang/dingo/pkg/plugin"
 	"golang.org/x/tools/go/ast/astutil"
 )
@@ -184,7 +185,13 @@ func (p *SafeNavTypePlugin) Transform(node ast.Node) (ast.Node, error) {
 		for _, inferNode := range p.inferNodes {
 			if err := p.resolveTypeForInferNode(inferNode); err != nil {
 				p.errors = append(p.errors, err.Error())
-				p.ctx.ReportError(err.Error(), inferNode.ident.Pos())
+				p.ctx.Report(diagnostic.Diagnostic{
+					Code:     diagnostic.CodeTypeInference,
+					Severity: diagnostic.SeverityError,
+					Pos:      p.ctx.Position(inferNode.ident.Pos()),
+					End:      p.ctx.Position(inferNode.ident.End()),
+					Message:  err.Error(),
+				})
 			}
 		}
 	}
@@ -338,6 +345,12 @@ func (p *SafeNavTypePlugin) resolveTypeForInferNode(node *inferNode) error {
 		// __INFER__ might be in a function call: __SAFE_NAV_INFER__(var, "field")
 		return p.inferFromFunctionCall(node, parent)
 
+	case *ast.Field:
+		// __INFER__ is the result type of a func() __INFER__ IIFE. Transform
+		// resolves it from the function body when it can; the rest is left to
+		// the generator's post-AST resolution, which type-checks the output.
+		return nil
+
 	default:
 		return fmt.Errorf("unexpected parent node type for __INFER__: %T", parent)
 	}
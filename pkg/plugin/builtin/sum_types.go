@@ -0,0 +1,271 @@
+package builtin
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/plugin"
+)
+
+// sumType is a tagged union generated by Dingo: an enum, or an Option or
+// Result type injected by the plugins. It may be declared by another Dingo
+// package, in which case its unexported fields are out of reach and only
+// its exported constructors and methods can be used.
+//
+// Generated sum types are recognized by their shape: a struct whose tag
+// field has a named type (OptionTag, ShapeTag) with one constant per variant
+// (OptionTagSome, ShapeTagCircle) declared next to it.
+type sumType struct {
+	named    *types.Named
+	tagType  *types.Named
+	variants []string // Variant names in tag order: Some, None
+}
+
+// sumTypeOf returns the sum type typ is, or nil
+func sumTypeOf(typ types.Type) *sumType {
+	if typ == nil {
+		return nil
+	}
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	var tagType *types.Named
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Name() == "tag" {
+			tagType, _ = f.Type().(*types.Named)
+		}
+	}
+	if tagType == nil || tagType.Obj().Pkg() == nil {
+		return nil
+	}
+
+	prefix := tagType.Obj().Name()
+	scope := tagType.Obj().Pkg().Scope()
+	var tags []*types.Const
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if ok && strings.HasPrefix(name, prefix) && len(name) > len(prefix) && types.Identical(c.Type(), tagType) {
+			tags = append(tags, c)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		vi, _ := constant.Int64Val(tags[i].Val())
+		vj, _ := constant.Int64Val(tags[j].Val())
+		return vi < vj
+	})
+
+	t := &sumType{named: named, tagType: tagType}
+	for _, c := range tags {
+		t.variants = append(t.variants, strings.TrimPrefix(c.Name(), prefix))
+	}
+	return t
+}
+
+// pkg returns the package declaring the sum type
+func (t *sumType) pkg() *types.Package {
+	return t.named.Obj().Pkg()
+}
+
+// variantOf returns the variant a match pattern names, or "" when it names
+// none. Patterns are written as the variant (Some, Circle), prefixed by the
+// type (Shape_Circle) or constructor style (OptionSome).
+func (t *sumType) variantOf(pattern string) string {
+	if i := strings.LastIndex(pattern, "."); i >= 0 {
+		pattern = pattern[i+1:] // Qualified: a.Shape_Circle
+	}
+	base := strings.TrimSuffix(t.tagType.Obj().Name(), "Tag")
+	for _, v := range t.variants {
+		if pattern == v || pattern == base+v || strings.HasSuffix(pattern, "_"+v) {
+			return v
+		}
+	}
+	return ""
+}
+
+// variantOfTag returns the variant of a tag constant, or ""
+func (t *sumType) variantOfTag(name string) string {
+	prefix := t.tagType.Obj().Name()
+	for _, v := range t.variants {
+		if name == prefix+v {
+			return v
+		}
+	}
+	return ""
+}
+
+// method returns the exported method of the sum type called name, or nil
+func (t *sumType) method(name string) *types.Func {
+	obj, _, _ := types.LookupFieldOrMethod(t.named, false, t.pkg(), name)
+	fn, _ := obj.(*types.Func)
+	return fn
+}
+
+// constructor returns the exported function building a variant:
+// Option_int_Some for injected types, ShapeCircle for enums
+func (t *sumType) constructor(variant string) *types.Func {
+	scope := t.pkg().Scope()
+	for _, name := range []string{t.named.Obj().Name() + "_" + variant, t.named.Obj().Name() + variant} {
+		if fn, ok := scope.Lookup(name).(*types.Func); ok && fn.Exported() {
+			return fn
+		}
+	}
+	return nil
+}
+
+// checkedPackage returns the package info was filled for: everything the
+// checked files define belongs to it
+func checkedPackage(info *types.Info) *types.Package {
+	if info == nil {
+		return nil
+	}
+	for _, obj := range info.Defs {
+		if obj != nil && obj.Pkg() != nil {
+			return obj.Pkg()
+		}
+	}
+	return nil
+}
+
+// importedSumType returns the sum type typ is when another package declares
+// it, or nil
+func importedSumType(info *types.Info, typ types.Type) *sumType {
+	t := sumTypeOf(typ)
+	if t == nil || t.pkg() == checkedPackage(info) {
+		return nil
+	}
+	return t
+}
+
+// importName returns the name file imports pkg by, or "" when pkg is not
+// imported by name
+func importName(info *types.Info, file *ast.File, pkg *types.Package) string {
+	if info == nil || file == nil {
+		return ""
+	}
+	for _, spec := range file.Imports {
+		obj, ok := info.Implicits[spec].(*types.PkgName)
+		if spec.Name != nil {
+			obj, ok = info.Defs[spec.Name].(*types.PkgName)
+		}
+		if ok && obj.Imported() == pkg && obj.Name() != "_" && obj.Name() != "." {
+			return obj.Name()
+		}
+	}
+	return ""
+}
+
+// expectedType returns the type the context of expr requires: the result of
+// the enclosing function for a returned value, the variable assigned to, the
+// declared type of a var, or the parameter of a call argument. It returns
+// nil when the context requires no particular type.
+func expectedType(ctx *plugin.Context, info *types.Info, expr ast.Expr) types.Type {
+	if ctx == nil || info == nil {
+		return nil
+	}
+	var node ast.Node = expr
+	parent := ctx.GetParent(node)
+	for {
+		paren, ok := parent.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		node, parent = paren, ctx.GetParent(paren)
+	}
+
+	switch p := parent.(type) {
+	case *ast.ReturnStmt:
+		var sig *types.Signature
+		ctx.WalkParents(p, func(n ast.Node) bool {
+			switch fn := n.(type) {
+			case *ast.FuncDecl:
+				if obj, ok := info.Defs[fn.Name].(*types.Func); ok {
+					sig, _ = obj.Type().(*types.Signature)
+				}
+				return false
+			case *ast.FuncLit:
+				sig, _ = info.TypeOf(fn).(*types.Signature)
+				return false
+			}
+			return true
+		})
+		if i := indexOf(p.Results, node); sig != nil && i >= 0 && i < sig.Results().Len() && len(p.Results) == sig.Results().Len() {
+			return sig.Results().At(i).Type()
+		}
+	case *ast.AssignStmt:
+		if i := indexOf(p.Rhs, node); p.Tok == token.ASSIGN && i >= 0 && len(p.Lhs) == len(p.Rhs) {
+			return info.TypeOf(p.Lhs[i])
+		}
+	case *ast.ValueSpec:
+		if p.Type != nil && indexOf(p.Values, node) >= 0 {
+			return info.TypeOf(p.Type)
+		}
+	case *ast.CallExpr:
+		sig, ok := info.TypeOf(p.Fun).(*types.Signature)
+		i := indexOf(p.Args, node)
+		if !ok || i < 0 || sig.Params().Len() == 0 {
+			return nil
+		}
+		if sig.Variadic() && i >= sig.Params().Len()-1 {
+			if slice, ok := sig.Params().At(sig.Params().Len() - 1).Type().(*types.Slice); ok && !p.Ellipsis.IsValid() {
+				return slice.Elem()
+			}
+		}
+		if i < sig.Params().Len() {
+			return sig.Params().At(i).Type()
+		}
+	}
+	return nil
+}
+
+// importedConstructorCall builds a call of the exported constructor of
+// variant when the context of expr requires a sum type declared by another
+// package, e.g. Ok(x) → a.Result_int_error_Ok(x) or None →
+// a.Option_User_None(). It returns nil otherwise.
+func importedConstructorCall(ctx *plugin.Context, expr ast.Expr, variant string, args []ast.Expr) ast.Expr {
+	info, _ := ctx.TypeInfo.(*types.Info)
+	t := importedSumType(info, expectedType(ctx, info, expr))
+	if t == nil {
+		return nil
+	}
+	fn := t.constructor(variant)
+	name := importName(info, enclosingFile(ctx, expr), t.pkg())
+	if fn == nil || name == "" {
+		return nil
+	}
+	return &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(name), Sel: ast.NewIdent(fn.Name())},
+		Args: args,
+	}
+}
+
+// enclosingFile returns the file node belongs to, or nil
+func enclosingFile(ctx *plugin.Context, node ast.Node) *ast.File {
+	var file *ast.File
+	ctx.WalkParents(node, func(n ast.Node) bool {
+		file, _ = n.(*ast.File)
+		return file == nil
+	})
+	return file
+}
+
+// indexOf returns the position of node in exprs, or -1
+func indexOf(exprs []ast.Expr, node ast.Node) int {
+	for i, e := range exprs {
+		if e == node {
+			return i
+		}
+	}
+	return -1
+}
@@ -31,6 +31,7 @@ type TypeInferenceService struct {
 
 	// go/types integration for accurate type inference
 	typesInfo *types.Info
+	pkg       *types.Package // The package typesInfo was checked for
 
 	// Phase 4: Parent tracking for context-based inference
 	parentMap map[ast.Node]ast.Node
@@ -95,6 +96,7 @@ func NewTypeInferenceService(fset *token.FileSet, file *ast.File, logger plugin.
 // This should be called after running the type checker
 func (s *TypeInferenceService) SetTypesInfo(info *types.Info) {
 	s.typesInfo = info
+	s.pkg = checkedPackage(info)
 	s.logger.Debugf("Type inference service updated with go/types information")
 }
 
@@ -567,8 +569,8 @@ func (s *TypeInferenceService) TypeToString(typ types.Type) string {
 		// Named type (struct, interface, or type alias)
 		obj := t.Obj()
 		if obj != nil {
-			// Check if the type is from a package
-			if pkg := obj.Pkg(); pkg != nil && pkg.Name() != "" {
+			// Check if the type is from another package
+			if pkg := obj.Pkg(); pkg != nil && pkg.Name() != "" && pkg != s.pkg {
 				// Qualified name: pkg.Type
 				return pkg.Name() + "." + obj.Name()
 			}
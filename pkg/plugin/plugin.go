@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // MaxErrors is the maximum number of errors to accumulate
@@ -25,6 +27,7 @@ type Pipeline struct {
 	Ctx              *Context
 	plugins          []Plugin
 	injectedTypesAST *ast.File // Separate AST for injected type declarations (Option B)
+	transformHook    func(plugin string, file *ast.File)
 }
 
 // NewPipeline creates a new plugin pipeline
@@ -50,6 +53,12 @@ func (p *Pipeline) RegisterPlugin(plugin Plugin) {
 	}
 }
 
+// SetTransformHook installs a hook called with the file after each plugin's
+// Transform in phase 2 (used by dingo expand to show intermediate results)
+func (p *Pipeline) SetTransformHook(hook func(plugin string, file *ast.File)) {
+	p.transformHook = hook
+}
+
 // Transform transforms an AST using the 3-phase pipeline
 // Phase 1: Discovery - Process() to discover types
 // Phase 2: Transform - Transform() to replace constructor calls
@@ -59,6 +68,14 @@ func (p *Pipeline) Transform(file *ast.File) (*ast.File, error) {
 		return file, nil // No plugins, no transformation
 	}
 
+	// Hand the context to plugins again: the parent map and TypeInfo of this
+	// file were not available when the plugins were registered
+	for _, plugin := range p.plugins {
+		if ca, ok := plugin.(ContextAware); ok {
+			ca.SetContext(p.Ctx)
+		}
+	}
+
 	// Phase 1: Discovery - Let plugins analyze the AST
 	for _, plugin := range p.plugins {
 		if err := plugin.Process(file); err != nil {
@@ -79,6 +96,9 @@ func (p *Pipeline) Transform(file *ast.File) (*ast.File, error) {
 					transformed = f
 				}
 			}
+			if p.transformHook != nil {
+				p.transformHook(plugin.Name(), transformed)
+			}
 		}
 	}
 
@@ -309,9 +329,22 @@ type DeclarationProvider interface {
 
 // ReportError reports a compile error to the context
 // Errors are accumulated and can be retrieved later
+func (ctx *Context) ReportError(message string, location token.Pos) {
+	ctx.Report(diagnostic.Diagnostic{
+		Code:     diagnostic.CodeGeneration,
+		Severity: diagnostic.SeverityError,
+		Pos:      ctx.Position(location),
+		Message:  message,
+		Source:   diagnostic.SourceDingo,
+	})
+}
+
+// Report records a diagnostic. Its positions are in the file being
+// transformed (see Position); the transpiler maps them back to the .dingo
+// source.
 //
 // CRITICAL FIX #2: Limits error accumulation to prevent OOM
-func (ctx *Context) ReportError(message string, location token.Pos) {
+func (ctx *Context) Report(d diagnostic.Diagnostic) {
 	if ctx.errors == nil {
 		ctx.errors = make([]error, 0)
 	}
@@ -326,10 +359,23 @@ func (ctx *Context) ReportError(message string, location token.Pos) {
 		return
 	}
 
-	ctx.errors = append(ctx.errors, fmt.Errorf("%s (at position %d)", message, location))
+	if d.Source == "" {
+		d.Source = diagnostic.SourceDingo
+	}
+	ctx.errors = append(ctx.errors, &d)
+}
+
+// Position returns the position of pos in the file being transformed, or the
+// zero position when pos is invalid
+func (ctx *Context) Position(pos token.Pos) token.Position {
+	if ctx == nil || ctx.FileSet == nil || !pos.IsValid() {
+		return token.Position{}
+	}
+	return ctx.FileSet.PositionFor(pos, false)
 }
 
-// GetErrors returns all accumulated compile errors
+// GetErrors returns all accumulated compile errors: *diagnostic.Diagnostic
+// values, followed by a plain error when MaxErrors was exceeded
 func (ctx *Context) GetErrors() []error {
 	if ctx.errors == nil {
 		return []error{}
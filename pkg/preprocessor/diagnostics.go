@@ -0,0 +1,148 @@
+package preprocessor
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/plugin"
+)
+
+// processorCodes gives the diagnostic code for failures of each processor
+// that do not carry a more specific one
+var processorCodes = map[string]string{
+	"rust_match":                 diagnostic.CodeMatch,
+	"lambda":                     diagnostic.CodeLambda,
+	"tuples":                     diagnostic.CodeTuple,
+	"safe_navigation":            diagnostic.CodeSafeNavigation,
+	"null_coalescing":            diagnostic.CodeNullCoalescing,
+	"ternary_operator":           diagnostic.CodeTernary,
+	"error_propagation":          diagnostic.CodeErrorPropagation,
+	"enum":                       diagnostic.CodeEnum,
+	"UnqualifiedImportProcessor": diagnostic.CodeImport,
+}
+
+// lineError reports err at a line of the processor's input. An err that
+// already is a diagnostic keeps its code and column.
+func lineError(code string, line int, err error) *diagnostic.Diagnostic {
+	var d *diagnostic.Diagnostic
+	if errors.As(err, &d) {
+		if d.Pos.Line == 0 {
+			d.Pos.Line = line
+		}
+		if d.Code == "" {
+			d.Code = code
+		}
+		return d
+	}
+	return &diagnostic.Diagnostic{
+		Code:     code,
+		Severity: diagnostic.SeverityError,
+		Pos:      token.Position{Line: line},
+		Message:  err.Error(),
+		Source:   diagnostic.SourcePreprocess,
+	}
+}
+
+// offsetError reports err at a byte offset of the processor's input. The
+// offset of an errorAt failure counts from offset.
+func offsetError(code string, source []byte, offset int, err error) *diagnostic.Diagnostic {
+	var pe *posError
+	if errors.As(err, &pe) {
+		offset += pe.offset
+	}
+	if offset < 0 || offset > len(source) {
+		return lineError(code, 0, err)
+	}
+	before := source[:offset]
+	line := strings.Count(string(before), "\n") + 1
+	col := offset - strings.LastIndex(string(before), "\n")
+	d := lineError(code, line, err)
+	if d.Pos.Column == 0 {
+		d.Pos.Column = col
+	}
+	return d
+}
+
+// posError is a failure at a byte offset of the text a helper parses
+type posError struct {
+	offset int
+	err    error
+}
+
+func (e *posError) Error() string { return e.err.Error() }
+func (e *posError) Unwrap() error { return e.err }
+
+// errorAt reports a failure at a byte offset of the text being parsed.
+// Helpers given part of a larger text move it with shiftError, and the
+// processor places it in its input with offsetError.
+func errorAt(offset int, format string, args ...any) error {
+	return &posError{offset: offset, err: fmt.Errorf(format, args...)}
+}
+
+// shiftError moves the offset of an errorAt failure in err by base
+func shiftError(err error, base int) error {
+	var pe *posError
+	if errors.As(err, &pe) {
+		pe.offset += base
+	}
+	return err
+}
+
+// lineOffset returns the byte offset of line i (0-based) of lines
+func lineOffset(lines []string, i int) int {
+	offset := 0
+	for _, line := range lines[:i] {
+		offset += len(line) + 1
+	}
+	return offset
+}
+
+// processorErrors turns a failure of the named processor into diagnostics at
+// .dingo positions. lines maps the processor's input to the .dingo source.
+// err is a diagnostic.List when the processor recovered from several errors.
+func processorErrors(name string, lines *lineMap, err error) diagnostic.List {
+	code := processorCodes[name]
+	if code == "" {
+		code = diagnostic.CodeSyntax
+	}
+
+	var list diagnostic.List
+	var d *diagnostic.Diagnostic
+	switch {
+	case errors.As(err, &list):
+	case errors.As(err, &d):
+		list = diagnostic.List{d}
+	default:
+		return diagnostic.List{{
+			Code:     code,
+			Severity: diagnostic.SeverityError,
+			Message:  fmt.Sprintf("%s preprocessing failed: %v", name, err),
+			Source:   diagnostic.SourcePreprocess,
+		}}
+	}
+
+	for _, d := range list {
+		if d.Code == "" {
+			d.Code = code
+		}
+		if d.Source == "" {
+			d.Source = diagnostic.SourcePreprocess
+		}
+		lines.mapDiagnostic(d)
+	}
+	return list
+}
+
+// tooManyErrors is appended to the diagnostics of a file once
+// plugin.MaxErrors is reached
+func tooManyErrors() *diagnostic.Diagnostic {
+	return &diagnostic.Diagnostic{
+		Code:     diagnostic.CodeSyntax,
+		Severity: diagnostic.SeverityError,
+		Message:  fmt.Sprintf("too many errors (>%d), stopping error collection", plugin.MaxErrors),
+		Source:   diagnostic.SourcePreprocess,
+	}
+}
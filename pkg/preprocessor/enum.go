@@ -3,9 +3,13 @@ package preprocessor
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
 	"regexp"
 	"sort"
 	"strings"
+	"unicode"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // Package-level compiled regexes for enum processing
@@ -21,6 +25,9 @@ var (
 
 	// Matches tuple variant: Variant(type1, type2, ...)
 	tupleVariantPattern = regexp.MustCompile(`^\s*(\w+)\s*\(([^)]*)\)\s*,?\s*$`)
+
+	// Matches the package clause: package name
+	packageClausePattern = regexp.MustCompile(`(?m)^\s*package\s+(\w+)`)
 )
 
 // EnumProcessor transforms enum declarations into Go sum types
@@ -58,20 +65,29 @@ func (e *EnumProcessor) ProcessInternal(code string) (string, []TransformMetadat
 		return code, nil, nil
 	}
 
+	// Only the enums of packages other packages can import need accessors
+	importable := true
+	if m := packageClausePattern.FindStringSubmatch(code); m != nil && m[1] == "main" {
+		importable = false
+	}
+
 	// Process enums in reverse order to maintain correct offsets
 	result := []byte(code)
+	var errs diagnostic.List
 	for i := len(enums) - 1; i >= 0; i-- {
 		enum := enums[i]
 
 		// Parse variants
 		variants, err := e.parseVariants(enum.body)
 		if err != nil {
-			// Lenient error handling - continue
+			// Keep the enum as written and report the others too
+			d := offsetError(diagnostic.CodeEnum, []byte(code), enum.bodyStart, err)
+			errs = append(diagnostic.List{d}, errs...)
 			continue
 		}
 
 		// Generate Go sum type with marker
-		generated := e.generateSumTypeWithMarker(enum.name, variants, &counter)
+		generated := e.generateSumTypeWithMarker(enum.name, variants, importable, &counter)
 
 		// Replace enum declaration with generated code
 		result = append(result[:enum.start], append([]byte(generated), result[enum.end:]...)...)
@@ -89,16 +105,20 @@ func (e *EnumProcessor) ProcessInternal(code string) (string, []TransformMetadat
 		}
 		metadata = append(metadata, meta)
 	}
+	if len(errs) > 0 {
+		return string(result), metadata, errs
+	}
 
 	return string(result), metadata, nil
 }
 
 // enumDecl represents a parsed enum declaration
 type enumDecl struct {
-	start int
-	end   int
-	name  string
-	body  string
+	start     int
+	end       int
+	name      string
+	body      string
+	bodyStart int // Offset of body in the source
 }
 
 // findEnumDeclarations finds all enum declarations with proper brace matching
@@ -176,10 +196,11 @@ func (e *EnumProcessor) findEnumDeclarations(source []byte) []enumDecl {
 		}
 
 		decls = append(decls, enumDecl{
-			start: idx,
-			end:   enumEnd,
-			name:  enumName,
-			body:  body,
+			start:     idx,
+			end:       enumEnd,
+			name:      enumName,
+			body:      body,
+			bodyStart: braceStart + 1,
 		})
 
 		pos = braceEnd + 1
@@ -243,14 +264,18 @@ type Field struct {
 	Type string
 }
 
-// parseVariants parses the enum body into variants
+// parseVariants parses the enum body into variants. Errors are at offsets
+// of body.
 func (e *EnumProcessor) parseVariants(body string) ([]Variant, error) {
 	variants := []Variant{}
 
 	// Split by lines or commas
 	lines := strings.Split(body, "\n")
 
+	offset := 0 // Of the line in body
 	for _, line := range lines {
+		at := offset + len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
+		offset += len(line) + 1
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "//") {
 			continue
@@ -263,7 +288,7 @@ func (e *EnumProcessor) parseVariants(body string) ([]Variant, error) {
 
 			fields, err := e.parseFields(fieldsStr)
 			if err != nil {
-				return nil, fmt.Errorf("failed to parse fields for variant %s: %w", variantName, err)
+				return nil, errorAt(at, "failed to parse fields for variant %s: %v", variantName, err)
 			}
 
 			variants = append(variants, Variant{
@@ -280,7 +305,7 @@ func (e *EnumProcessor) parseVariants(body string) ([]Variant, error) {
 
 			fields, err := e.parseTupleFields(typesStr)
 			if err != nil {
-				return nil, fmt.Errorf("failed to parse tuple for variant %s: %w", variantName, err)
+				return nil, errorAt(at, "failed to parse tuple for variant %s: %v", variantName, err)
 			}
 
 			variants = append(variants, Variant{
@@ -302,7 +327,7 @@ func (e *EnumProcessor) parseVariants(body string) ([]Variant, error) {
 	}
 
 	if len(variants) == 0 {
-		return nil, fmt.Errorf("no variants found")
+		return nil, errorAt(-1, "no variants found") // At the opening brace
 	}
 
 	return variants, nil
@@ -372,8 +397,9 @@ func (e *EnumProcessor) parseTupleFields(typesStr string) ([]Field, error) {
 	return fields, nil
 }
 
-// generateSumType generates Go sum type code from enum definition
-func (e *EnumProcessor) generateSumType(enumName string, variants []Variant) string {
+// generateSumType generates Go sum type code from enum definition. importable
+// reports whether other packages can import the enum's package.
+func (e *EnumProcessor) generateSumType(enumName string, variants []Variant, importable bool) string {
 	var buf bytes.Buffer
 
 	// 1. Generate tag type
@@ -409,40 +435,9 @@ func (e *EnumProcessor) generateSumType(enumName string, variants []Variant) str
 	fieldMap := make(map[string]string) // fieldName -> fieldType (for deduplication)
 
 	for _, variant := range variants {
-		if len(variant.Fields) > 0 {
-			// Determine field naming strategy for this variant
-			isSingleTupleVariant := len(variant.Fields) == 1 &&
-				len(variant.Fields[0].Name) > 0 &&
-				variant.Fields[0].Name[0] >= '0' &&
-				variant.Fields[0].Name[0] <= '9'
-
-			for fieldIdx, field := range variant.Fields {
-				var fieldName string
-				isTupleField := len(field.Name) > 0 && field.Name[0] >= '0' && field.Name[0] <= '9'
-
-				if isSingleTupleVariant {
-					// Single tuple field - use variant name (e.g., "ok", "err", "some")
-					fieldName = strings.ToLower(variant.Name)
-				} else if isTupleField {
-					// Multiple tuple fields - use proper naming convention
-					// First field: lowercase variant name (no suffix)
-					// Second field: lowercase variant name + "1"
-					// Third field: lowercase variant name + "2"
-					// etc.
-					baseName := strings.ToLower(variant.Name)
-					if fieldIdx == 0 {
-						fieldName = baseName // First field: no suffix
-					} else {
-						fieldName = fmt.Sprintf("%s%d", baseName, fieldIdx) // 2nd+ fields: suffix with index (1, 2, 3...)
-					}
-				} else {
-					// Struct variant with named fields - use variant_fieldname
-					fieldName = strings.ToLower(variant.Name) + "_" + field.Name
-				}
-
-				// Add to field map (deduplicates if same field used in multiple variants)
-				fieldMap[fieldName] = field.Type
-			}
+		for fieldIdx, field := range variant.Fields {
+			// Add to field map (deduplicates if same field used in multiple variants)
+			fieldMap[variantFieldName(variant, fieldIdx)] = field.Type
 		}
 	}
 
@@ -476,38 +471,16 @@ func (e *EnumProcessor) generateSumType(enumName string, variants []Variant) str
 			params := []string{}
 			assignments := []string{}
 
-			// CRITICAL: Use same field naming strategy as struct generation above
-			isSingleTupleVariant := len(variant.Fields) == 1 &&
-				len(variant.Fields[0].Name) > 0 &&
-				variant.Fields[0].Name[0] >= '0' &&
-				variant.Fields[0].Name[0] <= '9'
-
 			for fieldIdx, field := range variant.Fields {
 				// Determine parameter name
 				paramName := field.Name
-				isTupleField := len(field.Name) > 0 && field.Name[0] >= '0' && field.Name[0] <= '9'
-				if isTupleField {
+				if isTupleField(field) {
 					// Tuple field - numeric name like "0", "1" → "arg0", "arg1"
 					paramName = "arg" + field.Name
 				}
 
-				// Determine field name using SAME logic as struct generation
-				var fieldName string
-				if isSingleTupleVariant {
-					// Single tuple field - use variant name (e.g., "ok", "err", "some")
-					fieldName = strings.ToLower(variant.Name)
-				} else if isTupleField {
-					// Multiple tuple fields - use proper naming convention (first, second1, third2)
-					baseName := strings.ToLower(variant.Name)
-					if fieldIdx == 0 {
-						fieldName = baseName // First field: no suffix
-					} else {
-						fieldName = fmt.Sprintf("%s%d", baseName, fieldIdx) // 2nd+ fields: suffix 1, 2, 3
-					}
-				} else {
-					// Struct variant with named fields - use variant_fieldname
-					fieldName = strings.ToLower(variant.Name) + "_" + field.Name
-				}
+				// CRITICAL: Use same field naming strategy as struct generation above
+				fieldName := variantFieldName(variant, fieldIdx)
 
 				params = append(params, fmt.Sprintf("%s %s", paramName, field.Type))
 				assignments = append(assignments, fmt.Sprintf("%s: &%s", fieldName, paramName))
@@ -529,16 +502,65 @@ func (e *EnumProcessor) generateSumType(enumName string, variants []Variant) str
 		buf.WriteString("}\n")
 	}
 
-	// 6. Generate Map and AndThen methods for Option/Result-like enums
+	// 6. Generate accessors returning the values of variants with fields, so
+	// that matches in other packages can bind them (the fields are unexported).
+	// Only exported enums of importable packages are matched elsewhere.
+	// Results are named after the struct fields they read; like Unwrap, an
+	// accessor panics on another variant.
+	for _, variant := range variants {
+		if !importable || !ast.IsExported(enumName) || len(variant.Fields) == 0 {
+			continue
+		}
+		tagConstName := fmt.Sprintf("%s%s", tagTypeName, variant.Name)
+		results := make([]string, len(variant.Fields))
+		values := make([]string, len(variant.Fields))
+		for fieldIdx, field := range variant.Fields {
+			fieldName := variantFieldName(variant, fieldIdx)
+			results[fieldIdx] = fmt.Sprintf("%s %s", fieldName, field.Type)
+			values[fieldIdx] = "*e." + fieldName
+		}
+		buf.WriteString(fmt.Sprintf("// %s returns the fields of the %s variant. It panics if e is another variant.\n", variant.Name, variant.Name))
+		buf.WriteString(fmt.Sprintf("func (e %s) %s() (%s) {\n", enumName, variant.Name, strings.Join(results, ", ")))
+		buf.WriteString(fmt.Sprintf("\tif e.tag != %s {\n", tagConstName))
+		buf.WriteString(fmt.Sprintf("\t\tpanic(\"called %s on a %s that is not %s\")\n", variant.Name, enumName, variant.Name))
+		buf.WriteString("\t}\n")
+		buf.WriteString(fmt.Sprintf("\treturn %s\n", strings.Join(values, ", ")))
+		buf.WriteString("}\n")
+	}
+
+	// 7. Generate Map and AndThen methods for Option/Result-like enums
 	e.generateHelperMethods(&buf, enumName, tagTypeName, variants)
 
 	return buf.String()
 }
 
+// variantFieldName returns the struct field holding a variant's field:
+//   - Single-field tuple variants: lowercase variant name (ok, err, some)
+//   - Multi-field tuple variants: lowercase variant name, suffixed from the
+//     second field on (first, first1, first2)
+//   - Struct variants: variant_fieldname
+func variantFieldName(variant Variant, fieldIdx int) string {
+	field := variant.Fields[fieldIdx]
+	baseName := strings.ToLower(variant.Name)
+	switch {
+	case !isTupleField(field):
+		return baseName + "_" + field.Name
+	case len(variant.Fields) == 1 || fieldIdx == 0:
+		return baseName
+	default:
+		return fmt.Sprintf("%s%d", baseName, fieldIdx)
+	}
+}
+
+// isTupleField reports whether a variant field is positional (named 0, 1, ...)
+func isTupleField(field Field) bool {
+	return len(field.Name) > 0 && field.Name[0] >= '0' && field.Name[0] <= '9'
+}
+
 // generateSumTypeWithMarker generates Go sum type code with marker support
-func (e *EnumProcessor) generateSumTypeWithMarker(enumName string, variants []Variant, markerCounter *int) string {
+func (e *EnumProcessor) generateSumTypeWithMarker(enumName string, variants []Variant, importable bool, markerCounter *int) string {
 	// Generate the sum type using existing method
-	generated := e.generateSumType(enumName, variants)
+	generated := e.generateSumType(enumName, variants, importable)
 
 	// Insert marker
 	marker := fmt.Sprintf("// dingo:n:%d\n", *markerCounter)
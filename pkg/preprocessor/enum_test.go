@@ -152,6 +152,50 @@ enum Shape {
 	}
 }
 
+func TestEnumProcessor_Accessors(t *testing.T) {
+	const enum = `
+enum Shape {
+	Point,
+	Circle { radius: float64 },
+	Rectangle { width: float64, height: float64 },
+}
+`
+	tests := []struct {
+		name      string
+		source    string
+		accessors bool
+	}{
+		{"importable package", "package shapes\n" + enum, true},
+		{"main package", "package main\n" + enum, false},
+		{"unexported enum", "package shapes\n" + strings.Replace(enum, "Shape", "shape", 1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _, err := NewEnumProcessor().Process([]byte(tt.source))
+			if err != nil {
+				t.Fatalf("Process() failed: %v", err)
+			}
+			output := string(result)
+
+			accessor := ") Rectangle() (rectangle_width float64, rectangle_height float64) {\n" +
+				"\tif e.tag != "
+			if got := strings.Contains(output, accessor); got != tt.accessors {
+				t.Errorf("Rectangle accessor generated = %v, want %v\n%s", got, tt.accessors, output)
+			}
+			if tt.accessors && !strings.Contains(output, `panic("called Rectangle on a Shape that is not Rectangle")`) {
+				t.Errorf("Rectangle accessor does not panic on other variants:\n%s", output)
+			}
+			if strings.Contains(output, ") Point()") {
+				t.Error("Unexpected accessor for the Point variant without fields")
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), "", result, parser.AllErrors); err != nil {
+				t.Errorf("Generated code does not compile: %v\n%s", err, output)
+			}
+		})
+	}
+}
+
 func TestEnumProcessor_GenericEnum(t *testing.T) {
 	source := `package main
 
@@ -10,6 +10,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // Package-level compiled regexes (Issue 2: Regex Performance)
@@ -189,15 +191,11 @@ func (e *ErrorPropProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 // ProcessV2 implements FeatureProcessorV2 interface with metadata support
 func (e *ErrorPropProcessor) ProcessV2(source []byte) (ProcessResult, error) {
 	transformed, metadata, err := e.ProcessInternal(string(source))
-	if err != nil {
-		return ProcessResult{}, err
-	}
-
 	return ProcessResult{
 		Source:   []byte(transformed),
 		Mappings: nil, // We use metadata instead of legacy mappings
 		Metadata: metadata,
-	}, nil
+	}, err
 }
 
 // ProcessInternal transforms error propagation operators with metadata support
@@ -215,6 +213,7 @@ func (e *ErrorPropProcessor) ProcessInternal(code string) (string, []TransformMe
 	outputLineNum := 1          // Track current output line number (1-based)
 	markerCounter := 0          // Counter for unique markers
 	var metadata []TransformMetadata // Collect metadata
+	var errs diagnostic.List
 
 	for inputLineNum < len(e.lines) {
 		line := e.lines[inputLineNum]
@@ -228,7 +227,9 @@ func (e *ErrorPropProcessor) ProcessInternal(code string) (string, []TransformMe
 		// Process the line with metadata collection
 		transformed, meta, err := e.processLineWithMetadata(line, inputLineNum+1, outputLineNum, &markerCounter)
 		if err != nil {
-			return "", nil, fmt.Errorf("line %d: %w", inputLineNum+1, err)
+			// Keep the statement as written and report the rest of the file too
+			errs = append(errs, lineError(diagnostic.CodeErrorPropagation, inputLineNum+1, err))
+			transformed, meta = line, nil
 		}
 		output.WriteString(transformed)
 		if inputLineNum < len(e.lines)-1 {
@@ -251,7 +252,7 @@ func (e *ErrorPropProcessor) ProcessInternal(code string) (string, []TransformMe
 	result := output.String()
 
 	// DEBUG: Check if markers are in output
-	return result, metadata, nil
+	return result, metadata, errs.Err()
 }
 
 // GetNeededImports implements the ImportProvider interface
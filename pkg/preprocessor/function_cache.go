@@ -8,6 +8,7 @@ import (
 	"go/token"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -93,6 +94,36 @@ func (c *FunctionExclusionCache) IsLocalSymbol(name string) bool {
 // ScanPackage scans all files in the package and builds the exclusion list (Tier 3: full rescan)
 // Uses go/parser for 100% accuracy. Time: ~50ms for 10 files.
 func (c *FunctionExclusionCache) ScanPackage(files []string) error {
+	sources := make([]packageSource, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("scanning %s: %w", file, err)
+		}
+		sources = append(sources, packageSource{path: file, content: content})
+	}
+	return c.scanSources(sources)
+}
+
+// ScanSources is like ScanPackage but takes file contents from memory
+// (path → source) instead of reading them from disk
+func (c *FunctionExclusionCache) ScanSources(files map[string][]byte) error {
+	sources := make([]packageSource, 0, len(files))
+	for path, content := range files {
+		sources = append(sources, packageSource{path: path, content: content})
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].path < sources[j].path })
+	return c.scanSources(sources)
+}
+
+// packageSource is one file handed to scanSources
+type packageSource struct {
+	path    string
+	content []byte
+}
+
+// scanSources rebuilds the exclusion list from the given file contents
+func (c *FunctionExclusionCache) scanSources(files []packageSource) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -106,16 +137,16 @@ func (c *FunctionExclusionCache) ScanPackage(files []string) error {
 
 	// Scan each file
 	for _, file := range files {
-		symbols, hash, err := c.scanFile(file)
+		symbols, hash, err := c.scanContent(file.path, file.content)
 		if err != nil {
-			return fmt.Errorf("scanning %s: %w", file, err)
+			return fmt.Errorf("scanning %s: %w", file.path, err)
 		}
 
 		// Store file hash
-		c.fileHashes[file] = hash
+		c.fileHashes[file.path] = hash
 
 		// Store symbols for this file
-		c.symbolsByFile[file] = symbols
+		c.symbolsByFile[file.path] = symbols
 
 		// Add to global exclusion list
 		for _, sym := range symbols {
@@ -123,11 +154,8 @@ func (c *FunctionExclusionCache) ScanPackage(files []string) error {
 		}
 
 		// Check for unqualified patterns (early bailout optimization)
-		if !c.hasUnqualifiedImports {
-			content, _ := os.ReadFile(file)
-			if containsUnqualifiedPattern(content) {
-				c.hasUnqualifiedImports = true
-			}
+		if !c.hasUnqualifiedImports && containsUnqualifiedPattern(file.content) {
+			c.hasUnqualifiedImports = true
 		}
 	}
 
@@ -138,14 +166,8 @@ func (c *FunctionExclusionCache) ScanPackage(files []string) error {
 	return nil
 }
 
-// scanFile parses a single file and extracts function declarations
-// Returns: symbols, file hash, error
-func (c *FunctionExclusionCache) scanFile(filePath string) ([]string, uint64, error) {
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, 0, err
-	}
-
+// scanContent extracts top-level function declarations from file content
+func (c *FunctionExclusionCache) scanContent(filePath string, content []byte) ([]string, uint64, error) {
 	// Calculate hash for invalidation
 	hash := xxhash.Sum64(content)
 
@@ -371,6 +393,27 @@ func (c *FunctionExclusionCache) HasUnqualifiedImports() bool {
 	return c.hasUnqualifiedImports
 }
 
+// Fingerprint identifies what the cache tells a preprocessor: the local
+// functions and whether the package has unqualified stdlib calls. Caches
+// with the same fingerprint preprocess a file the same way.
+func (c *FunctionExclusionCache) Fingerprint() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.localFunctions))
+	for name := range c.localFunctions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := xxhash.New()
+	for _, name := range names {
+		h.WriteString(name + "\n")
+	}
+	if c.hasUnqualifiedImports {
+		h.WriteString("unqualified")
+	}
+	return h.Sum64()
+}
+
 // containsUnqualifiedPattern checks if content has potential unqualified stdlib calls
 // This is a quick heuristic for the early bailout optimization.
 // Pattern: Capitalized function call (e.g., ReadFile(...), Printf(...))
@@ -3,11 +3,12 @@ package preprocessor
 import (
 	"bytes"
 	"fmt"
+	"go/token"
 	"regexp"
 	"strings"
 
 	"github.com/MadAppGang/dingo/pkg/config"
-	dingoerrors "github.com/MadAppGang/dingo/pkg/errors"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // Package-level compiled regexes for lambda transformations
@@ -43,7 +44,7 @@ const (
 // - Rust pipes: |x| expr, |x, y| expr, |x: int| expr, |x: int| -> bool { ... }
 type LambdaProcessor struct {
 	style              LambdaStyle
-	errors             []*dingoerrors.EnhancedError
+	errors             diagnostic.List
 	strictTypeChecking bool // TODO(v1.1): Enable strict type checking via dingo.toml config
 }
 
@@ -139,12 +140,8 @@ func (l *LambdaProcessor) ProcessInternal(code string) (string, []TransformMetad
 		}
 	}
 
-	// If we collected errors, return them
-	if len(l.errors) > 0 {
-		return "", nil, l.errors[0]
-	}
-
-	return result.String(), metadata, nil
+	// Every untyped lambda is reported, not just the first
+	return result.String(), metadata, l.errors.Err()
 }
 
 
@@ -252,7 +249,7 @@ func (l *LambdaProcessor) processSingleParamArrow(line []byte, lineNum int) []by
 			if len(param) > 0 && !bytes.Contains(param, []byte(" ")) && !isInCallContext {
 				// param is just "x" not "x int", and it's not in a call context
 				// This indicates missing type information for standalone lambda
-				l.addTypeInferenceError(lineNum, matchStart, string(line), string(param))
+				l.addTypeInferenceError(lineNum, loc[4], string(param))
 			}
 		}
 
@@ -339,7 +336,7 @@ func (l *LambdaProcessor) processMultiParamArrow(line []byte, lineNum int) []byt
 			isInCallContext := len(prefix) > 0 && prefix[len(prefix)-1] == '('
 
 			if l.hasUntypedParams(params) && !isInCallContext {
-				l.addTypeInferenceError(lineNum, matchStart, string(line), string(params))
+				l.addTypeInferenceError(lineNum, loc[4]+bytes.Index(line[loc[4]:loc[5]], params), string(params))
 			}
 		}
 
@@ -427,7 +424,7 @@ func (l *LambdaProcessor) processRustPipe(line []byte, lineNum int) []byte {
 			isInCallContext := len(prefix) > 0 && prefix[len(prefix)-1] == '('
 
 			if l.hasUntypedParams(params) && !isInCallContext {
-				l.addTypeInferenceError(lineNum, matchStart, string(line), string(params))
+				l.addTypeInferenceError(lineNum, loc[4]+bytes.Index(line[loc[4]:loc[5]], params), string(params))
 			}
 		}
 
@@ -531,8 +528,9 @@ func (l *LambdaProcessor) hasUntypedParams(params []byte) bool {
 	return false
 }
 
-// addTypeInferenceError creates and stores a type inference error
-func (l *LambdaProcessor) addTypeInferenceError(lineNum, column int, lineText, params string) {
+// addTypeInferenceError records a type inference error for the untyped
+// params starting at byte paramsStart of line lineNum
+func (l *LambdaProcessor) addTypeInferenceError(lineNum, paramsStart int, params string) {
 	// Create error message based on style
 	var exampleSyntax string
 	switch l.style {
@@ -550,25 +548,21 @@ func (l *LambdaProcessor) addTypeInferenceError(lineNum, column int, lineText, p
 		}
 	}
 
-	message := "Cannot infer lambda parameter type"
-	annotation := "Missing type annotation"
-	suggestion := fmt.Sprintf("Add explicit type annotation. Example: %s", exampleSyntax)
-
-	// Create enhanced error (without token.FileSet for preprocessor)
-	// We'll create a simple error structure
-	err := &dingoerrors.EnhancedError{
-		Message:     message,
-		Filename:    "source.dingo", // Will be set by caller if available
-		Line:        lineNum,
-		Column:      column + 1, // Convert 0-indexed to 1-indexed
-		Length:      len(params),
-		SourceLines: []string{lineText},
-		HighlightLine: 0,
-		Annotation:  annotation,
-		Suggestion:  suggestion,
-	}
-
-	l.errors = append(l.errors, err)
+	// Positions are in this processor's input; the file is set by the transpiler
+	pos := token.Position{Line: lineNum, Column: paramsStart + 1} // Convert 0-indexed to 1-indexed
+	end := token.Position{Line: lineNum, Column: paramsStart + 1 + len(params)}
+	l.errors = append(l.errors, &diagnostic.Diagnostic{
+		Code:     diagnostic.CodeLambda,
+		Severity: diagnostic.SeverityError,
+		Pos:      pos,
+		End:      end,
+		Message:  "Cannot infer lambda parameter type",
+		Source:   diagnostic.SourcePreprocess,
+		Labels:   []diagnostic.Label{{Pos: pos, End: end, Message: "Missing type annotation"}},
+		Fixes: []diagnostic.Fix{
+			{Message: fmt.Sprintf("Add explicit type annotation. Example: %s", exampleSyntax)},
+		},
+	})
 }
 
 // processLambdaBody handles lambda body transformation
@@ -1,8 +1,11 @@
 package preprocessor
 
 import (
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // TestLambdaErrorDetection_TypeScriptStyle tests error detection for TypeScript arrow syntax
@@ -214,6 +217,27 @@ func TestLambdaErrorMessages(t *testing.T) {
 	}
 }
 
+// TestLambdaErrorDiagnostic tests that the error is a structured diagnostic
+// pointing at the untyped parameters
+func TestLambdaErrorDiagnostic(t *testing.T) {
+	processor := &LambdaProcessor{style: StyleTypeScript, strictTypeChecking: true}
+	_, _, err := processor.Process([]byte("package main\n\nlet f = x => x * 2"))
+
+	var d *diagnostic.Diagnostic
+	if !errors.As(err, &d) {
+		t.Fatalf("Expected a *diagnostic.Diagnostic, got %T: %v", err, err)
+	}
+	if d.Code != diagnostic.CodeLambda {
+		t.Errorf("Code = %q, want %q", d.Code, diagnostic.CodeLambda)
+	}
+	if d.Pos.Line != 3 || d.Pos.Column != 9 || d.End.Column != 10 {
+		t.Errorf("Range = %d:%d-%d, want 3:9-10", d.Pos.Line, d.Pos.Column, d.End.Column)
+	}
+	if len(d.Fixes) != 1 || !strings.Contains(d.Fixes[0].Message, "(x: int) => x * 2") {
+		t.Errorf("Fixes = %+v, want the typed example", d.Fixes)
+	}
+}
+
 // TestLambdaNoErrorForValidSyntax ensures valid lambdas don't trigger errors
 func TestLambdaNoErrorForValidSyntax(t *testing.T) {
 	validCases := []struct {
@@ -0,0 +1,311 @@
+package preprocessor
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+)
+
+// lineMap maps the lines of code derived from a .dingo source back to it.
+// Every processor's output is compared with its input line by line: lines it
+// kept keep their origin, lines it rewrote take the origins of the lines they
+// replace in order, and lines it inserted the origin of the line above.
+type lineMap struct {
+	source  []string // Lines of the .dingo source
+	code    []string // Lines of the derived code
+	origins []int    // .dingo line of each line of code
+}
+
+// newLineMap returns the identity map of source
+func newLineMap(source []byte) *lineMap {
+	lines := strings.Split(string(source), "\n")
+	origins := make([]int, len(lines))
+	for i := range origins {
+		origins[i] = i + 1
+	}
+	return &lineMap{source: lines, code: lines, origins: origins}
+}
+
+// update returns the map of output, which a processor produced from the code
+// of m
+func (m *lineMap) update(output []byte) *lineMap {
+	lines := strings.Split(string(output), "\n")
+	kept := keptLines(m.code, lines)
+	origins := make([]int, len(lines))
+	prev := -1 // Line of m.code kept last
+	for j := 0; j < len(lines); {
+		if kept[j] >= 0 {
+			prev = kept[j]
+			origins[j] = m.origins[prev]
+			j++
+			continue
+		}
+		next := len(m.code)
+		end := j
+		for end < len(lines) && kept[end] < 0 {
+			end++
+		}
+		if end < len(lines) {
+			next = kept[end]
+		}
+		replaced := next - prev - 1
+		for i := 0; j < end; i, j = i+1, j+1 {
+			switch {
+			case replaced > 0:
+				origins[j] = m.origins[prev+1+min(i, replaced-1)]
+			case prev >= 0:
+				origins[j] = m.origins[prev]
+			case next < len(m.code):
+				origins[j] = m.origins[next]
+			default:
+				origins[j] = 1
+			}
+		}
+	}
+	return &lineMap{source: m.source, code: lines, origins: origins}
+}
+
+// line returns the .dingo line of a line of code. Lines out of range are
+// returned as is.
+func (m *lineMap) line(line int) int {
+	if line < 1 || line > len(m.origins) {
+		return line
+	}
+	return m.origins[line-1]
+}
+
+// exact reports whether a line of code has the text of its .dingo line, so
+// that its columns are .dingo columns too
+func (m *lineMap) exact(line int) bool {
+	if line < 1 || line > len(m.code) {
+		return false
+	}
+	origin := m.origins[line-1]
+	return origin <= len(m.source) && m.code[line-1] == m.source[origin-1]
+}
+
+// position returns the .dingo line and column of a position in the code.
+// Columns of rewritten lines become the start of their .dingo line; column 0
+// stays the whole line.
+func (m *lineMap) position(line, col int) (int, int) {
+	origin := m.line(line)
+	if col == 0 || m.exact(line) {
+		return origin, col
+	}
+	if origin >= 1 && origin <= len(m.source) {
+		text := m.source[origin-1]
+		return origin, len(text) - len(strings.TrimLeft(text, " \t")) + 1
+	}
+	return origin, 0
+}
+
+// mapRange maps a range in place and reports whether it mapped exactly. An
+// inexact end is cleared; a position with Line 0 stays the whole file.
+func (m *lineMap) mapRange(pos, end *token.Position) bool {
+	if pos.Line == 0 {
+		*end = token.Position{}
+		return false
+	}
+	startExact := m.exact(pos.Line)
+	pos.Line, pos.Column = m.position(pos.Line, pos.Column)
+	pos.Offset = 0
+	if end.Line == 0 {
+		return startExact
+	}
+	endExact := m.exact(end.Line)
+	end.Line, end.Column = m.position(end.Line, end.Column)
+	end.Offset = 0
+	if !startExact || !endExact {
+		*end = token.Position{}
+		return false
+	}
+	return true
+}
+
+// mapDiagnostic maps every position of d from the code to the .dingo source.
+// Ranges are kept only where the text is unchanged; edits that cannot be
+// mapped exactly are dropped, leaving their fix as advice.
+func (m *lineMap) mapDiagnostic(d *diagnostic.Diagnostic) {
+	m.mapRange(&d.Pos, &d.End)
+
+	labels := make([]diagnostic.Label, len(d.Labels))
+	for i, l := range d.Labels {
+		m.mapRange(&l.Pos, &l.End)
+		labels[i] = l
+	}
+	d.Labels = labels
+
+	fixes := make([]diagnostic.Fix, len(d.Fixes))
+	for i, fix := range d.Fixes {
+		var edits []diagnostic.Edit
+		for _, e := range fix.Edits {
+			if m.mapRange(&e.Pos, &e.End) {
+				edits = append(edits, e)
+			}
+		}
+		fixes[i] = diagnostic.Fix{Message: fix.Message, Edits: edits}
+	}
+	d.Fixes = fixes
+}
+
+// keptLines returns, for each line of b, the index of the line of a it keeps
+// when a is edited into b, or -1 for lines b inserts
+func keptLines(a, b []string) []int {
+	kept := make([]int, len(b))
+	for i := range kept {
+		kept[i] = -1
+	}
+	matchLines(a, b, 0, 0, kept)
+	return kept
+}
+
+// matchLines records in kept the lines a and b share, where a and b start at
+// lines a0 and b0 of the whole texts. Lines that occur once on each side
+// anchor the match (patience diff), so that files with many scattered edits
+// stay fast; the lines between anchors are matched with commonLines.
+func matchLines(a, b []string, a0, b0 int, kept []int) {
+	// Common ends need no search
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		kept[b0] = a0
+		a, b, a0, b0 = a[1:], b[1:], a0+1, b0+1
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		kept[b0+len(b)-1] = a0 + len(a) - 1
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	if len(a) == 0 || len(b) == 0 {
+		return
+	}
+
+	anchors := uniqueAnchors(a, b)
+	if len(anchors) == 0 {
+		for _, p := range commonLines(a, b) {
+			kept[b0+p[1]] = a0 + p[0]
+		}
+		return
+	}
+	i, j := 0, 0
+	for _, p := range anchors {
+		matchLines(a[i:p[0]], b[j:p[1]], a0+i, b0+j, kept)
+		kept[b0+p[1]] = a0 + p[0]
+		i, j = p[0]+1, p[1]+1
+	}
+	matchLines(a[i:], b[j:], a0+i, b0+j, kept)
+}
+
+// uniqueAnchors returns the longest run of pairs of indexes, increasing on
+// both sides, of lines that occur exactly once in a and once in b
+func uniqueAnchors(a, b []string) [][2]int {
+	type occurrences struct{ inA, inB, j int }
+	lines := make(map[string]*occurrences, len(a))
+	for _, line := range a {
+		o := lines[line]
+		if o == nil {
+			o = &occurrences{}
+			lines[line] = o
+		}
+		o.inA++
+	}
+	for j, line := range b {
+		if o := lines[line]; o != nil {
+			o.inB++
+			o.j = j
+		}
+	}
+	var pairs [][2]int
+	for i, line := range a {
+		if o := lines[line]; o.inA == 1 && o.inB == 1 {
+			pairs = append(pairs, [2]int{i, o.j})
+		}
+	}
+
+	// Longest increasing subsequence of the b indexes, by patience sorting:
+	// tails[n] ends the best run of length n+1 found so far
+	var tails []int
+	prev := make([]int, len(pairs))
+	for k, p := range pairs {
+		n := sort.Search(len(tails), func(t int) bool { return pairs[tails[t]][1] > p[1] })
+		prev[k] = -1
+		if n > 0 {
+			prev[k] = tails[n-1]
+		}
+		if n == len(tails) {
+			tails = append(tails, k)
+		} else {
+			tails[n] = k
+		}
+	}
+	if len(tails) == 0 {
+		return nil
+	}
+	anchors := make([][2]int, len(tails))
+	for k, n := tails[len(tails)-1], len(tails)-1; n >= 0; k, n = prev[k], n-1 {
+		anchors[n] = pairs[k]
+	}
+	return anchors
+}
+
+// commonLines returns the pairs of indexes of the lines a and b share in a
+// shortest edit script, found with Myers' O(ND) algorithm
+func commonLines(a, b []string) [][2]int {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return nil
+	}
+
+	// v[k] is the furthest x reached on diagonal k = x-y; trace[d] holds the
+	// diagonals -d..d of v before step d
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	steps := -1
+	for d := 0; d <= n+m && steps < 0; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				steps = d
+				break
+			}
+		}
+	}
+
+	// Walk the edits back from the end, collecting the diagonal moves
+	var pairs [][2]int
+	x, y := n, m
+	for d := steps; d > 0; d-- {
+		prev := trace[d]
+		at := func(k int) int { return prev[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			pairs = append(pairs, [2]int{x, y})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		x, y = x-1, y-1
+		pairs = append(pairs, [2]int{x, y})
+	}
+	return pairs
+}
@@ -0,0 +1,136 @@
+package preprocessor
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestLineMapUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		outputs []string // Successive processor outputs
+		want    []int
+	}{
+		{
+			name:    "unchanged",
+			source:  "a\nb\nc",
+			outputs: []string{"a\nb\nc"},
+			want:    []int{1, 2, 3},
+		},
+		{
+			name:    "duplicate lines keep their own origin",
+			source:  "a\nX\nX",
+			outputs: []string{"a\nnew\nX\nX"},
+			want:    []int{1, 1, 2, 3},
+		},
+		{
+			name:    "expanded line",
+			source:  "a\nx?\nb",
+			outputs: []string{"a\ntmp\nif err\n}\nx\nb"},
+			want:    []int{1, 2, 2, 2, 2, 3},
+		},
+		{
+			name:    "rewritten block",
+			source:  "a\nmatch {\nOk\nErr\n}\nb",
+			outputs: []string{"a\nswitch {\ncase 1\nx\ncase 2\ny\n}\nb"},
+			want:    []int{1, 2, 3, 4, 4, 4, 5, 6},
+		},
+		{
+			name:    "inserted at the top",
+			source:  "package p\nfunc f()",
+			outputs: []string{"import \"os\"\npackage p\nfunc f()"},
+			want:    []int{1, 1, 2},
+		},
+		{
+			name:    "composed",
+			source:  "a\nx?\nb\nb",
+			outputs: []string{"a\ntmp\nx\nb\nb", "import\na\ntmp\nx\nb\nb"},
+			want:    []int{1, 1, 2, 2, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newLineMap([]byte(tt.source))
+			for _, out := range tt.outputs {
+				m = m.update([]byte(out))
+			}
+			if !slices.Equal(m.origins, tt.want) {
+				t.Errorf("origins = %v, want %v", m.origins, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineMapPosition(t *testing.T) {
+	m := newLineMap([]byte("a\n\tx := f()?\n\ty := 1")).update([]byte("a\n\ttmp, err := f()\n\tx := tmp\n\ty := 1"))
+
+	tests := []struct {
+		line, col         int
+		wantLine, wantCol int
+	}{
+		{4, 7, 3, 7}, // Unchanged line keeps its column
+		{2, 7, 2, 2}, // Rewritten line starts at the .dingo text
+		{3, 0, 2, 0}, // Column 0 stays the whole line
+	}
+	for _, tt := range tests {
+		line, col := m.position(tt.line, tt.col)
+		if line != tt.wantLine || col != tt.wantCol {
+			t.Errorf("position(%d, %d) = %d:%d, want %d:%d", tt.line, tt.col, line, col, tt.wantLine, tt.wantCol)
+		}
+	}
+}
+
+// TestKeptLines checks that keptLines keeps a common subsequence of the lines,
+// and that commonLines finds a longest one
+func TestKeptLines(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, r.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(6)))
+		}
+		return lines
+	}
+
+	for range 2000 {
+		a, b := random(), random()
+
+		last := -1
+		for j, i := range keptLines(a, b) {
+			if i < 0 {
+				continue
+			}
+			if i <= last || a[i] != b[j] {
+				t.Fatalf("keptLines(%q, %q): line %d kept from %d", a, b, j, i)
+			}
+			last = i
+		}
+
+		// Longest common subsequence by dynamic programming
+		lcs := make([][]int, len(a)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		common := commonLines(a, b)
+		for k, p := range common {
+			if a[p[0]] != b[p[1]] || (k > 0 && (p[0] >= common[k-1][0] || p[1] >= common[k-1][1])) {
+				t.Fatalf("commonLines(%q, %q) = %v: not a common subsequence", a, b, common)
+			}
+		}
+		if len(common) != lcs[0][0] {
+			t.Fatalf("commonLines(%q, %q) shares %d lines, want %d", a, b, len(common), lcs[0][0])
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // NullCoalesceProcessor handles the ?? operator for null coalescing
@@ -61,6 +63,7 @@ func (n *NullCoalesceProcessor) ProcessInternal(code string) (string, []Transfor
 
 	inputLineNum := 0
 	outputLineNum := 1
+	var errs diagnostic.List
 
 	for inputLineNum < len(lines) {
 		line := lines[inputLineNum]
@@ -68,7 +71,9 @@ func (n *NullCoalesceProcessor) ProcessInternal(code string) (string, []Transfor
 		// Process the line with metadata
 		transformed, meta, err := n.processLineWithMetadata(line, inputLineNum+1, outputLineNum, &counter)
 		if err != nil {
-			return "", nil, fmt.Errorf("line %d: %w", inputLineNum+1, err)
+			// Keep the line as written and report the rest of the file too
+			errs = append(errs, lineError(diagnostic.CodeNullCoalescing, inputLineNum+1, err))
+			transformed, meta = line, nil
 		}
 
 		output.WriteString(transformed)
@@ -89,7 +94,7 @@ func (n *NullCoalesceProcessor) ProcessInternal(code string) (string, []Transfor
 		inputLineNum++
 	}
 
-	return output.String(), metadata, nil
+	return output.String(), metadata, errs.Err()
 }
 
 // processLine processes a single line for null coalescing
@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/plugin"
 )
 
 // Preprocessor orchestrates multiple feature processors to transform
@@ -20,6 +22,20 @@ type Preprocessor struct {
 	// Package-wide cache (optional, for unqualified import inference)
 	// When present, enables early bailout optimization and local function exclusion
 	cache *FunctionExclusionCache
+
+	// Optional observer of intermediate results (dingo expand)
+	stageHook StageHook
+}
+
+// StageHook observes the source after a preprocessing stage, together with
+// the metadata that stage emitted. Stages are named after the feature
+// processors (generic_syntax, rust_match, lambda, ...) plus "imports" for
+// import injection.
+type StageHook func(stage string, source []byte, metadata []TransformMetadata)
+
+// SetStageHook installs a hook called after every preprocessing stage
+func (p *Preprocessor) SetStageHook(hook StageHook) {
+	p.stageHook = hook
 }
 
 // TransformMetadata holds metadata about a transformation (NOT final mappings)
@@ -91,6 +107,12 @@ func NewWithMainConfig(source []byte, cfg *config.Config) *Preprocessor {
 	return newWithConfigAndCache(source, cfg, nil)
 }
 
+// NewWithMainConfigAndCache creates a preprocessor with main Dingo configuration
+// and a package-level cache
+func NewWithMainConfigAndCache(source []byte, cfg *config.Config, cache *FunctionExclusionCache) *Preprocessor {
+	return newWithConfigAndCache(source, cfg, cache)
+}
+
 // newWithConfigAndCache is the internal constructor that accepts an optional cache
 func newWithConfigAndCache(source []byte, cfg *config.Config, cache *FunctionExclusionCache) *Preprocessor {
 	return newWithConfigAndCacheAndLegacy(source, cfg, cache, nil)
@@ -160,22 +182,31 @@ func (p *Preprocessor) ProcessWithMetadata() (string, *SourceMap, []TransformMet
 	_ = skipUnqualifiedProcessing // TODO: Use when UnqualifiedImportProcessor is integrated
 
 	result := p.source
+	lines := newLineMap(result)
 	sourceMap := NewSourceMap()
 	allMetadata := []TransformMetadata{}
 	neededImports := []string{}
 
-	// Run each processor in sequence
+	// Run each processor in sequence. Processors that recover from errors
+	// return their output along with them, so later processors still run and
+	// report problems elsewhere in the file; a processor without output ends
+	// preprocessing.
+	var errs diagnostic.List
 	for _, proc := range p.processors {
 		// Check if processor implements V2 interface
 		if procV2, ok := proc.(FeatureProcessorV2); ok {
 			// Use new ProcessV2 method
 			procResult, err := procV2.ProcessV2(result)
 			if err != nil {
-				return "", nil, nil, fmt.Errorf("%s preprocessing failed: %w", proc.Name(), err)
+				errs = collectErrors(errs, proc.Name(), lines, err)
+				if len(procResult.Source) == 0 || len(errs) > plugin.MaxErrors {
+					return "", nil, nil, errs
+				}
 			}
 
 			// Update result
 			result = procResult.Source
+			lines = lines.update(result)
 
 			// Merge mappings (legacy support)
 			for _, m := range procResult.Mappings {
@@ -184,20 +215,32 @@ func (p *Preprocessor) ProcessWithMetadata() (string, *SourceMap, []TransformMet
 
 			// Collect metadata
 			allMetadata = append(allMetadata, procResult.Metadata...)
+
+			if p.stageHook != nil {
+				p.stageHook(proc.Name(), result, procResult.Metadata)
+			}
 		} else {
 			// Fall back to legacy Process method
 			processed, mappings, err := proc.Process(result)
 			if err != nil {
-				return "", nil, nil, fmt.Errorf("%s preprocessing failed: %w", proc.Name(), err)
+				errs = collectErrors(errs, proc.Name(), lines, err)
+				if len(processed) == 0 || len(errs) > plugin.MaxErrors {
+					return "", nil, nil, errs
+				}
 			}
 
 			// Update result
 			result = processed
+			lines = lines.update(result)
 
 			// Merge mappings
 			for _, m := range mappings {
 				sourceMap.AddMapping(m)
 			}
+
+			if p.stageHook != nil {
+				p.stageHook(proc.Name(), result, nil)
+			}
 		}
 
 		// Collect needed imports if processor implements ImportProvider
@@ -206,6 +249,9 @@ func (p *Preprocessor) ProcessWithMetadata() (string, *SourceMap, []TransformMet
 			neededImports = append(neededImports, imports...)
 		}
 	}
+	if len(errs) > 0 {
+		return "", nil, nil, errs
+	}
 
 	// Convert metadata to legacy mappings for backward compatibility
 	// This allows tests and tools that expect mappings to continue working
@@ -224,8 +270,9 @@ func (p *Preprocessor) ProcessWithMetadata() (string, *SourceMap, []TransformMet
 		// CRITICAL FIX: Get both import start and end lines for accurate shifting
 		result, importInsertLine, importBlockEndLine, err = injectImportsWithPosition(result, neededImports)
 		if err != nil {
-			return "", nil, nil, fmt.Errorf("failed to inject imports: %w", err)
+			return "", nil, nil, processorErrors("imports", lines, lineError(diagnostic.CodeSyntax, 1, fmt.Errorf("failed to inject imports: %w", err)))
 		}
+		lines = lines.update(result)
 
 		// Calculate how many lines the import block occupies
 		// importInsertLine is where imports are inserted (after package declaration)
@@ -240,11 +287,28 @@ func (p *Preprocessor) ProcessWithMetadata() (string, *SourceMap, []TransformMet
 			// TODO: Adjust metadata line numbers
 			// This will be needed when we integrate metadata-based source maps
 		}
+
+		if p.stageHook != nil {
+			p.stageHook("imports", result, nil)
+		}
 	}
+	sourceMap.lines = lines
 
 	return string(result), sourceMap, allMetadata, nil
 }
 
+// collectErrors adds the diagnostics of a failed processor to errs, keeping
+// at most plugin.MaxErrors followed by a too-many-errors diagnostic
+func collectErrors(errs diagnostic.List, name string, lines *lineMap, err error) diagnostic.List {
+	for _, d := range processorErrors(name, lines, err) {
+		if len(errs) == plugin.MaxErrors {
+			return append(errs, tooManyErrors())
+		}
+		errs = append(errs, d)
+	}
+	return errs
+}
+
 // Process runs all feature processors in sequence and combines source maps
 // This is the legacy method that returns only source maps (for backward compatibility)
 func (p *Preprocessor) Process() (string, *SourceMap, error) {
@@ -266,6 +330,7 @@ func (p *Preprocessor) processLegacy() (string, *SourceMap, error) {
 	_ = skipUnqualifiedProcessing // TODO: Use when UnqualifiedImportProcessor is integrated
 
 	result := p.source
+	lines := newLineMap(result)
 	sourceMap := NewSourceMap()
 	neededImports := []string{}
 
@@ -273,11 +338,12 @@ func (p *Preprocessor) processLegacy() (string, *SourceMap, error) {
 	for _, proc := range p.processors {
 		processed, mappings, err := proc.Process(result)
 		if err != nil {
-			return "", nil, fmt.Errorf("%s preprocessing failed: %w", proc.Name(), err)
+			return "", nil, processorErrors(proc.Name(), lines, err)
 		}
 
 		// Update result
 		result = processed
+		lines = lines.update(result)
 
 		// Merge mappings
 		for _, m := range mappings {
@@ -298,7 +364,7 @@ func (p *Preprocessor) processLegacy() (string, *SourceMap, error) {
 		// CRITICAL FIX: Get both import start and end lines for accurate shifting
 		result, importInsertLine, importBlockEndLine, err = injectImportsWithPosition(result, neededImports)
 		if err != nil {
-			return "", nil, fmt.Errorf("failed to inject imports: %w", err)
+			return "", nil, processorErrors("imports", lines, lineError(diagnostic.CodeSyntax, 1, fmt.Errorf("failed to inject imports: %w", err)))
 		}
 
 		// Calculate how many lines the import block occupies
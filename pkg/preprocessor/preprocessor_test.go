@@ -1,9 +1,13 @@
 package preprocessor
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/plugin"
 )
 
 func TestErrorPropagationBasic(t *testing.T) {
@@ -1368,3 +1372,124 @@ func process(path string, num string) ([]byte, error) {
 		})
 	}
 }
+
+// TestProcessCollectsErrors verifies that processors recover from errors and
+// every problem of a file is reported in one run
+func TestProcessCollectsErrors(t *testing.T) {
+	src := `package main
+
+func a(r Result) int {
+	match r {
+	}
+	return 0
+}
+
+func b() {
+	let () = pair()
+}
+
+func c(r Result) int {
+	match r {
+	}
+	return 0
+}
+`
+	_, _, _, err := New([]byte(src)).ProcessWithMetadata()
+
+	var list diagnostic.List
+	if !errors.As(err, &list) {
+		t.Fatalf("Expected a diagnostic.List, got %T: %v", err, err)
+	}
+	got := make(map[int]string)
+	for _, d := range list {
+		got[d.Pos.Line] = d.Code
+	}
+	want := map[int]string{4: diagnostic.CodeMatch, 10: diagnostic.CodeTuple, 14: diagnostic.CodeMatch}
+	if len(list) != len(want) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(want), len(list), err)
+	}
+	for line, code := range want {
+		if got[line] != code {
+			t.Errorf("line %d: expected %s, got %q", line, code, got[line])
+		}
+	}
+}
+
+// TestProcessErrorPositions verifies that processor errors point at the
+// .dingo line and column of the problem, after earlier processors have
+// rewritten the lines above
+func TestProcessErrorPositions(t *testing.T) {
+	src := `package main
+
+func f(r Result) int {
+	let v = match r {
+		Ok(x) => x,
+		Err(e) => 0,
+	}
+	return v
+}
+
+func g(r Result) {
+	let t = (1,)
+	let (a) = pair()
+	match r {
+		Ok(x) =>
+	}
+}
+
+enum Shape {
+	Circle { radius },
+}
+
+enum Empty {
+}
+`
+	_, _, _, err := New([]byte(src)).ProcessWithMetadata()
+
+	var list diagnostic.List
+	if !errors.As(err, &list) {
+		t.Fatalf("Expected a diagnostic.List, got %T: %v", err, err)
+	}
+	want := []struct {
+		line, col int
+		code      string
+	}{
+		{15, 9, diagnostic.CodeMatch},  // =>
+		{12, 10, diagnostic.CodeTuple}, // (1,)
+		{13, 6, diagnostic.CodeTuple},  // (a)
+		{20, 2, diagnostic.CodeEnum},   // Circle
+		{23, 12, diagnostic.CodeEnum},  // {
+	}
+	if len(list) != len(want) {
+		t.Fatalf("Expected %d diagnostics, got %d: %v", len(want), len(list), err)
+	}
+	for i, w := range want {
+		d := list[i]
+		if d.Pos.Line != w.line || d.Pos.Column != w.col || d.Code != w.code {
+			t.Errorf("diagnostic %d: got %d:%d %s (%s), want %d:%d %s", i, d.Pos.Line, d.Pos.Column, d.Code, d.Message, w.line, w.col, w.code)
+		}
+	}
+}
+
+// TestProcessErrorLimit verifies that error collection stops at plugin.MaxErrors
+func TestProcessErrorLimit(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("package main\n\nfunc f() {\n")
+	for i := 0; i < plugin.MaxErrors+20; i++ {
+		src.WriteString("\tlet () = pair()\n")
+	}
+	src.WriteString("}\n")
+
+	_, _, _, err := New([]byte(src.String())).ProcessWithMetadata()
+
+	var list diagnostic.List
+	if !errors.As(err, &list) {
+		t.Fatalf("Expected a diagnostic.List, got %T: %v", err, err)
+	}
+	if len(list) != plugin.MaxErrors+1 {
+		t.Fatalf("Expected %d diagnostics, got %d", plugin.MaxErrors+1, len(list))
+	}
+	if last := list[len(list)-1]; !strings.Contains(last.Message, "too many errors") {
+		t.Errorf("Expected the last diagnostic to stop collection, got %q", last.Message)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // RustMatchProcessor handles Rust-like pattern matching syntax
@@ -13,6 +15,8 @@ import (
 type RustMatchProcessor struct {
 	matchCounter int
 	mappings     []Mapping
+	errors       diagnostic.List
+	failed       map[string]int // Per text, how many match expressions were left as written after an error
 }
 
 // Pattern-matching regex for Rust-like match expressions
@@ -60,6 +64,8 @@ func (r *RustMatchProcessor) Name() string {
 func (r *RustMatchProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 	r.mappings = []Mapping{}
 	r.matchCounter = 0
+	r.errors = nil
+	r.failed = make(map[string]int)
 
 	// Run multiple passes until no more match keywords remain
 	// This handles nested match blocks recursively
@@ -73,10 +79,7 @@ func (r *RustMatchProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 		}
 
 		// Process one level of match expressions
-		newResult, newMappings, err := r.processSinglePass(result)
-		if err != nil {
-			return nil, nil, err
-		}
+		newResult, newMappings := r.processSinglePass(result)
 
 		// If nothing changed, we're done
 		if bytes.Equal(result, newResult) {
@@ -91,7 +94,7 @@ func (r *RustMatchProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 	// Pattern: `result = { switch ... }` is invalid Go, should be just the switch
 	result = r.cleanupBlockExpressions(result)
 
-	return result, r.mappings, nil
+	return result, r.mappings, r.errors.Err()
 }
 
 // cleanupBlockExpressions removes invalid block wrappers around switch statements
@@ -191,8 +194,10 @@ func containsMatchKeyword(source []byte) bool {
 	return strings.Contains(text, "match ")
 }
 
-// processSinglePass performs one pass of match expression transformation
-func (r *RustMatchProcessor) processSinglePass(source []byte) ([]byte, []Mapping, error) {
+// processSinglePass performs one pass of match expression transformation.
+// A match expression that fails to transform is recorded in r.errors and left
+// as written, so the other expressions of the file are still checked.
+func (r *RustMatchProcessor) processSinglePass(source []byte) ([]byte, []Mapping) {
 	input := string(source)
 	lines := strings.Split(input, "\n")
 
@@ -200,6 +205,7 @@ func (r *RustMatchProcessor) processSinglePass(source []byte) ([]byte, []Mapping
 	var mappings []Mapping
 	inputLineNum := 0
 	outputLineNum := 1
+	seen := make(map[string]int) // Occurrences of each match expression text in this pass
 
 	for inputLineNum < len(lines) {
 		line := lines[inputLineNum]
@@ -224,10 +230,19 @@ func (r *RustMatchProcessor) processSinglePass(source []byte) ([]byte, []Mapping
 			// Collect the entire match expression (may span multiple lines)
 			matchExpr, linesConsumed := r.collectMatchExpression(lines, inputLineNum)
 			if matchExpr != "" {
-				// Transform the match expression
-				transformed, newMappings, err := r.transformMatch(matchExpr, inputLineNum+1, outputLineNum)
-				if err != nil {
-					return nil, nil, fmt.Errorf("line %d: %w", inputLineNum+1, err)
+				// Transform the match expression. Failed expressions stay in
+				// place in the same order, so the first failed[text] occurrences
+				// already failed in an earlier pass and are not reported again.
+				transformed, newMappings := matchExpr, []Mapping(nil)
+				seen[matchExpr]++
+				if seen[matchExpr] > r.failed[matchExpr] {
+					var err error
+					transformed, newMappings, err = r.transformMatch(matchExpr, inputLineNum+1, outputLineNum)
+					if err != nil {
+						r.errors = append(r.errors, offsetError(diagnostic.CodeMatch, source, lineOffset(lines, inputLineNum), err))
+						r.failed[matchExpr]++
+						transformed, newMappings = matchExpr, nil
+					}
 				}
 
 				output.WriteString(transformed)
@@ -255,7 +270,7 @@ func (r *RustMatchProcessor) processSinglePass(source []byte) ([]byte, []Mapping
 		outputLineNum++
 	}
 
-	return output.Bytes(), mappings, nil
+	return output.Bytes(), mappings
 }
 
 // isAlphanumeric checks if a rune is alphanumeric or underscore
@@ -303,7 +318,7 @@ func (r *RustMatchProcessor) transformMatch(matchExpr string, originalLine int,
 	// Extract scrutinee and arms using boundary-aware parsing instead of regex
 	// This fixes the issue where DOTALL flag (.+) matches across all newlines until EOF
 	// in files with multiple match expressions
-	scrutinee, armsText, err := r.extractScrutineeAndArms(matchExpr)
+	scrutinee, armsText, armsStart, err := r.extractScrutineeAndArms(matchExpr)
 	if err != nil {
 		return "", nil, fmt.Errorf("extracting match components: %w", err)
 	}
@@ -316,14 +331,16 @@ func (r *RustMatchProcessor) transformMatch(matchExpr string, originalLine int,
 	// Check if scrutinee is a tuple expression
 	isTuple, tupleElements, err := r.detectTuple(scrutinee)
 	if err != nil {
-		return "", nil, err
+		// The scrutinee follows the match keyword
+		at := strings.Index(matchExpr, "match") + len("match")
+		return "", nil, shiftError(err, at+strings.Index(matchExpr[at:], scrutinee))
 	}
 
 	if isTuple {
 		// Parse tuple pattern arms
 		tupleArms, err := r.parseTupleArms(armsText)
 		if err != nil {
-			return "", nil, fmt.Errorf("parsing tuple pattern arms: %w", err)
+			return "", nil, shiftError(fmt.Errorf("parsing tuple pattern arms: %w", err), armsStart)
 		}
 
 		// Generate tuple match (elements extraction + pattern info)
@@ -334,7 +351,7 @@ func (r *RustMatchProcessor) transformMatch(matchExpr string, originalLine int,
 	// Parse pattern arms (non-tuple)
 	arms, err := r.parseArms(armsText)
 	if err != nil {
-		return "", nil, fmt.Errorf("parsing pattern arms: %w", err)
+		return "", nil, shiftError(fmt.Errorf("parsing pattern arms: %w", err), armsStart)
 	}
 
 	// Generate Go switch statement
@@ -345,14 +362,13 @@ func (r *RustMatchProcessor) transformMatch(matchExpr string, originalLine int,
 // extractScrutineeAndArms extracts the scrutinee expression and arms text from a match expression
 // using boundary-aware parsing instead of regex to avoid DOTALL flag issues
 // This properly separates "match expr { arms }" without capturing beyond the closing brace
-func (r *RustMatchProcessor) extractScrutineeAndArms(matchExpr string) (scrutinee string, armsText string, err error) {
-	matchExpr = strings.TrimSpace(matchExpr)
-
+// armsStart is the offset of the arms text in matchExpr
+func (r *RustMatchProcessor) extractScrutineeAndArms(matchExpr string) (scrutinee string, armsText string, armsStart int, err error) {
 	// Find the opening brace for the arms
 	// We need to find the first { that comes after the match keyword and expression
 	matchKeywordIdx := strings.Index(matchExpr, "match")
 	if matchKeywordIdx == -1 {
-		return "", "", fmt.Errorf("no match keyword found")
+		return "", "", 0, errorAt(0, "no match keyword found")
 	}
 
 	// Find the opening brace - it's the first { after the expression
@@ -366,7 +382,7 @@ func (r *RustMatchProcessor) extractScrutineeAndArms(matchExpr string) (scrutine
 	}
 
 	if braceIdx == -1 {
-		return "", "", fmt.Errorf("no opening brace found in match expression")
+		return "", "", 0, errorAt(matchKeywordIdx, "no opening brace found in match expression")
 	}
 
 	// Scrutinee is everything between "match" and the opening brace
@@ -376,7 +392,7 @@ func (r *RustMatchProcessor) extractScrutineeAndArms(matchExpr string) (scrutine
 	// Arms text is between the braces
 	// Use depth-aware search starting from braceIdx to find the matching closing brace
 	// This ensures we don't stop at a } from a nested block expression
-	armsStart := braceIdx + 1
+	armsStart = braceIdx + 1
 	armsEnd := -1
 	depth := 1 // Start with depth 1 because we're already past the opening brace
 
@@ -394,12 +410,12 @@ func (r *RustMatchProcessor) extractScrutineeAndArms(matchExpr string) (scrutine
 	}
 
 	if armsEnd == -1 {
-		return "", "", fmt.Errorf("no closing brace found in match expression")
+		return "", "", 0, errorAt(braceIdx, "no closing brace found in match expression")
 	}
 
-	armsText = strings.TrimSpace(matchExpr[armsStart:armsEnd])
+	armsText = matchExpr[armsStart:armsEnd]
 
-	return scrutinee, armsText, nil
+	return scrutinee, armsText, armsStart, nil
 }
 
 // extractAssignmentVar extracts the variable name if match is in assignment context
@@ -470,6 +486,7 @@ type patternArm struct {
 func (r *RustMatchProcessor) parseArms(armsText string) ([]patternArm, error) {
 	arms := []patternArm{}
 	text := strings.TrimSpace(armsText)
+	lead := strings.Index(armsText, text) // Errors are at offsets of armsText
 
 	// Parse arms manually to handle nested braces
 	i := 0
@@ -489,6 +506,7 @@ func (r *RustMatchProcessor) parseArms(armsText string) ([]patternArm, error) {
 		}
 
 		patternAndGuard := strings.TrimSpace(text[i : i+arrowPos])
+		arrow := i + arrowPos
 		i += arrowPos + 2 // Skip =>
 
 		// Split pattern from guard if present
@@ -501,7 +519,7 @@ func (r *RustMatchProcessor) parseArms(armsText string) ([]patternArm, error) {
 			i++
 		}
 		if i >= len(text) {
-			return nil, fmt.Errorf("unexpected end after =>")
+			return nil, errorAt(lead+arrow, "unexpected end after =>")
 		}
 
 		// Extract expression (until comma or end)
@@ -528,7 +546,7 @@ func (r *RustMatchProcessor) parseArms(armsText string) ([]patternArm, error) {
 			// Simple expression - find comma or end (respecting strings and nesting)
 			start := i
 			if start >= len(text) {
-				return nil, fmt.Errorf("unexpected end of text after =>")
+				return nil, errorAt(lead+arrow, "unexpected end of text after =>")
 			}
 			exprEnd := r.findExpressionEnd(text, start)
 			if exprEnd > start {
@@ -539,7 +557,7 @@ func (r *RustMatchProcessor) parseArms(armsText string) ([]patternArm, error) {
 					i++
 				}
 			} else {
-				return nil, fmt.Errorf("invalid expression end at position %d", start)
+				return nil, errorAt(lead+start, "invalid expression after =>")
 			}
 		}
 
@@ -566,7 +584,7 @@ func (r *RustMatchProcessor) parseArms(armsText string) ([]patternArm, error) {
 	}
 
 	if len(arms) == 0 {
-		return nil, fmt.Errorf("no pattern arms found")
+		return nil, errorAt(-1, "no pattern arms found") // At the opening brace
 	}
 
 	return arms, nil
@@ -1481,7 +1499,8 @@ func (r *RustMatchProcessor) detectTuple(scrutinee string) (bool, []string, erro
 
 	// Enforce 6-element limit (USER DECISION)
 	if len(elements) > 6 {
-		return false, nil, fmt.Errorf(
+		return false, nil, errorAt(
+			strings.Index(scrutinee, trimmed),
 			"tuple patterns limited to 6 elements (found %d)",
 			len(elements),
 		)
@@ -1546,6 +1565,7 @@ type tupleElementPattern struct {
 func (r *RustMatchProcessor) parseTupleArms(armsText string) ([]tuplePatternArm, error) {
 	arms := []tuplePatternArm{}
 	text := strings.TrimSpace(armsText)
+	lead := strings.Index(armsText, text) // Errors are at offsets of armsText
 
 	i := 0
 	for i < len(text) {
@@ -1559,7 +1579,7 @@ func (r *RustMatchProcessor) parseTupleArms(armsText string) ([]tuplePatternArm,
 
 		// Expect tuple pattern: (Pattern1, Pattern2, ...)
 		if text[i] != '(' {
-			return nil, fmt.Errorf("expected tuple pattern at position %d", i)
+			return nil, errorAt(lead+i, "expected tuple pattern")
 		}
 
 		// Find matching close paren
@@ -1579,7 +1599,7 @@ func (r *RustMatchProcessor) parseTupleArms(armsText string) ([]tuplePatternArm,
 		// Parse tuple elements
 		tupleElements, err := r.parseTuplePattern(tuplePatternStr)
 		if err != nil {
-			return nil, fmt.Errorf("parsing tuple pattern: %w", err)
+			return nil, shiftError(fmt.Errorf("parsing tuple pattern: %w", err), lead+tupleStart)
 		}
 
 		// Skip whitespace
@@ -1590,12 +1610,13 @@ func (r *RustMatchProcessor) parseTupleArms(armsText string) ([]tuplePatternArm,
 		// Check for guard (if)
 		guard := ""
 		if i < len(text) && strings.HasPrefix(text[i:], "if ") {
+			guardAt := i
 			i += 3 // skip "if "
 
 			// Extract guard condition (until =>)
 			arrowPos := strings.Index(text[i:], "=>")
 			if arrowPos == -1 {
-				return nil, fmt.Errorf("expected => after guard")
+				return nil, errorAt(lead+guardAt, "expected => after guard")
 			}
 			guard = strings.TrimSpace(text[i : i+arrowPos])
 			i += arrowPos
@@ -1603,7 +1624,7 @@ func (r *RustMatchProcessor) parseTupleArms(armsText string) ([]tuplePatternArm,
 
 		// Expect =>
 		if !strings.HasPrefix(text[i:], "=>") {
-			return nil, fmt.Errorf("expected => at position %d", i)
+			return nil, errorAt(lead+i, "expected =>")
 		}
 		i += 2
 
@@ -1649,7 +1670,7 @@ func (r *RustMatchProcessor) parseTupleArms(armsText string) ([]tuplePatternArm,
 	}
 
 	if len(arms) == 0 {
-		return nil, fmt.Errorf("no tuple pattern arms found")
+		return nil, errorAt(-1, "no tuple pattern arms found") // At the opening brace
 	}
 
 	return arms, nil
@@ -1660,7 +1681,7 @@ func (r *RustMatchProcessor) parseTuplePattern(tupleStr string) ([]tupleElementP
 	// Remove outer parens
 	tupleStr = strings.TrimSpace(tupleStr)
 	if !strings.HasPrefix(tupleStr, "(") || !strings.HasSuffix(tupleStr, ")") {
-		return nil, fmt.Errorf("invalid tuple pattern: %s", tupleStr)
+		return nil, errorAt(0, "invalid tuple pattern: %s", tupleStr)
 	}
 	inner := tupleStr[1 : len(tupleStr)-1]
 
@@ -1685,7 +1706,7 @@ func (r *RustMatchProcessor) parseTuplePattern(tupleStr string) ([]tupleElementP
 			start := strings.Index(elemStr, "(")
 			end := strings.Index(elemStr, ")")
 			if end <= start {
-				return nil, fmt.Errorf("invalid pattern: %s", elemStr)
+				return nil, errorAt(0, "invalid pattern: %s", elemStr)
 			}
 			variant := strings.TrimSpace(elemStr[:start])
 			binding := strings.TrimSpace(elemStr[start+1 : end])
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // SafeNavProcessor handles the ?. operator for safe navigation
@@ -197,13 +199,16 @@ func (s *SafeNavProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 
 	inputLineNum := 0
 	outputLineNum := 1
+	var errs diagnostic.List
 
 	for inputLineNum < len(lines) {
 		line := lines[inputLineNum]
 
 		transformed, mappings, err := s.processLine(line, inputLineNum+1, outputLineNum)
 		if err != nil {
-			return nil, nil, fmt.Errorf("line %d: %w", inputLineNum+1, err)
+			// Keep the line as written and report the rest of the file too
+			errs = append(errs, lineError(diagnostic.CodeSafeNavigation, inputLineNum+1, err))
+			transformed, mappings = line, nil
 		}
 
 		output.WriteString(transformed)
@@ -222,7 +227,7 @@ func (s *SafeNavProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 		inputLineNum++
 	}
 
-	return output.Bytes(), allMappings, nil
+	return output.Bytes(), allMappings, errs.Err()
 }
 
 // ProcessInternal implements safe navigation transformation with metadata emission
@@ -241,6 +246,7 @@ func (s *SafeNavProcessor) ProcessInternal(code string) (string, []TransformMeta
 
 	inputLineNum := 0
 	outputLineNum := 1
+	var errs diagnostic.List
 
 	for inputLineNum < len(lines) {
 		line := lines[inputLineNum]
@@ -248,7 +254,9 @@ func (s *SafeNavProcessor) ProcessInternal(code string) (string, []TransformMeta
 		// Process the line with metadata
 		transformed, meta, err := s.processLineWithMetadata(line, inputLineNum+1, outputLineNum, &counter)
 		if err != nil {
-			return "", nil, fmt.Errorf("line %d: %w", inputLineNum+1, err)
+			// Keep the line as written and report the rest of the file too
+			errs = append(errs, lineError(diagnostic.CodeSafeNavigation, inputLineNum+1, err))
+			transformed, meta = line, nil
 		}
 
 		output.WriteString(transformed)
@@ -269,7 +277,7 @@ func (s *SafeNavProcessor) ProcessInternal(code string) (string, []TransformMeta
 		inputLineNum++
 	}
 
-	return output.String(), metadata, nil
+	return output.String(), metadata, errs.Err()
 }
 
 // safeNavPosition represents a safe navigation chain position in a line
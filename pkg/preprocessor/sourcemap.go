@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // SourceMap tracks position mappings between original Dingo source
@@ -15,6 +17,8 @@ type SourceMap struct {
 	DingoFile string    `json:"dingo_file,omitempty"` // Original .dingo file path
 	GoFile    string    `json:"go_file,omitempty"`    // Generated .go file path
 	Mappings  []Mapping `json:"mappings"`
+
+	lines *lineMap // Every preprocessed line → its .dingo line, when preprocessed in memory
 }
 
 // Mapping represents a single position mapping
@@ -195,6 +199,30 @@ func (sm *SourceMap) MapToOriginalWithDebug(line, col int, debug bool) (int, int
 	return line, col
 }
 
+// MapToOriginalExact maps a generated position to the original Dingo
+// position only through a mapping whose range holds it; the end of a range
+// counts, so that the ends of ranges map too. ok is false for code the
+// transpiler generated, for which MapToOriginal guesses an origin.
+func (sm *SourceMap) MapToOriginalExact(line, col int) (origLine, origCol int, ok bool) {
+	var end *Mapping
+	for i := range sm.Mappings {
+		m := &sm.Mappings[i]
+		if m.GeneratedLine != line || col < m.GeneratedColumn || col > m.GeneratedColumn+m.Length {
+			continue
+		}
+		if col < m.GeneratedColumn+m.Length {
+			return m.OriginalLine, m.OriginalColumn + col - m.GeneratedColumn, true
+		}
+		if end == nil {
+			end = m
+		}
+	}
+	if end == nil || end.Length == 0 {
+		return 0, 0, false
+	}
+	return end.OriginalLine, end.OriginalColumn + col - end.GeneratedColumn, true
+}
+
 func abs(x int) int {
 	if x < 0 {
 		return -x
@@ -202,6 +230,26 @@ func abs(x int) int {
 	return x
 }
 
+// MapPosition returns the .dingo line and column of a position in the
+// preprocessed Go. Columns survive only on lines the preprocessor left
+// unchanged; on rewritten lines they become the start of the .dingo line.
+// Maps without line tracking return the position as is.
+func (sm *SourceMap) MapPosition(line, col int) (int, int) {
+	if sm.lines == nil {
+		return line, col
+	}
+	return sm.lines.position(line, col)
+}
+
+// MapDiagnostic maps every position of d from the preprocessed Go to the
+// .dingo source. Ranges are kept only where the text is unchanged; edits that
+// cannot be mapped exactly are dropped, leaving their fix as advice.
+func (sm *SourceMap) MapDiagnostic(d *diagnostic.Diagnostic) {
+	if sm.lines != nil {
+		sm.lines.mapDiagnostic(d)
+	}
+}
+
 // MapToGenerated maps an original Dingo position to the preprocessed position
 // Returns the mapped position or the input position if no mapping found
 func (sm *SourceMap) MapToGenerated(line, col int) (int, int) {
@@ -218,4 +218,30 @@ func TestSourceMapOffsetBounds(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestSourceMapExact(t *testing.T) {
+	sm := NewSourceMap()
+	sm.AddMapping(Mapping{GeneratedLine: 12, GeneratedColumn: 1, OriginalLine: 8, OriginalColumn: 1, Length: 40, Name: "identity"})
+	sm.AddMapping(Mapping{GeneratedLine: 13, GeneratedColumn: 2, OriginalLine: 9, OriginalColumn: 30, Length: 1, Name: "error_prop"})
+	sm.AddMapping(Mapping{GeneratedLine: 18, GeneratedColumn: 1, OriginalLine: 12, OriginalColumn: 1, Length: 0, Name: "identity"})
+
+	tests := []struct {
+		line, col       int
+		expLine, expCol int
+		expOK           bool
+	}{
+		{12, 6, 8, 6, true},   // Inside a mapping
+		{12, 41, 8, 41, true}, // End of a mapping
+		{13, 2, 9, 30, true},
+		{13, 10, 0, 0, false}, // Generated around the mapping
+		{14, 2, 0, 0, false},  // Generated line
+		{18, 1, 0, 0, false},  // Empty mapping
+	}
+	for _, tt := range tests {
+		line, col, ok := sm.MapToOriginalExact(tt.line, tt.col)
+		if line != tt.expLine || col != tt.expCol || ok != tt.expOK {
+			t.Errorf("MapToOriginalExact(%d, %d) = (%d, %d, %v), want (%d, %d, %v)",
+				tt.line, tt.col, line, col, ok, tt.expLine, tt.expCol, tt.expOK)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // MaxTernaryNestingDepth is the maximum depth of nested ternary operators allowed.
@@ -171,6 +173,7 @@ func (t *TernaryProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 	var output bytes.Buffer
 	inputLineNum := 0
 	outputLineNum := 1 // Track current output line number (1-based)
+	var errs diagnostic.List
 
 	for inputLineNum < len(lines) {
 		line := lines[inputLineNum]
@@ -178,7 +181,9 @@ func (t *TernaryProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 		// Process the line, passing the current output line number
 		transformed, newMappings, err := t.processLine(line, inputLineNum+1, outputLineNum)
 		if err != nil {
-			return nil, nil, fmt.Errorf("line %d: %w", inputLineNum+1, err)
+			// Keep the line as written and report the rest of the file too
+			errs = append(errs, lineError(diagnostic.CodeTernary, inputLineNum+1, err))
+			transformed, newMappings = line, nil
 		}
 		output.WriteString(transformed)
 		if inputLineNum < len(lines)-1 {
@@ -198,7 +203,7 @@ func (t *TernaryProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 		inputLineNum++
 	}
 
-	return output.Bytes(), t.mappings, nil
+	return output.Bytes(), t.mappings, errs.Err()
 }
 
 // processLine processes a single line for ternary operators
@@ -5,6 +5,8 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // TupleProcessor handles tuple literal and destructuring syntax
@@ -46,6 +48,7 @@ func (t *TupleProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 
 	inputLineNum := 0
 	outputLineNum := 1
+	var errs diagnostic.List
 
 	for inputLineNum < len(lines) {
 		line := lines[inputLineNum]
@@ -53,7 +56,9 @@ func (t *TupleProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 		// Process the line
 		transformed, newMappings, err := t.processLine(line, inputLineNum+1, outputLineNum)
 		if err != nil {
-			return nil, nil, fmt.Errorf("line %d: %w", inputLineNum+1, err)
+			// Keep the line as written and report the rest of the file too
+			errs = append(errs, offsetError(diagnostic.CodeTuple, source, lineOffset(lines, inputLineNum), err))
+			transformed, newMappings = line, nil
 		}
 
 		output.WriteString(transformed)
@@ -74,7 +79,7 @@ func (t *TupleProcessor) Process(source []byte) ([]byte, []Mapping, error) {
 		inputLineNum++
 	}
 
-	return output.Bytes(), t.mappings, nil
+	return output.Bytes(), t.mappings, errs.Err()
 }
 
 // processLine processes a single line for tuple syntax
@@ -158,7 +163,7 @@ func (t *TupleProcessor) processDestructuring(line string, originalLineNum int,
 	parenIdx := strings.Index(trimmed, "(")
 	closeParen := findMatchingParen(trimmed, parenIdx)
 	if closeParen == -1 {
-		return "", nil, fmt.Errorf("unmatched parenthesis in destructuring")
+		return "", nil, errorAt(len(indent)+parenIdx, "unmatched parenthesis in destructuring")
 	}
 
 	// Extract pattern (between parens)
@@ -168,18 +173,18 @@ func (t *TupleProcessor) processDestructuring(line string, originalLineNum int,
 	afterParen := trimmed[closeParen+1:]
 	eqIdx := strings.Index(afterParen, "=")
 	if eqIdx == -1 {
-		return "", nil, fmt.Errorf("missing = in destructuring")
+		return "", nil, errorAt(len(indent)+closeParen+1, "missing = in destructuring")
 	}
 	expr := strings.TrimSpace(afterParen[eqIdx+1:])
 
 	// Parse pattern: identifiers separated by commas (handles nested patterns)
 	identifiers := parseDestructurePattern(pattern)
 	if len(identifiers) == 0 {
-		return "", nil, fmt.Errorf("empty destructuring pattern")
+		return "", nil, errorAt(len(indent)+parenIdx, "empty destructuring pattern")
 	}
 
 	// Validate arity
-	if err := t.validateArity(len(identifiers), originalLineNum); err != nil {
+	if err := t.validateArity(len(identifiers), len(indent)+parenIdx); err != nil {
 		return "", nil, err
 	}
 
@@ -312,7 +317,7 @@ func (t *TupleProcessor) processTupleLiterals(line string, originalLineNum int,
 
 		// Validate arity
 		arity := len(elements)
-		if err := t.validateArity(arity, originalLineNum); err != nil {
+		if err := t.validateArity(arity, i-offset); err != nil {
 			return "", nil, err
 		}
 
@@ -534,18 +539,19 @@ func splitTupleElements(s string) []string {
 	return elements
 }
 
-// validateArity checks if tuple arity is within valid range
-func (t *TupleProcessor) validateArity(arity int, lineNum int) error {
+// validateArity checks if tuple arity is within valid range. offset is the
+// position of the tuple in the line.
+func (t *TupleProcessor) validateArity(arity int, offset int) error {
 	if arity == 0 {
-		return fmt.Errorf("empty tuples are not supported (line %d). Use 'struct{}' if you need a zero-size type", lineNum)
+		return errorAt(offset, "empty tuples are not supported. Use 'struct{}' if you need a zero-size type")
 	}
 
 	if arity == 1 {
-		return fmt.Errorf("single-element tuples are not supported (line %d). Remove parentheses", lineNum)
+		return errorAt(offset, "single-element tuples are not supported. Remove parentheses")
 	}
 
 	if arity > 12 {
-		return fmt.Errorf("tuple has %d elements, maximum is 12 (line %d). Consider using a struct instead", arity, lineNum)
+		return errorAt(offset, "tuple has %d elements, maximum is 12. Consider using a struct instead", arity)
 	}
 
 	return nil
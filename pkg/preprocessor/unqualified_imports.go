@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 )
 
 // UnqualifiedImportProcessor transforms unqualified stdlib calls to qualified calls
@@ -53,6 +55,7 @@ func (p *UnqualifiedImportProcessor) Process(source []byte) ([]byte, []Mapping,
 
 	var result strings.Builder
 	var mappings []Mapping
+	var errs diagnostic.List
 	lastEnd := 0
 
 	matches := p.pattern.FindAllSubmatchIndex(source, -1)
@@ -82,8 +85,9 @@ func (p *UnqualifiedImportProcessor) Process(source []byte) ([]byte, []Mapping,
 		// Look up in stdlib registry
 		pkg, err := GetPackageForFunction(funcName)
 		if err != nil {
-			// Ambiguous function
-			return nil, nil, fmt.Errorf("%w (at position %d)", err, funcNameStart)
+			// Ambiguous function: leave the call unqualified and keep going
+			errs = append(errs, offsetError(diagnostic.CodeImport, source, funcNameStart, err))
+			continue
 		}
 
 		if pkg == "" {
@@ -133,7 +137,7 @@ func (p *UnqualifiedImportProcessor) Process(source []byte) ([]byte, []Mapping,
 	// Write remaining source
 	result.Write(source[lastEnd:])
 
-	return []byte(result.String()), mappings, nil
+	return []byte(result.String()), mappings, errs.Err()
 }
 
 // GetNeededImports returns the list of import paths that should be added
@@ -2,7 +2,6 @@
 package sourcemap
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"go/token"
@@ -63,12 +62,16 @@ func (g *Generator) AddMappingWithName(src, gen token.Position, name string) {
 
 // SourceMapV3 represents a Source Map v3 JSON structure
 type SourceMapV3 struct {
-	Version    int      `json:"version"`
-	File       string   `json:"file"`
-	SourceRoot string   `json:"sourceRoot"`
-	Sources    []string `json:"sources"`
-	Names      []string `json:"names"`
-	Mappings   string   `json:"mappings"`
+	Version        int      `json:"version"`
+	File           string   `json:"file"`
+	SourceRoot     string   `json:"sourceRoot"`
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent,omitempty"`
+	Names          []string `json:"names"`
+	Mappings       string   `json:"mappings"`
+
+	// Dingo keeps what v3 cannot express, see ToV3
+	Dingo *DingoExtension `json:"x_dingo,omitempty"`
 }
 
 // Generate creates a source map in JSON format with VLQ-encoded mappings
@@ -100,7 +103,7 @@ func (g *Generator) Generate() ([]byte, error) {
 	names := g.collectUniqueNames(validMappings)
 
 	// Generate VLQ-encoded mappings
-	mappings := generateVLQMappings(validMappings)
+	mappings := generateVLQMappings(validMappings, names)
 
 	// Build source map structure
 	sm := SourceMapV3{
@@ -143,11 +146,7 @@ func (g *Generator) GenerateInline() (string, error) {
 		return "", err
 	}
 
-	// Base64 encode
-	encoded := base64.StdEncoding.EncodeToString(data)
-
-	// Return as inline comment
-	return fmt.Sprintf("//# sourceMappingURL=data:application/json;base64,%s", encoded), nil
+	return InlineComment(data), nil
 }
 
 
@@ -168,15 +167,15 @@ func NewConsumer(data []byte) (*Consumer, error) {
 
 // Source looks up the original source position for a generated position
 func (c *Consumer) Source(line, column int) (*token.Position, error) {
-	// Note: go-sourcemap uses 0-based indexing, but we use 1-based
-	file, _, line, col, ok := c.sm.Source(line-1, column-1)
+	// Note: go-sourcemap uses 1-based lines but 0-based columns
+	file, _, srcLine, srcCol, ok := c.sm.Source(line, column-1)
 	if !ok {
 		return nil, fmt.Errorf("no mapping found for position %d:%d", line, column)
 	}
 
 	return &token.Position{
 		Filename: file,
-		Line:     line + 1,
-		Column:   col + 1,
+		Line:     srcLine,
+		Column:   srcCol + 1,
 	}, nil
 }
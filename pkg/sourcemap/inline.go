@@ -0,0 +1,52 @@
+package sourcemap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// inlinePrefix starts the comment an inline source map is embedded in, the
+// data URL convention of JavaScript source maps
+const inlinePrefix = "//# sourceMappingURL=data:application/json;base64,"
+
+// InlineComment returns the comment embedding the source map data
+func InlineComment(data []byte) string {
+	return inlinePrefix + base64.StdEncoding.EncodeToString(data)
+}
+
+// AppendInline returns goSrc with the source map data, JSON in either
+// format, embedded as a comment on its last line. Appending leaves the lines
+// the map describes unchanged.
+func AppendInline(goSrc, data []byte) ([]byte, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, fmt.Errorf("failed to encode source map: %w", err)
+	}
+	out := make([]byte, 0, len(goSrc)+len(inlinePrefix)+compact.Len()*4/3+8)
+	out = append(out, goSrc...)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		out = append(out, '\n')
+	}
+	out = append(out, InlineComment(compact.Bytes())...)
+	return append(out, '\n'), nil
+}
+
+// ExtractInline returns the source map data embedded in goSrc by
+// AppendInline. ok is false when there is none.
+func ExtractInline(goSrc []byte) (data []byte, ok bool, err error) {
+	i := bytes.LastIndex(goSrc, []byte(inlinePrefix))
+	if i < 0 || (i > 0 && goSrc[i-1] != '\n') {
+		return nil, false, nil
+	}
+	encoded := goSrc[i+len(inlinePrefix):]
+	if end := bytes.IndexByte(encoded, '\n'); end >= 0 {
+		encoded = encoded[:end]
+	}
+	data, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid inline source map: %w", err)
+	}
+	return data, true, nil
+}
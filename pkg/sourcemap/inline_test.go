@@ -0,0 +1,61 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+)
+
+func TestInlineSourceMap(t *testing.T) {
+	sm := &preprocessor.SourceMap{
+		Version:  1,
+		Mappings: []preprocessor.Mapping{{OriginalLine: 4, OriginalColumn: 2, GeneratedLine: 6, GeneratedColumn: 2, Length: 3}},
+	}
+	goSrc := "package main\n\nfunc main() {}"
+	data, err := json.Marshal(sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := AppendInline([]byte(goSrc), data)
+	if err != nil {
+		t.Fatalf("AppendInline failed: %v", err)
+	}
+	if !strings.HasPrefix(string(out), goSrc+"\n//# sourceMappingURL=data:application/json;base64,") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	dir := t.TempDir()
+	goPath := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(goPath, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(goPath)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Mappings) != 1 || loaded.Mappings[0] != sm.Mappings[0] {
+		t.Errorf("Load = %+v, want %+v", loaded.Mappings, sm.Mappings)
+	}
+
+	r := NewRewriter()
+	if err := r.AddDir(dir); err != nil {
+		t.Fatalf("AddDir failed: %v", err)
+	}
+	if got := r.Rewrite("main.go:6:2: undefined: x", dir); got != "main.dingo:4:2: undefined: x" {
+		t.Errorf("Rewrite = %q", got)
+	}
+
+	plain := filepath.Join(dir, "plain.go")
+	if err := os.WriteFile(plain, []byte(goSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(plain); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Load(plain.go) error = %v, want fs.ErrNotExist", err)
+	}
+}
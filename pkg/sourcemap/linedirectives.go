@@ -0,0 +1,127 @@
+package sourcemap
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+)
+
+// InsertLineDirectives adds //line directives to generated Go so that the Go
+// toolchain attributes every line to its .dingo position, and returns the
+// source map shifted to the lines of the result.
+//
+// sm must describe goSrc. dingoFile is written into the directives as is; a
+// relative name is resolved by the toolchain against the directory of the .go
+// file. Directives are only placed where the position the toolchain would
+// assume differs from the mapped one:
+//   - lines copied from the .dingo source get //line dingoFile:N:C, so
+//     columns stay exact;
+//   - rewritten lines and lines expanded from one .dingo line (error
+//     handling, injected helpers) get //line dingoFile:N of their mapping or
+//     the nearest mapped line above, column unknown;
+//   - blank and comment-only lines, the package clause and anything before
+//     it, and lines inside multi-line raw strings or block comments are left
+//     alone, the latter because a directive there would change the program.
+func InsertLineDirectives(goSrc []byte, dingoFile string, sm *preprocessor.SourceMap) ([]byte, *preprocessor.SourceMap) {
+	lines := strings.SplitAfter(string(goSrc), "\n")
+	packageLine, inside := scanLines(goSrc)
+
+	exact := make(map[int]preprocessor.Mapping, len(sm.Mappings))
+	for _, m := range sm.Mappings {
+		if prev, ok := exact[m.GeneratedLine]; !ok || m.GeneratedColumn < prev.GeneratedColumn {
+			exact[m.GeneratedLine] = m
+		}
+	}
+
+	var out strings.Builder
+	shift := make([]int, len(lines)+2) // Directives inserted up to and including each line
+	inserted := 0
+	active := false   // A directive is in effect
+	colKnown := false // ... and it set a column
+	next := 0         // .dingo line the toolchain assumes for the current line
+
+	for i, text := range lines {
+		line := i + 1
+		trimmed := strings.TrimSpace(text)
+		if line > packageLine && !inside[line] && trimmed != "" && !strings.HasPrefix(trimmed, "//") {
+			if m, ok := exact[line]; ok && m.Name == "identity" {
+				col := m.OriginalColumn - m.GeneratedColumn + 1
+				if col < 1 {
+					col = 1
+				}
+				if !active || !colKnown || next != m.OriginalLine || col != 1 {
+					fmt.Fprintf(&out, "//line %s:%d:%d\n", dingoFile, m.OriginalLine, col)
+					inserted++
+					active, colKnown, next = true, true, m.OriginalLine
+				}
+			} else if target, _, ok := MapLine(sm, line, 1); ok && (!active || next != target) {
+				fmt.Fprintf(&out, "//line %s:%d\n", dingoFile, target)
+				inserted++
+				active, colKnown, next = true, false, target
+			}
+		}
+		shift[line] = inserted
+		out.WriteString(text)
+		next++
+	}
+
+	shifted := *sm
+	shifted.Mappings = make([]preprocessor.Mapping, len(sm.Mappings))
+	for i, m := range sm.Mappings {
+		if m.GeneratedLine >= 1 && m.GeneratedLine <= len(lines) {
+			m.GeneratedLine += shift[m.GeneratedLine]
+		}
+		shifted.Mappings[i] = m
+	}
+	return []byte(out.String()), &shifted
+}
+
+// scanLines returns the line of the package clause and the lines that start
+// inside a multi-line raw string literal or block comment
+func scanLines(src []byte) (packageLine int, inside map[int]bool) {
+	inside = make(map[int]bool)
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.PACKAGE && packageLine == 0 {
+			packageLine = fset.PositionFor(pos, false).Line
+		}
+		if (tok == token.STRING || tok == token.COMMENT) && strings.Contains(lit, "\n") {
+			start := fset.PositionFor(pos, false).Line
+			for line := start + 1; line <= start+strings.Count(lit, "\n"); line++ {
+				inside[line] = true
+			}
+		}
+	}
+	return packageLine, inside
+}
+
+// DirectiveFile returns the name of the .dingo file to write into the
+// //line directives of goPath: relative to the directory of goPath when
+// possible, so that generated files do not embed absolute paths.
+func DirectiveFile(dingoPath, goPath string) string {
+	absDingo, err := filepath.Abs(dingoPath)
+	if err != nil {
+		return dingoPath
+	}
+	absGo, err := filepath.Abs(goPath)
+	if err != nil {
+		return absDingo
+	}
+	rel, err := filepath.Rel(filepath.Dir(absGo), absDingo)
+	if err != nil {
+		return absDingo
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,119 @@
+package sourcemap
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+)
+
+func TestInsertLineDirectives(t *testing.T) {
+	// Generated from (main.dingo):
+	//  1 package main
+	//  2
+	//  3 func load() (string, error) {
+	//  4 	let data = read()?
+	//  5 	return data + `x
+	//  6 y`, nil
+	//  7 }
+	goSrc := "package main\n" +
+		"\n" +
+		"func load() (string, error) {\n" +
+		"\ttmp, err := read()\n" +
+		"\t// dingo:e:0\n" +
+		"\tif err != nil {\n" +
+		"\t\treturn \"\", err\n" +
+		"\t}\n" +
+		"\tvar data = tmp\n" +
+		"\treturn data + `x\n" +
+		"y`, nil\n" +
+		"}\n"
+	sm := &preprocessor.SourceMap{
+		Version: 1,
+		Mappings: []preprocessor.Mapping{
+			{GeneratedLine: 1, GeneratedColumn: 1, OriginalLine: 1, OriginalColumn: 1, Name: "identity"},
+			{GeneratedLine: 3, GeneratedColumn: 1, OriginalLine: 3, OriginalColumn: 1, Name: "identity"},
+			{GeneratedLine: 4, GeneratedColumn: 2, OriginalLine: 4, OriginalColumn: 19, Name: "error_prop"},
+			{GeneratedLine: 10, GeneratedColumn: 1, OriginalLine: 5, OriginalColumn: 1, Name: "identity"},
+			{GeneratedLine: 12, GeneratedColumn: 1, OriginalLine: 7, OriginalColumn: 1, Name: "identity"},
+		},
+	}
+
+	out, shifted := InsertLineDirectives([]byte(goSrc), "main.dingo", sm)
+
+	want := "package main\n" +
+		"\n" +
+		"//line main.dingo:3:1\n" +
+		"func load() (string, error) {\n" +
+		"\ttmp, err := read()\n" +
+		"\t// dingo:e:0\n" +
+		"//line main.dingo:4\n" +
+		"\tif err != nil {\n" +
+		"//line main.dingo:4\n" +
+		"\t\treturn \"\", err\n" +
+		"//line main.dingo:4\n" +
+		"\t}\n" +
+		"//line main.dingo:4\n" +
+		"\tvar data = tmp\n" +
+		"//line main.dingo:5:1\n" +
+		"\treturn data + `x\n" +
+		"y`, nil\n" +
+		"}\n"
+	if string(out) != want {
+		t.Fatalf("InsertLineDirectives() =\n%s\nwant\n%s", out, want)
+	}
+
+	// The shifted map still points at the same text
+	outLines := strings.Split(string(out), "\n")
+	srcLines := strings.Split(goSrc, "\n")
+	for i, m := range shifted.Mappings {
+		if got, want := outLines[m.GeneratedLine-1], srcLines[sm.Mappings[i].GeneratedLine-1]; got != want {
+			t.Errorf("mapping %d now points at %q, want %q", i, got, want)
+		}
+	}
+
+	// The Go toolchain sees .dingo positions
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "/src/main.go", out, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+	positions := make(map[string]string)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			positions[id.Name] = fset.Position(id.Pos()).String() // Last use
+
+		}
+		return true
+	})
+	for name, want := range map[string]string{
+		"main": "/src/main.go:1:9", // Before the first directive
+		"load": "/src/main.dingo:3:6",
+		"read": "/src/main.dingo:4:14", // Continues from the directive above
+		"err":  "/src/main.dingo:4",    // Expanded error handling
+		"tmp":  "/src/main.dingo:4",
+		"data": "/src/main.dingo:5:9",
+		"nil":  "/src/main.dingo:6:5", // After the raw string
+	} {
+		if positions[name] != want {
+			t.Errorf("position of %s = %s, want %s", name, positions[name], want)
+		}
+	}
+}
+
+func TestDirectiveFile(t *testing.T) {
+	tests := []struct {
+		dingo, goPath, want string
+	}{
+		{"/ws/pkg/main.dingo", "/ws/pkg/main.go", "main.dingo"},
+		{"/ws/pkg/main.dingo", "/ws/out/main.go", "../pkg/main.dingo"},
+	}
+	for _, tt := range tests {
+		if got := DirectiveFile(tt.dingo, tt.goPath); got != tt.want {
+			t.Errorf("DirectiveFile(%q, %q) = %q, want %q", tt.dingo, tt.goPath, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/scanner"
 	"go/token"
 	"os"
 	"sort"
@@ -21,6 +22,10 @@ type PostASTGenerator struct {
 	fset          *token.FileSet // From go/parser (single source of truth)
 	goAST         *ast.File      // From go/parser
 	metadata      []preprocessor.TransformMetadata
+
+	// In-memory sources; when nil, matchIdentity reads the files from disk
+	dingoSource []byte
+	goSource    []byte
 }
 
 // NewPostASTGenerator creates a generator from transpilation output
@@ -93,7 +98,8 @@ func (g *PostASTGenerator) matchTransformations() []preprocessor.Mapping {
 		}
 
 		// Extract ACTUAL position from FileSet (GROUND TRUTH)
-		actualPos := g.fset.Position(pos)
+		// Unadjusted: //line directives must not redirect it to the .dingo file
+		actualPos := g.fset.PositionFor(pos, false)
 
 		// Create mapping: original_pos → generated_pos
 		mapping := preprocessor.Mapping{
@@ -138,7 +144,7 @@ func (g *PostASTGenerator) findMarkerPosition(marker string) token.Pos {
 	}
 
 	// Get marker line and check if there's code on the same line (inline comment)
-	markerLine := g.fset.Position(markerPos).Line
+	markerLine := g.fset.PositionFor(markerPos, false).Line
 
 	// Strategy: The marker can be:
 	// 1. Inline comment (same line as code): tmp, err := foo() // dingo:e:0
@@ -154,7 +160,7 @@ func (g *PostASTGenerator) findMarkerPosition(marker string) token.Pos {
 		}
 
 		nodePos := n.Pos()
-		nodeLine := g.fset.Position(nodePos).Line
+		nodeLine := g.fset.PositionFor(nodePos, false).Line
 
 		if nodeLine != markerLine {
 			return true
@@ -190,7 +196,7 @@ func (g *PostASTGenerator) findMarkerPosition(marker string) token.Pos {
 		}
 
 		nodePos := n.Pos()
-		nodeLine := g.fset.Position(nodePos).Line
+		nodeLine := g.fset.PositionFor(nodePos, false).Line
 
 		if nodeLine != targetLine {
 			return true
@@ -248,18 +254,26 @@ func (g *PostASTGenerator) matchIdentity(transformedGoLines map[int]bool) []prep
 	mappings := make([]preprocessor.Mapping, 0)
 
 	// Read .dingo file to get line count
-	dingoContent, err := os.ReadFile(g.dingoFilePath)
-	if err != nil {
-		// If can't read file, return empty (transformations only)
-		return mappings
+	dingoContent := g.dingoSource
+	if dingoContent == nil {
+		var err error
+		dingoContent, err = os.ReadFile(g.dingoFilePath)
+		if err != nil {
+			// If can't read file, return empty (transformations only)
+			return mappings
+		}
 	}
 
 	dingoLines := strings.Split(string(dingoContent), "\n")
 
 	// Read .go file to match content
-	goContent, err := os.ReadFile(g.goFilePath)
-	if err != nil {
-		return mappings
+	goContent := g.goSource
+	if goContent == nil {
+		var err error
+		goContent, err = os.ReadFile(g.goFilePath)
+		if err != nil {
+			return mappings
+		}
 	}
 	goLines := strings.Split(string(goContent), "\n")
 
@@ -276,19 +290,37 @@ func (g *PostASTGenerator) matchIdentity(transformedGoLines map[int]bool) []prep
 	// This prevents multiple identity mappings for the same generated line
 	usedGoLines := make(map[int]bool)
 
-	// For each line in .dingo file without transformation:
-	// Apply line-specific offset to map to correct .go line
+	// Offsets for lines without a content match (rewritten or blank lines)
+	// come from the closest matched line above; generated code before it
+	// (imports, injected types) shifts everything that follows.
+	lineOffsets := make(map[int]int, len(dingoLines))
+	lastOffset := 0
 	for dingoLineNum := 1; dingoLineNum <= len(dingoLines); dingoLineNum++ {
-		if !transformedDingoLines[dingoLineNum] {
-			// Get line-specific offset
-			lineOffset, exists := offsetMap[dingoLineNum]
-			if !exists {
-				// No offset found - try identity mapping
-				lineOffset = 0
+		if offset, exists := offsetMap[dingoLineNum]; exists {
+			lastOffset = offset
+		}
+		lineOffsets[dingoLineNum] = lastOffset
+	}
+
+	// For each line in .dingo file without transformation:
+	// Apply line-specific offset to map to correct .go line.
+	// Passes: content-matched lines first so inferred lines cannot take their
+	// .go line, then inferred offsets, then plain identity for the rest.
+	mappedDingoLines := make(map[int]bool)
+	for pass := 0; pass < 3; pass++ {
+		for dingoLineNum := 1; dingoLineNum <= len(dingoLines); dingoLineNum++ {
+			if transformedDingoLines[dingoLineNum] || mappedDingoLines[dingoLineNum] {
+				continue
+			}
+			if _, matched := offsetMap[dingoLineNum]; matched != (pass == 0) {
+				continue
 			}
 
 			// Calculate corresponding .go line (with offset)
-			goLineNum := dingoLineNum + lineOffset
+			goLineNum := dingoLineNum + lineOffsets[dingoLineNum]
+			if pass == 2 {
+				goLineNum = dingoLineNum // No offset found - try identity mapping
+			}
 
 			// Verify the line exists in .go file
 			if goLineNum < 1 || goLineNum > len(goLines) {
@@ -309,28 +341,118 @@ func (g *PostASTGenerator) matchIdentity(transformedGoLines map[int]bool) []prep
 
 			// Mark this generated line as used
 			usedGoLines[goLineNum] = true
+			mappedDingoLines[dingoLineNum] = true
+
+			// Create mappings with offset applied
+			mappings = append(mappings, lineMappings(dingoLineNum, dingoLines[dingoLineNum-1], goLineNum, goLines[goLineNum-1])...)
+		}
+	}
+
+	return mappings
+}
+
+// maxAlignedTokens bounds the token pairs compared to align a rewritten line
+const maxAlignedTokens = 1 << 16
+
+// lineMappings maps a .dingo line to the .go line generated from it. An
+// unchanged line maps as a whole. In a rewritten line (let x: T became
+// var x T, a lambda became a func literal) each token the two lines share
+// maps on its own, so that columns inside it stay exact; a line sharing no
+// token maps as a whole.
+func lineMappings(dingoLineNum int, dingoLine string, goLineNum int, goLine string) []preprocessor.Mapping {
+	whole := []preprocessor.Mapping{{
+		OriginalLine:    dingoLineNum,
+		OriginalColumn:  1,
+		GeneratedLine:   goLineNum,
+		GeneratedColumn: 1,
+		Length:          len(dingoLine),
+		Name:            "identity",
+	}}
+	if dingoLine == goLine {
+		return whole
+	}
+
+	a, b := lineTokens(dingoLine), lineTokens(goLine)
+	if len(a)*len(b) > maxAlignedTokens {
+		return whole
+	}
+
+	// Longest common subsequence: lcs[i][j] is the length for a[i:], b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].text == b[j].text {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
 
-			// Create mapping with offset applied
-			mapping := preprocessor.Mapping{
+	var mappings []preprocessor.Mapping
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].text == b[j].text:
+			mappings = append(mappings, preprocessor.Mapping{
 				OriginalLine:    dingoLineNum,
-				OriginalColumn:  1,
+				OriginalColumn:  a[i].col,
 				GeneratedLine:   goLineNum,
-				GeneratedColumn: 1,
-				Length:          len(dingoLines[dingoLineNum-1]),
+				GeneratedColumn: b[j].col,
+				Length:          len(a[i].text),
 				Name:            "identity",
-			}
-			mappings = append(mappings, mapping)
+			})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
 		}
 	}
-
+	if len(mappings) == 0 {
+		return whole
+	}
 	return mappings
 }
 
+// lineToken is a token of a single line and its 1-based column
+type lineToken struct {
+	text string
+	col  int
+}
+
+// lineTokens scans a line of Go or Dingo. Dingo-only syntax (=>, ?) scans
+// as Go operators or illegal tokens, which still compare alike.
+func lineTokens(line string) []lineToken {
+	src := []byte(line)
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	var toks []lineToken
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return toks
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+		text := lit
+		if text == "" || tok == token.SEMICOLON {
+			text = tok.String()
+		}
+		toks = append(toks, lineToken{text: text, col: file.Offset(pos) + 1})
+	}
+}
+
 // buildOffsetMap creates a map of line number → offset by matching content
 // Handles variable offsets (e.g., lines before/after import block have different offsets)
 func (g *PostASTGenerator) buildOffsetMap(dingoLines, goLines []string) map[int]int {
 	offsetMap := make(map[int]int)
 	usedGoLines := make(map[int]bool) // Track which go lines have been matched
+	lastOffset := 0                   // Offset of the previous match; injected code shifts it
 
 	// Match each dingo line to corresponding go line by content
 	for i, dingoLine := range dingoLines {
@@ -343,9 +465,9 @@ func (g *PostASTGenerator) buildOffsetMap(dingoLines, goLines []string) map[int]
 		}
 
 		// Search for matching line in .go file (within reasonable range)
-		// Start from same line number, search within ±20 lines
-		searchStart := maxInt(0, i-5)
-		searchEnd := minInt(len(goLines), i+20)
+		// Start from the line implied by the previous match, search -5/+20 lines
+		searchStart := maxInt(0, i+lastOffset-5)
+		searchEnd := minInt(len(goLines), i+lastOffset+20)
 
 		// Find first unused matching line
 		for j := searchStart; j < searchEnd; j++ {
@@ -363,6 +485,7 @@ func (g *PostASTGenerator) buildOffsetMap(dingoLines, goLines []string) map[int]
 				offset := goLineNum - dingoLineNum
 				offsetMap[dingoLineNum] = offset
 				usedGoLines[goLineNum] = true // Mark as used
+				lastOffset = offset
 				break
 			}
 		}
@@ -406,3 +529,24 @@ func GenerateFromFiles(
 	// Generate source map
 	return gen.Generate()
 }
+
+// GenerateFromSource is the in-memory counterpart of GenerateFromFiles: it
+// builds the source map from the .dingo and generated .go contents without
+// touching the disk. The paths are recorded in the map only.
+func GenerateFromSource(
+	dingoPath, goPath string,
+	dingoSrc, goSrc []byte,
+	metadata []preprocessor.TransformMetadata,
+) (*preprocessor.SourceMap, error) {
+	fset := token.NewFileSet()
+	goAST, err := parser.ParseFile(fset, goPath, goSrc, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Go file: %w", err)
+	}
+
+	gen := NewPostASTGenerator(dingoPath, goPath, fset, goAST, metadata)
+	gen.dingoSource = dingoSrc
+	gen.goSource = goSrc
+
+	return gen.Generate()
+}
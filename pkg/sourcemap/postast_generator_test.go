@@ -567,3 +567,34 @@ func test() error {
 	t.Logf("   Original: line %d → Generated: line %d (FileSet verified)",
 		mapping.OriginalLine, mapping.GeneratedLine)
 }
+
+func TestLineMappings_RewrittenLine(t *testing.T) {
+	sm := preprocessor.NewSourceMap()
+	for _, m := range lineMappings(4, "\tlet y: int = \"str\"", 6, "\tvar y int = \"str\"") {
+		sm.AddMapping(m)
+	}
+
+	tests := []struct {
+		name    string
+		goCol   int
+		wantCol int
+	}{
+		{"name", 6, 6},
+		{"type", 8, 9},
+		{"string", 14, 15},
+		{"inside string", 16, 17},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, col, ok := sm.MapToOriginalExact(6, tt.goCol)
+			if !ok || line != 4 || col != tt.wantCol {
+				t.Errorf("MapToOriginalExact(6, %d) = %d:%d, %v; want 4:%d", tt.goCol, line, col, ok, tt.wantCol)
+			}
+		})
+	}
+
+	// The Go-only keyword has no exact origin
+	if _, _, ok := sm.MapToOriginalExact(6, 2); ok {
+		t.Error("var must not map exactly")
+	}
+}
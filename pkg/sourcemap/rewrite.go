@@ -0,0 +1,346 @@
+package sourcemap
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+)
+
+// Load reads the source map of a generated .go file, in either format (see
+// Parse): the one stored next to it (goPath + ".map"), or else the one
+// embedded in it ([sourcemaps] format "inline"). The error satisfies
+// errors.Is(err, fs.ErrNotExist) when there is neither.
+func Load(goPath string) (*preprocessor.SourceMap, error) {
+	data, err := os.ReadFile(goPath + ".map")
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = loadInline(goPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// loadInline reads the source map embedded in the .go file goPath
+func loadInline(goPath string) ([]byte, error) {
+	src, err := os.ReadFile(goPath)
+	if err != nil {
+		return nil, err
+	}
+	data, ok, err := ExtractInline(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", goPath, err)
+	}
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: goPath + ".map", Err: fs.ErrNotExist}
+	}
+	return data, nil
+}
+
+// MapLine maps a generated Go position to its Dingo position. Unlike
+// SourceMap.MapToOriginal it never falls back to identity: generated lines
+// without a mapping of their own (expanded error handling, injected helpers)
+// map to the nearest mapped line above them. ok is false when no mapped line
+// precedes the position.
+func MapLine(sm *preprocessor.SourceMap, line, col int) (dingoLine, dingoCol int, ok bool) {
+	nearest := -1
+	for i, m := range sm.Mappings {
+		if m.GeneratedLine == line {
+			dingoLine, dingoCol = sm.MapToOriginal(line, col)
+			return dingoLine, dingoCol, true
+		}
+		if m.GeneratedLine < line && (nearest < 0 || m.GeneratedLine > sm.Mappings[nearest].GeneratedLine) {
+			nearest = i
+		}
+	}
+	if nearest < 0 {
+		return 0, 0, false
+	}
+	m := sm.Mappings[nearest]
+	return m.OriginalLine, m.OriginalColumn, true
+}
+
+// goPositionRegex matches file.go:LINE and file.go:LINE:COL references
+var goPositionRegex = regexp.MustCompile(`([^\s:()"'\x60]*\.go):(\d+)(?::(\d+))?`)
+
+// Rewriter rewrites references to generated .go positions in tool output
+// (compiler errors, test failures, stack traces) into .dingo positions.
+// It is safe for concurrent use.
+type Rewriter struct {
+	mu       sync.RWMutex
+	maps     map[string]*preprocessor.SourceMap // Absolute .go path -> source map
+	byBase   map[string][]string                // Base name -> absolute .go paths
+	autoLoad bool                               // Load goPath.map of unknown files on demand
+	missing  map[string]bool                    // Absolute .go paths without a source map
+}
+
+// NewRewriter creates an empty rewriter
+func NewRewriter() *Rewriter {
+	return &Rewriter{
+		maps:    make(map[string]*preprocessor.SourceMap),
+		byBase:  make(map[string][]string),
+		missing: make(map[string]bool),
+	}
+}
+
+// SetAutoLoad makes the rewriter load the source map stored next to a .go
+// file (goPath + ".map") the first time a reference to it is seen. This lets
+// stack traces be symbolicated without registering every package up front.
+func (r *Rewriter) SetAutoLoad(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.autoLoad = enabled
+}
+
+// Add registers the source map of a generated .go file
+func (r *Rewriter) Add(goPath string, sm *preprocessor.SourceMap) error {
+	abs, err := filepath.Abs(goPath)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.maps[abs]; !exists {
+		base := filepath.Base(abs)
+		r.byBase[base] = append(r.byBase[base], abs)
+	}
+	r.maps[abs] = sm
+	return nil
+}
+
+// AddDir registers every source map found in dir: *.go.map files and maps
+// embedded in .go files
+func (r *Rewriter) AddDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if err := r.addFile(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddTree registers every source map below root. Hidden directories,
+// vendor and testdata are skipped.
+func (r *Rewriter) AddTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return r.addFile(path)
+	})
+}
+
+// addFile registers the source map path is, or the one embedded in the .go
+// file path. Other files are ignored, and so are .go files with a .go.map,
+// which is registered on its own.
+func (r *Rewriter) addFile(path string) error {
+	var sm *preprocessor.SourceMap
+	var err error
+	goPath := strings.TrimSuffix(path, ".map")
+	switch {
+	case strings.HasSuffix(path, ".go.map"):
+		sm, err = Load(goPath)
+	case strings.HasSuffix(path, ".go"):
+		if _, statErr := os.Stat(path + ".map"); statErr == nil {
+			return nil
+		}
+		var data []byte
+		data, err = loadInline(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Not generated by Dingo
+		}
+		if err == nil {
+			sm, err = Parse(data)
+		}
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load source map of %s: %w", goPath, err)
+	}
+	return r.Add(goPath, sm)
+}
+
+// Len returns the number of registered source maps
+func (r *Rewriter) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.maps)
+}
+
+// Lookup maps a position in a generated .go file to the .dingo file it came
+// from. Relative paths are resolved against dir (when set) and the working
+// directory; a bare file name, or a path recorded on another machine, also
+// matches when it is unambiguous.
+func (r *Rewriter) Lookup(goPath string, line, col int, dir string) (dingoPath string, dingoLine, dingoCol int, ok bool) {
+	sm := r.find(goPath, dir)
+	if sm == nil {
+		return "", 0, 0, false
+	}
+	dingoLine, dingoCol, ok = MapLine(sm, line, col)
+	if !ok {
+		return "", 0, 0, false
+	}
+	return strings.TrimSuffix(goPath, ".go") + ".dingo", dingoLine, dingoCol, true
+}
+
+// Rewrite replaces every file.go:LINE[:COL] reference in text that belongs to
+// a registered generated file with the matching file.dingo:LINE[:COL].
+// dir resolves relative paths, e.g. the package directory of a test.
+func (r *Rewriter) Rewrite(text, dir string) string {
+	if !strings.Contains(text, ".go:") {
+		return text
+	}
+	r.mu.RLock()
+	empty := len(r.maps) == 0 && !r.autoLoad
+	r.mu.RUnlock()
+	if empty {
+		return text
+	}
+
+	return goPositionRegex.ReplaceAllStringFunc(text, func(ref string) string {
+		m := goPositionRegex.FindStringSubmatch(ref)
+		line, _ := strconv.Atoi(m[2])
+		col := 1
+		if m[3] != "" {
+			col, _ = strconv.Atoi(m[3])
+		}
+
+		dingoPath, dingoLine, dingoCol, ok := r.Lookup(m[1], line, col, dir)
+		if !ok {
+			return ref
+		}
+		if m[3] == "" {
+			return fmt.Sprintf("%s:%d", dingoPath, dingoLine)
+		}
+		return fmt.Sprintf("%s:%d:%d", dingoPath, dingoLine, dingoCol)
+	})
+}
+
+// find resolves goPath to a registered source map
+func (r *Rewriter) find(goPath, dir string) *preprocessor.SourceMap {
+	var candidates []string
+	if filepath.IsAbs(goPath) {
+		candidates = append(candidates, filepath.Clean(goPath))
+	} else {
+		if dir != "" {
+			candidates = append(candidates, filepath.Join(dir, goPath))
+		}
+		candidates = append(candidates, goPath)
+		for i, candidate := range candidates {
+			if abs, err := filepath.Abs(candidate); err == nil {
+				candidates[i] = abs
+			}
+		}
+	}
+
+	r.mu.RLock()
+	for _, candidate := range candidates {
+		if sm, ok := r.maps[candidate]; ok {
+			r.mu.RUnlock()
+			return sm
+		}
+	}
+	autoLoad := r.autoLoad
+	r.mu.RUnlock()
+
+	if autoLoad {
+		if sm := r.load(candidates); sm != nil {
+			return sm
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.matchSuffix(goPath)
+}
+
+// load registers the first candidate that has a source map on disk
+func (r *Rewriter) load(candidates []string) *preprocessor.SourceMap {
+	for _, candidate := range candidates {
+		r.mu.RLock()
+		missing := r.missing[candidate]
+		r.mu.RUnlock()
+		if missing {
+			continue
+		}
+
+		sm, err := Load(candidate)
+		if err == nil && r.Add(candidate, sm) == nil {
+			return sm
+		}
+		r.mu.Lock()
+		r.missing[candidate] = true
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// matchSuffix finds a registered file by the trailing elements of goPath.
+// A bare file name (go test prints "foo_test.go:12: ...") must match exactly
+// one registered file. A path from another machine, e.g. a deployed binary
+// built elsewhere, must share its file name and at least the directory above
+// it with exactly one registered file that matches best.
+func (r *Rewriter) matchSuffix(goPath string) *preprocessor.SourceMap {
+	elems := splitPath(goPath)
+	paths := r.byBase[elems[len(elems)-1]]
+	if len(elems) == 1 {
+		if len(paths) == 1 {
+			return r.maps[paths[0]]
+		}
+		return nil
+	}
+
+	best, bestLen, ambiguous := "", 1, false
+	for _, path := range paths {
+		n := commonSuffixLen(elems, splitPath(path))
+		switch {
+		case n > bestLen:
+			best, bestLen, ambiguous = path, n, false
+		case n == bestLen:
+			ambiguous = true
+		}
+	}
+	if best == "" || ambiguous {
+		return nil
+	}
+	return r.maps[best]
+}
+
+// splitPath splits a slash or backslash separated path into its elements
+func splitPath(path string) []string {
+	return strings.FieldsFunc(path, func(c rune) bool { return c == '/' || c == '\\' })
+}
+
+// commonSuffixLen counts the trailing elements a and b share
+func commonSuffixLen(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
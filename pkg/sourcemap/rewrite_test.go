@@ -0,0 +1,210 @@
+package sourcemap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+)
+
+func testSourceMap() *preprocessor.SourceMap {
+	return &preprocessor.SourceMap{
+		Version: 1,
+		Mappings: []preprocessor.Mapping{
+			{GeneratedLine: 1, GeneratedColumn: 1, OriginalLine: 1, OriginalColumn: 1, Length: 12, Name: "identity"},
+			{GeneratedLine: 7, GeneratedColumn: 1, OriginalLine: 5, OriginalColumn: 1, Length: 40, Name: "identity"},
+			{GeneratedLine: 8, GeneratedColumn: 2, OriginalLine: 6, OriginalColumn: 34, Length: 1, Name: "error_prop"},
+			{GeneratedLine: 14, GeneratedColumn: 1, OriginalLine: 7, OriginalColumn: 1, Length: 17, Name: "identity"},
+		},
+	}
+}
+
+func TestMapLine(t *testing.T) {
+	sm := testSourceMap()
+
+	tests := []struct {
+		name     string
+		line     int
+		wantLine int
+		wantOK   bool
+	}{
+		{"mapped line", 7, 5, true},
+		{"expanded error handling maps to nearest line above", 11, 6, true},
+		{"identity line", 14, 7, true},
+		{"before first mapping", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, _, ok := MapLine(sm, tt.line, 1)
+			if ok != tt.wantOK {
+				t.Fatalf("MapLine(%d) ok = %v, want %v", tt.line, ok, tt.wantOK)
+			}
+			if line != tt.wantLine {
+				t.Errorf("MapLine(%d) = %d, want %d", tt.line, line, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestRewriterRewrite(t *testing.T) {
+	dir := t.TempDir()
+	r := NewRewriter()
+	if err := r.Add(filepath.Join(dir, "main_test.go"), testSourceMap()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "test failure with bare file name",
+			input: "    main_test.go:14: got 1, want 2\n",
+			want:  "    main_test.dingo:7: got 1, want 2\n",
+		},
+		{
+			name:  "absolute path in stack trace",
+			input: "\t" + filepath.Join(dir, "main_test.go") + ":8 +0x1d\n",
+			want:  "\t" + filepath.Join(dir, "main_test.dingo") + ":6 +0x1d\n",
+		},
+		{
+			name:  "compiler error with column",
+			input: "./main_test.go:7:3: undefined: foo",
+			want:  "./main_test.dingo:5:3: undefined: foo",
+		},
+		{
+			name:  "unrelated go file untouched",
+			input: "other.go:10: failed",
+			want:  "other.go:10: failed",
+		},
+		{
+			name:  "text without positions untouched",
+			input: "--- FAIL: TestFoo (0.00s)",
+			want:  "--- FAIL: TestFoo (0.00s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Rewrite(tt.input, dir); got != tt.want {
+				t.Errorf("Rewrite() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriterAmbiguousBaseName(t *testing.T) {
+	r := NewRewriter()
+	for _, dir := range []string{t.TempDir(), t.TempDir()} {
+		if err := r.Add(filepath.Join(dir, "main.go"), testSourceMap()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	input := "main.go:14: boom"
+	if got := r.Rewrite(input, ""); got != input {
+		t.Errorf("ambiguous bare name was rewritten: %q", got)
+	}
+}
+
+func TestRewriterAddDir(t *testing.T) {
+	dir := t.TempDir()
+	data, err := testSourceMap().ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib.go.map"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRewriter()
+	if err := r.AddDir(dir); err != nil {
+		t.Fatalf("AddDir() error: %v", err)
+	}
+	if r.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", r.Len())
+	}
+	if got := r.Rewrite("lib.go:7", dir); got != "lib.dingo:5" {
+		t.Errorf("Rewrite() = %q, want %q", got, "lib.dingo:5")
+	}
+}
+
+func TestRewriterAutoLoad(t *testing.T) {
+	dir := t.TempDir()
+	data, err := testSourceMap().ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go.map"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	trace := "main.main()\n\t" + filepath.Join(dir, "main.go") + ":14 +0x1d\n\t" + filepath.Join(dir, "other.go") + ":3 +0x5\n"
+	want := "main.main()\n\t" + filepath.Join(dir, "main.dingo") + ":7 +0x1d\n\t" + filepath.Join(dir, "other.go") + ":3 +0x5\n"
+
+	r := NewRewriter()
+	if got := r.Rewrite(trace, ""); got != trace {
+		t.Errorf("empty rewriter changed the trace: %q", got)
+	}
+	r.SetAutoLoad(true)
+	if got := r.Rewrite(trace, ""); got != want {
+		t.Errorf("Rewrite() = %q, want %q", got, want)
+	}
+	if r.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", r.Len())
+	}
+}
+
+func TestRewriterPathFromOtherMachine(t *testing.T) {
+	root := t.TempDir()
+	r := NewRewriter()
+	for _, rel := range []string{"cmd/server/main.go", "cmd/worker/main.go", "pkg/api/handler.go"} {
+		if err := r.Add(filepath.Join(root, filepath.FromSlash(rel)), testSourceMap()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"/build/src/app/cmd/server/main.go:8 +0x1d", "/build/src/app/cmd/server/main.dingo:6 +0x1d"},
+		{"app/pkg/api/handler.go:7", "app/pkg/api/handler.dingo:5"},
+		{"/build/src/app/cmd/main.go:8", "/build/src/app/cmd/main.go:8"},            // Only the file name matches
+		{"/usr/lib/go/src/api/handler.go:7", "/usr/lib/go/src/api/handler.dingo:5"}, // Directory and name match
+		{"/usr/lib/go/src/runtime/panic.go:770", "/usr/lib/go/src/runtime/panic.go:770"},
+	}
+	for _, tt := range tests {
+		if got := r.Rewrite(tt.input, ""); got != tt.want {
+			t.Errorf("Rewrite(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRewriterAddTree(t *testing.T) {
+	root := t.TempDir()
+	data, err := testSourceMap().ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"main.go.map", "pkg/lib/lib.go.map", ".git/x.go.map", "vendor/dep/dep.go.map"} {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewRewriter()
+	if err := r.AddTree(root); err != nil {
+		t.Fatalf("AddTree() error: %v", err)
+	}
+	if r.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", r.Len())
+	}
+}
@@ -0,0 +1,198 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+)
+
+// DingoExtension holds what a Dingo source map records and Source Map v3
+// cannot express. It is stored under the x_dingo key, which v3 consumers
+// ignore, and lets FromV3 restore the Dingo map exactly. Arrays with an entry
+// per mapping follow the order of the segments in the mappings string.
+type DingoExtension struct {
+	Version    int                    `json:"version"`               // Version of the Dingo map
+	Lengths    []int                  `json:"lengths"`               // Length of each mapping
+	InputLines []int                  `json:"input_lines,omitempty"` // ProcessorInputLine of each mapping, when one is set
+	Columns    [][2]int               `json:"columns,omitempty"`     // Generated and original column of each mapping, when one is below 1
+	Unmapped   []preprocessor.Mapping `json:"unmapped,omitempty"`    // Mappings with a line below 1, which v3 cannot place
+	Order      []int                  `json:"order,omitempty"`       // Index in the Dingo map of each mapping, then of each unmapped one, unless in generated order
+}
+
+// ToV3 converts a Dingo source map to Source Map v3. dingoSrc, when not nil,
+// is embedded as sourcesContent. Dingo columns are 1-based and become the
+// 0-based columns of v3; mapping names go to names.
+func ToV3(sm *preprocessor.SourceMap, dingoSrc []byte) *SourceMapV3 {
+	var placed, unmapped []int
+	for i, m := range sm.Mappings {
+		if m.GeneratedLine >= 1 && m.OriginalLine >= 1 {
+			placed = append(placed, i)
+		} else {
+			unmapped = append(unmapped, i)
+		}
+	}
+	// v3 segments are in generated order
+	sort.SliceStable(placed, func(a, b int) bool {
+		ma, mb := sm.Mappings[placed[a]], sm.Mappings[placed[b]]
+		if ma.GeneratedLine != mb.GeneratedLine {
+			return ma.GeneratedLine < mb.GeneratedLine
+		}
+		return max(ma.GeneratedColumn, 1) < max(mb.GeneratedColumn, 1)
+	})
+
+	ext := &DingoExtension{Version: sm.Version, Lengths: make([]int, len(placed))}
+	segments := make([]Mapping, len(placed))
+	inputLines := make([]int, len(placed))
+	columns := make([][2]int, len(placed))
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for k, i := range placed {
+		m := sm.Mappings[i]
+		segments[k] = Mapping{
+			SourceLine:   m.OriginalLine,
+			SourceColumn: max(m.OriginalColumn, 1),
+			GenLine:      m.GeneratedLine,
+			GenColumn:    max(m.GeneratedColumn, 1),
+			Name:         m.Name,
+		}
+		if m.Name != "" && !seen[m.Name] {
+			seen[m.Name] = true
+			names = append(names, m.Name)
+		}
+		ext.Lengths[k] = m.Length
+		inputLines[k] = m.ProcessorInputLine
+		if m.ProcessorInputLine != 0 {
+			ext.InputLines = inputLines
+		}
+		columns[k] = [2]int{m.GeneratedColumn, m.OriginalColumn}
+		if m.GeneratedColumn < 1 || m.OriginalColumn < 1 {
+			ext.Columns = columns
+		}
+	}
+	for _, i := range unmapped {
+		ext.Unmapped = append(ext.Unmapped, sm.Mappings[i])
+	}
+	order := append(placed, unmapped...)
+	for k, i := range order {
+		if k != i {
+			ext.Order = order
+			break
+		}
+	}
+
+	v3 := &SourceMapV3{
+		Version:  3,
+		File:     sm.GoFile,
+		Sources:  []string{sm.DingoFile},
+		Names:    names,
+		Mappings: generateVLQMappings(segments, names),
+		Dingo:    ext,
+	}
+	if dingoSrc != nil {
+		v3.SourcesContent = []string{string(dingoSrc)}
+	}
+	return v3
+}
+
+// FromV3 converts a Source Map v3 to a Dingo source map, the inverse of ToV3.
+// Maps produced by other tools are converted too: the Dingo map describes
+// their first source, with mappings of length 0.
+func FromV3(v3 *SourceMapV3) (*preprocessor.SourceMap, error) {
+	if v3.Version != 3 {
+		return nil, fmt.Errorf("not a v3 source map: version %d", v3.Version)
+	}
+	segments, err := decodeVLQMappings(v3.Mappings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid v3 mappings: %w", err)
+	}
+
+	sm := &preprocessor.SourceMap{Version: 1, GoFile: v3.File, Mappings: make([]preprocessor.Mapping, 0, len(segments))}
+	if len(v3.Sources) > 0 {
+		root := v3.SourceRoot
+		if root != "" && !strings.HasSuffix(root, "/") {
+			root += "/"
+		}
+		sm.DingoFile = root + v3.Sources[0]
+	}
+	for _, seg := range segments {
+		if seg.source != 0 {
+			continue // Unmapped, or mapped to another source
+		}
+		m := preprocessor.Mapping{
+			GeneratedLine:   seg.genLine + 1,
+			GeneratedColumn: seg.genColumn + 1,
+			OriginalLine:    seg.sourceLine + 1,
+			OriginalColumn:  seg.sourceCol + 1,
+		}
+		if seg.name >= 0 && seg.name < len(v3.Names) {
+			m.Name = v3.Names[seg.name]
+		}
+		sm.Mappings = append(sm.Mappings, m)
+	}
+
+	ext := v3.Dingo
+	if ext == nil {
+		return sm, nil
+	}
+	n := len(sm.Mappings)
+	if len(ext.Lengths) != n || (ext.InputLines != nil && len(ext.InputLines) != n) || (ext.Columns != nil && len(ext.Columns) != n) {
+		return nil, fmt.Errorf("x_dingo does not match the %d mappings", n)
+	}
+	for k := range sm.Mappings {
+		sm.Mappings[k].Length = ext.Lengths[k]
+		if ext.InputLines != nil {
+			sm.Mappings[k].ProcessorInputLine = ext.InputLines[k]
+		}
+		if ext.Columns != nil {
+			sm.Mappings[k].GeneratedColumn, sm.Mappings[k].OriginalColumn = ext.Columns[k][0], ext.Columns[k][1]
+		}
+	}
+	sm.Mappings = append(sm.Mappings, ext.Unmapped...)
+	if ext.Order != nil {
+		if len(ext.Order) != len(sm.Mappings) {
+			return nil, fmt.Errorf("x_dingo order has %d entries for %d mappings", len(ext.Order), len(sm.Mappings))
+		}
+		ordered := make([]preprocessor.Mapping, len(sm.Mappings))
+		filled := make([]bool, len(sm.Mappings))
+		for k, i := range ext.Order {
+			if i < 0 || i >= len(ordered) || filled[i] {
+				return nil, fmt.Errorf("x_dingo order is not a permutation")
+			}
+			ordered[i], filled[i] = sm.Mappings[k], true
+		}
+		sm.Mappings = ordered
+	}
+	sm.Version = ext.Version
+	return sm, nil
+}
+
+// MarshalV3 encodes sm as an indented Source Map v3, see ToV3
+func MarshalV3(sm *preprocessor.SourceMap, dingoSrc []byte) ([]byte, error) {
+	data, err := json.MarshalIndent(ToV3(sm, dingoSrc), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode source map: %w", err)
+	}
+	return data, nil
+}
+
+// Parse decodes a source map in either format: the Dingo format, or Source
+// Map v3, which is converted with FromV3
+func Parse(data []byte) (*preprocessor.SourceMap, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse source map: %w", err)
+	}
+	if header.Version != 3 {
+		return preprocessor.FromJSON(data)
+	}
+	var v3 SourceMapV3
+	if err := json.Unmarshal(data, &v3); err != nil {
+		return nil, fmt.Errorf("failed to parse source map: %w", err)
+	}
+	return FromV3(&v3)
+}
@@ -0,0 +1,144 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+)
+
+func TestV3RoundTrip(t *testing.T) {
+	sm := &preprocessor.SourceMap{
+		Version:   1,
+		DingoFile: "main.dingo",
+		GoFile:    "main.go",
+		Mappings: []preprocessor.Mapping{
+			{GeneratedLine: 7, GeneratedColumn: 2, OriginalLine: 4, OriginalColumn: 6, Length: 9, Name: "expr_mapping"},
+			{GeneratedLine: 3, GeneratedColumn: 1, OriginalLine: 3, OriginalColumn: 1, Length: 4, ProcessorInputLine: 2},
+			{GeneratedLine: 7, GeneratedColumn: 0, OriginalLine: 4, OriginalColumn: 0, Length: 1}, // Line-level
+			{GeneratedLine: 0, GeneratedColumn: 1, OriginalLine: 5, OriginalColumn: 1, Length: 2}, // Unplaced
+			{GeneratedLine: 10, GeneratedColumn: 5, OriginalLine: 6, OriginalColumn: 3, Length: 3, Name: "expr_mapping"},
+		},
+	}
+	src := []byte("package main\n\nfunc main() {\n\tlet x = f()?\n}\n")
+
+	data, err := MarshalV3(sm, src)
+	if err != nil {
+		t.Fatalf("MarshalV3 failed: %v", err)
+	}
+	var v3 SourceMapV3
+	if err := json.Unmarshal(data, &v3); err != nil {
+		t.Fatal(err)
+	}
+	if v3.Version != 3 || !reflect.DeepEqual(v3.Sources, []string{"main.dingo"}) || v3.File != "main.go" {
+		t.Errorf("unexpected v3 header: %+v", v3)
+	}
+	if !reflect.DeepEqual(v3.SourcesContent, []string{string(src)}) {
+		t.Errorf("sourcesContent = %q", v3.SourcesContent)
+	}
+	if !reflect.DeepEqual(v3.Names, []string{"expr_mapping"}) {
+		t.Errorf("names = %q", v3.Names)
+	}
+
+	// Standard consumers see the positions
+	c, err := NewConsumer(data)
+	if err != nil {
+		t.Fatalf("NewConsumer failed: %v", err)
+	}
+	pos, err := c.Source(10, 5)
+	if err != nil {
+		t.Fatalf("Source failed: %v", err)
+	}
+	if pos.Filename != "main.dingo" || pos.Line != 6 || pos.Column != 3 {
+		t.Errorf("Source(10, 5) = %v, want main.dingo:6:3", pos)
+	}
+
+	// Dingo reads back exactly what it wrote
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, sm) {
+		t.Errorf("round trip mismatch:\ngot  %+v\nwant %+v", got, sm)
+	}
+
+	// The Dingo schema still parses
+	data, err = json.Marshal(sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Parse(data); err != nil || !reflect.DeepEqual(got, sm) {
+		t.Errorf("Parse(dingo) = %+v, %v", got, err)
+	}
+}
+
+func TestFromV3Foreign(t *testing.T) {
+	// A map from another tool: no x_dingo and a source root
+	g := NewGenerator("main.dingo", "main.go")
+	g.AddMappingWithName(pos(2, 1), pos(3, 1), "main")
+	g.AddMapping(pos(4, 2), pos(6, 5))
+	data, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v3 SourceMapV3
+	if err := json.Unmarshal(data, &v3); err != nil {
+		t.Fatal(err)
+	}
+	v3.SourceRoot = "src"
+
+	sm, err := FromV3(&v3)
+	if err != nil {
+		t.Fatalf("FromV3 failed: %v", err)
+	}
+	want := &preprocessor.SourceMap{
+		Version:   1,
+		DingoFile: "src/main.dingo",
+		GoFile:    "main.go",
+		Mappings: []preprocessor.Mapping{
+			{GeneratedLine: 3, GeneratedColumn: 1, OriginalLine: 2, OriginalColumn: 1, Name: "main"},
+			{GeneratedLine: 6, GeneratedColumn: 5, OriginalLine: 4, OriginalColumn: 2},
+		},
+	}
+	if !reflect.DeepEqual(sm, want) {
+		t.Errorf("FromV3 = %+v, want %+v", sm, want)
+	}
+
+	v3.Dingo = &DingoExtension{Version: 1, Lengths: []int{1}}
+	if _, err := FromV3(&v3); err == nil {
+		t.Error("FromV3 accepted an x_dingo that does not match the mappings")
+	}
+	v3.Dingo, v3.Mappings = nil, "A!"
+	if _, err := FromV3(&v3); err == nil {
+		t.Error("FromV3 accepted invalid mappings")
+	}
+}
+
+func TestDecodeVLQMappings(t *testing.T) {
+	mappings := []Mapping{
+		{GenLine: 1, GenColumn: 1, SourceLine: 1, SourceColumn: 1},
+		{GenLine: 1, GenColumn: 20, SourceLine: 3, SourceColumn: 4, Name: "b"},
+		{GenLine: 4, GenColumn: 2, SourceLine: 2, SourceColumn: 1, Name: "a"},
+		{GenLine: 4, GenColumn: 900, SourceLine: 300, SourceColumn: 70},
+	}
+	names := []string{"a", "b"}
+	segments, err := decodeVLQMappings(generateVLQMappings(mappings, names))
+	if err != nil {
+		t.Fatalf("decodeVLQMappings failed: %v", err)
+	}
+	want := []segment{
+		{genLine: 0, genColumn: 0, source: 0, sourceLine: 0, sourceCol: 0, name: -1},
+		{genLine: 0, genColumn: 19, source: 0, sourceLine: 2, sourceCol: 3, name: 1},
+		{genLine: 3, genColumn: 1, source: 0, sourceLine: 1, sourceCol: 0, name: 0},
+		{genLine: 3, genColumn: 899, source: 0, sourceLine: 299, sourceCol: 69, name: -1},
+	}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("decodeVLQMappings = %+v, want %+v", segments, want)
+	}
+}
+
+func pos(line, column int) token.Position {
+	return token.Position{Line: line, Column: column}
+}
@@ -1,5 +1,10 @@
 package sourcemap
 
+import (
+	"fmt"
+	"strings"
+)
+
 // VLQ (Variable Length Quantity) encoding for source maps
 // Based on the Source Map v3 specification
 
@@ -53,18 +58,25 @@ func encodeVLQSegment(values []int) string {
 	return result
 }
 
-// generateVLQMappings generates VLQ-encoded mappings string
-func generateVLQMappings(mappings []Mapping) string {
+// generateVLQMappings generates VLQ-encoded mappings string. Mappings with a
+// name get its index in names as 5th field.
+func generateVLQMappings(mappings []Mapping, names []string) string {
 	if len(mappings) == 0 {
 		return ""
 	}
 
+	nameIndex := make(map[string]int, len(names))
+	for i, name := range names {
+		nameIndex[name] = i
+	}
+
 	var result string
 	var prevGenLine = 0
 	var prevGenColumn = 0
 	var prevSourceIndex = 0
 	var prevSourceLine = 0
 	var prevSourceColumn = 0
+	var prevName = 0
 
 	for _, m := range mappings {
 		// Each new generated line is separated by ';'
@@ -89,9 +101,10 @@ func generateVLQMappings(mappings []Mapping) string {
 			m.SourceLine - 1 - prevSourceLine,     // Original line (delta)
 			m.SourceColumn - 1 - prevSourceColumn, // Original column (delta)
 		}
-
-		// TODO: Add name index if m.Name is set (5th field)
-		// For now, we only encode the 4-field version
+		if i, ok := nameIndex[m.Name]; ok && m.Name != "" {
+			segment = append(segment, i-prevName) // Name index (delta)
+			prevName = i
+		}
 
 		result += encodeVLQSegment(segment)
 
@@ -105,3 +118,76 @@ func generateVLQMappings(mappings []Mapping) string {
 
 	return result
 }
+
+// segment is a decoded mapping segment. Fields are absolute and 0-based;
+// source and name are -1 when the segment has none.
+type segment struct {
+	genLine, genColumn    int
+	source                int
+	sourceLine, sourceCol int
+	name                  int
+}
+
+// decodeVLQMappings decodes the mappings string of a Source Map v3
+func decodeVLQMappings(mappings string) ([]segment, error) {
+	var segments []segment
+	var genColumn, source, sourceLine, sourceCol, name int
+	for line, group := range strings.Split(mappings, ";") {
+		genColumn = 0
+		for _, field := range strings.Split(group, ",") {
+			if field == "" {
+				continue
+			}
+			values, err := decodeVLQSegment(field)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line+1, err)
+			}
+			genColumn += values[0]
+			seg := segment{genLine: line, genColumn: genColumn, source: -1, name: -1}
+			switch len(values) {
+			case 1:
+			case 4, 5:
+				source += values[1]
+				sourceLine += values[2]
+				sourceCol += values[3]
+				seg.source, seg.sourceLine, seg.sourceCol = source, sourceLine, sourceCol
+				if len(values) == 5 {
+					name += values[4]
+					seg.name = name
+				}
+			default:
+				return nil, fmt.Errorf("line %d: segment %q has %d fields", line+1, field, len(values))
+			}
+			segments = append(segments, seg)
+		}
+	}
+	return segments, nil
+}
+
+// decodeVLQSegment decodes the VLQ values of one segment
+func decodeVLQSegment(field string) ([]int, error) {
+	var values []int
+	value, shift := 0, 0
+	for i := 0; i < len(field); i++ {
+		digit := strings.IndexByte(base64Chars, field[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base64 VLQ character %q", field[i])
+		}
+		value += (digit & vlqBaseMask) << shift
+		if digit&vlqContinuationBit != 0 {
+			shift += vlqBaseShift
+			continue
+		}
+		if value&1 != 0 {
+			value = -(value >> 1)
+		} else {
+			value >>= 1
+		}
+		values = append(values, value)
+		value, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("truncated VLQ value in %q", field)
+	}
+	return values, nil
+}
@@ -123,7 +123,7 @@ func TestGenerateVLQMappings(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := generateVLQMappings(tt.mappings)
+			result := generateVLQMappings(tt.mappings, nil)
 			if result != tt.expected {
 				t.Errorf("generateVLQMappings() = %q, expected %q", result, tt.expected)
 			}
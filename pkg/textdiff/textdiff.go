@@ -0,0 +1,231 @@
+// Package textdiff produces line-based unified diffs.
+// It is used by commands that show proposed source changes (dingo fmt -d).
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// OpKind identifies a line-level edit
+type OpKind int
+
+const (
+	OpEqual OpKind = iota
+	OpDelete
+	OpInsert
+)
+
+// Op is a single line of an edit script
+type Op struct {
+	Kind OpKind
+	Line string // Line content without trailing newline
+}
+
+// Lines splits text into lines. A trailing newline does not produce an empty final line.
+func Lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff returns the shortest edit script turning a into b (Myers' algorithm)
+func Diff(a, b []string) []Op {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+
+	// v[k+offset] holds the furthest x reached on diagonal k.
+	// trace keeps a copy of v for every edit distance d, used to backtrack.
+	offset := max
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset] // Move down (insertion)
+			} else {
+				x = v[k-1+offset] + 1 // Move right (deletion)
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+offset] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Backtrack from (n, m) to (0, 0)
+	ops := make([]Op, 0, n+m)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+offset]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, Op{Kind: OpEqual, Line: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, Op{Kind: OpInsert, Line: b[prevY]})
+			} else {
+				ops = append(ops, Op{Kind: OpDelete, Line: a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	// Reverse into forward order
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// Unified returns a unified diff between a and b, or "" when they are equal.
+// fromName and toName label the --- and +++ headers.
+func Unified(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := Diff(Lines(a), Lines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	for _, h := range hunks(ops, DefaultContext) {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(h.fromLine, h.fromCount), hunkRange(h.toLine, h.toCount))
+		for _, op := range h.ops {
+			switch op.Kind {
+			case OpEqual:
+				sb.WriteString(" ")
+			case OpDelete:
+				sb.WriteString("-")
+			case OpInsert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(op.Line)
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// hunk is a group of changes with surrounding context
+type hunk struct {
+	fromLine, fromCount int // 1-based start line and length in a
+	toLine, toCount     int // 1-based start line and length in b
+	ops                 []Op
+}
+
+// hunks groups an edit script into hunks with context lines around each change
+func hunks(ops []Op, context int) []hunk {
+	var result []hunk
+
+	i := 0
+	aLine, bLine := 1, 1
+	for i < len(ops) {
+		// Skip to the next change
+		if ops[i].Kind == OpEqual {
+			aLine++
+			bLine++
+			i++
+			continue
+		}
+
+		// Start the hunk up to `context` equal lines before the change
+		start := i
+		for start > 0 && i-start < context && ops[start-1].Kind == OpEqual {
+			start--
+		}
+		h := hunk{fromLine: aLine - (i - start), toLine: bLine - (i - start)}
+
+		// Extend until a run of more than 2*context equal lines (or the end)
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != OpEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == OpEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		h.ops = ops[start:end]
+		for _, op := range h.ops {
+			if op.Kind != OpInsert {
+				h.fromCount++
+			}
+			if op.Kind != OpDelete {
+				h.toCount++
+			}
+		}
+		result = append(result, h)
+
+		// Advance line counters past the hunk
+		for _, op := range ops[i:end] {
+			if op.Kind != OpInsert {
+				aLine++
+			}
+			if op.Kind != OpDelete {
+				bLine++
+			}
+		}
+		i = end
+	}
+
+	return result
+}
+
+// hunkRange formats a unified diff range ("start,count")
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// Empty ranges refer to the line before the insertion point
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,84 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified_Equal(t *testing.T) {
+	if got := Unified("a", "b", "x\ny\n", "x\ny\n"); got != "" {
+		t.Errorf("Expected empty diff, got %q", got)
+	}
+}
+
+func TestUnified_SingleChange(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	b := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n"
+
+	want := `--- a.dingo
++++ b.dingo
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`
+	if got := Unified("a.dingo", "b.dingo", a, b); got != want {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_SeparateHunks(t *testing.T) {
+	var aLines, bLines []string
+	for i := 0; i < 20; i++ {
+		line := string(rune('a' + i))
+		aLines = append(aLines, line)
+		switch i {
+		case 1:
+			bLines = append(bLines, "B")
+		case 17:
+			// deleted
+		default:
+			bLines = append(bLines, line)
+		}
+	}
+	got := Unified("x", "y", strings.Join(aLines, "\n")+"\n", strings.Join(bLines, "\n")+"\n")
+
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Fatalf("Expected 2 hunks, got %d:\n%s", n, got)
+	}
+	if !strings.Contains(got, "@@ -1,5 +1,5 @@") {
+		t.Errorf("Expected first hunk header @@ -1,5 +1,5 @@, got:\n%s", got)
+	}
+	if !strings.Contains(got, "@@ -15,6 +15,5 @@") {
+		t.Errorf("Expected second hunk header @@ -15,6 +15,5 @@, got:\n%s", got)
+	}
+}
+
+func TestDiff_Roundtrip(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"", "x\n"},
+		{"x\n", ""},
+		{"a\nb\nc\n", "a\nc\nd\n"},
+		{"a\nb\na\nb\n", "b\na\nb\na\n"},
+	}
+	for _, tt := range tests {
+		var rebuiltA, rebuiltB []string
+		for _, op := range Diff(Lines(tt.a), Lines(tt.b)) {
+			if op.Kind != OpInsert {
+				rebuiltA = append(rebuiltA, op.Line)
+			}
+			if op.Kind != OpDelete {
+				rebuiltB = append(rebuiltB, op.Line)
+			}
+		}
+		if strings.Join(rebuiltA, "\n") != strings.Join(Lines(tt.a), "\n") ||
+			strings.Join(rebuiltB, "\n") != strings.Join(Lines(tt.b), "\n") {
+			t.Errorf("Diff(%q, %q) does not reproduce inputs", tt.a, tt.b)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package transpiler
+
+import (
+	"context"
+	"errors"
+	"go/scanner"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/generator"
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+)
+
+// CheckSource transpiles src in memory and turns every failure into
+// diagnostics at .dingo positions instead of stopping at the first error.
+// pkgSources holds the .dingo sources of the whole package for unqualified
+// import inference; nil means src is the only file.
+//
+// When transpilation succeeds, the generated Go and its source map are
+// returned, with any warnings, so callers can type-check the result. Nothing
+// touches the disk.
+func (t *Transpiler) CheckSource(
+	inputPath string,
+	src []byte,
+	pkgSources map[string][]byte,
+) ([]byte, *preprocessor.SourceMap, []diagnostic.Diagnostic) {
+	res, err := Transpile(context.Background(), inputPath, src, Options{Config: t.config, PackageSources: pkgSources})
+	if err != nil {
+		return nil, nil, nil // Not reached: the context is never done
+	}
+	if diagnostic.HasErrors(res.Diagnostics) {
+		return nil, nil, res.Diagnostics
+	}
+	sm := res.SourceMap
+	if sm == nil {
+		sm = preprocessor.NewSourceMap()
+	}
+	return res.Go, sm, res.Diagnostics
+}
+
+// goOutputPath returns the .go path generated for a .dingo file
+func goOutputPath(inputPath string) string {
+	if strings.HasSuffix(inputPath, ".dingo") {
+		return strings.TrimSuffix(inputPath, ".dingo") + ".go"
+	}
+	return inputPath + ".go"
+}
+
+// transpileDiagnostics converts a transpile error into diagnostics at .dingo
+// positions
+func transpileDiagnostics(inputPath string, src []byte, res *transpileResult, err error) []diagnostic.Diagnostic {
+	newDiag := func(code string, line, col int, source, message string) diagnostic.Diagnostic {
+		d := diagnostic.Diagnostic{Code: code, Severity: diagnostic.SeverityError, Message: message, Source: source}
+		d.Pos.Line, d.Pos.Column = line, col
+		setFilename(&d, inputPath)
+		d.ExtendToToken(src)
+		return d
+	}
+
+	// Preprocessing failed: no Go was produced, the preprocessor reports .dingo
+	// lines, possibly several as processors recover from errors
+	if res.fset == nil {
+		var list diagnostic.List
+		if errors.As(err, &list) {
+			diags := make([]diagnostic.Diagnostic, len(list))
+			for i, d := range list {
+				diags[i] = *d
+				setFilename(&diags[i], inputPath)
+				diags[i].ExtendToToken(src)
+			}
+			return diags
+		}
+		var d *diagnostic.Diagnostic
+		if errors.As(err, &d) {
+			out := *d
+			setFilename(&out, inputPath)
+			out.ExtendToToken(src)
+			return []diagnostic.Diagnostic{out}
+		}
+		message := strings.TrimPrefix(err.Error(), "preprocessing error: ")
+		return []diagnostic.Diagnostic{newDiag(diagnostic.CodeSyntax, 0, 0, diagnostic.SourcePreprocess, message)}
+	}
+
+	var parseErrs scanner.ErrorList
+	if errors.As(err, &parseErrs) {
+		diags := make([]diagnostic.Diagnostic, 0, len(parseErrs))
+		for _, e := range parseErrs {
+			line, col := res.preMap.MapPosition(e.Pos.Line, e.Pos.Column)
+			diags = append(diags, newDiag(diagnostic.CodeGoSyntax, line, col, diagnostic.SourceParse, e.Msg))
+		}
+		return diags
+	}
+
+	var compileErrs *generator.CompileErrors
+	if errors.As(err, &compileErrs) {
+		diags := make([]diagnostic.Diagnostic, 0, len(compileErrs.Errors))
+		for _, e := range compileErrs.Errors {
+			var d *diagnostic.Diagnostic
+			if !errors.As(e, &d) {
+				diags = append(diags, newDiag(diagnostic.CodeGeneration, 0, 0, diagnostic.SourceDingo, e.Error()))
+				continue
+			}
+			// Plugins report positions in the preprocessed Go
+			out := *d
+			res.preMap.MapDiagnostic(&out)
+			if out.Code == diagnostic.CodeNonExhaustive {
+				addMatchArmFixes(&out, d.Pos.Line, src, res.goSource)
+			}
+			setFilename(&out, inputPath)
+			out.ExtendToToken(src)
+			diags = append(diags, out)
+		}
+		return diags
+	}
+
+	return []diagnostic.Diagnostic{newDiag(diagnostic.CodeGeneration, 0, 0, diagnostic.SourceDingo, err.Error())}
+}
+
+// setFilename attributes every position of d, a copy of a producer's
+// diagnostic, to filename
+func setFilename(d *diagnostic.Diagnostic, filename string) {
+	d.Pos.Filename = filename
+	if d.End.Line > 0 {
+		d.End.Filename = filename
+	}
+	d.Labels = append([]diagnostic.Label(nil), d.Labels...)
+	for i := range d.Labels {
+		d.Labels[i].Pos.Filename = filename
+		if d.Labels[i].End.Line > 0 {
+			d.Labels[i].End.Filename = filename
+		}
+	}
+	d.Fixes = append([]diagnostic.Fix(nil), d.Fixes...)
+	for i := range d.Fixes {
+		d.Fixes[i].Edits = append([]diagnostic.Edit(nil), d.Fixes[i].Edits...)
+		for j := range d.Fixes[i].Edits {
+			d.Fixes[i].Edits[j].Pos.Filename = filename
+			d.Fixes[i].Edits[j].End.Filename = filename
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package transpiler
+
+import (
+	"context"
+
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+)
+
+// Stage is the source after one step of the transpile pipeline
+type Stage struct {
+	// Name identifies the step: "source", a preprocessor name (generic_syntax,
+	// rust_match, lambda, ...), "imports", "plugin:<name>", "transform",
+	// "post_ast", "output" or "line_directives"
+	Name string
+
+	Source []byte
+
+	// Metadata holds the transformations the step emitted (preprocessors
+	// implementing FeatureProcessorV2 only)
+	Metadata []preprocessor.TransformMetadata
+}
+
+// Expand transpiles src in memory and records the source after every stage,
+// starting with the input itself. When transpilation fails, the stages that
+// completed are returned together with the error.
+func (t *Transpiler) Expand(inputPath string, src []byte) ([]Stage, error) {
+	stages := []Stage{{Name: "source", Source: src}}
+	cfg := t.cfg()
+	res, err := transpile(context.Background(), cfg, inputPath, src, map[string][]byte{inputPath: src}, false, func(stage Stage) {
+		stages = append(stages, stage)
+	})
+	if err != nil {
+		return stages, err
+	}
+
+	if cfg.SourceMap.LineDirectives {
+		if err := finish(cfg, res, inputPath, goOutputPath(inputPath), src); err != nil {
+			return stages, err
+		}
+		stages = append(stages, Stage{Name: "line_directives", Source: res.code})
+	}
+	return stages, nil
+}
+
+// preprocessorHook adapts onStage to the preprocessor's stage hook
+func preprocessorHook(onStage func(Stage)) preprocessor.StageHook {
+	return func(stage string, source []byte, metadata []preprocessor.TransformMetadata) {
+		onStage(Stage{
+			Name:     stage,
+			Source:   append([]byte(nil), source...),
+			Metadata: append([]preprocessor.TransformMetadata(nil), metadata...),
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package transpiler
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+)
+
+// matchStartMarker marks every match expression in the preprocessed Go
+const matchStartMarker = "DINGO_MATCH_START"
+
+// addMatchArmFixes turns the missing arms of a non-exhaustive match into
+// edits of the .dingo source: the first fix inserts an arm for every missing
+// pattern, the second a wildcard arm. goLine is the line of the match in the
+// preprocessed Go. The match is found by its rank among the matches of the
+// file, which also points d at its match keyword. Single-line matches keep
+// their fixes as advice.
+func addMatchArmFixes(d *diagnostic.Diagnostic, goLine int, src []byte, goSource string) {
+	if len(d.MissingItems) == 0 || len(d.Fixes) != 2 {
+		return
+	}
+	rank := 0
+	for i, line := range strings.Split(goSource, "\n") {
+		if i >= goLine {
+			break
+		}
+		if strings.Contains(line, matchStartMarker) {
+			rank++
+		}
+	}
+	if rank == 0 {
+		return
+	}
+
+	toks := scanTokens(src)
+	kw := nthMatchKeyword(toks, rank)
+	if kw < 0 {
+		return
+	}
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	file.SetLinesForContent(src)
+	position := func(offset int) token.Position {
+		p := file.Position(file.Pos(offset))
+		return token.Position{Line: p.Line, Column: p.Column}
+	}
+	d.Pos = position(toks[kw].offset)
+	d.End = position(toks[kw].offset + len("match"))
+
+	// The arms go on their own lines before the closing brace
+	open, closing := -1, -1
+	depth := 0
+	for i := kw + 1; i < len(toks) && closing < 0; i++ {
+		switch toks[i].tok {
+		case token.LBRACE:
+			if open < 0 {
+				open = i
+			}
+			depth++
+		case token.RBRACE:
+			depth--
+			if open >= 0 && depth == 0 {
+				closing = i
+			}
+		}
+	}
+	if closing < 0 {
+		return
+	}
+	openPos, closePos := position(toks[open].offset), position(toks[closing].offset)
+	lineStart := toks[closing].offset - (closePos.Column - 1)
+	if openPos.Line == closePos.Line || strings.TrimSpace(string(src[lineStart:toks[closing].offset])) != "" {
+		return
+	}
+	indent := string(src[lineStart:toks[closing].offset]) + "\t"
+	if open+1 < closing {
+		first := toks[open+1].offset
+		firstStart := first - (position(first).Column - 1)
+		indent = string(src[firstStart:first])
+	}
+
+	var comma []diagnostic.Edit
+	if last := toks[closing-1]; closing-1 > open && last.tok != token.COMMA {
+		end := position(last.end)
+		comma = []diagnostic.Edit{{Pos: end, End: end, NewText: ","}}
+	}
+	insert := func(patterns ...string) []diagnostic.Edit {
+		var b strings.Builder
+		for _, p := range patterns {
+			fmt.Fprintf(&b, "%s%s => todo,\n", indent, p)
+		}
+		at := token.Position{Line: closePos.Line, Column: 1}
+		return append(append([]diagnostic.Edit(nil), comma...), diagnostic.Edit{Pos: at, End: at, NewText: b.String()})
+	}
+
+	wildcard := "_"
+	if first := d.MissingItems[0]; strings.HasPrefix(first, "(") {
+		n := len(strings.Split(first, ", "))
+		wildcard = "(" + strings.TrimSuffix(strings.Repeat("_, ", n), ", ") + ")"
+	}
+	d.Fixes = []diagnostic.Fix{
+		{Message: d.Fixes[0].Message, Edits: insert(d.MissingItems...)},
+		{Message: d.Fixes[1].Message, Edits: insert(wildcard)},
+	}
+}
+
+// sourceToken is a token of .dingo source, spanning [offset, end)
+type sourceToken struct {
+	tok         token.Token
+	lit         string
+	offset, end int
+	lineStart   bool // Follows a semicolon inserted at a line end
+}
+
+// scanTokens scans src as Go, skipping comments and the semicolons inserted
+// at line ends. Dingo-only syntax (=>, ?) scans as operators or errors, which
+// is enough to find brackets.
+func scanTokens(src []byte) []sourceToken {
+	file := token.NewFileSet().AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+	var toks []sourceToken
+	lineStart := true
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return toks
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			lineStart = true
+			continue
+		}
+		offset := file.Offset(pos)
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		toks = append(toks, sourceToken{tok: tok, lit: lit, offset: offset, end: offset + len(text), lineStart: lineStart})
+		lineStart = false
+	}
+}
+
+// nthMatchKeyword returns the index of the nth (1-based) match keyword in
+// toks, or -1. The token before match tells whether it starts an
+// expression, since the scrutinee may begin with any operand or operator
+// (match *p, match <-ch, match []int{...}). A match identifier that is
+// assigned or selected from is not a keyword.
+func nthMatchKeyword(toks []sourceToken, n int) int {
+	for i, t := range toks {
+		if t.tok != token.IDENT || t.lit != "match" || i+1 == len(toks) || !startsExpr(toks, i) {
+			continue
+		}
+		if next := toks[i+1].tok; next == token.ASSIGN || next == token.DEFINE || next == token.PERIOD {
+			continue
+		}
+		if n--; n == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// startsExpr reports whether toks[i] is where a statement or an operand
+// starts: the first token of a statement, or one after =, :=, return, (,
+// a comma or the => of a match arm
+func startsExpr(toks []sourceToken, i int) bool {
+	if i == 0 || toks[i].lineStart {
+		return true
+	}
+	prev := toks[i-1]
+	switch prev.tok {
+	case token.SEMICOLON, token.LBRACE, token.RBRACE, token.ASSIGN, token.DEFINE, token.RETURN, token.LPAREN, token.COMMA:
+		return true
+	case token.GTR: // =>
+		return i >= 2 && toks[i-2].tok == token.ASSIGN && toks[i-2].end == prev.offset
+	}
+	return false
+}
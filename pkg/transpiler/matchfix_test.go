@@ -0,0 +1,42 @@
+package transpiler
+
+import "testing"
+
+func TestNthMatchKeyword(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string // Text after the first match keyword found, or "" for none
+	}{
+		{"statement start", "match x {\n}", "x {"},
+		{"after let", "let v = match x {\n}", "x {"},
+		{"after return", "return match x {\n}", "x {"},
+		{"dereference", "match *p {\n}", "*p {"},
+		{"receive", "v := match <-ch {\n}", "<-ch {"},
+		{"composite literal", "f(match []int{1, 2} {\n})", "[]int{1"},
+		{"tuple", "return match (a, b) {\n}", "(a, b)"},
+		{"selector", "s.match(x)", ""},
+		{"assigned", "match = 1\nmatch := 2", ""},
+		{"operand", "n := a * match", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte(tt.src)
+			toks := scanTokens(src)
+			got := ""
+			if i := nthMatchKeyword(toks, 1); i >= 0 {
+				start := toks[i].end + 1
+				got = string(src[start:min(start+len(tt.want), len(src))])
+			}
+			if got != tt.want {
+				t.Errorf("nthMatchKeyword(%q) found %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+
+	// The match of an arm counts as well
+	toks := scanTokens([]byte("match x {\n\tA => match y {\n\t},\n}"))
+	if i := nthMatchKeyword(toks, 2); i < 0 || toks[i+1].lit != "y" {
+		t.Errorf("nthMatchKeyword(2) = %d, want the match of the arm", i)
+	}
+}
@@ -0,0 +1,289 @@
+package transpiler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/generator"
+	"github.com/MadAppGang/dingo/pkg/preprocessor"
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
+)
+
+// Options configures Transpile
+type Options struct {
+	// Config selects the language features; nil means config.DefaultConfig()
+	Config *config.Config
+
+	// GoPath is the file the generated Go is meant for. It is recorded in the
+	// source map and anchors the .dingo path of //line directives. Defaults
+	// to filename with .dingo replaced by .go.
+	GoPath string
+
+	// PackageSources holds the .dingo sources of the package, used to tell
+	// local functions from unqualified stdlib calls and to type-check the
+	// package as a whole; nil means src is the only .dingo file of its
+	// package. See PackageSources.
+	PackageSources map[string][]byte
+
+	// SharedTypes leaves the types the plugins inject (Option_int,
+	// Result_int_error, tuples, ...) out of Go and reports them in
+	// Result.Types instead, so that a package declares each of them once:
+	// WriteFiles then maintains the package's TypesFile. It only applies
+	// when PackageSources holds more than one file: the Go of a package with
+	// a single .dingo file declares its types itself.
+	SharedTypes bool
+}
+
+// PackageSources returns the .dingo sources of filename's package as read
+// from disk, with src for filename itself. Files that cannot be read are
+// left out.
+func PackageSources(filename string, src []byte) map[string][]byte {
+	sources := map[string][]byte{filename: src}
+	paths, _ := filepath.Glob(filepath.Join(filepath.Dir(filename), "*.dingo"))
+	for _, path := range paths {
+		if filepath.Clean(path) == filepath.Clean(filename) {
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			sources[path] = data
+		}
+	}
+	return sources
+}
+
+// Result is the outcome of Transpile
+type Result struct {
+	Go          []byte                           // Generated Go; nil when an error was reported
+	SourceMap   *preprocessor.SourceMap          // Go → .dingo positions; nil without Go, with format "none" or if it could not be built
+	Metadata    []preprocessor.TransformMetadata // Transformations done by the preprocessors
+	Diagnostics []diagnostic.Diagnostic          // Problems at .dingo positions, sorted
+	Timings     Timings
+
+	// The package name of Go and, when its types are shared (see
+	// Options.SharedTypes), the injected types it uses, which Go does not
+	// declare
+	Package     string
+	Types       []generator.TypeDecl
+	sharedTypes bool
+
+	sourceMapFormat config.SourceMapFormat // Where WriteFiles puts SourceMap
+	sourceMapSchema config.SourceMapSchema // How WriteFiles encodes SourceMap
+	dingoSrc        []byte                 // Embedded in v3 source maps
+}
+
+// Timings records how long each stage of the pipeline took
+type Timings struct {
+	Preprocess time.Duration
+	Parse      time.Duration
+	Generate   time.Duration // Plugins, code generation and source map
+}
+
+// Transpile converts the Dingo source src to Go. filename is used for
+// positions and package context only: nothing is written to the filesystem,
+// and Transpile is safe for concurrent use. To type-check the package as a
+// whole, the .go files next to filename are read and the packages they
+// import are loaded by the go command. The imports of a directory stay
+// cached until they, go.mod, go.sum or an imported module-local package
+// change; the preprocessed siblings in PackageSources until their source
+// does.
+//
+// Problems in the source are reported as diagnostics of the result, not as
+// an error. A source map that cannot be built is a warning, since the Go is
+// still usable. The error is non-nil only when ctx is done before the
+// pipeline finished.
+func Transpile(ctx context.Context, filename string, src []byte, opts Options) (*Result, error) {
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	goPath := opts.GoPath
+	if goPath == "" {
+		goPath = goOutputPath(filename)
+	}
+	pkgSources := opts.PackageSources
+	if pkgSources == nil {
+		pkgSources = map[string][]byte{filename: src}
+	}
+
+	shared := opts.SharedTypes && len(pkgSources) > 1
+	res, err := transpile(ctx, cfg, filename, src, pkgSources, shared, nil)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	result := &Result{
+		Metadata:        res.metadata,
+		Timings:         res.timings,
+		sourceMapFormat: cfg.SourceMap.OutputFormat(),
+		sourceMapSchema: cfg.SourceMap.Schema,
+		dingoSrc:        src,
+	}
+	if err != nil {
+		result.Diagnostics = transpileDiagnostics(filename, src, res, err)
+		diagnostic.Sort(result.Diagnostics)
+		return result, nil
+	}
+
+	start := time.Now()
+	if err := finish(cfg, res, filename, goPath, src); err != nil {
+		d := diagnostic.Diagnostic{
+			Code:     diagnostic.CodeSourceMap,
+			Severity: diagnostic.SeverityWarning,
+			Pos:      token.Position{Filename: filename},
+			Message:  err.Error(),
+			Source:   diagnostic.SourceMapping,
+		}
+		result.Diagnostics = append(result.Diagnostics, d)
+	}
+	result.Timings.Generate += time.Since(start)
+	result.Go, result.SourceMap, result.Package = res.code, res.sourceMap, res.pkgName
+	if shared {
+		result.Types, result.sharedTypes = res.types, true
+	}
+	return result, nil
+}
+
+// SharesTypes reports whether the Go leaves the injected types to the
+// package's TypesFile (see Options.SharedTypes)
+func (r *Result) SharesTypes() bool {
+	return r.sharedTypes
+}
+
+// Err returns the error diagnostics of the result as an error, or nil when
+// Go was generated
+func (r *Result) Err() error {
+	var errs []diagnostic.Diagnostic
+	for _, d := range r.Diagnostics {
+		if d.Severity == diagnostic.SeverityError {
+			errs = append(errs, d)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &Error{Diagnostics: errs}
+}
+
+// Error is a failed transpilation
+type Error struct {
+	Diagnostics []diagnostic.Diagnostic
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteFiles writes the generated Go to goPath and, when there is one, its
+// source map as [sourcemaps] format says: embedded in the Go ("inline"), in
+// goPath+".map" ("separate") or both, encoded as [sourcemaps] schema says.
+// A goPath+".map" left by an earlier build is removed when none is written.
+// When the types are shared, the TypesFile next to goPath is updated with the
+// types the Go uses; otherwise goPath is dropped from it, since the Go
+// declares its types itself.
+func (r *Result) WriteFiles(goPath string) error {
+	if r.Go == nil {
+		return fmt.Errorf("no Go code to write to %s", goPath)
+	}
+	format := r.sourceMapFormat
+	if r.SourceMap == nil {
+		format = config.FormatNone
+	}
+
+	var data []byte
+	if format != config.FormatNone {
+		var err error
+		if data, err = r.encodeSourceMap(goPath); err != nil {
+			return err
+		}
+	}
+
+	code := r.Go
+	if format == config.FormatInline || format == config.FormatBoth {
+		var err error
+		if code, err = sourcemap.AppendInline(code, data); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(goPath, code, 0644); err != nil {
+		return err
+	}
+	dir, name := filepath.Split(goPath)
+	if _, err := os.Stat(filepath.Join(dir, TypesFile)); r.sharedTypes || err == nil {
+		if err := updateTypesFile(filepath.Clean(dir), name, r.Package, r.Types); err != nil {
+			return fmt.Errorf("failed to update %s: %w", TypesFile, err)
+		}
+	}
+
+	if format != config.FormatSeparate && format != config.FormatBoth {
+		if err := os.Remove(goPath + ".map"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(goPath+".map", data, 0644)
+}
+
+// TypesFileFor returns the TypesFile that WriteFiles(goPath) would leave
+// next to goPath, without writing anything: nil when there would be none
+// or when the types are not shared. Editors use it to show the package as it
+// will be once the file is saved.
+func (r *Result) TypesFileFor(goPath string) ([]byte, error) {
+	if r.Go == nil || !r.sharedTypes {
+		return nil, nil
+	}
+	dir, name := filepath.Split(goPath)
+	typesFileMu.Lock()
+	defer typesFileMu.Unlock()
+	return mergeTypesFile(filepath.Clean(dir), name, r.Package, r.Types)
+}
+
+// encodeSourceMap encodes SourceMap for the Go written to goPath, in the
+// schema of [sourcemaps] schema. Its paths are relative to the directory of
+// goPath, so that builds on different machines write the same files.
+func (r *Result) encodeSourceMap(goPath string) ([]byte, error) {
+	sm := *r.SourceMap
+	sm.GoFile = filepath.Base(goPath)
+	if sm.DingoFile != "" {
+		sm.DingoFile = relativeTo(filepath.Dir(goPath), sm.DingoFile)
+	}
+	if r.sourceMapSchema == config.SchemaV3 {
+		return sourcemap.MarshalV3(&sm, r.dingoSrc)
+	}
+	data, err := json.MarshalIndent(&sm, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode source map: %w", err)
+	}
+	return data, nil
+}
+
+// relativeTo returns path relative to dir with forward slashes, or path when
+// it has no relative form
+func relativeTo(dir, path string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return path
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,928 @@
+package transpiler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/generator"
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
+	"github.com/MadAppGang/dingo/pkg/transpiler"
+)
+
+const readConfigSrc = `package main
+
+func readConfig(path string) ([]byte, error) {
+	let data = os.ReadFile(path)?
+	return data, nil
+}
+`
+
+func TestTranspile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "config.dingo") // Never created
+
+	res, err := transpiler.Transpile(context.Background(), filename, []byte(readConfigSrc), transpiler.Options{})
+	if err != nil {
+		t.Fatalf("Transpile failed: %v", err)
+	}
+	if err := res.Err(); err != nil {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+	if len(res.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics: %v", res.Diagnostics)
+	}
+	if !strings.Contains(string(res.Go), "os.ReadFile(path)") {
+		t.Errorf("generated Go lacks the call:\n%s", res.Go)
+	}
+	if res.SourceMap == nil || len(res.SourceMap.Mappings) == 0 {
+		t.Error("expected a source map")
+	}
+	if len(res.Metadata) == 0 {
+		t.Error("expected transform metadata for the ? operator")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Transpile wrote %d file(s)", len(entries))
+	}
+}
+
+func TestTranspileDiagnostics(t *testing.T) {
+	src := []byte(`package main
+
+func broken() {
+	x := foo bar
+}
+`)
+	res, err := transpiler.Transpile(context.Background(), "broken.dingo", src, transpiler.Options{})
+	if err != nil {
+		t.Fatalf("Transpile failed: %v", err)
+	}
+	if res.Go != nil || res.SourceMap != nil {
+		t.Error("expected no Go and no source map on error")
+	}
+
+	var transpileErr *transpiler.Error
+	if !errors.As(res.Err(), &transpileErr) || len(transpileErr.Diagnostics) == 0 {
+		t.Fatalf("Err() = %v, want a *transpiler.Error", res.Err())
+	}
+
+	d := res.Diagnostics[0]
+	if d.Severity != diagnostic.SeverityError || d.Source != diagnostic.SourceParse || d.Code != diagnostic.CodeGoSyntax {
+		t.Errorf("unexpected diagnostic kind: %+v", d)
+	}
+	if d.Pos.Filename != "broken.dingo" || d.Pos.Line != 4 || d.Pos.Column != 11 {
+		t.Errorf("diagnostic at %s, want broken.dingo:4:11", d.Pos)
+	}
+	if d.End.Line != 4 || d.End.Column != 14 {
+		t.Errorf("diagnostic ends at %d:%d, want 4:14 (end of bar)", d.End.Line, d.End.Column)
+	}
+}
+
+func TestTranspileReportsEveryError(t *testing.T) {
+	src := []byte(`package main
+
+func a(r Result) int {
+	match r {
+	}
+	return 0
+}
+
+func b(r Result) int {
+	match r {
+	}
+	return 0
+}
+`)
+	res, err := transpiler.Transpile(context.Background(), "matches.dingo", src, transpiler.Options{})
+	if err != nil {
+		t.Fatalf("Transpile failed: %v", err)
+	}
+	if len(res.Diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", res.Diagnostics)
+	}
+	for i, line := range []int{4, 10} {
+		d := res.Diagnostics[i]
+		if d.Code != diagnostic.CodeMatch || d.Pos.Filename != "matches.dingo" || d.Pos.Line != line {
+			t.Errorf("diagnostic %d = %s [%s], want a match error at line %d", i, d, d.Code, line)
+		}
+	}
+}
+
+func TestTranspileOptions(t *testing.T) {
+	src := []byte(`package main
+
+func apply(f func(int) int) int {
+	return f(1)
+}
+
+func main() {
+	apply(|x| x + 1)
+}
+`)
+	res, err := transpiler.Transpile(context.Background(), "lambda.dingo", src, transpiler.Options{})
+	if err != nil {
+		t.Fatalf("Transpile failed: %v", err)
+	}
+	if res.Err() == nil {
+		t.Fatal("rust lambdas must not be accepted with the default (typescript) style")
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Features.LambdaStyle = "rust"
+	res, err = transpiler.Transpile(context.Background(), "lambda.dingo", src, transpiler.Options{
+		Config: cfg,
+		GoPath: "out/lambda.go",
+	})
+	if err != nil {
+		t.Fatalf("Transpile failed: %v", err)
+	}
+	if err := res.Err(); err != nil {
+		t.Fatalf("rust lambdas must follow the config: %v", err)
+	}
+	if res.SourceMap.GoFile != "out/lambda.go" {
+		t.Errorf("source map Go file = %q, want out/lambda.go", res.SourceMap.GoFile)
+	}
+}
+
+func TestTranspileTypeChecksPackage(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":    "module example.com/app\n\ngo 1.21\n",
+		"helper.go": "package main\n\nfunc apply(f func(int) int) int {\n\treturn f(1)\n}\n",
+		"util/util.go": "package util\n\nfunc Each(xs []string, f func(string) bool) bool {\n" +
+			"\tfor _, x := range xs {\n\t\tif !f(x) {\n\t\t\treturn false\n\t\t}\n\t}\n\treturn true\n}\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sibling := []byte(`package main
+
+func twice(f func(string) string) string {
+	return f(f("a"))
+}
+`)
+	src := []byte(`package main
+
+import "example.com/app/util"
+
+func main() {
+	println(apply((x) => x + 1))
+	println(twice((s) => s + "!"))
+	println(util.Each([]string{"a"}, (s) => s != ""))
+}
+`)
+	filename := filepath.Join(dir, "main.dingo")
+	res, err := transpiler.Transpile(context.Background(), filename, src, transpiler.Options{
+		PackageSources: map[string][]byte{
+			filename:                          src,
+			filepath.Join(dir, "twice.dingo"): sibling,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Transpile failed: %v", err)
+	}
+	if err := res.Err(); err != nil {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+	for _, want := range []string{
+		"func(x int) int { return x + 1 }",         // Declared in a .go file
+		`func(s string) string { return s + "!" }`, // Declared in a sibling .dingo file
+		`func(s string) bool { return s != "" }`,   // Declared in a module-local package
+	} {
+		if !strings.Contains(string(res.Go), want) {
+			t.Errorf("generated Go lacks %q:\n%s", want, res.Go)
+		}
+	}
+}
+
+func TestTranspileSeesPackageChanges(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/app\n\ngo 1.21\n")
+	write("util/util.go", "package util\n\nfunc Apply(f func(string) bool) bool {\n\treturn f(\"\")\n}\n")
+
+	src := []byte(`package main
+
+import "example.com/app/util"
+
+func main() {
+	println(util.Apply((x) => x == x))
+	println(twice((y) => y))
+}
+`)
+	filename := filepath.Join(dir, "main.dingo")
+	sibling := filepath.Join(dir, "twice.dingo")
+	transpile := func(siblingSrc string, want ...string) {
+		t.Helper()
+		res, err := transpiler.Transpile(context.Background(), filename, src, transpiler.Options{
+			PackageSources: map[string][]byte{filename: src, sibling: []byte(siblingSrc)},
+		})
+		if err != nil || res.Err() != nil {
+			t.Fatalf("Transpile failed: %v %v", err, res.Err())
+		}
+		for _, w := range want {
+			if !strings.Contains(string(res.Go), w) {
+				t.Errorf("generated Go lacks %q:\n%s", w, res.Go)
+			}
+		}
+	}
+
+	twice := "package main\n\nfunc twice(f func(string) string) string {\n\tvar zero string\n\treturn f(f(zero))\n}\n"
+	transpile(twice, "func(x string) bool", "func(y string) string")
+
+	// The imports and siblings of a package are cached: changes must show
+	write("util/util.go", "package util\n\nfunc Apply(f func(int) bool) bool {\n\treturn f(0)\n}\n")
+	transpile(strings.ReplaceAll(twice, "string", "int"), "func(x int) bool", "func(y int) int")
+}
+
+func TestTranspileSharedTypes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string]string{
+		"a": "package main\n\nfunc pair() int {\n\tt := (1, \"a\")\n\treturn t._0\n}\n",
+		"b": "package main\n\nfunc other() string {\n\tt := (2, \"b\")\n\treturn t._1\n}\n",
+	}
+	pkgSources := func(name, src string) map[string][]byte {
+		pkg := make(map[string][]byte, len(sources))
+		for other, otherSrc := range sources {
+			pkg[filepath.Join(dir, other+".dingo")] = []byte(otherSrc)
+		}
+		pkg[filepath.Join(dir, name+".dingo")] = []byte(src)
+		return pkg
+	}
+	build := func(name string) {
+		t.Helper()
+		dingoPath := filepath.Join(dir, name+".dingo")
+		res, err := transpiler.Transpile(context.Background(), dingoPath, []byte(sources[name]), transpiler.Options{
+			PackageSources: pkgSources(name, sources[name]),
+			SharedTypes:    true,
+		})
+		if err != nil || res.Err() != nil {
+			t.Fatalf("Transpile failed: %v %v", err, res.Err())
+		}
+		if strings.Contains(string(res.Go), "type Tuple2IntString") {
+			t.Errorf("%s: generated Go declares the shared type:\n%s", name, res.Go)
+		}
+		if len(res.Types) != 1 || res.Types[0].Name != "Tuple2IntString" || res.Package != "main" {
+			t.Errorf("%s: got types %+v in package %q", name, res.Types, res.Package)
+		}
+		if err := res.WriteFiles(filepath.Join(dir, name+".go")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	typesFile := func() string {
+		t.Helper()
+		src, err := os.ReadFile(filepath.Join(dir, transpiler.TypesFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !transpiler.IsTypesFile(src) {
+			t.Errorf("%s lacks the generated header:\n%s", transpiler.TypesFile, src)
+		}
+		return string(src)
+	}
+
+	build("a")
+	build("b")
+	if got := strings.Count(typesFile(), "type Tuple2IntString struct"); got != 1 {
+		t.Errorf("Tuple2IntString declared %d times:\n%s", got, typesFile())
+	}
+
+	// Rebuilding one file keeps what the other one uses
+	build("a")
+	if src := typesFile(); !strings.Contains(src, "dingo:uses b.go Tuple2IntString") {
+		t.Errorf("rebuilding a.go dropped b.go:\n%s", src)
+	}
+
+	// Generated files that are gone are forgotten
+	if err := os.Remove(filepath.Join(dir, "b.go")); err != nil {
+		t.Fatal(err)
+	}
+	build("a")
+	if src := typesFile(); strings.Contains(src, "b.go") {
+		t.Errorf("deleted b.go is still recorded:\n%s", src)
+	}
+
+	// TypesFileFor previews the TypesFile without writing it
+	edited := "package main\n\nfunc pair() int {\n\treturn 1\n}\n"
+	res, err := transpiler.Transpile(context.Background(), filepath.Join(dir, "a.dingo"), []byte(edited), transpiler.Options{
+		PackageSources: pkgSources("a", edited),
+		SharedTypes:    true,
+	})
+	if err != nil || res.Err() != nil {
+		t.Fatalf("Transpile failed: %v %v", err, res.Err())
+	}
+	before := typesFile()
+	if src, err := res.TypesFileFor(filepath.Join(dir, "a.go")); err != nil || src != nil {
+		t.Errorf("TypesFileFor = %q, %v; want nil once no file uses a type", src, err)
+	}
+	if typesFile() != before {
+		t.Errorf("TypesFileFor changed %s", transpiler.TypesFile)
+	}
+}
+
+func TestTranspileSharedTypes_SingleFile(t *testing.T) {
+	dir := t.TempDir()
+	dingoPath, goPath := filepath.Join(dir, "main.dingo"), filepath.Join(dir, "main.go")
+	src := []byte("package main\n\nfunc main() {\n\tt := (1, \"a\")\n\tprintln(t._0)\n}\n")
+
+	// A stale TypesFile from when the package had more files
+	stale, err := transpiler.TypesFileSource("main", []generator.TypeDecl{{Name: "Tuple2IntString", Source: "type Tuple2IntString struct {\n\t_0 int\n\t_1 string\n}\n"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale = []byte(strings.Replace(string(stale), "\npackage main", "\n// dingo:uses main.go Tuple2IntString\n\npackage main", 1))
+	if err := os.WriteFile(filepath.Join(dir, transpiler.TypesFile), stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The only .dingo file of its package declares its types itself
+	res, err := transpiler.Transpile(context.Background(), dingoPath, src, transpiler.Options{
+		PackageSources: transpiler.PackageSources(dingoPath, src),
+		SharedTypes:    true,
+	})
+	if err != nil || res.Err() != nil {
+		t.Fatalf("Transpile failed: %v %v", err, res.Err())
+	}
+	if res.SharesTypes() || len(res.Types) != 0 || !strings.Contains(string(res.Go), "type Tuple2IntString struct") {
+		t.Errorf("single-file package shares its types %+v:\n%s", res.Types, res.Go)
+	}
+	if err := res.WriteFiles(goPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, transpiler.TypesFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stale %s was kept: %v", transpiler.TypesFile, err)
+	}
+}
+
+func TestTranspileImportedSumTypes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "a"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lib := []byte(`package a
+
+type User struct {
+	Name string
+}
+
+enum Shape {
+	Circle(float64),
+	Rect(float64, float64),
+	Point,
+}
+
+func Find(id int) Option_User {
+	if id > 0 {
+		return Option_User_Some(User{Name: "x"})
+	}
+	return None
+}
+`)
+	libPath := filepath.Join(dir, "a", "a.dingo")
+	res, err := transpiler.Transpile(context.Background(), libPath, lib, transpiler.Options{SharedTypes: true})
+	if err != nil || res.Err() != nil {
+		t.Fatalf("Transpile of package a failed: %v %v", err, res.Err())
+	}
+	if err := res.WriteFiles(filepath.Join(dir, "a", "a.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	src := `package main
+
+import "example.com/app/a"
+
+func describe(s a.Shape) float64 {
+	match s {
+		Shape_Circle(r) => return r,
+		Shape_Rect(w, h) => return w * h,
+		Shape_Point => return 0,
+	}
+	return 0
+}
+
+func name(id int) string {
+	u := a.Find(id)
+	match u {
+		Some(x) => return x.Name,
+		None => return "",
+	}
+	return ""
+}
+
+func nobody() a.Option_User {
+	return None
+}
+`
+	filename := filepath.Join(dir, "main.dingo")
+	res, err = transpiler.Transpile(context.Background(), filename, []byte(src), transpiler.Options{SharedTypes: true})
+	if err != nil || res.Err() != nil {
+		t.Fatalf("Transpile of package main failed: %v %v", err, res.Err())
+	}
+	for _, want := range []string{
+		"case scrutinee.IsCircle():",
+		"w, _ := scrutinee.Rect()",
+		"_, h := scrutinee.Rect()",
+		"x := scrutinee2.Unwrap()",
+		"return a.Option_User_None()",
+	} {
+		if !strings.Contains(string(res.Go), want) {
+			t.Errorf("generated Go lacks %q:\n%s", want, res.Go)
+		}
+	}
+	if len(res.Types) != 0 {
+		t.Errorf("package main redeclares types of package a: %+v", res.Types)
+	}
+
+	// Exhaustiveness is checked against the variants package a declares
+	src = strings.Replace(src, "\t\tShape_Point => return 0,\n", "", 1)
+	res, err = transpiler.Transpile(context.Background(), filename, []byte(src), transpiler.Options{SharedTypes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Diagnostics) != 1 || res.Diagnostics[0].Code != diagnostic.CodeNonExhaustive || !strings.Contains(res.Diagnostics[0].Message, "Point") {
+		t.Errorf("got diagnostics %v, want a non-exhaustive match missing Point", res.Diagnostics)
+	}
+	if got := res.Diagnostics[0].MissingItems; !reflect.DeepEqual(got, []string{"Shape_Point"}) {
+		t.Errorf("MissingItems = %v, want the prefix of the other arms", got)
+	}
+}
+
+func TestTranspileMatchArmFixes(t *testing.T) {
+	src := []byte(`package main
+
+enum Shape {
+	Point,
+	Circle { radius: float64 },
+	Pair(int, string),
+}
+
+func area(s Shape) float64 {
+	return match s {
+		Point => 0.0
+	}
+}
+
+func half(r Result<int, error>) int {
+	return match r {
+		Ok(v) => v / 2,
+	}
+}
+`)
+	res, err := transpiler.Transpile(context.Background(), "shapes.dingo", src, transpiler.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Diagnostics) != 2 {
+		t.Fatalf("expected 2 diagnostics, got %v", res.Diagnostics)
+	}
+
+	at := func(line, col int) token.Position {
+		return token.Position{Filename: "shapes.dingo", Line: line, Column: col}
+	}
+	insert := func(line int, text string) diagnostic.Edit {
+		return diagnostic.Edit{Pos: at(line, 1), End: at(line, 1), NewText: text}
+	}
+	comma := diagnostic.Edit{Pos: at(11, 15), End: at(11, 15), NewText: ","}
+	tests := []struct {
+		line     int
+		missing  []string
+		arms     []diagnostic.Edit
+		wildcard []diagnostic.Edit
+	}{
+		{
+			line:     10,
+			missing:  []string{"Circle(radius)", "Pair(value1, value2)"},
+			arms:     []diagnostic.Edit{comma, insert(12, "\t\tCircle(radius) => todo,\n\t\tPair(value1, value2) => todo,\n")},
+			wildcard: []diagnostic.Edit{comma, insert(12, "\t\t_ => todo,\n")},
+		},
+		{
+			line:     16,
+			missing:  []string{"Err(err)"},
+			arms:     []diagnostic.Edit{insert(18, "\t\tErr(err) => todo,\n")},
+			wildcard: []diagnostic.Edit{insert(18, "\t\t_ => todo,\n")},
+		},
+	}
+	for i, tt := range tests {
+		d := res.Diagnostics[i]
+		if d.Code != diagnostic.CodeNonExhaustive || d.Pos != at(tt.line, 9) || d.End != at(tt.line, 14) {
+			t.Errorf("diagnostic %d = %s [%s] ending at %s, want a non-exhaustive match at line %d", i, d, d.Code, d.End, tt.line)
+		}
+		if !reflect.DeepEqual(d.MissingItems, tt.missing) {
+			t.Errorf("diagnostic %d MissingItems = %q, want %q", i, d.MissingItems, tt.missing)
+		}
+		if len(d.Fixes) != 2 {
+			t.Fatalf("diagnostic %d has fixes %+v, want missing arms and wildcard", i, d.Fixes)
+		}
+		if !reflect.DeepEqual(d.Fixes[0].Edits, tt.arms) {
+			t.Errorf("diagnostic %d arms edits = %+v, want %+v", i, d.Fixes[0].Edits, tt.arms)
+		}
+		if !reflect.DeepEqual(d.Fixes[1].Edits, tt.wildcard) {
+			t.Errorf("diagnostic %d wildcard edits = %+v, want %+v", i, d.Fixes[1].Edits, tt.wildcard)
+		}
+	}
+}
+
+func TestTranspileMatchArmFixesAfterOperatorScrutinees(t *testing.T) {
+	// Scrutinees starting with an operator must not shift which match the
+	// diagnostic points at
+	src := []byte(`package main
+
+enum Shape {
+	Point,
+	Circle { radius: float64 },
+}
+
+func describe(p *Shape, ch chan Shape) string {
+	let a = match *p {
+		Point => "point",
+		Circle(r) => "circle",
+	}
+	let b = match <-ch {
+		Point => "point",
+		Circle(r) => "circle",
+	}
+	return match *p {
+		Point => a + b
+	}
+}
+`)
+	res, err := transpiler.Transpile(context.Background(), "shapes.dingo", src, transpiler.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %v", res.Diagnostics)
+	}
+	d := res.Diagnostics[0]
+	at := func(line, col int) token.Position {
+		return token.Position{Filename: "shapes.dingo", Line: line, Column: col}
+	}
+	if d.Code != diagnostic.CodeNonExhaustive || d.Pos != at(17, 9) || d.End != at(17, 14) {
+		t.Errorf("diagnostic = %s [%s] ending at %s, want the match of line 17", d, d.Code, d.End)
+	}
+	want := []diagnostic.Edit{
+		{Pos: at(18, 17), End: at(18, 17), NewText: ","},
+		{Pos: at(19, 1), End: at(19, 1), NewText: "\t\t_ => todo,\n"},
+	}
+	if len(d.Fixes) != 2 || !reflect.DeepEqual(d.Fixes[1].Edits, want) {
+		t.Errorf("fixes = %+v, want the wildcard edits %+v", d.Fixes, want)
+	}
+}
+
+// genericRuntimeDecls starts every source of TestTranspileGenericRuntime:
+// find makes the plugins declare Option_User, which generic mode replaces
+// with the runtime library
+const genericRuntimeDecls = `package main
+
+type User struct {
+	Name    string
+	Address *Address
+}
+
+type Address struct {
+	City string
+	Geo  *Geo
+}
+
+type Geo struct {
+	Lat, Lng float64
+}
+
+func find(id int) Option_User {
+	if id > 0 {
+		return Option_User_Some(User{Name: "ann"})
+	}
+	return None
+}
+`
+
+func TestTranspileGenericRuntime(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "option match",
+			src: `
+func describe(id int) string {
+	u := find(id)
+	match u {
+		Some(x) => return x.Name,
+		None => return "nobody",
+	}
+	return ""
+}
+`,
+			want: []string{
+				"func find(id int) dingo.Option[User]",
+				"return dingo.Some[User](User{Name: \"ann\"})",
+				"return dingo.None[User]()",
+				"case scrutinee.IsSome():",
+				"x := scrutinee.Unwrap()",
+			},
+		},
+		{
+			name: "result match",
+			src: `
+func parse(s string) Result_int_error {
+	return Ok(len(s))
+}
+
+func size(s string) int {
+	r := parse(s)
+	match r {
+		Ok(n) => return n,
+		Err(e) => return len(e.Error()),
+	}
+	return 0
+}
+`,
+			want: []string{
+				"func parse(s string) dingo.Result[int, error]",
+				"dingo.Ok[int, error](",
+				"case scrutinee.IsErr():",
+				"e := scrutinee.UnwrapErr()",
+			},
+		},
+		{
+			name: "error propagation",
+			src: `
+func atoi(s string) (int, error) {
+	return len(s), nil
+}
+
+func first(s string) (Option_User, error) {
+	let id = atoi(s)?
+	return find(id), nil
+}
+`,
+			want: []string{
+				"func first(s string) (dingo.Option[User], error)",
+				"return dingo.Option[User]{}, err",
+			},
+		},
+		{
+			name: "null coalescing",
+			src: `
+func age(id int) Option_int {
+	if id > 0 {
+		return Option_int_Some(30)
+	}
+	return None
+}
+
+func years(id int) int {
+	n := age(id) ?? 0
+	return n
+}
+`,
+			want: []string{
+				"func age(id int) dingo.Option[int]",
+				"func() int {",
+				"if coalesce.IsSome() {",
+				"return coalesce.Unwrap()",
+			},
+		},
+		{
+			name: "safe navigation",
+			src: `
+func lookup(id int) *User {
+	if u := find(id); u.IsSome() {
+		return &User{Name: u.Unwrap().Name}
+	}
+	return nil
+}
+
+func geo(id int) *Geo {
+	let u: *User = lookup(id)
+	g := u?.Address?.Geo
+	return g
+}
+`,
+			want: []string{
+				"func() *Geo {",
+				"return uTmp.Geo",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			src := genericRuntimeDecls + tt.src
+			cfg := config.DefaultConfig()
+			cfg.Features.TypeGeneration = config.TypesGeneric
+			res, err := transpiler.Transpile(context.Background(), "users.dingo", []byte(src), transpiler.Options{
+				Config:         cfg,
+				PackageSources: map[string][]byte{"users.dingo": []byte(src), "other.dingo": []byte("package main\n")},
+				SharedTypes:    true,
+			})
+			if err != nil || res.Err() != nil {
+				t.Fatalf("Transpile failed: %v %v", err, res.Err())
+			}
+
+			code := string(res.Go)
+			for _, want := range append([]string{`"` + config.RuntimePath + `"`}, tt.want...) {
+				if !strings.Contains(code, want) {
+					t.Errorf("generated Go lacks %q:\n%s", want, code)
+				}
+			}
+			for _, unwanted := range []string{"Option_User", "Option_int", "Result_int_error", "OptionTag", "ResultTag", "__INFER__"} {
+				if strings.Contains(code, unwanted) {
+					t.Errorf("generated Go still refers to %s:\n%s", unwanted, code)
+				}
+			}
+			if len(res.Types) != 0 {
+				t.Errorf("got shared types %+v, want none", res.Types)
+			}
+			typeCheck(t, "users.go", res.Go)
+		})
+	}
+}
+
+// typeCheck type-checks the generated Go of a single-file package. The
+// runtime library is imported from the source of this module.
+func typeCheck(t *testing.T, filename string, code []byte) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, code, 0)
+	if err != nil {
+		t.Fatalf("generated Go does not parse: %v\n%s", err, code)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("main", fset, []*ast.File{file}, nil); err != nil {
+		t.Errorf("generated Go does not type-check: %v\n%s", err, code)
+	}
+}
+
+func TestTranspileSourceMapFormats(t *testing.T) {
+	dir := t.TempDir()
+	goPath := filepath.Join(dir, "config.go")
+	for _, tt := range []struct {
+		format           config.SourceMapFormat
+		inline, separate bool
+	}{
+		{config.FormatSeparate, false, true},
+		{config.FormatInline, true, false}, // Removes the .go.map of the previous build
+		{config.FormatBoth, true, true},
+		{config.FormatNone, false, false},
+	} {
+		cfg := config.DefaultConfig()
+		cfg.SourceMap.Format = tt.format
+		res, err := transpiler.Transpile(context.Background(), filepath.Join(dir, "config.dingo"), []byte(readConfigSrc), transpiler.Options{Config: cfg})
+		if err != nil || res.Err() != nil {
+			t.Fatalf("%s: Transpile failed: %v %v", tt.format, err, res.Err())
+		}
+		if (res.SourceMap != nil) != (tt.format != config.FormatNone) {
+			t.Errorf("%s: SourceMap = %v", tt.format, res.SourceMap)
+		}
+		if err := res.WriteFiles(goPath); err != nil {
+			t.Fatalf("%s: WriteFiles failed: %v", tt.format, err)
+		}
+
+		code, err := os.ReadFile(goPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(code), "//# sourceMappingURL=data:application/json;base64,"); got != tt.inline {
+			t.Errorf("%s: inline source map written = %v, want %v", tt.format, got, tt.inline)
+		}
+		if _, err := os.Stat(goPath + ".map"); (err == nil) != tt.separate {
+			t.Errorf("%s: .go.map written = %v, want %v", tt.format, err == nil, tt.separate)
+		}
+		sm, err := sourcemap.Load(goPath)
+		if (err == nil) != (tt.inline || tt.separate) {
+			t.Errorf("%s: sourcemap.Load error = %v", tt.format, err)
+		}
+		if err == nil && (sm.DingoFile != "config.dingo" || sm.GoFile != "config.go") {
+			t.Errorf("%s: source map of %q and %q, want paths relative to the .go file", tt.format, sm.DingoFile, sm.GoFile)
+		}
+	}
+
+	// Disabling source maps is format "none"
+	cfg := config.DefaultConfig()
+	cfg.SourceMap.Enabled = false
+	res, err := transpiler.Transpile(context.Background(), "config.dingo", []byte(readConfigSrc), transpiler.Options{Config: cfg})
+	if err != nil || res.SourceMap != nil {
+		t.Errorf("disabled source maps: SourceMap = %v, err = %v", res.SourceMap, err)
+	}
+}
+
+func TestTranspileSourceMapV3(t *testing.T) {
+	dir := t.TempDir()
+	goPath := filepath.Join(dir, "config.go")
+	cfg := config.DefaultConfig()
+	cfg.SourceMap.Format = config.FormatSeparate
+	cfg.SourceMap.Schema = config.SchemaV3
+	res, err := transpiler.Transpile(context.Background(), filepath.Join(dir, "config.dingo"), []byte(readConfigSrc), transpiler.Options{Config: cfg})
+	if err != nil || res.Err() != nil {
+		t.Fatalf("Transpile failed: %v %v", err, res.Err())
+	}
+	if err := res.WriteFiles(goPath); err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+
+	data, err := os.ReadFile(goPath + ".map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v3 sourcemap.SourceMapV3
+	if err := json.Unmarshal(data, &v3); err != nil {
+		t.Fatal(err)
+	}
+	if v3.Version != 3 || len(v3.SourcesContent) != 1 || v3.SourcesContent[0] != readConfigSrc {
+		t.Errorf("not a v3 map embedding the .dingo source: version %d, sourcesContent %q", v3.Version, v3.SourcesContent)
+	}
+	if _, err := sourcemap.NewConsumer(data); err != nil {
+		t.Errorf("NewConsumer failed: %v", err)
+	}
+
+	loaded, err := sourcemap.Load(goPath)
+	if err != nil {
+		t.Fatalf("sourcemap.Load failed: %v", err)
+	}
+	want := *res.SourceMap
+	want.DingoFile, want.GoFile = "config.dingo", "config.go" // Relative to the .go file
+	if !reflect.DeepEqual(*loaded, want) {
+		t.Errorf("sourcemap.Load = %+v, want %+v", *loaded, want)
+	}
+}
+
+func TestTranspileConcurrent(t *testing.T) {
+	sources := make([][]byte, 8)
+	want := make([]string, len(sources))
+	for i := range sources {
+		sources[i] = []byte(strings.Replace(readConfigSrc, "readConfig", fmt.Sprintf("readConfig%d", i), 1))
+		res, err := transpiler.Transpile(context.Background(), "config.dingo", sources[i], transpiler.Options{})
+		if err != nil || res.Err() != nil {
+			t.Fatalf("Transpile failed: %v %v", err, res.Err())
+		}
+		want[i] = string(res.Go)
+	}
+
+	var wg sync.WaitGroup
+	for round := 0; round < 4; round++ {
+		for i := range sources {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				res, err := transpiler.Transpile(context.Background(), "config.dingo", sources[i], transpiler.Options{})
+				if err != nil || res.Err() != nil {
+					t.Errorf("Transpile failed: %v %v", err, res.Err())
+					return
+				}
+				if string(res.Go) != want[i] {
+					t.Errorf("source %d: concurrent output differs from sequential output", i)
+				}
+			}(i)
+		}
+	}
+	wg.Wait()
+}
+
+func TestTranspileCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	res, err := transpiler.Transpile(ctx, "config.dingo", []byte(readConfigSrc), transpiler.Options{})
+	if !errors.Is(err, context.Canceled) || res != nil {
+		t.Errorf("Transpile = %v, %v; want nil, context.Canceled", res, err)
+	}
+}
@@ -3,13 +3,20 @@
 package transpiler
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"go/token"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
 
 	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
 	"github.com/MadAppGang/dingo/pkg/generator"
 	"github.com/MadAppGang/dingo/pkg/parser"
 	"github.com/MadAppGang/dingo/pkg/plugin"
@@ -38,104 +45,314 @@ func NewWithConfig(cfg *config.Config) *Transpiler {
 	return &Transpiler{config: cfg}
 }
 
+// cfg returns the configuration, defaulting when none was given
+func (t *Transpiler) cfg() *config.Config {
+	if t.config == nil {
+		return config.DefaultConfig()
+	}
+	return t.config
+}
+
+// Config returns the configuration the transpiler was created with
+func (t *Transpiler) Config() *config.Config {
+	return t.cfg()
+}
+
 // TranspileFile transpiles a single .dingo file to .go with source maps
 // This is the library equivalent of `dingo build file.dingo`
 func (t *Transpiler) TranspileFile(inputPath string) error {
 	return t.TranspileFileWithOutput(inputPath, "")
 }
 
-// TranspileFileWithOutput transpiles with custom output path
+// TranspileFileWithOutput transpiles with custom output path and writes the
+// .go file and its source map, as [sourcemaps] format says
 func (t *Transpiler) TranspileFileWithOutput(inputPath, outputPath string) error {
 	if outputPath == "" {
-		// Default: replace .dingo with .go
-		if len(inputPath) > 6 && inputPath[len(inputPath)-6:] == ".dingo" {
-			outputPath = inputPath[:len(inputPath)-6] + ".go"
-		} else {
-			outputPath = inputPath + ".go"
-		}
+		outputPath = goOutputPath(inputPath)
 	}
 
-	// Step 1: Read source
 	src, err := os.ReadFile(inputPath)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	// Step 2: Preprocess
-	var goSource string
-	var metadata []preprocessor.TransformMetadata
-
-	pkgDir := filepath.Dir(inputPath)
-	cache := preprocessor.NewFunctionExclusionCache(pkgDir)
-	err = cache.ScanPackage([]string{inputPath})
-
+	res, err := Transpile(context.Background(), inputPath, src, Options{
+		Config:         t.config,
+		GoPath:         outputPath,
+		PackageSources: PackageSources(inputPath, src),
+		SharedTypes:    true,
+	})
 	if err != nil {
-		// Fall back to no cache if scanning fails
-		prep := preprocessor.NewWithMainConfig(src, t.config)
-		var legacyMap *preprocessor.SourceMap
-		goSource, legacyMap, metadata, err = prep.ProcessWithMetadata()
-		_ = legacyMap // Discard legacy map - Phase 3 uses PostASTGenerator
-		if err != nil {
-			return fmt.Errorf("preprocessing error: %w", err)
+		return err
+	}
+	if err := res.Err(); err != nil {
+		return err
+	}
+	if err := res.WriteFiles(outputPath); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	if res.SourceMap == nil && t.cfg().SourceMap.OutputFormat() != config.FormatNone {
+		// The Go is written, but positions cannot be mapped back to Dingo
+		for _, d := range res.Diagnostics {
+			if d.Source == diagnostic.SourceMapping {
+				return errors.New(d.String())
+			}
 		}
-	} else {
-		// Cache scan successful
-		prep := preprocessor.NewWithCache(src, cache)
-		var legacyMap *preprocessor.SourceMap
-		goSource, legacyMap, metadata, err = prep.ProcessWithMetadata()
-		_ = legacyMap
-		if err != nil {
-			return fmt.Errorf("preprocessing error: %w", err)
+	}
+	return nil
+}
+
+// TranspileSource transpiles Dingo source held in memory and returns the generated Go code.
+// inputPath is used for package context and error positions only; nothing is
+// read from or written to disk.
+func (t *Transpiler) TranspileSource(inputPath string, src []byte) ([]byte, error) {
+	res, err := Transpile(context.Background(), inputPath, src, Options{Config: t.config})
+	if err != nil {
+		return nil, err
+	}
+	if err := res.Err(); err != nil {
+		return nil, err
+	}
+	return res.Go, nil
+}
+
+// transpileResult holds the products of the pipeline. On parse or generation
+// errors it is still returned, so positions can be mapped back to Dingo.
+type transpileResult struct {
+	code      []byte                           // Generated Go (nil on error)
+	sourceMap *preprocessor.SourceMap          // Set by finish
+	metadata  []preprocessor.TransformMetadata // Transformations for source maps
+	goSource  string                           // Preprocessed Go, as parsed
+	preMap    *preprocessor.SourceMap          // Preprocessed lines → .dingo lines
+	fset      *token.FileSet                   // Positions of the preprocessed Go
+	pkgName   string                           // Package of the generated Go
+	types     []generator.TypeDecl             // Injected types left out of code (sharedTypes)
+	timings   Timings
+}
+
+// transpile runs the preprocess → parse → generate pipeline on src.
+// pkgSources populates the package function cache used for unqualified import
+// inference. sharedTypes leaves the injected types out of the code (see
+// Options.SharedTypes). onStage, when non-nil, receives the source after every pipeline
+// stage. The result is returned even on error, so positions can be mapped
+// back to Dingo; its fset is nil when preprocessing failed.
+func transpile(
+	ctx context.Context,
+	cfg *config.Config,
+	inputPath string,
+	src []byte,
+	pkgSources map[string][]byte,
+	sharedTypes bool,
+	onStage func(Stage),
+) (*transpileResult, error) {
+	res := &transpileResult{}
+
+	// Step 1: Preprocess
+	start := time.Now()
+	cache := preprocessor.NewFunctionExclusionCache(filepath.Dir(inputPath))
+	if err := cache.ScanSources(pkgSources); err != nil {
+		// Siblings may have experimental syntax: scan the file alone, and
+		// fall back to no cache if that fails too
+		if err := cache.ScanSources(map[string][]byte{inputPath: src}); err != nil {
+			cache = nil
 		}
 	}
+	prep := newPreprocessor(src, cfg, cache)
+	if onStage != nil {
+		prep.SetStageHook(preprocessorHook(onStage))
+	}
+	goSource, legacyMap, metadata, err := prep.ProcessWithMetadata() // legacyMap only maps diagnostics
+	res.timings.Preprocess = time.Since(start)
+	if err != nil {
+		return res, fmt.Errorf("preprocessing error: %w", err)
+	}
+	res.metadata, res.goSource, res.preMap = metadata, goSource, legacyMap
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
 
-	// Step 3: Parse preprocessed Go
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, inputPath, []byte(goSource), parser.ParseComments)
+	// Step 2: Parse preprocessed Go
+	start = time.Now()
+	res.fset = token.NewFileSet()
+	file, err := parser.ParseFile(res.fset, inputPath, []byte(goSource), parser.ParseComments)
+	res.timings.Parse = time.Since(start)
 	if err != nil {
-		return fmt.Errorf("parse error: %w", err)
+		return res, fmt.Errorf("parse error: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return res, err
 	}
 
-	// Step 4: Setup plugins
+	// Step 3: Generate with plugins
+	start = time.Now()
+	defer func() { res.timings.Generate = time.Since(start) }()
 	registry, err := builtin.NewDefaultRegistry()
 	if err != nil {
-		return fmt.Errorf("failed to setup plugins: %w", err)
+		return res, fmt.Errorf("failed to setup plugins: %w", err)
 	}
-
-	// Step 5: Generate with plugins
 	logger := plugin.NewNoOpLogger() // Silent logger for library use
-	gen, err := generator.NewWithPlugins(fset, registry, logger)
+	gen, err := generator.NewWithPlugins(res.fset, registry, logger)
 	if err != nil {
-		return fmt.Errorf("failed to create generator: %w", err)
+		return res, fmt.Errorf("failed to create generator: %w", err)
+	}
+	if onStage != nil {
+		gen.SetStageHook(func(stage string, source []byte) {
+			onStage(Stage{Name: stage, Source: source})
+		})
 	}
+	if pkg := packageOf(cfg, cache, inputPath, pkgSources); pkg != nil {
+		gen.SetPackage(ctx, pkg)
+	}
+	gen.SetSharedTypes(sharedTypes)
+	gen.SetGenericRuntime(cfg.Features.TypeGeneration == config.TypesGeneric)
 
 	outputCode, err := gen.Generate(file)
 	if err != nil {
-		return fmt.Errorf("generation error: %w", err)
+		return res, fmt.Errorf("generation error: %w", err)
 	}
 
-	// Step 6: Write .go file
-	if err := os.WriteFile(outputPath, outputCode, 0644); err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
+	res.code, res.pkgName, res.types = withHeader(outputCode, inputPath), file.Name.Name, gen.InjectedTypes()
+	return res, nil
+}
+
+// generatedPrefix and generatedSuffix frame the header of every .go file
+// transpiled from a .dingo file, in the form the Go tools recognize
+const (
+	generatedPrefix = "// Code generated by dingo from "
+	generatedSuffix = ". DO NOT EDIT."
+)
+
+// withHeader prepends the generated code header naming inputPath to code
+func withHeader(code []byte, inputPath string) []byte {
+	header := generatedPrefix + filepath.Base(inputPath) + generatedSuffix + "\n\n"
+	return append([]byte(header), code...)
+}
+
+// IsGenerated reports whether src is Go transpiled by dingo from a .dingo
+// file, i.e. starts with the header written by transpilation
+func IsGenerated(src []byte) bool {
+	line, _, _ := bytes.Cut(src, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return bytes.HasPrefix(line, []byte(generatedPrefix)) && bytes.HasSuffix(line, []byte(generatedSuffix))
+}
+
+// newPreprocessor creates the preprocessor of src, using cache when non-nil
+func newPreprocessor(src []byte, cfg *config.Config, cache *preprocessor.FunctionExclusionCache) *preprocessor.Preprocessor {
+	if cache == nil {
+		return preprocessor.NewWithMainConfig(src, cfg)
 	}
+	return preprocessor.NewWithMainConfigAndCache(src, cfg, cache)
+}
 
-	// Step 7: Generate source map
-	sourceMapPath := outputPath + ".map"
-	sourceMap, err := sourcemap.GenerateFromFiles(inputPath, outputPath, metadata)
+// packageOf describes the package of inputPath for package-wide type
+// checking: the .go files of its directory plus the preprocessed Go of every
+// sibling in pkgSources, at the path its output would have. Siblings that
+// fail to preprocess are left out.
+func packageOf(cfg *config.Config, cache *preprocessor.FunctionExclusionCache, inputPath string, pkgSources map[string][]byte) *generator.Package {
+	abs, err := filepath.Abs(inputPath)
 	if err != nil {
-		// Non-fatal: just skip source map
 		return nil
 	}
+	pkg := &generator.Package{
+		Dir:     filepath.Dir(abs),
+		GoPath:  goOutputPath(abs),
+		Sources: make(map[string][]byte),
+	}
+	dir := siblings.dir(pkg.Dir, cfg, cache)
+	defer dir.mu.Unlock()
+	for path, src := range pkgSources {
+		siblingAbs, err := filepath.Abs(path)
+		if err != nil || siblingAbs == abs || filepath.Dir(siblingAbs) != pkg.Dir {
+			continue
+		}
+		if code := dir.preprocess(siblingAbs, src, cfg, cache); code != nil {
+			pkg.Sources[goOutputPath(siblingAbs)] = code
+		}
+	}
+	return pkg
+}
 
-	// Write source map
-	sourceMapJSON, err := json.MarshalIndent(sourceMap, "", "  ")
-	if err != nil {
-		return nil // Non-fatal
+// siblings caches the preprocessed Go of the .dingo files of each package
+// directory, so that transpiling every file of a package, or a buffer as it
+// is edited, preprocesses each sibling once instead of once per file
+var siblings = &siblingCache{dirs: make(map[string]*siblingDir)}
+
+// siblingCache holds a siblingDir per package directory
+type siblingCache struct {
+	mu   sync.Mutex
+	dirs map[string]*siblingDir
+}
+
+// siblingDir holds the preprocessed Go of a directory's .dingo files, by
+// absolute path. It is valid for one configuration and function cache
+// fingerprint, and emptied when they change.
+type siblingDir struct {
+	mu          sync.Mutex
+	cfg         config.Config
+	fingerprint uint64
+	files       map[string]preprocessedFile
+}
+
+// preprocessedFile is the preprocessed Go of a source, nil if it failed
+type preprocessedFile struct {
+	hash uint64
+	code []byte
+}
+
+// dir returns the locked siblingDir of path for cfg and cache
+func (c *siblingCache) dir(path string, cfg *config.Config, cache *preprocessor.FunctionExclusionCache) *siblingDir {
+	c.mu.Lock()
+	dir := c.dirs[path]
+	if dir == nil {
+		dir = &siblingDir{}
+		c.dirs[path] = dir
 	}
+	c.mu.Unlock()
 
-	if err := os.WriteFile(sourceMapPath, sourceMapJSON, 0644); err != nil {
-		return nil // Non-fatal
+	dir.mu.Lock()
+	var fingerprint uint64
+	if cache != nil {
+		fingerprint = cache.Fingerprint()
 	}
+	if dir.files == nil || dir.cfg != *cfg || dir.fingerprint != fingerprint {
+		dir.cfg, dir.fingerprint, dir.files = *cfg, fingerprint, make(map[string]preprocessedFile)
+	}
+	return dir
+}
 
+// preprocess returns the preprocessed Go of the sibling at path, or nil if
+// it fails to preprocess
+func (d *siblingDir) preprocess(path string, src []byte, cfg *config.Config, cache *preprocessor.FunctionExclusionCache) []byte {
+	hash := xxhash.Sum64(src)
+	if file, ok := d.files[path]; ok && file.hash == hash {
+		return file.code
+	}
+	var code []byte
+	if goSource, _, _, err := newPreprocessor(src, cfg, cache).ProcessWithMetadata(); err == nil {
+		code = []byte(goSource)
+	}
+	d.files[path] = preprocessedFile{hash: hash, code: code}
+	return code
+}
+
+// finish builds the source map of successfully generated code for goPath
+// and, with [sourcemaps] line_directives, adds //line directives to the code
+// (shifting the source map to match). With [sourcemaps] format "none", the
+// source map is only built when the directives need it, and not kept.
+func finish(cfg *config.Config, res *transpileResult, inputPath, goPath string, src []byte) error {
+	none := cfg.SourceMap.OutputFormat() == config.FormatNone
+	if none && !cfg.SourceMap.LineDirectives {
+		return nil
+	}
+	sm, err := sourcemap.GenerateFromSource(inputPath, goPath, src, res.code, res.metadata)
+	if err != nil {
+		return fmt.Errorf("source map generation failed: %w", err)
+	}
+	if cfg.SourceMap.LineDirectives {
+		res.code, sm = sourcemap.InsertLineDirectives(res.code, sourcemap.DirectiveFile(inputPath, goPath), sm)
+	}
+	if !none {
+		res.sourceMap = sm
+	}
 	return nil
 }
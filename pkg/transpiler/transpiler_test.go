@@ -2,9 +2,13 @@ package transpiler_test
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 
+	"github.com/MadAppGang/dingo/pkg/config"
+	"github.com/MadAppGang/dingo/pkg/diagnostic"
+	"github.com/MadAppGang/dingo/pkg/sourcemap"
 	"github.com/MadAppGang/dingo/pkg/transpiler"
 )
 
@@ -43,10 +47,12 @@ func readConfig(path string) ([]byte, error) {
 		t.Errorf(".go file not created: %s", goPath)
 	}
 
-	// Verify .go.map file exists
-	mapPath := goPath + ".map"
-	if _, err := os.Stat(mapPath); os.IsNotExist(err) {
-		t.Errorf(".go.map file not created: %s", mapPath)
+	// Verify the source map is written next to the .go file, the default format
+	if _, err := os.Stat(goPath + ".map"); err != nil {
+		t.Errorf("no .go.map file for the separate format: %v", err)
+	}
+	if _, err := sourcemap.Load(goPath); err != nil {
+		t.Errorf("no source map for %s: %v", goPath, err)
 	}
 
 	// Read and verify .go file contains expected transformations
@@ -60,6 +66,14 @@ func readConfig(path string) ([]byte, error) {
 	if !contains(goStr, "if err != nil") {
 		t.Errorf(".go file should contain error propagation, got:\n%s", goStr)
 	}
+
+	// Should start with the header dingo clean looks for
+	if !transpiler.IsGenerated(goContent) {
+		t.Errorf(".go file should start with the generated code header, got:\n%s", goStr)
+	}
+	if transpiler.IsGenerated([]byte(dingoSrc)) {
+		t.Error("hand-written source should not count as generated")
+	}
 }
 
 func TestTranspileFileWithCustomOutput(t *testing.T) {
@@ -93,10 +107,9 @@ func test() {
 		t.Errorf("Custom .go file not created: %s", customOutput)
 	}
 
-	// Verify source map uses custom path
-	mapPath := customOutput + ".map"
-	if _, err := os.Stat(mapPath); os.IsNotExist(err) {
-		t.Errorf("Custom .go.map file not created: %s", mapPath)
+	// Verify the source map is written for the custom .go file
+	if _, err := sourcemap.Load(customOutput); err != nil {
+		t.Errorf("no source map for %s: %v", customOutput, err)
 	}
 }
 
@@ -104,3 +117,183 @@ func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > len(substr) &&
 		(s[:len(substr)] == substr || contains(s[1:], substr)))
 }
+
+func TestCheckSource(t *testing.T) {
+	tr := transpiler.NewWithConfig(config.DefaultConfig())
+
+	t.Run("valid source", func(t *testing.T) {
+		src := []byte("package main\n\nfunc add(a int, b int) int {\n\treturn a + b\n}\n")
+		code, sm, diags := tr.CheckSource("/virtual/add.dingo", src, nil)
+		if len(diags) != 0 {
+			t.Fatalf("unexpected diagnostics: %v", diags)
+		}
+		if code == nil || sm == nil {
+			t.Fatal("expected generated code and source map")
+		}
+		if _, err := os.Stat("/virtual/add.go"); !os.IsNotExist(err) {
+			t.Error("CheckSource must not write files")
+		}
+	})
+
+	t.Run("parse error after transformed code", func(t *testing.T) {
+		src := []byte(`package main
+
+func readConfig(path string) ([]byte, error) {
+	let data = os.ReadFile(path)?
+	return data, nil
+}
+
+func broken() {
+	let x = (1 +
+}
+`)
+		_, _, diags := tr.CheckSource("broken.dingo", src, nil)
+		if len(diags) == 0 {
+			t.Fatal("expected a diagnostic")
+		}
+		d := diags[0]
+		if d.Severity != diagnostic.SeverityError || d.Source != diagnostic.SourceParse {
+			t.Errorf("unexpected diagnostic kind: %+v", d)
+		}
+		if d.Pos.Filename != "broken.dingo" || d.Pos.Line != 10 {
+			t.Errorf("diagnostic at %s:%d, want broken.dingo:10", d.Pos.Filename, d.Pos.Line)
+		}
+	})
+
+	t.Run("parse error on a repeated line", func(t *testing.T) {
+		src := []byte(`package main
+
+func pick(r Result[int, error]) int {
+	let v = match r {
+		Ok(x) => x,
+		Err(e) => 0,
+	}
+	return v
+}
+
+func broken() {
+	return v
+	x := (1 +
+	return v
+}
+`)
+		_, _, diags := tr.CheckSource("repeated.dingo", src, nil)
+		if len(diags) == 0 {
+			t.Fatal("expected a diagnostic")
+		}
+		if d := diags[0]; d.Pos.Line != 14 || d.Pos.Column != 2 {
+			t.Errorf("diagnostic at %d:%d, want 14:2", d.Pos.Line, d.Pos.Column)
+		}
+	})
+}
+
+func TestExpand(t *testing.T) {
+	tr := transpiler.NewWithConfig(config.DefaultConfig())
+	src := []byte(`package main
+
+func readConfig(path string) ([]byte, error) {
+	let data = os.ReadFile(path)?
+	return data, nil
+}
+`)
+	stages, err := tr.Expand("/virtual/config.dingo", src)
+	if err != nil {
+		t.Fatalf("Expand() error: %v", err)
+	}
+
+	index := make(map[string]int)
+	for i, s := range stages {
+		index[s.Name] = i
+	}
+	order := []string{"source", "generic_syntax", "lambda", "error_propagation", "keywords", "transform", "post_ast", "output"}
+	for i, name := range order {
+		if _, ok := index[name]; !ok {
+			t.Fatalf("missing stage %q in %v", name, stages)
+		}
+		if i > 0 && index[order[i-1]] > index[name] {
+			t.Errorf("stage %q before %q", name, order[i-1])
+		}
+	}
+
+	errProp := stages[index["error_propagation"]]
+	if len(errProp.Metadata) != 1 || errProp.Metadata[0].Type != "error_prop" {
+		t.Errorf("error_propagation metadata = %+v, want one error_prop", errProp.Metadata)
+	}
+	if contains(string(errProp.Source), "?") || !contains(string(stages[index["source"]].Source), "?") {
+		t.Error("error_propagation stage should remove the ? operator")
+	}
+	if !contains(string(stages[len(stages)-1].Source), "// dingo:e:0") {
+		t.Error("output stage lacks generated markers")
+	}
+}
+
+func TestExpandStopsAtFailingStage(t *testing.T) {
+	tr := transpiler.NewWithConfig(config.DefaultConfig())
+	src := []byte("package main\n\nfunc broken() {\n\tx := (1 +\n}\n")
+
+	stages, err := tr.Expand("broken.dingo", src)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if last := stages[len(stages)-1].Name; last == "transform" || last == "output" {
+		t.Errorf("last stage = %s, want a preprocessor stage", last)
+	}
+}
+
+func TestLineDirectivesCompileError(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/directives\n\ngo 1.21\n",
+		"main.dingo": `package main
+
+import "strconv"
+
+func parse(s string) (int, error) {
+	let n = strconv.Atoi(s)?
+	return n, nil
+}
+
+func main() {
+	var count int = "three"
+	println(count)
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.SourceMap.LineDirectives = true
+	if err := transpiler.NewWithConfig(cfg).TranspileFile(filepath.Join(dir, "main.dingo")); err != nil {
+		t.Fatalf("TranspileFile() error: %v", err)
+	}
+
+	goCode, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(string(goCode), "//line main.dingo:") || !contains(string(goCode), "// dingo:e:0") {
+		t.Fatalf("expected //line directives next to the markers:\n%s", goCode)
+	}
+
+	cmd := exec.Command(goBin, "build", "-o", os.DevNull, ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatal("go build succeeded, want a type error")
+	}
+	if !contains(string(out), "main.dingo:11:") {
+		t.Errorf("go build did not report the error at main.dingo:11:\n%s", out)
+	}
+	if contains(string(out), "main.go:") {
+		t.Errorf("go build reported a generated position:\n%s", out)
+	}
+}
@@ -0,0 +1,177 @@
+package transpiler
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/MadAppGang/dingo/pkg/generator"
+)
+
+// TypesFile is the file next to the generated Go of a package that declares
+// the types injected for its .dingo files once (see Options.SharedTypes)
+const TypesFile = "dingo_types.go"
+
+// typesFileHeader starts every TypesFile
+const typesFileHeader = "// Code generated by dingo. DO NOT EDIT.\n"
+
+// usesPrefix starts the lines of a TypesFile that record which types each
+// generated .go file uses, so that rebuilding some files of a package keeps
+// the types the others need
+const usesPrefix = "// dingo:uses "
+
+// typesFileMu serializes updates of TypesFiles
+var typesFileMu sync.Mutex
+
+// IsTypesFile reports whether src is a TypesFile written by dingo
+func IsTypesFile(src []byte) bool {
+	return bytes.HasPrefix(src, []byte(typesFileHeader))
+}
+
+// TypesFileSource returns the source of a TypesFile declaring types in
+// package pkgName; types declared by several files are declared once. It
+// returns nil when there are no types.
+func TypesFileSource(pkgName string, types []generator.TypeDecl) ([]byte, error) {
+	decls := make(map[string]generator.TypeDecl, len(types))
+	for _, td := range types {
+		decls[td.Name] = td
+	}
+	return renderTypesFile(pkgName, nil, decls)
+}
+
+// updateTypesFile records in the TypesFile of dir that goFile uses types
+// and rewrites it with every type still used by a .go file of dir. Files
+// that no longer exist are forgotten; the TypesFile is removed when no type
+// is left.
+func updateTypesFile(dir, goFile, pkgName string, types []generator.TypeDecl) error {
+	typesFileMu.Lock()
+	defer typesFileMu.Unlock()
+
+	path := filepath.Join(dir, TypesFile)
+	src, err := mergeTypesFile(dir, goFile, pkgName, types)
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(path, src, 0644)
+}
+
+// mergeTypesFile returns the TypesFile of dir once goFile uses types, or
+// nil when no type is left; see updateTypesFile
+func mergeTypesFile(dir, goFile, pkgName string, types []generator.TypeDecl) ([]byte, error) {
+	path := filepath.Join(dir, TypesFile)
+	uses := make(map[string][]string)
+	decls := make(map[string]generator.TypeDecl)
+	if src, err := os.ReadFile(path); err == nil && IsTypesFile(src) {
+		// A TypesFile that does not parse is rebuilt from the current types
+		if _, existing, err := generator.ParseTypeDecls(src); err == nil {
+			uses = parseUses(src)
+			for _, td := range existing {
+				decls[td.Name] = td
+			}
+		}
+	}
+
+	delete(uses, goFile)
+	for file := range uses {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			delete(uses, file)
+		}
+	}
+	for _, td := range types {
+		uses[goFile] = append(uses[goFile], td.Name)
+		decls[td.Name] = td
+	}
+
+	used := make(map[string]generator.TypeDecl)
+	for _, names := range uses {
+		for _, name := range names {
+			if td, ok := decls[name]; ok {
+				used[name] = td
+			}
+		}
+	}
+	return renderTypesFile(pkgName, uses, used)
+}
+
+// parseUses reads the uses lines of a TypesFile: generated file → types
+func parseUses(src []byte) map[string][]string {
+	uses := make(map[string][]string)
+	for _, line := range strings.Split(string(src), "\n") {
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, usesPrefix))
+		if strings.HasPrefix(line, usesPrefix) && len(fields) > 1 {
+			uses[fields[0]] = fields[1:]
+		}
+	}
+	return uses
+}
+
+// renderTypesFile formats a TypesFile with the uses lines and the
+// declarations sorted by name. It returns nil without declarations.
+func renderTypesFile(pkgName string, uses map[string][]string, decls map[string]generator.TypeDecl) ([]byte, error) {
+	if len(decls) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(typesFileHeader)
+	if len(uses) > 0 {
+		buf.WriteString("\n")
+		files := make([]string, 0, len(uses))
+		for file := range uses {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			names := append([]string(nil), uses[file]...)
+			sort.Strings(names)
+			fmt.Fprintf(&buf, "%s%s %s\n", usesPrefix, file, strings.Join(names, " "))
+		}
+	}
+	fmt.Fprintf(&buf, "\npackage %s\n", pkgName)
+
+	names := make([]string, 0, len(decls))
+	imports := make(map[string]bool)
+	for name, td := range decls {
+		names = append(names, name)
+		for _, spec := range td.Imports {
+			imports[spec] = true
+		}
+	}
+	sort.Strings(names)
+	if len(imports) > 0 {
+		specs := make([]string, 0, len(imports))
+		for spec := range imports {
+			specs = append(specs, spec)
+		}
+		sort.Strings(specs)
+		buf.WriteString("\nimport (\n")
+		for _, spec := range specs {
+			fmt.Fprintf(&buf, "\t%s\n", spec)
+		}
+		buf.WriteString(")\n")
+	}
+	for _, name := range names {
+		buf.WriteString("\n")
+		buf.WriteString(decls[name].Source)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", TypesFile, err)
+	}
+	return src, nil
+}
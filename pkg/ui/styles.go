@@ -155,6 +155,72 @@ func (b *BuildOutput) PrintFileStart(inputPath, outputPath string) {
 	fmt.Println()
 }
 
+// PrintPackageBuildStart prints the workspace build start message
+func (b *BuildOutput) PrintPackageBuildStart(root string, packageCount int) {
+	var msg string
+	if packageCount == 1 {
+		msg = "📦 Building 1 package"
+	} else {
+		msg = fmt.Sprintf("📦 Building %d packages", packageCount)
+	}
+
+	fmt.Println(styleSection.Render(msg) + " " + styleMuted.Render("in "+root))
+	fmt.Println()
+}
+
+// PrintPackageResult prints one line per built package
+//
+// Format: "  ✓ pkg/foo     3 transpiled, 1 cached (12ms)"
+func (b *BuildOutput) PrintPackageResult(path string, processed, skipped int, duration time.Duration, err error) {
+	icon := styleSuccess.Render("✓")
+	if err != nil {
+		icon = styleError.Render("✗")
+	} else if processed == 0 {
+		icon = styleMuted.Render("○")
+	}
+
+	line := fmt.Sprintf("  %s %s", icon, styleFilePath.Render(path))
+
+	var parts []string
+	if processed > 0 {
+		parts = append(parts, fmt.Sprintf("%d transpiled", processed))
+	}
+	if skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d cached", skipped))
+	}
+	if len(parts) > 0 {
+		line += " " + styleFileInput.Render(strings.Join(parts, ", "))
+	}
+	if duration > 0 {
+		line += " " + styleStepTime.Render("("+formatDuration(duration)+")")
+	}
+
+	fmt.Println(line)
+
+	if err != nil {
+		for _, msg := range strings.Split(err.Error(), "\n") {
+			fmt.Println(styleMuted.Render("    " + msg))
+		}
+	}
+}
+
+// PrintWatching prints the watch mode banner
+func (b *BuildOutput) PrintWatching(root string) {
+	fmt.Println()
+	fmt.Println(styleSection.Render("👀 Watching for changes") + " " +
+		styleMuted.Render("in "+root+" (Ctrl+C to stop)"))
+}
+
+// PrintChangeDetected prints the files that triggered a rebuild
+func (b *BuildOutput) PrintChangeDetected(files []string) {
+	b.startTime = time.Now()
+
+	fmt.Println()
+	fmt.Println(styleSection.Render("🔄 Change detected") + " " +
+		styleFileInput.Render(strings.Join(files, ", ")))
+	fmt.Println()
+}
+
 // Step represents a build step status
 type Step struct {
 	Name     string
@@ -253,12 +319,34 @@ func (b *BuildOutput) PrintSummary(success bool, errorMsg string) {
 	fmt.Println(styleSummary.Render(summaryLine))
 }
 
+// PrintErrorList prints every error of a failed build, one per line, under
+// a count heading
+func (b *BuildOutput) PrintErrorList(errs []string) {
+	if len(errs) == 0 {
+		return
+	}
+	fmt.Println()
+	heading := fmt.Sprintf("%d errors:", len(errs))
+	if len(errs) == 1 {
+		heading = "1 error:"
+	}
+	fmt.Println(styleError.Render(heading))
+	for _, msg := range errs {
+		fmt.Println(styleIndent.Render(msg))
+	}
+}
+
 // PrintError prints an error message
 func (b *BuildOutput) PrintError(msg string) {
 	errLine := styleError.Render("✗ Error: ") + msg
 	fmt.Println(styleIndent.Render(errLine))
 }
 
+// PrintDiagnostic prints a rendered diagnostic snippet
+func (b *BuildOutput) PrintDiagnostic(snippet string) {
+	fmt.Println(styleIndent.Render(strings.TrimRight(snippet, "\n")))
+}
+
 // PrintWarning prints a warning message
 func (b *BuildOutput) PrintWarning(msg string) {
 	warnLine := styleWarning.Render("⚠ Warning: ") + msg
@@ -393,8 +481,16 @@ func PrintDingoHelp(version string) {
 	// Commands
 	fmt.Println(section.Render("Available Commands:"))
 	commands := []struct{ name, desc string }{
+		{"init", "Create a new Dingo project in the current directory"},
 		{"build", "Transpile Dingo source files to Go"},
 		{"run", "Compile and run a Dingo program"},
+		{"check", "Type-check Dingo packages without writing files"},
+		{"test", "Transpile packages and run their tests"},
+		{"fmt", "Format Dingo source files"},
+		{"migrate", "Rewrite Go source files as Dingo"},
+		{"clean", "Remove generated Go files and build caches"},
+		{"expand", "Show the source after each transpiler stage"},
+		{"trace", "Map panics and stack traces back to .dingo lines"},
 		{"version", "Print the version number of Dingo"},
 		{"help", "Help about any command"},
 	}
@@ -0,0 +1,245 @@
+// Package watch provides a debounced, recursive file watcher for .dingo sources.
+// It is shared by the CLI (--watch) and the language server.
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce batches rapid saves (editor auto-save, format-on-save)
+const DefaultDebounce = 500 * time.Millisecond
+
+// ignoreDirs are never watched
+var ignoreDirs = []string{
+	"node_modules",
+	"vendor",
+	".git",
+	".dingo-cache",
+	"dist",
+	"build",
+	".idea",
+	".vscode",
+	"bin",
+	"obj",
+}
+
+// Logger is the logging interface used by Watcher
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Watcher monitors a directory tree for .dingo file changes
+type Watcher struct {
+	watcher       *fsnotify.Watcher
+	logger        Logger
+	onChange      func(dingoPaths []string)
+	debounceTimer *time.Timer
+	debounceDur   time.Duration
+	pendingFiles  map[string]bool
+	mu            sync.Mutex
+	done          chan struct{}
+	closed        bool
+}
+
+// New creates a watcher rooted at root. onChange receives every .dingo file
+// written or created within one debounce window, sorted by path.
+// A nil logger discards all log output.
+func New(root string, logger Logger, onChange func(dingoPaths []string)) (*Watcher, error) {
+	return NewWithDebounce(root, logger, DefaultDebounce, onChange)
+}
+
+// NewWithDebounce is like New with a custom debounce duration
+func NewWithDebounce(root string, logger Logger, debounce time.Duration, onChange func(dingoPaths []string)) (*Watcher, error) {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		watcher:      watcher,
+		logger:       logger,
+		onChange:     onChange,
+		debounceDur:  debounce,
+		pendingFiles: make(map[string]bool),
+		done:         make(chan struct{}),
+	}
+
+	// Watch root recursively
+	if err := w.watchRecursive(root); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	// Start event loop
+	go w.watchLoop()
+
+	logger.Infof("File watcher started (root: %s, debounce: %s)", root, w.debounceDur)
+	return w, nil
+}
+
+// watchRecursive adds all directories below root to the watcher
+func (w *Watcher) watchRecursive(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip ignored directories
+		if info.IsDir() && path != root && ShouldIgnoreDir(path) {
+			w.logger.Debugf("Ignoring directory: %s", path)
+			return filepath.SkipDir
+		}
+
+		// Watch directories only (fsnotify watches files within directories)
+		if info.IsDir() {
+			if err := w.watcher.Add(path); err != nil {
+				w.logger.Warnf("Failed to watch %s: %v", path, err)
+			} else {
+				w.logger.Debugf("Watching directory: %s", path)
+			}
+		}
+
+		return nil
+	})
+}
+
+// ShouldIgnoreDir reports whether a directory is skipped by the watcher
+func ShouldIgnoreDir(path string) bool {
+	base := filepath.Base(path)
+
+	for _, ignore := range ignoreDirs {
+		if base == ignore {
+			return true
+		}
+	}
+
+	// Ignore hidden directories (start with .)
+	if strings.HasPrefix(base, ".") && base != "." {
+		return true
+	}
+
+	return false
+}
+
+// watchLoop processes file system events
+func (w *Watcher) watchLoop() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			// Start watching directories created after startup
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				info, err := os.Stat(event.Name)
+				if err == nil && info.IsDir() {
+					if !ShouldIgnoreDir(event.Name) {
+						if err := w.watcher.Add(event.Name); err != nil {
+							w.logger.Warnf("Failed to watch new directory %s: %v", event.Name, err)
+						} else {
+							w.logger.Debugf("Started watching new directory: %s", event.Name)
+						}
+					}
+				}
+			}
+
+			// Only .dingo files trigger rebuilds
+			if !strings.HasSuffix(event.Name, ".dingo") {
+				continue
+			}
+
+			// Handle write/create events
+			if event.Op&fsnotify.Write == fsnotify.Write ||
+				event.Op&fsnotify.Create == fsnotify.Create {
+				w.logger.Debugf("File event: %s (%s)", event.Name, event.Op.String())
+				w.handleFileChange(event.Name)
+			}
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Errorf("File watcher error: %v", err)
+
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// handleFileChange adds a file to the pending set and resets the debounce timer
+func (w *Watcher) handleFileChange(dingoPath string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	w.pendingFiles[dingoPath] = true
+
+	if w.debounceTimer != nil {
+		w.debounceTimer.Stop()
+	}
+
+	w.debounceTimer = time.AfterFunc(w.debounceDur, w.processPendingFiles)
+}
+
+// processPendingFiles delivers all files that changed within the debounce window
+func (w *Watcher) processPendingFiles() {
+	w.mu.Lock()
+	if w.closed || len(w.pendingFiles) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	files := make([]string, 0, len(w.pendingFiles))
+	for path := range w.pendingFiles {
+		files = append(files, path)
+	}
+	w.pendingFiles = make(map[string]bool)
+	w.mu.Unlock()
+
+	sort.Strings(files)
+	w.logger.Debugf("Processing %d debounced file change(s)", len(files))
+	w.onChange(files)
+}
+
+// Close stops the watcher (idempotent)
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil // Already closed
+	}
+
+	w.closed = true
+	if w.debounceTimer != nil {
+		w.debounceTimer.Stop()
+	}
+	close(w.done)
+	return w.watcher.Close()
+}
+
+// nopLogger discards all log output
+type nopLogger struct{}
+
+func (nopLogger) Debugf(string, ...interface{}) {}
+func (nopLogger) Infof(string, ...interface{})  {}
+func (nopLogger) Warnf(string, ...interface{})  {}
+func (nopLogger) Errorf(string, ...interface{}) {}
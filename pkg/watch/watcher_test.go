@@ -0,0 +1,86 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatcher_BatchesChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.dingo")
+	b := filepath.Join(tmpDir, "b.dingo")
+
+	batches := make(chan []string, 10)
+	w, err := NewWithDebounce(tmpDir, nil, 100*time.Millisecond, func(paths []string) {
+		batches <- paths
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	for _, path := range []string{b, a, b} {
+		if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	select {
+	case got := <-batches:
+		want := []string{a, b}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected batch %v, got %v", want, got)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for batch")
+	}
+}
+
+func TestWatcher_IgnoresNonDingoAndIgnoredDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	vendorDir := filepath.Join(tmpDir, "vendor")
+	if err := os.MkdirAll(vendorDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	batches := make(chan []string, 10)
+	w, err := NewWithDebounce(tmpDir, nil, 50*time.Millisecond, func(paths []string) {
+		batches <- paths
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(vendorDir, "dep.dingo"), []byte("package dep\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-batches:
+		t.Errorf("Expected no events, got %v", got)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestShouldIgnoreDir(t *testing.T) {
+	tests := map[string]bool{
+		"/src/node_modules": true,
+		"/src/.git":         true,
+		"/src/.hidden":      true,
+		"/src/.dingo-cache": true,
+		"/src/pkg":          false,
+		".":                 false,
+	}
+	for path, want := range tests {
+		if got := ShouldIgnoreDir(path); got != want {
+			t.Errorf("ShouldIgnoreDir(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package dingo
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestOption(t *testing.T) {
+	var zero Option[int]
+	if !zero.IsNone() {
+		t.Error("zero Option is not None")
+	}
+
+	some := Some(21)
+	if !some.IsSome() || some.Unwrap() != 21 {
+		t.Errorf("Some(21) = %+v", some)
+	}
+	if got := some.Map(func(n int) int { return n * 2 }).UnwrapOr(0); got != 42 {
+		t.Errorf("Map = %d, want 42", got)
+	}
+	if got := None[int]().UnwrapOr(7); got != 7 {
+		t.Errorf("None UnwrapOr = %d, want 7", got)
+	}
+	if got := some.Filter(func(n int) bool { return n > 30 }); !got.IsNone() {
+		t.Errorf("Filter kept %+v", got)
+	}
+	if got := MapOption(some, strconv.Itoa).Unwrap(); got != "21" {
+		t.Errorf("MapOption = %q, want \"21\"", got)
+	}
+
+	if value, ok := some.Get(); !ok || value != 21 {
+		t.Errorf("Get = %d, %v", value, ok)
+	}
+	if p := FromPtr(some.ToPtr()); p.Unwrap() != 21 {
+		t.Errorf("FromPtr(ToPtr) = %+v", p)
+	}
+	if p := None[int]().ToPtr(); p != nil {
+		t.Errorf("None ToPtr = %v, want nil", p)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Unwrap on None did not panic")
+		}
+	}()
+	None[string]().Unwrap()
+}
+
+func TestResult(t *testing.T) {
+	ok := FromGo(strconv.Atoi("21"))
+	if !ok.IsOk() || ok.Unwrap() != 21 {
+		t.Errorf("FromGo(21, nil) = %+v", ok)
+	}
+	failed := FromGo(strconv.Atoi("x"))
+	if !failed.IsErr() || failed.UnwrapErr() == nil {
+		t.Errorf("FromGo(0, err) = %+v", failed)
+	}
+	if got := failed.UnwrapOr(-1); got != -1 {
+		t.Errorf("UnwrapOr = %d, want -1", got)
+	}
+	if got := failed.Ok(); !got.IsNone() {
+		t.Errorf("Ok of Err = %+v", got)
+	}
+
+	wrapped := failed.MapErr(func(err error) error { return errors.New("wrapped") })
+	if wrapped.UnwrapErr().Error() != "wrapped" {
+		t.Errorf("MapErr = %v", wrapped.UnwrapErr())
+	}
+	doubled := MapResult(ok, func(n int) string { return strconv.Itoa(n * 2) })
+	if value, err := doubled.Get(); value != "42" || err != nil {
+		t.Errorf("MapResult = %q, %v", value, err)
+	}
+	if got := Err[int](errors.New("x")).Or(Ok[int, error](1)).Unwrap(); got != 1 {
+		t.Errorf("Or = %d, want 1", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("UnwrapErr on Ok did not panic")
+		}
+	}()
+	ok.UnwrapErr()
+}
@@ -0,0 +1,133 @@
+// Package dingo is the runtime library of Dingo's generic type mode
+// (type_generation = "generic" in dingo.toml). Generated code uses
+// Option[T] and Result[T, E] from this package in place of the Option_int,
+// Result_int_error, ... types that are otherwise declared for every
+// instantiation.
+//
+// The types are plain Go generics, so Go code can use them directly:
+//
+//	user, ok := FindUser(id).Get()
+package dingo
+
+// OptionTag identifies the variant of an Option
+type OptionTag uint8
+
+const (
+	OptionTagNone OptionTag = iota // The zero Option is None
+	OptionTagSome
+)
+
+// Option holds a value of type T (Some) or nothing (None)
+type Option[T any] struct {
+	tag  OptionTag
+	some T
+}
+
+// Some returns an Option holding value
+func Some[T any](value T) Option[T] {
+	return Option[T]{tag: OptionTagSome, some: value}
+}
+
+// None returns an empty Option
+func None[T any]() Option[T] {
+	return Option[T]{tag: OptionTagNone}
+}
+
+// FromPtr returns None for a nil pointer and Some of the value p points to
+// otherwise
+func FromPtr[T any](p *T) Option[T] {
+	if p == nil {
+		return None[T]()
+	}
+	return Some(*p)
+}
+
+// IsSome reports whether o holds a value
+func (o Option[T]) IsSome() bool {
+	return o.tag == OptionTagSome
+}
+
+// IsNone reports whether o is empty
+func (o Option[T]) IsNone() bool {
+	return o.tag == OptionTagNone
+}
+
+// Unwrap returns the value of o. It panics if o is None.
+func (o Option[T]) Unwrap() T {
+	if o.tag != OptionTagSome {
+		panic("called Unwrap on None")
+	}
+	return o.some
+}
+
+// UnwrapOr returns the value of o, or defaultValue if o is None
+func (o Option[T]) UnwrapOr(defaultValue T) T {
+	if o.tag == OptionTagSome {
+		return o.some
+	}
+	return defaultValue
+}
+
+// UnwrapOrElse returns the value of o, or the result of fn if o is None
+func (o Option[T]) UnwrapOrElse(fn func() T) T {
+	if o.tag == OptionTagSome {
+		return o.some
+	}
+	return fn()
+}
+
+// Get returns the value of o and whether there is one, the Go way
+func (o Option[T]) Get() (T, bool) {
+	return o.some, o.tag == OptionTagSome
+}
+
+// ToPtr returns a pointer to a copy of the value of o, or nil if o is None
+func (o Option[T]) ToPtr() *T {
+	if o.tag != OptionTagSome {
+		return nil
+	}
+	value := o.some
+	return &value
+}
+
+// Map returns Some of fn applied to the value of o, or None if o is None.
+// See MapOption to change the type of the value.
+func (o Option[T]) Map(fn func(T) T) Option[T] {
+	if o.tag != OptionTagSome {
+		return o
+	}
+	return Some(fn(o.some))
+}
+
+// AndThen returns fn applied to the value of o, or None if o is None
+func (o Option[T]) AndThen(fn func(T) Option[T]) Option[T] {
+	if o.tag != OptionTagSome {
+		return o
+	}
+	return fn(o.some)
+}
+
+// Filter returns o if it holds a value satisfying predicate, None otherwise
+func (o Option[T]) Filter(predicate func(T) bool) Option[T] {
+	if o.tag == OptionTagSome && predicate(o.some) {
+		return o
+	}
+	return None[T]()
+}
+
+// Or returns o if it holds a value, other otherwise
+func (o Option[T]) Or(other Option[T]) Option[T] {
+	if o.tag == OptionTagSome {
+		return o
+	}
+	return other
+}
+
+// MapOption returns Some of fn applied to the value of o, or None if o is
+// None
+func MapOption[T, U any](o Option[T], fn func(T) U) Option[U] {
+	if o.tag != OptionTagSome {
+		return None[U]()
+	}
+	return Some(fn(o.some))
+}
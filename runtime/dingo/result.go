@@ -0,0 +1,148 @@
+package dingo
+
+// ResultTag identifies the variant of a Result
+type ResultTag uint8
+
+const (
+	ResultTagOk ResultTag = iota
+	ResultTagErr
+)
+
+// Result holds either a value of type T (Ok) or an error of type E (Err)
+type Result[T, E any] struct {
+	tag ResultTag
+	ok  T
+	err E
+}
+
+// Ok returns a successful Result holding value
+func Ok[T, E any](value T) Result[T, E] {
+	return Result[T, E]{tag: ResultTagOk, ok: value}
+}
+
+// Err returns a failed Result holding err
+func Err[T, E any](err E) Result[T, E] {
+	return Result[T, E]{tag: ResultTagErr, err: err}
+}
+
+// FromGo converts the (T, error) results of a Go function to a Result
+func FromGo[T any](value T, err error) Result[T, error] {
+	if err != nil {
+		return Err[T](err)
+	}
+	return Ok[T, error](value)
+}
+
+// IsOk reports whether r holds a value
+func (r Result[T, E]) IsOk() bool {
+	return r.tag == ResultTagOk
+}
+
+// IsErr reports whether r holds an error
+func (r Result[T, E]) IsErr() bool {
+	return r.tag == ResultTagErr
+}
+
+// Unwrap returns the value of r. It panics if r is Err.
+func (r Result[T, E]) Unwrap() T {
+	if r.tag != ResultTagOk {
+		panic("called Unwrap on Err")
+	}
+	return r.ok
+}
+
+// UnwrapErr returns the error of r. It panics if r is Ok.
+func (r Result[T, E]) UnwrapErr() E {
+	if r.tag != ResultTagErr {
+		panic("called UnwrapErr on Ok")
+	}
+	return r.err
+}
+
+// UnwrapOr returns the value of r, or defaultValue if r is Err
+func (r Result[T, E]) UnwrapOr(defaultValue T) T {
+	if r.tag == ResultTagOk {
+		return r.ok
+	}
+	return defaultValue
+}
+
+// UnwrapOrElse returns the value of r, or fn applied to the error if r is
+// Err
+func (r Result[T, E]) UnwrapOrElse(fn func(E) T) T {
+	if r.tag == ResultTagOk {
+		return r.ok
+	}
+	return fn(r.err)
+}
+
+// Get returns the value and the error of r, the Go way: one of them is the
+// zero value
+func (r Result[T, E]) Get() (T, E) {
+	return r.ok, r.err
+}
+
+// Ok returns the value of r as an Option: None if r is Err
+func (r Result[T, E]) Ok() Option[T] {
+	if r.tag != ResultTagOk {
+		return None[T]()
+	}
+	return Some(r.ok)
+}
+
+// Err returns the error of r as an Option: None if r is Ok
+func (r Result[T, E]) Err() Option[E] {
+	if r.tag != ResultTagErr {
+		return None[E]()
+	}
+	return Some(r.err)
+}
+
+// Map returns Ok of fn applied to the value of r, or r if it is Err.
+// See MapResult to change the type of the value.
+func (r Result[T, E]) Map(fn func(T) T) Result[T, E] {
+	if r.tag != ResultTagOk {
+		return r
+	}
+	return Ok[T, E](fn(r.ok))
+}
+
+// MapErr returns Err of fn applied to the error of r, or r if it is Ok
+func (r Result[T, E]) MapErr(fn func(E) E) Result[T, E] {
+	if r.tag != ResultTagErr {
+		return r
+	}
+	return Err[T](fn(r.err))
+}
+
+// AndThen returns fn applied to the value of r, or r if it is Err
+func (r Result[T, E]) AndThen(fn func(T) Result[T, E]) Result[T, E] {
+	if r.tag != ResultTagOk {
+		return r
+	}
+	return fn(r.ok)
+}
+
+// OrElse returns fn applied to the error of r, or r if it is Ok
+func (r Result[T, E]) OrElse(fn func(E) Result[T, E]) Result[T, E] {
+	if r.tag != ResultTagErr {
+		return r
+	}
+	return fn(r.err)
+}
+
+// Or returns r if it is Ok, other otherwise
+func (r Result[T, E]) Or(other Result[T, E]) Result[T, E] {
+	if r.tag == ResultTagOk {
+		return r
+	}
+	return other
+}
+
+// MapResult returns Ok of fn applied to the value of r, or the error of r
+func MapResult[T, U, E any](r Result[T, E], fn func(T) U) Result[U, E] {
+	if r.tag != ResultTagOk {
+		return Err[U](r.err)
+	}
+	return Ok[U, E](fn(r.ok))
+}